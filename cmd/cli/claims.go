@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/stores"
+	"github.com/lulzshadowwalker/green-backend/internal/service"
+)
+
+func claimsUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: cli claims <list|approve|reject> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "  list [-status pending]                     list device provisioning claims")
+	fmt.Fprintln(os.Stderr, "  approve [-name N] [-zone Z] [-by B] <id>   admit a device")
+	fmt.Fprintln(os.Stderr, "  reject [-by B] <id>                        turn a device away")
+}
+
+// runClaims reviews devices asking to be provisioned, as an alternative to
+// the /api/provisioning endpoints
+func runClaims(args []string) error {
+	if len(args) < 1 {
+		claimsUsage()
+		return errors.New("a claims subcommand is required")
+	}
+
+	fs := flag.NewFlagSet("claims "+args[0], flag.ContinueOnError)
+	status := fs.String("status", internal.ClaimPending, "only list claims with this status; empty lists all")
+	name := fs.String("name", "", "name to register the device under")
+	zone := fs.String("zone", "", "zone to assign the device to")
+	by := fs.String("by", os.Getenv("USER"), "who is reviewing the claim")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var id int64
+	switch args[0] {
+	case "list":
+	case "approve", "reject":
+		if fs.NArg() != 1 {
+			claimsUsage()
+			return errors.New("exactly one claim id is required")
+		}
+		var err error
+		if id, err = strconv.ParseInt(fs.Arg(0), 10, 64); err != nil {
+			return fmt.Errorf("invalid claim id %q", fs.Arg(0))
+		}
+	default:
+		claimsUsage()
+		return fmt.Errorf("unknown claims subcommand %q", args[0])
+	}
+
+	pool, err := psql.Connect(psql.ConnectionParams{
+		Host:     os.Getenv("DB_HOST"),
+		Port:     os.Getenv("DB_PORT"),
+		Username: os.Getenv("DB_USERNAME"),
+		Password: os.Getenv("DB_PASSWORD"),
+		Name:     os.Getenv("DB_NAME"),
+		SSLMode:  os.Getenv("DB_SSLMODE"),
+	})
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	configs := service.NewDeviceConfigs(stores.NewDeviceConfigs(pool), service.NewDevices(stores.NewDevices(pool), 0))
+	s := service.NewProvisioning(stores.NewProvisioning(pool), configs, 0)
+	ctx := context.Background()
+
+	var out any
+	switch args[0] {
+	case "list":
+		out, err = s.ListDeviceClaims(ctx, *status)
+	case "approve":
+		approval := internal.ClaimApproval{Name: *name, ReviewedBy: *by}
+		if *zone != "" {
+			approval.Zone = zone
+		}
+		out, err = s.ApproveDeviceClaim(ctx, id, approval)
+	case "reject":
+		out, err = s.RejectDeviceClaim(ctx, id, *by)
+	}
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
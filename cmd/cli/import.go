@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal/importer"
+	"github.com/lulzshadowwalker/green-backend/internal/psql"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/stores"
+	"github.com/lulzshadowwalker/green-backend/internal/service"
+)
+
+// multiFlag collects a flag that may be repeated
+type multiFlag []string
+
+func (m *multiFlag) String() string {
+	return strings.Join(*m, ",")
+}
+
+func (m *multiFlag) Set(v string) error {
+	*m = append(*m, v)
+	return nil
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "source format: csv or ndjson (default: from file extension)")
+	tsColumn := fs.String("timestamp-column", "", "column holding the reading time (default: auto-detect)")
+	sensorColumn := fs.String("sensor-column", "", "column holding the sensor name, for one-reading-per-row sources")
+	valueColumn := fs.String("value-column", "value", "column holding the value, for one-reading-per-row sources")
+	unitColumn := fs.String("unit-column", "", "column holding a per-row unit, for one-reading-per-row sources")
+	tz := fs.String("tz", "UTC", "time zone of timestamps that carry no offset")
+	layout := fs.String("time-layout", "", "Go time layout, or unix / unixms (default: auto-detect)")
+	dryRun := fs.Bool("dry-run", false, "validate and report without writing anything")
+	var columns, units multiFlag
+	fs.Var(&columns, "map", "map a column to a sensor, e.g. -map \"Soil %=soilMoisture\" (repeatable)")
+	fs.Var(&units, "unit", "unit a sensor is recorded in, e.g. -unit temperature=F (repeatable)")
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: cli import [flags] <file|->")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("exactly one source file is required")
+	}
+	path := fs.Arg(0)
+
+	opts := importer.Options{
+		Mapping: importer.Mapping{
+			TimestampColumn: *tsColumn,
+			SensorColumn:    *sensorColumn,
+			ValueColumn:     *valueColumn,
+			UnitColumn:      *unitColumn,
+		},
+		TimeLayout: *layout,
+	}
+
+	var err error
+	if opts.Mapping.Columns, err = importer.ParseAssignments(columns); err != nil {
+		return err
+	}
+	if opts.Mapping.Units, err = importer.ParseAssignments(units); err != nil {
+		return err
+	}
+	if opts.Location, err = time.LoadLocation(*tz); err != nil {
+		return fmt.Errorf("unknown time zone %q", *tz)
+	}
+
+	f := *format
+	if f == "" {
+		f = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	if opts.Format, err = importer.ParseFormat(f); err != nil {
+		return err
+	}
+
+	var src io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		src = file
+	}
+
+	pool, err := psql.Connect(psql.ConnectionParams{
+		Host:     os.Getenv("DB_HOST"),
+		Port:     os.Getenv("DB_PORT"),
+		Username: os.Getenv("DB_USERNAME"),
+		Password: os.Getenv("DB_PASSWORD"),
+		Name:     os.Getenv("DB_NAME"),
+		SSLMode:  os.Getenv("DB_SSLMODE"),
+	})
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	// Imported readings are calibrated and graded as the server would
+	r := stores.NewSensorReadings(pool)
+	quality := service.NewSensorQuality(stores.NewSensorQuality(db.New(pool)), r)
+	calibrations := service.NewSensorCalibrations(stores.NewSensorCalibrations(db.New(pool)), r, quality)
+	s := service.NewSensorReadings(r,
+		service.WithCalibrator(calibrations),
+		service.WithQualityChecker(quality),
+	)
+	report, err := s.ImportSensorReadings(context.Background(), src, opts, *dryRun)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if encErr := enc.Encode(report); encErr != nil && err == nil {
+		err = encErr
+	}
+
+	return err
+}
@@ -2,8 +2,40 @@ package main
 
 import (
 	"fmt"
+	"os"
 )
 
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: cli <command> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  import    bulk import historical readings from CSV or NDJSON")
+	fmt.Fprintln(os.Stderr, "  claims    review devices asking to be provisioned")
+}
+
 func main() {
-	fmt.Println("hello, cli")
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(os.Args[2:])
+	case "claims":
+		err = runClaims(os.Args[2:])
+	case "-h", "--help", "help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 }
@@ -44,6 +44,17 @@ func main() {
 		os.Exit(1)
 	}
 
+	fmt.Print("Grant admin access? [y/N]: ")
+	answer, err := reader.ReadString('\n')
+	if err != nil {
+		fmt.Printf("Failed to read answer: %v\n", err)
+		os.Exit(1)
+	}
+	role := internal.RoleUser
+	if a := strings.ToLower(strings.TrimSpace(answer)); a == "y" || a == "yes" {
+		role = internal.RoleAdmin
+	}
+
 	pool, err := psql.Connect(psql.ConnectionParams{
 		Host:     os.Getenv("DB_HOST"),
 		Port:     os.Getenv("DB_PORT"),
@@ -91,13 +102,18 @@ func main() {
 		}
 	}
 
+	if err := q.UpdateUserRole(ctx, db.UpdateUserRoleParams{ID: user.ID, Role: role}); err != nil {
+		fmt.Printf("Failed to set user role: %v\n", err)
+		os.Exit(1)
+	}
+
 	token, err := internal.GenerateJWT(int(user.ID), user.Username)
 	if err != nil {
 		fmt.Printf("Failed to generate JWT token: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Println("\nUser created/updated successfully!")
+	fmt.Printf("\nUser created/updated successfully with the %s role!\n", role)
 	fmt.Println("JWT access token (save this somewhere safe):")
 	fmt.Printf("\n%s\n\n", token)
 	fmt.Println("Use this token as a Bearer token for API access.")
@@ -0,0 +1,215 @@
+// Package advisor gives plain-language plant care advice from a plant's
+// profile, alert thresholds and recent readings, without a language model.
+// It is what farmers get when no LLM is reachable.
+package advisor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// Daylight hours, in the server's time zone, when low light is worth
+// mentioning
+const (
+	dayStartHour = 8
+	dayEndHour   = 18
+)
+
+// sensorOrder is the order advice is given in, most urgent first
+var sensorOrder = []string{"temperature", "soil", "humidity", "water", "light"}
+
+// Recommendation is one piece of advice about a sensor
+type Recommendation struct {
+	SensorType string `json:"sensor_type"`
+	// Problem is "low" or "high"; empty means all is well
+	Problem string  `json:"problem,omitempty"`
+	Value   float64 `json:"value"`
+	// Since is when the problem started, as far back as the readings go
+	Since   time.Time `json:"since"`
+	Message string    `json:"message"`
+}
+
+// Input is what advice is based on
+type Input struct {
+	Profile internal.PlantProfile
+	// Rules are alert rules; enabled, farm-wide threshold rules tighten the
+	// profile's ranges
+	Rules    []internal.AlertRule
+	Readings []internal.SensorReading
+	Now      time.Time
+	// Locale is the language and units messages are written in; English
+	// and Celsius by default
+	Locale internal.Locale
+}
+
+// Limits returns the acceptable range per sensor: the profile's, narrowed by
+// any threshold alert rules
+func Limits(profile internal.PlantProfile, rules []internal.AlertRule) map[string]internal.Range {
+	limits := make(map[string]internal.Range, len(profile.Ranges))
+	for sensor, r := range profile.Ranges {
+		limits[sensor] = r
+	}
+
+	for _, rule := range rules {
+		if !rule.Enabled || rule.Kind != internal.AlertThreshold || rule.Zone != nil {
+			continue
+		}
+		r, ok := limits[rule.SensorType]
+		if !ok {
+			continue
+		}
+		if rule.Comparison == internal.ComparisonBelow {
+			r.Min = max(r.Min, rule.Threshold)
+		} else {
+			r.Max = min(r.Max, rule.Threshold)
+		}
+		limits[rule.SensorType] = r
+	}
+
+	return limits
+}
+
+// Advise checks the latest reading of each sensor against its limits and
+// says what to do about any that are out of range, and for how long they
+// have been
+func Advise(in Input) []Recommendation {
+	limits := Limits(in.Profile, in.Rules)
+
+	bySensor := make(map[string][]internal.SensorReading)
+	for _, r := range in.Readings {
+		if r.Quality == internal.QualityBad {
+			continue
+		}
+		bySensor[r.SensorType] = append(bySensor[r.SensorType], r)
+	}
+
+	var recs []Recommendation
+	for _, sensor := range sensorOrder {
+		limit, ok := limits[sensor]
+		if !ok {
+			continue
+		}
+
+		// Not every farm has every sensor; silent ones are for missing data
+		// alerts to catch
+		readings := bySensor[sensor]
+		if len(readings) == 0 {
+			continue
+		}
+		slices.SortFunc(readings, func(a, b internal.SensorReading) int {
+			return a.Timestamp.Compare(b.Timestamp)
+		})
+
+		latest := readings[len(readings)-1]
+		rec := Recommendation{SensorType: sensor, Value: latest.Value, Since: latest.Timestamp}
+		var outside func(float64) bool
+		switch {
+		case latest.Value < limit.Min:
+			rec.Problem = "low"
+			outside = func(v float64) bool { return v < limit.Min }
+		case latest.Value > limit.Max:
+			rec.Problem = "high"
+			outside = func(v float64) bool { return v > limit.Max }
+		default:
+			recs = append(recs, rec)
+			continue
+		}
+
+		// Low light is only a problem while the sun should be up
+		if sensor == "light" && rec.Problem == "low" {
+			if h := in.Now.Hour(); h < dayStartHour || h >= dayEndHour {
+				rec.Problem = ""
+				recs = append(recs, rec)
+				continue
+			}
+		}
+
+		for i := len(readings) - 1; i >= 0 && outside(readings[i].Value); i-- {
+			rec.Since = readings[i].Timestamp
+		}
+		rec.Message = message(sensor, rec, limit, in.Now, in.Locale.Or(internal.DefaultLocale))
+		recs = append(recs, rec)
+	}
+
+	return recs
+}
+
+// Render writes recommendations as plain text, one per line, in locale
+func Render(plant string, recs []Recommendation, locale internal.Locale) string {
+	locale = locale.Or(internal.DefaultLocale)
+	p := phrasesFor(locale)
+	if len(recs) == 0 {
+		return p.noReadings
+	}
+
+	var b strings.Builder
+	var fine []string
+	problems := 0
+	for _, rec := range recs {
+		switch rec.Problem {
+		case "":
+			fine = append(fine, fmt.Sprintf("%s %s", p.sensorName(rec.SensorType), formatValue(rec.SensorType, rec.Value, locale)))
+		default:
+			problems++
+			b.WriteString(rec.Message)
+			b.WriteString("\n")
+		}
+	}
+
+	if len(fine) > 0 {
+		if problems == 0 {
+			fmt.Fprintf(&b, p.allGood, p.plantName(plant), p.joinList(fine))
+		} else {
+			fmt.Fprintf(&b, p.restFine, p.joinList(fine))
+		}
+	}
+
+	return b.String()
+}
+
+func message(sensor string, rec Recommendation, limit internal.Range, now time.Time, locale internal.Locale) string {
+	p := phrasesFor(locale)
+	bound := limit.Min
+	side := p.below
+	if rec.Problem == "high" {
+		bound = limit.Max
+		side = p.above
+	}
+
+	condition := fmt.Sprintf(p.condition, capitalize(p.sensorName(sensor)), side, formatValue(sensor, bound, locale))
+	if d := now.Sub(rec.Since); d >= time.Minute {
+		condition += fmt.Sprintf(p.lasting, p.formatDuration(d))
+	}
+	condition += fmt.Sprintf(p.now, formatValue(sensor, rec.Value, locale))
+
+	return condition + " " + p.actions[sensor][rec.Problem]
+}
+
+// formatValue shows a stored value of sensor in the unit locale wants
+func formatValue(sensor string, v float64, locale internal.Locale) string {
+	v, unit := locale.Value(sensor, v), locale.Unit(sensor)
+	switch unit {
+	case "%":
+		return fmt.Sprintf("%.0f%%", v)
+	case "°C", "°F":
+		return fmt.Sprintf("%.1f%s", v, unit)
+	case "":
+		return fmt.Sprintf("%.1f", v)
+	default:
+		return fmt.Sprintf("%.0f %s", v, unit)
+	}
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
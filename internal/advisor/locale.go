@@ -0,0 +1,221 @@
+package advisor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// phrases is the wording of advice in one language
+type phrases struct {
+	noReadings string
+	// allGood takes the plant and the sensors that are fine
+	allGood string
+	// restFine takes the sensors that are fine
+	restFine string
+	// condition takes the sensor, below or above, and the limit crossed
+	condition string
+	below     string
+	above     string
+	// lasting takes how long the problem has lasted
+	lasting string
+	// now takes the latest reading
+	now string
+	// listSep joins list items but the last, which listLast joins
+	listSep  string
+	listLast string
+	// durationSep joins hours and minutes
+	durationSep string
+	// count says how many minutes or hours
+	count       func(n int, unit string) string
+	sensorNames map[string]string
+	plantNames  map[string]string
+	actions     map[string]map[string]string
+}
+
+var english = phrases{
+	noReadings:  "There are no recent sensor readings, so check that your sensors are connected and online.\n",
+	allGood:     "Everything looks good for your %s: %s are all in the ideal range. Keep up your current routine.\n",
+	restFine:    "The rest looks fine: %s.\n",
+	condition:   "%s has been %s %s",
+	below:       "below",
+	above:       "above",
+	lasting:     " for %s",
+	now:         " (now %s).",
+	listSep:     ", ",
+	listLast:    " and ",
+	durationSep: " ",
+	count: func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	},
+	sensorNames: map[string]string{
+		"soil":  "soil moisture",
+		"water": "water level",
+	},
+	actions: map[string]map[string]string{
+		"temperature": {
+			"low":  "Close the vents and turn on heating to protect the plants from cold.",
+			"high": "Open the vents, run the fans and shade the plants to cool them down.",
+		},
+		"humidity": {
+			"low":  "The air is dry: mist the plants or damp down the paths, and ventilate a little less.",
+			"high": "The air is damp: ventilate to keep mould and fungal disease away.",
+		},
+		"soil": {
+			"low":  "Water now.",
+			"high": "Hold off watering and check that the beds drain well.",
+		},
+		"light": {
+			"low":  "Turn on grow lights if you have them.",
+			"high": "Use shade cloth to stop the leaves from scorching.",
+		},
+		"water": {
+			"low":  "Refill the water tank soon so irrigation does not run dry.",
+			"high": "Check the tank is not overflowing.",
+		},
+	},
+}
+
+var arabic = phrases{
+	noReadings:  "لا توجد قراءات حديثة من الحساسات، لذا تأكد من أن الحساسات موصولة وتعمل.\n",
+	allGood:     "كل شيء يبدو جيدًا لنبات %s: %s كلها ضمن النطاق المثالي. استمر على روتينك الحالي.\n",
+	restFine:    "باقي القراءات جيدة: %s.\n",
+	condition:   "%s %s %s",
+	below:       "أقل من",
+	above:       "أعلى من",
+	lasting:     " منذ %s",
+	now:         " (القراءة الآن %s).",
+	listSep:     "، ",
+	listLast:    " و",
+	durationSep: " و",
+	count:       arabicCount,
+	sensorNames: map[string]string{
+		"temperature": "درجة الحرارة",
+		"humidity":    "الرطوبة",
+		"soil":        "رطوبة التربة",
+		"light":       "الإضاءة",
+		"water":       "مستوى الماء",
+		"vpd":         "عجز ضغط البخار",
+		"dew_point":   "نقطة الندى",
+		"heat_index":  "مؤشر الحرارة",
+		"dli":         "التكامل الضوئي اليومي",
+	},
+	plantNames: map[string]string{
+		"strawberry": "الفراولة",
+		"tomato":     "الطماطم",
+		"cucumber":   "الخيار",
+		"lettuce":    "الخس",
+		"pepper":     "الفلفل",
+		"basil":      "الريحان",
+		"plant":      "النبات",
+	},
+	actions: map[string]map[string]string{
+		"temperature": {
+			"low":  "أغلق فتحات التهوية وشغّل التدفئة لحماية النباتات من البرد.",
+			"high": "افتح فتحات التهوية وشغّل المراوح وظلّل النباتات لتبريدها.",
+		},
+		"humidity": {
+			"low":  "الهواء جاف: رشّ النباتات بالماء أو بلّل الممرات، وقلّل التهوية قليلًا.",
+			"high": "الهواء رطب: زِد التهوية لإبعاد العفن والأمراض الفطرية.",
+		},
+		"soil": {
+			"low":  "اسقِ النباتات الآن.",
+			"high": "أوقف الري مؤقتًا وتأكد من أن الأحواض تصرّف الماء جيدًا.",
+		},
+		"light": {
+			"low":  "شغّل مصابيح النمو إن وُجدت.",
+			"high": "استخدم شبك التظليل لحماية الأوراق من الاحتراق.",
+		},
+		"water": {
+			"low":  "املأ خزان الماء قريبًا حتى لا يتوقف الري.",
+			"high": "تأكد من أن الخزان لا يفيض.",
+		},
+	},
+}
+
+// arabicUnits are the singular, dual, plural (3 to 10) and counted (11 and
+// up) forms of each unit
+var arabicUnits = map[string][4]string{
+	"minute": {"دقيقة", "دقيقتين", "دقائق", "دقيقة"},
+	"hour":   {"ساعة", "ساعتين", "ساعات", "ساعة"},
+}
+
+func arabicCount(n int, unit string) string {
+	forms := arabicUnits[unit]
+	switch {
+	case n == 1:
+		return forms[0]
+	case n == 2:
+		return forms[1]
+	case n >= 3 && n <= 10:
+		return fmt.Sprintf("%d %s", n, forms[2])
+	default:
+		return fmt.Sprintf("%d %s", n, forms[3])
+	}
+}
+
+func phrasesFor(locale internal.Locale) phrases {
+	if locale.Language == internal.LanguageArabic {
+		return arabic
+	}
+	return english
+}
+
+// SensorName is what sensor is called in locale's language
+func SensorName(sensor string, locale internal.Locale) string {
+	return phrasesFor(locale).sensorName(sensor)
+}
+
+func (p phrases) sensorName(sensor string) string {
+	if name, ok := p.sensorNames[sensor]; ok {
+		return name
+	}
+	return strings.ReplaceAll(sensor, "_", " ")
+}
+
+// PlantName is what plant is called in locale's language
+func PlantName(plant string, locale internal.Locale) string {
+	return phrasesFor(locale).plantName(plant)
+}
+
+// plantName is the plant as the farmer called it, or its name in the
+// language of the advice if it is one advice knows about
+func (p phrases) plantName(plant string) string {
+	if profile, ok := internal.LookupPlantProfile(plant); ok {
+		if name, ok := p.plantNames[profile.Name]; ok {
+			return name
+		}
+	}
+	return plant
+}
+
+func (p phrases) formatDuration(d time.Duration) string {
+	if d < time.Hour {
+		return p.count(int(d.Round(time.Minute)/time.Minute), "minute")
+	}
+	if d < 2*time.Hour {
+		d = d.Round(10 * time.Minute)
+		s := p.count(int(d/time.Hour), "hour")
+		if m := int((d % time.Hour) / time.Minute); m > 0 {
+			s += p.durationSep + p.count(m, "minute")
+		}
+		return s
+	}
+	return p.count(int(d.Round(time.Hour)/time.Hour), "hour")
+}
+
+func (p phrases) joinList(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	default:
+		return strings.Join(items[:len(items)-1], p.listSep) + p.listLast + items[len(items)-1]
+	}
+}
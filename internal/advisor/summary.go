@@ -0,0 +1,173 @@
+package advisor
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// Summary condenses one sensor's readings over a period
+type Summary struct {
+	SensorType string
+	Count      int
+	// From and To are the first and last readings
+	From   time.Time
+	To     time.Time
+	Min    float64
+	Max    float64
+	Mean   float64
+	Latest float64
+	// TrendPerHour is how fast the readings moved, fitted over the whole
+	// period
+	TrendPerHour float64
+	// Limit is the acceptable range, if the plant has one for this sensor
+	Limit *internal.Range
+	// Excursions are the stretches spent outside Limit, oldest first
+	Excursions []Excursion
+}
+
+// Excursion is a stretch of readings outside a sensor's range
+type Excursion struct {
+	// Problem is "low" or "high"
+	Problem string
+	// Bound is the limit that was crossed
+	Bound float64
+	From  time.Time
+	// To is the last reading outside the range
+	To time.Time
+	// Peak is the reading furthest outside the range
+	Peak float64
+	// Ongoing is set when the latest reading is still outside the range
+	Ongoing bool
+}
+
+// Summarize condenses the readings of each sensor into statistics, most
+// urgent sensor first. Bad readings are left out, and so is low light
+// outside daylight hours.
+func Summarize(in Input) []Summary {
+	limits := Limits(in.Profile, in.Rules)
+
+	bySensor := make(map[string][]internal.SensorReading)
+	for _, r := range in.Readings {
+		if r.Quality == internal.QualityBad {
+			continue
+		}
+		bySensor[r.SensorType] = append(bySensor[r.SensorType], r)
+	}
+
+	sensors := make([]string, 0, len(bySensor))
+	for sensor := range bySensor {
+		sensors = append(sensors, sensor)
+	}
+	sort.Slice(sensors, func(i, j int) bool {
+		oi, oj := slices.Index(sensorOrder, sensors[i]), slices.Index(sensorOrder, sensors[j])
+		if oi < 0 {
+			oi = len(sensorOrder)
+		}
+		if oj < 0 {
+			oj = len(sensorOrder)
+		}
+		if oi != oj {
+			return oi < oj
+		}
+		return sensors[i] < sensors[j]
+	})
+
+	summaries := make([]Summary, 0, len(sensors))
+	for _, sensor := range sensors {
+		readings := bySensor[sensor]
+		slices.SortFunc(readings, func(a, b internal.SensorReading) int {
+			return a.Timestamp.Compare(b.Timestamp)
+		})
+
+		s := summarizeSensor(sensor, readings)
+		if limit, ok := limits[sensor]; ok {
+			s.Limit = &limit
+			s.Excursions = excursions(sensor, readings, limit)
+		}
+		summaries = append(summaries, s)
+	}
+
+	return summaries
+}
+
+func summarizeSensor(sensor string, readings []internal.SensorReading) Summary {
+	first, last := readings[0], readings[len(readings)-1]
+	s := Summary{
+		SensorType: sensor,
+		Count:      len(readings),
+		From:       first.Timestamp,
+		To:         last.Timestamp,
+		Min:        first.Value,
+		Max:        first.Value,
+		Latest:     last.Value,
+	}
+
+	// Least squares slope of value over hours since the first reading
+	var sumX, sumY, sumXY, sumXX float64
+	for _, r := range readings {
+		x := r.Timestamp.Sub(first.Timestamp).Hours()
+		sumX += x
+		sumY += r.Value
+		sumXY += x * r.Value
+		sumXX += x * x
+		s.Min = min(s.Min, r.Value)
+		s.Max = max(s.Max, r.Value)
+	}
+	n := float64(len(readings))
+	s.Mean = sumY / n
+	if d := n*sumXX - sumX*sumX; d > 0 {
+		s.TrendPerHour = (n*sumXY - sumX*sumY) / d
+	}
+
+	return s
+}
+
+func excursions(sensor string, readings []internal.SensorReading, limit internal.Range) []Excursion {
+	var res []Excursion
+	var cur *Excursion
+	for _, r := range readings {
+		problem := ""
+		switch {
+		case r.Value < limit.Min:
+			problem = "low"
+		case r.Value > limit.Max:
+			problem = "high"
+		}
+		// Low light is only a problem while the sun should be up
+		if sensor == "light" && problem == "low" {
+			if h := r.Timestamp.Hour(); h < dayStartHour || h >= dayEndHour {
+				problem = ""
+			}
+		}
+
+		if cur != nil && cur.Problem != problem {
+			res = append(res, *cur)
+			cur = nil
+		}
+		if problem == "" {
+			continue
+		}
+		if cur == nil {
+			bound := limit.Min
+			if problem == "high" {
+				bound = limit.Max
+			}
+			cur = &Excursion{Problem: problem, Bound: bound, From: r.Timestamp, Peak: r.Value}
+		}
+		cur.To = r.Timestamp
+		if problem == "low" {
+			cur.Peak = min(cur.Peak, r.Value)
+		} else {
+			cur.Peak = max(cur.Peak, r.Value)
+		}
+	}
+	if cur != nil {
+		cur.Ongoing = true
+		res = append(res, *cur)
+	}
+
+	return res
+}
@@ -0,0 +1,111 @@
+package internal
+
+import "time"
+
+// Alert rule kinds
+const (
+	// AlertThreshold fires when a sensor stays above or below Threshold for
+	// the rule's whole duration
+	AlertThreshold = "threshold"
+	// AlertRateOfChange fires when a sensor moves by more than Threshold
+	// within the rule's duration
+	AlertRateOfChange = "rate_of_change"
+	// AlertMissingData fires when a sensor has not reported for longer than
+	// the rule's duration
+	AlertMissingData = "missing_data"
+	// AlertCommandTimeout fires when a device has not acknowledged a command
+	// for the actuator named by SensorType within the rule's duration
+	AlertCommandTimeout = "command_timeout"
+)
+
+const (
+	ComparisonAbove = "above"
+	ComparisonBelow = "below"
+)
+
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Alert lifecycle: open -> acknowledged -> resolved. Alerts resolve on their
+// own once the rule stops firing, whether or not they were acknowledged.
+const (
+	AlertOpen         = "open"
+	AlertAcknowledged = "acknowledged"
+	AlertResolved     = "resolved"
+)
+
+type AlertRule struct {
+	ID              int64     `json:"id"`
+	Name            string    `json:"name"`
+	SensorType      string    `json:"sensor_type"`
+	Kind            string    `json:"kind"`
+	Comparison      string    `json:"comparison"`
+	Threshold       float64   `json:"threshold"`
+	DurationSeconds int       `json:"duration_seconds"`
+	Severity        string    `json:"severity"`
+	Enabled         bool      `json:"enabled"`
+	Zone            *string   `json:"zone,omitempty"` // only devices in this zone; nil watches every device
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Duration is how long the rule's condition must hold before it fires
+func (r AlertRule) Duration() time.Duration {
+	return time.Duration(r.DurationSeconds) * time.Second
+}
+
+// Breaches reports whether value is on the wrong side of a threshold rule
+func (r AlertRule) Breaches(value float64) bool {
+	if r.Comparison == ComparisonBelow {
+		return value < r.Threshold
+	}
+	return value > r.Threshold
+}
+
+type AlertRuleParams struct {
+	Name            string
+	SensorType      string
+	Kind            string
+	Comparison      string
+	Threshold       float64
+	DurationSeconds int
+	Severity        string
+	Enabled         bool
+	Zone            *string
+}
+
+type Alert struct {
+	ID             int64      `json:"id"`
+	RuleID         int64      `json:"rule_id"`
+	SensorType     string     `json:"sensor_type"`
+	Severity       string     `json:"severity"`
+	Status         string     `json:"status"`
+	Message        string     `json:"message"`
+	Value          *float64   `json:"value,omitempty"`
+	OpenedAt       time.Time  `json:"opened_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy *string    `json:"acknowledged_by,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	// Silenced alerts opened during a silence or maintenance window and
+	// were recorded without notifying anyone
+	Silenced bool `json:"silenced"`
+}
+
+type CreateAlertParams struct {
+	RuleID     int64
+	SensorType string
+	Severity   string
+	Message    string
+	Value      *float64
+	Silenced   bool
+}
+
+// SeverityAtLeast reports whether severity is as serious as min
+func SeverityAtLeast(severity, min string) bool {
+	rank := map[string]int{SeverityInfo: 0, SeverityWarning: 1, SeverityCritical: 2}
+	return rank[severity] >= rank[min]
+}
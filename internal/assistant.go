@@ -0,0 +1,85 @@
+package internal
+
+import "time"
+
+// Assistant message roles
+const (
+	AssistantRoleUser      = "user"
+	AssistantRoleAssistant = "assistant"
+)
+
+// Conversation is one user's chat with the greenhouse assistant about a
+// plant. Older turns are condensed into Summary to keep prompts small;
+// Summary covers every message up to and including SummarizedThrough.
+type Conversation struct {
+	ID                int64     `json:"id"`
+	UserID            int       `json:"user_id"`
+	Title             string    `json:"title"`
+	Plant             string    `json:"plant"`
+	Summary           string    `json:"-"`
+	SummarizedThrough int64     `json:"-"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type ConversationMessage struct {
+	ID             int64  `json:"id"`
+	ConversationID int64  `json:"conversation_id"`
+	Role           string `json:"role"`
+	Content        string `json:"content"`
+	// Source says whether an assistant reply came from the language model or
+	// the offline advisor
+	Source    *string   `json:"source,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateConversationParams struct {
+	Title string
+	Plant string
+}
+
+type CreateConversationMessageParams struct {
+	ConversationID int64
+	Role           string
+	Content        string
+	Source         *string
+	// Title names the conversation if it does not have a name yet
+	Title string
+}
+
+// Aggregates a history query can ask for
+const (
+	QueryMin   = "min"
+	QueryMax   = "max"
+	QueryAvg   = "avg"
+	QueryCount = "count"
+)
+
+// QueryPlan is a question about sensor history reduced to one aggregate of
+// one sensor over a period. The language model fills it in; it is validated
+// and run against the aggregate store, never turned into SQL.
+type QueryPlan struct {
+	SensorType string    `json:"sensor_type"`
+	Aggregate  string    `json:"aggregate"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Timezone   string    `json:"timezone"`
+}
+
+// QueryAnswer answers a question about sensor history
+type QueryAnswer struct {
+	Question string `json:"question"`
+	// Answer is the result in a sentence, written from the numbers rather
+	// than by the language model
+	Answer string `json:"answer"`
+	// Value is the aggregate asked for; nil when there were no readings
+	Value *float64 `json:"value"`
+	Unit  string   `json:"unit"`
+	// At is when a minimum or maximum was read
+	At    *time.Time `json:"at,omitempty"`
+	Min   *float64   `json:"min"`
+	Max   *float64   `json:"max"`
+	Avg   *float64   `json:"avg"`
+	Count int64      `json:"count"`
+	Plan  QueryPlan  `json:"plan"`
+}
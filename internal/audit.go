@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audited entity types
+const (
+	AuditDeviceConfig = "device_config"
+	AuditDeviceClaim  = "device_claim"
+	// AuditControlProposal covers proposals and, once approved, the control
+	// change made or the error that stopped it
+	AuditControlProposal = "control_proposal"
+	// AuditSensorControl covers automation being paused and resumed by
+	// silences and maintenance windows
+	AuditSensorControl = "sensor_control"
+)
+
+// Audited actions
+const (
+	AuditUpdate   = "update"
+	AuditApprove  = "approve"
+	AuditReject   = "reject"
+	AuditPropose  = "propose"
+	AuditApply    = "apply"
+	AuditFail     = "fail"
+	AuditSuppress = "suppress"
+	AuditResume   = "resume"
+)
+
+// AuditEntry records a change to an entity and who made it
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Action     string          `json:"action"`
+	Actor      string          `json:"actor"`
+	Data       json.RawMessage `json:"data"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type AuditFilter struct {
+	EntityType string // empty means every entity type
+	EntityID   string // empty means every entity
+}
@@ -0,0 +1,20 @@
+// Package blob stores large binary objects, such as firmware images, on local
+// disk or in an S3-compatible object store like MinIO.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("blob not found")
+
+type Store interface {
+	// Put stores size bytes from r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Open returns the object stored under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	CalibrationLinear     = "linear"
+	CalibrationTwoPoint   = "two_point"
+	CalibrationPolynomial = "polynomial"
+)
+
+// SensorCalibration corrects raw readings of a sensor from EffectiveFrom
+// onwards. Every method is stored as polynomial coefficients in ascending
+// powers, so value = c0 + c1*raw + c2*raw² + ...
+type SensorCalibration struct {
+	ID            int64     `json:"id"`
+	SensorType    string    `json:"sensor_type"`
+	DeviceID      *string   `json:"device_id,omitempty"` // nil applies to every device
+	Method        string    `json:"method"`
+	Coefficients  []float64 `json:"coefficients"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	Note          string    `json:"note"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// RecomputeFrom is where stored readings start to be out of date after
+	// EffectiveFrom was moved, until they are recomputed
+	RecomputeFrom *time.Time `json:"recompute_from,omitempty"`
+}
+
+type CreateSensorCalibrationParams struct {
+	SensorType    string
+	DeviceID      *string
+	Method        string
+	Coefficients  []float64
+	EffectiveFrom time.Time
+	Note          string
+}
+
+type UpdateSensorCalibrationParams struct {
+	Method        string
+	Coefficients  []float64
+	EffectiveFrom *time.Time // nil keeps the stored effective_from
+	Note          string
+}
+
+// Apply returns the calibrated value of raw
+func (c SensorCalibration) Apply(raw float64) float64 {
+	v := 0.0
+	for i := len(c.Coefficients) - 1; i >= 0; i-- {
+		v = v*raw + c.Coefficients[i]
+	}
+	return v
+}
+
+// LinearCoefficients returns the coefficients for value = raw*scale + offset
+func LinearCoefficients(scale, offset float64) []float64 {
+	return []float64{offset, scale}
+}
+
+// TwoPointCoefficients returns the line through two reference measurements,
+// e.g. a soil probe's raw reading in dry air and in water
+func TwoPointCoefficients(raw1, ref1, raw2, ref2 float64) ([]float64, error) {
+	if raw1 == raw2 {
+		return nil, errors.New("two-point calibration needs two different raw values")
+	}
+
+	scale := (ref2 - ref1) / (raw2 - raw1)
+	return LinearCoefficients(scale, ref1-raw1*scale), nil
+}
+
+// EffectiveCalibration picks the calibration that applies to a reading taken
+// by deviceID at the given time. Device specific calibrations win over ones
+// that apply to every device; otherwise the latest one in effect is used.
+func EffectiveCalibration(calibrations []SensorCalibration, deviceID string, at time.Time) *SensorCalibration {
+	var best *SensorCalibration
+	for i := range calibrations {
+		c := &calibrations[i]
+		if c.EffectiveFrom.After(at) {
+			continue
+		}
+		if c.DeviceID != nil && *c.DeviceID != deviceID {
+			continue
+		}
+
+		if best == nil {
+			best = c
+			continue
+		}
+
+		specific, bestSpecific := c.DeviceID != nil, best.DeviceID != nil
+		if specific != bestSpecific {
+			if specific {
+				best = c
+			}
+			continue
+		}
+		if c.EffectiveFrom.After(best.EffectiveFrom) {
+			best = c
+		}
+	}
+
+	return best
+}
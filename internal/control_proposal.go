@@ -0,0 +1,47 @@
+package internal
+
+import "time"
+
+// Control proposal statuses
+const (
+	ProposalPending  = "pending"
+	ProposalApproved = "approved"
+	ProposalRejected = "rejected"
+	// ProposalFailed is an approved proposal whose change could not be
+	// applied
+	ProposalFailed = "failed"
+)
+
+// ControlProposal is a control change suggested by the assistant. It is only
+// applied once an operator approves it.
+type ControlProposal struct {
+	ID              int64  `json:"id"`
+	SensorType      string `json:"sensor_type"`
+	Mode            string `json:"mode"` // "automatic" or "manual"
+	ManualIntValue  *int   `json:"manual_int_value,omitempty"`
+	ManualBoolValue *bool  `json:"manual_bool_value,omitempty"`
+	// DurationMinutes limits a manual override, counted from approval;
+	// nil leaves it in place until changed
+	DurationMinutes *int   `json:"duration_minutes,omitempty"`
+	Reason          string `json:"reason"`
+	Status          string `json:"status"`
+	ProposedBy      string `json:"proposed_by"`
+	// UserID and ConversationID are the chat the proposal came from
+	UserID         *int      `json:"user_id,omitempty"`
+	ConversationID *int64    `json:"conversation_id,omitempty"`
+	ReviewedBy     string    `json:"reviewed_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type CreateControlProposalParams struct {
+	SensorType      string
+	Mode            string
+	ManualIntValue  *int
+	ManualBoolValue *bool
+	DurationMinutes *int
+	Reason          string
+	ProposedBy      string
+	UserID          *int
+	ConversationID  *int64
+}
@@ -0,0 +1,263 @@
+// Package derived computes virtual sensors, such as vapour pressure deficit
+// and daily light integral, from the raw readings stored by the backend.
+package derived
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+const (
+	TypeVPD       = "vpd"
+	TypeDewPoint  = "dew_point"
+	TypeHeatIndex = "heat_index"
+	TypeDLI       = "dli"
+)
+
+// DefaultLuxToPPFD converts lux to µmol/m²/s for natural sunlight. Grow lights
+// have different spectra: roughly 0.014 for white LEDs and 0.0122 for HPS.
+const DefaultLuxToPPFD = 0.0185
+
+type Config struct {
+	// LuxToPPFD is the factor converting light readings (lux) to PPFD
+	LuxToPPFD float64
+	// PairTolerance is how far apart a temperature and a humidity reading may
+	// be and still be treated as taken at the same time
+	PairTolerance time.Duration
+	// MaxLightGap caps how long a single light reading is assumed to hold when
+	// integrating DLI, so that outages are not counted as constant light
+	MaxLightGap time.Duration
+	// Location decides where each day starts for DLI
+	Location *time.Location
+}
+
+func DefaultConfig() Config {
+	return Config{
+		LuxToPPFD:     DefaultLuxToPPFD,
+		PairTolerance: 2 * time.Minute,
+		MaxLightGap:   15 * time.Minute,
+		Location:      time.Local,
+	}
+}
+
+// Sources returns the raw sensor types a virtual sensor is computed from
+func Sources(virtualType string) []string {
+	switch virtualType {
+	case TypeVPD, TypeDewPoint, TypeHeatIndex:
+		return []string{"temperature", "humidity"}
+	case TypeDLI:
+		return []string{"light"}
+	default:
+		return nil
+	}
+}
+
+// SaturationVaporPressure returns the saturation vapour pressure in kPa at
+// tempC using the Tetens equation
+func SaturationVaporPressure(tempC float64) float64 {
+	return 0.6108 * math.Exp(17.27*tempC/(tempC+237.3))
+}
+
+// VPD returns the vapour pressure deficit in kPa
+func VPD(tempC, rh float64) float64 {
+	return SaturationVaporPressure(tempC) * (1 - clampRH(rh)/100)
+}
+
+// DewPoint returns the dew point in °C using the Magnus formula
+func DewPoint(tempC, rh float64) float64 {
+	const a, b = 17.62, 243.12
+	gamma := math.Log(math.Max(clampRH(rh), 0.1)/100) + a*tempC/(b+tempC)
+	return b * gamma / (a - gamma)
+}
+
+// HeatIndex returns the apparent temperature in °C using the NWS Rothfusz
+// regression, falling back to Steadman's simple formula in mild conditions
+func HeatIndex(tempC, rh float64) float64 {
+	rh = clampRH(rh)
+	t := tempC*9/5 + 32
+
+	hi := 0.5 * (t + 61 + (t-68)*1.2 + rh*0.094)
+	if (hi+t)/2 >= 80 {
+		hi = -42.379 + 2.04901523*t + 10.14333127*rh -
+			0.22475541*t*rh - 0.00683783*t*t - 0.05481717*rh*rh +
+			0.00122874*t*t*rh + 0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+
+		switch {
+		case rh < 13 && t >= 80 && t <= 112:
+			hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+		case rh > 85 && t >= 80 && t <= 87:
+			hi += (rh - 85) / 10 * (87 - t) / 5
+		}
+	}
+
+	return (hi - 32) * 5 / 9
+}
+
+func clampRH(rh float64) float64 {
+	return math.Min(math.Max(rh, 0), 100)
+}
+
+// Compute returns the series for virtualType from raw readings, which may
+// contain other sensor types and need not be sorted
+func Compute(cfg Config, virtualType string, readings []internal.SensorReading) ([]internal.SensorReading, error) {
+	switch virtualType {
+	case TypeVPD:
+		return pairwise(cfg, virtualType, readings, VPD), nil
+	case TypeDewPoint:
+		return pairwise(cfg, virtualType, readings, DewPoint), nil
+	case TypeHeatIndex:
+		return pairwise(cfg, virtualType, readings, HeatIndex), nil
+	case TypeDLI:
+		return dli(cfg, readings), nil
+	default:
+		return nil, fmt.Errorf("unknown virtual sensor %q", virtualType)
+	}
+}
+
+// byType returns the usable readings of sensorType, oldest first
+func byType(readings []internal.SensorReading, sensorType string) []internal.SensorReading {
+	var out []internal.SensorReading
+	for _, r := range readings {
+		if r.SensorType == sensorType && r.Quality != internal.QualityBad {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Timestamp.Before(out[j].Timestamp)
+	})
+
+	return out
+}
+
+// virtualReading has no ID, since it is computed on request rather than
+// stored
+func virtualReading(sensorType string, value float64, ts time.Time) internal.SensorReading {
+	value = math.Round(value*100) / 100
+	return internal.SensorReading{
+		SensorType: sensorType,
+		Value:      value,
+		Timestamp:  ts,
+		RawValue:   value,
+		Quality:    internal.QualityGood,
+	}
+}
+
+// pairwise matches every humidity reading with the nearest temperature reading
+// within the pair tolerance and applies fn to each pair
+func pairwise(cfg Config, virtualType string, readings []internal.SensorReading, fn func(tempC, rh float64) float64) []internal.SensorReading {
+	temps := byType(readings, "temperature")
+	hums := byType(readings, "humidity")
+
+	out := make([]internal.SensorReading, 0, len(hums))
+	j := 0
+	for _, h := range hums {
+		for j+1 < len(temps) && absDuration(temps[j+1].Timestamp.Sub(h.Timestamp)) <= absDuration(temps[j].Timestamp.Sub(h.Timestamp)) {
+			j++
+		}
+		if j >= len(temps) || absDuration(temps[j].Timestamp.Sub(h.Timestamp)) > cfg.PairTolerance {
+			continue
+		}
+
+		ts := h.Timestamp
+		if temps[j].Timestamp.After(ts) {
+			ts = temps[j].Timestamp
+		}
+		out = append(out, virtualReading(virtualType, fn(temps[j].Value, h.Value), ts))
+	}
+
+	return out
+}
+
+// dli integrates light readings into one daily light integral (mol/m²/d) per
+// day, stamped at the start of that day. The day still under way is marked
+// partial.
+func dli(cfg Config, readings []internal.SensorReading) []internal.SensorReading {
+	light := byType(readings, "light")
+	loc := cfg.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	now := time.Now()
+	dayReading := func(day time.Time, total float64) internal.SensorReading {
+		r := virtualReading(TypeDLI, total, day)
+		r.Partial = day.AddDate(0, 0, 1).After(now)
+		return r
+	}
+
+	var out []internal.SensorReading
+	var day time.Time
+	total := 0.0
+	for i, r := range light {
+		local := r.Timestamp.In(loc)
+		start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+		if !start.Equal(day) {
+			if !day.IsZero() {
+				out = append(out, dayReading(day, total))
+			}
+			day, total = start, 0
+		}
+
+		// Each reading is assumed to hold until the next one, up to the gap
+		// limit and never past midnight
+		end := start.AddDate(0, 0, 1)
+		if i+1 < len(light) && light[i+1].Timestamp.Before(end) {
+			end = light[i+1].Timestamp
+		}
+		dt := end.Sub(r.Timestamp)
+		if dt > cfg.MaxLightGap {
+			dt = cfg.MaxLightGap
+		}
+
+		total += r.Value * cfg.LuxToPPFD * dt.Seconds() / 1e6
+	}
+	if !day.IsZero() {
+		out = append(out, dayReading(day, total))
+	}
+
+	return out
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// Aggregate buckets a derived series the same way the store buckets raw
+// readings, with buckets aligned to from
+func Aggregate(series []internal.SensorReading, from time.Time, bucket time.Duration) []internal.SensorAggregate {
+	var out []internal.SensorAggregate
+	index := map[int64]int{}
+	for _, r := range series {
+		n := int64(r.Timestamp.Sub(from) / bucket)
+		i, ok := index[n]
+		if !ok {
+			out = append(out, internal.SensorAggregate{
+				SensorType: r.SensorType,
+				Bucket:     from.Add(time.Duration(n) * bucket),
+				Min:        r.Value,
+				Max:        r.Value,
+			})
+			i = len(out) - 1
+			index[n] = i
+		}
+
+		a := &out[i]
+		a.Min = math.Min(a.Min, r.Value)
+		a.Max = math.Max(a.Max, r.Value)
+		a.Avg += (r.Value - a.Avg) / float64(a.Count+1)
+		a.Count++
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Bucket.Before(out[j].Bucket)
+	})
+
+	return out
+}
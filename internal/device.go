@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"regexp"
+	"time"
+)
+
+// DeviceIDHeader lets a board identify itself on requests, such as reading
+// ingest, whose bodies have no room for it
+const DeviceIDHeader = "X-Device-ID"
+
+var deviceIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,63}$`)
+
+// ValidateDeviceID checks that id is usable as a device ID: up to 64 letters,
+// digits, dots, dashes, underscores or colons, so that MAC addresses work
+func ValidateDeviceID(id string) error {
+	if !deviceIDPattern.MatchString(id) {
+		return NewInputError("invalid device id %q", id)
+	}
+	return nil
+}
+
+const (
+	DeviceOnline  = "online"
+	DeviceOffline = "offline"
+)
+
+// Device is a board registered with the backend, either ahead of time or on
+// its first heartbeat or reading
+type Device struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	FirmwareVersion string     `json:"firmware_version"`
+	IPAddress       string     `json:"ip_address"`
+	Zone            *string    `json:"zone,omitempty"`
+	Sensors         []string   `json:"sensors"`
+	Status          string     `json:"status"`
+	LastSeenAt      *time.Time `json:"last_seen_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type DeviceParams struct {
+	Name string
+	Zone *string
+}
+
+// DeviceHeartbeat is what a device reports about itself. Empty fields leave
+// what is already on record.
+type DeviceHeartbeat struct {
+	FirmwareVersion string
+	IPAddress       string
+	Sensors         []string
+}
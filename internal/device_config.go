@@ -0,0 +1,48 @@
+package internal
+
+import "time"
+
+// DeviceConfig is the configuration a device applies without reflashing
+type DeviceConfig struct {
+	// SamplingIntervalSeconds is how often each sensor is read
+	SamplingIntervalSeconds int `json:"sampling_interval_seconds"`
+	// UploadBatchSize is how many readings are sent per upload
+	UploadBatchSize int `json:"upload_batch_size"`
+	// EnabledSensors lists the sensor types to read; empty means all of them
+	EnabledSensors []string `json:"enabled_sensors"`
+	// Calibration maps a sensor type to polynomial coefficients in ascending
+	// powers, for the device's own display and fallback logic. Uploads stay
+	// raw, so server-side calibrations still apply.
+	Calibration map[string][]float64 `json:"calibration"`
+	// LocalThresholds keep a sensor within bounds using the device's own
+	// automation while it cannot reach the server
+	LocalThresholds map[string]LocalThreshold `json:"local_thresholds"`
+}
+
+type LocalThreshold struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// DefaultDeviceConfig is served to devices that have never been configured
+func DefaultDeviceConfig() DeviceConfig {
+	return DeviceConfig{
+		SamplingIntervalSeconds: 60,
+		UploadBatchSize:         1,
+		EnabledSensors:          []string{},
+		Calibration:             map[string][]float64{},
+		LocalThresholds:         map[string]LocalThreshold{},
+	}
+}
+
+// DeviceConfigDocument is a device's configuration as served to it. Version
+// 0 means the defaults.
+type DeviceConfigDocument struct {
+	DeviceID  string       `json:"device_id"`
+	Version   int64        `json:"version"`
+	Config    DeviceConfig `json:"config"`
+	UpdatedBy string       `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time   `json:"updated_at,omitempty"`
+	// ServerTime lets devices without NTP set their clocks
+	ServerTime time.Time `json:"server_time"`
+}
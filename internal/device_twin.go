@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actuators are the controls devices switch on the server's behalf. Fan, heat
+// and light take a level; door and pump are on or off.
+var Actuators = []string{"fan", "heat", "light", "door", "pump"}
+
+func IsActuator(name string) bool {
+	for _, a := range Actuators {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ActuatorState pairs what the server wants an actuator in with what the
+// device last reported. Both are {"mode": ..., "value": ...} objects, as
+// served by the control endpoint.
+type ActuatorState struct {
+	DeviceID        string          `json:"-"`
+	Actuator        string          `json:"-"`
+	Desired         json.RawMessage `json:"desired"`
+	DesiredVersion  int64           `json:"desired_version"`
+	DesiredAt       *time.Time      `json:"desired_at,omitempty"`
+	Reported        json.RawMessage `json:"reported"`
+	ReportedVersion int64           `json:"reported_version"`
+	ReportedAt      *time.Time      `json:"reported_at,omitempty"`
+	// Pending is set until the device acknowledges the latest desired version
+	Pending bool `json:"pending"`
+	// Delta is set while the reported state differs from the desired state,
+	// such as when a relay failed to switch
+	Delta bool `json:"delta"`
+}
+
+// DeviceTwin is the server's view of a device's actuators, keyed by actuator
+type DeviceTwin struct {
+	DeviceID  string                   `json:"device_id"`
+	Actuators map[string]ActuatorState `json:"actuators"`
+}
+
+// ActuatorReport is a device's account of an actuator after applying the
+// desired state with version Version
+type ActuatorReport struct {
+	Actuator string
+	State    json.RawMessage
+	Version  int64
+}
@@ -0,0 +1,26 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned by stores when the requested record does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned by stores when a record with the same key exists
+var ErrConflict = errors.New("already exists")
+
+// InputError reports a request that services refuse to act on because of
+// what was asked, as opposed to a failure while acting on it
+type InputError struct {
+	Message string
+}
+
+func (e InputError) Error() string {
+	return e.Message
+}
+
+func NewInputError(format string, args ...any) error {
+	return InputError{Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,42 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+var csvHeader = []string{"id", "sensor_type", "value", "raw_value", "quality", "device_id", "timestamp"}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return nil, err
+	}
+
+	return &csvWriter{w: cw}, nil
+}
+
+func (c *csvWriter) Write(r internal.SensorReading) error {
+	return c.w.Write([]string{
+		r.ID,
+		r.SensorType,
+		strconv.FormatFloat(r.Value, 'f', -1, 64),
+		strconv.FormatFloat(r.RawValue, 'f', -1, 64),
+		r.Quality,
+		deviceID(r),
+		r.Timestamp.UTC().Format(time.RFC3339Nano),
+	})
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,101 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// Format identifies a serialization used for raw reading exports
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+var contentTypes = map[Format]string{
+	FormatCSV:     "text/csv",
+	FormatNDJSON:  "application/x-ndjson",
+	FormatParquet: "application/vnd.apache.parquet",
+}
+
+// acceptAliases maps media types clients commonly send to export formats
+var acceptAliases = map[string]Format{
+	"text/csv":                       FormatCSV,
+	"application/csv":                FormatCSV,
+	"application/x-ndjson":           FormatNDJSON,
+	"application/ndjson":             FormatNDJSON,
+	"application/jsonl":              FormatNDJSON,
+	"application/vnd.apache.parquet": FormatParquet,
+	"application/x-parquet":          FormatParquet,
+}
+
+// ContentType returns the media type written for f
+func (f Format) ContentType() string {
+	return contentTypes[f]
+}
+
+// Extension returns the file extension used for downloads of f
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// ParseFormat resolves a format= query value
+func ParseFormat(s string) (Format, error) {
+	f := Format(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := contentTypes[f]; !ok {
+		return "", fmt.Errorf("unsupported export format %q", s)
+	}
+
+	return f, nil
+}
+
+// FormatFromAccept picks the first supported format listed in an Accept
+// header, returning false when none of the listed media types are supported
+func FormatFromAccept(accept string) (Format, bool) {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if f, ok := acceptAliases[mediaType]; ok {
+			return f, true
+		}
+	}
+
+	return "", false
+}
+
+// Writer encodes readings one at a time onto an underlying stream
+type Writer interface {
+	Write(r internal.SensorReading) error
+	// Close flushes any buffered data; it does not close the underlying stream
+	Close() error
+}
+
+// NewWriter returns a Writer encoding readings as f onto w
+func NewWriter(f Format, w io.Writer) (Writer, error) {
+	switch f {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	case FormatParquet:
+		return newParquetWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", f)
+	}
+}
+
+// deviceID returns the reading's device, or "" for readings without one
+func deviceID(r internal.SensorReading) string {
+	if r.DeviceID == nil {
+		return ""
+	}
+	return *r.DeviceID
+}
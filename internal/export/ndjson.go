@@ -0,0 +1,44 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+type ndjsonRow struct {
+	ID         string    `json:"id"`
+	SensorType string    `json:"sensor_type"`
+	Value      float64   `json:"value"`
+	RawValue   float64   `json:"raw_value"`
+	Quality    string    `json:"quality"`
+	DeviceID   *string   `json:"device_id,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	return &ndjsonWriter{enc: json.NewEncoder(w)}
+}
+
+// Write emits one JSON object per line; json.Encoder appends the newline
+func (n *ndjsonWriter) Write(r internal.SensorReading) error {
+	return n.enc.Encode(ndjsonRow{
+		ID:         r.ID,
+		SensorType: r.SensorType,
+		Value:      r.Value,
+		RawValue:   r.RawValue,
+		Quality:    r.Quality,
+		DeviceID:   r.DeviceID,
+		Timestamp:  r.Timestamp.UTC(),
+	})
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/zstd"
+)
+
+// parquetRowGroupSize bounds how many rows are buffered before a row group is
+// flushed to the client
+const parquetRowGroupSize = 50_000
+
+type parquetRow struct {
+	ID         int64     `parquet:"id"`
+	SensorType string    `parquet:"sensor_type,dict"`
+	Value      float64   `parquet:"value"`
+	RawValue   float64   `parquet:"raw_value"`
+	Quality    string    `parquet:"quality,dict"`
+	DeviceID   string    `parquet:"device_id,dict"`
+	Timestamp  time.Time `parquet:"timestamp,timestamp(millisecond)"`
+}
+
+type parquetWriter struct {
+	w   *parquet.GenericWriter[parquetRow]
+	row [1]parquetRow
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{
+		w: parquet.NewGenericWriter[parquetRow](w,
+			parquet.MaxRowsPerRowGroup(parquetRowGroupSize),
+			parquet.Compression(&zstd.Codec{}),
+		),
+	}
+}
+
+func (p *parquetWriter) Write(r internal.SensorReading) error {
+	id, err := strconv.ParseInt(r.ID, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	p.row[0] = parquetRow{
+		ID:         id,
+		SensorType: r.SensorType,
+		Value:      r.Value,
+		RawValue:   r.RawValue,
+		Quality:    r.Quality,
+		DeviceID:   deviceID(r),
+		Timestamp:  r.Timestamp.UTC(),
+	}
+	_, err = p.w.Write(p.row[:])
+	return err
+}
+
+func (p *parquetWriter) Close() error {
+	return p.w.Close()
+}
@@ -0,0 +1,122 @@
+package internal
+
+import "time"
+
+// Rollout statuses. A rollout halts on its own once too many devices fail to
+// update; pausing and resuming is up to an operator.
+const (
+	RolloutActive = "active"
+	RolloutPaused = "paused"
+	RolloutHalted = "halted"
+)
+
+// Per-device update statuses, as reported by the device after being offered
+// an update
+const (
+	UpdateOffered     = "offered"
+	UpdateDownloading = "downloading"
+	UpdateInstalling  = "installing"
+	UpdateSucceeded   = "succeeded"
+	UpdateFailed      = "failed"
+)
+
+// FirmwareRelease is an uploaded firmware image
+type FirmwareRelease struct {
+	ID         int64     `json:"id"`
+	Version    string    `json:"version"`
+	SHA256     string    `json:"sha256"`
+	Size       int64     `json:"size"`
+	StorageKey string    `json:"-"`
+	Notes      string    `json:"notes"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type FirmwareReleaseParams struct {
+	Version    string
+	SHA256     string
+	Size       int64
+	StorageKey string
+	Notes      string
+}
+
+// RolloutGroup is a named set of devices that rollouts can target, such as
+// the boards in one greenhouse used as canaries
+type RolloutGroup struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	DeviceIDs []string  `json:"device_ids"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RolloutGroupParams struct {
+	Name      string
+	DeviceIDs []string
+}
+
+// FirmwareRollout offers a release to a growing share of its target devices,
+// one stage at a time
+type FirmwareRollout struct {
+	ID        int64  `json:"id"`
+	ReleaseID int64  `json:"release_id"`
+	Version   string `json:"version"`
+	// GroupID is the rollout group targeted; nil targets every device
+	GroupID *int64 `json:"group_id,omitempty"`
+	// Stages are the percentages of target devices offered the update, in
+	// order; Stage indexes the one in effect
+	Stages []int  `json:"stages"`
+	Stage  int    `json:"stage"`
+	Status string `json:"status"`
+	// FailureThreshold is how many failed updates halt the rollout
+	FailureThreshold int        `json:"failure_threshold"`
+	Failures         int        `json:"failures"`
+	HaltReason       *string    `json:"halt_reason,omitempty"`
+	ResumedAt        *time.Time `json:"resumed_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// Percent is the share of target devices currently offered the update
+func (r FirmwareRollout) Percent() int {
+	if r.Stage < 0 || r.Stage >= len(r.Stages) {
+		return 0
+	}
+	return r.Stages[r.Stage]
+}
+
+type FirmwareRolloutParams struct {
+	ReleaseID        int64
+	GroupID          *int64
+	Stages           []int
+	FailureThreshold int
+}
+
+// FirmwareRolloutCandidate is an active rollout targeting a device, with the
+// release it offers and how far that device has got with it
+type FirmwareRolloutCandidate struct {
+	Rollout      FirmwareRollout
+	Release      FirmwareRelease
+	UpdateStatus *string
+}
+
+// DeviceFirmwareUpdate tracks one device's progress through a rollout
+type DeviceFirmwareUpdate struct {
+	DeviceID  string    `json:"device_id"`
+	RolloutID int64     `json:"rollout_id"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FirmwareOffer answers a device asking whether it should update
+type FirmwareOffer struct {
+	UpdateAvailable bool   `json:"update_available"`
+	CurrentVersion  string `json:"current_version"`
+	Version         string `json:"version,omitempty"`
+	SHA256          string `json:"sha256,omitempty"`
+	Size            int64  `json:"size,omitempty"`
+	URL             string `json:"url,omitempty"`
+	RolloutID       int64  `json:"rollout_id,omitempty"`
+	Status          string `json:"status,omitempty"`
+}
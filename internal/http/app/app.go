@@ -3,15 +3,25 @@ package app
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/blob"
+	"github.com/lulzshadowwalker/green-backend/internal/derived"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
 	"github.com/lulzshadowwalker/green-backend/internal/http/handler"
+	"github.com/lulzshadowwalker/green-backend/internal/jobs"
+	"github.com/lulzshadowwalker/green-backend/internal/llm"
+	"github.com/lulzshadowwalker/green-backend/internal/notify"
+	"github.com/lulzshadowwalker/green-backend/internal/psql"
 	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
 	"github.com/lulzshadowwalker/green-backend/internal/psql/stores"
 	"github.com/lulzshadowwalker/green-backend/internal/service"
@@ -21,6 +31,24 @@ const (
 	AppDefaultReadTimeout  time.Duration = 2 * time.Second
 	AppDefaultWriteTimeout time.Duration = 2 * time.Second
 	AppDefaultAddr         string        = ":8080"
+
+	// flatlineCheckInterval is how often sensors are checked for flatlines
+	flatlineCheckInterval = 5 * time.Minute
+	// alertEvaluationInterval is how often every alert rule is evaluated.
+	// Rules are not evaluated as readings arrive, so this bounds how late an
+	// alert can open.
+	alertEvaluationInterval = 15 * time.Second
+	// webhookDispatchInterval is how often the webhook outbox is drained
+	webhookDispatchInterval = 5 * time.Second
+	// webhookPruneInterval is how often finished webhook events are pruned
+	webhookPruneInterval = time.Hour
+	// deviceOfflineCheckInterval is how often silent devices are marked offline
+	deviceOfflineCheckInterval = time.Minute
+	// claimExpiryInterval is how often stale provisioning claims are expired
+	claimExpiryInterval = 5 * time.Minute
+	// suppressionAuditInterval bounds how late the audit log notes a silence
+	// or maintenance window pausing or resuming automation
+	suppressionAuditInterval = time.Minute
 )
 
 type App struct {
@@ -28,6 +56,9 @@ type App struct {
 	addr    string
 	timeout time.Duration
 	db      *pgxpool.Pool
+	jobs    *jobs.Runner
+	// stopJobs cancels background jobs on Close
+	stopJobs context.CancelFunc
 }
 
 type AppOption func(*App) error
@@ -51,38 +82,191 @@ func New(opts ...AppOption) (*App, error) {
 		return nil, errors.New("db cannot be nil")
 	}
 
-	r := stores.NewSensorReadings(db.New(app.db))
-	s := service.NewSensorReadings(r)
-	h := handler.NewSensorReadings(s)
+	derivedConfig := derived.DefaultConfig()
+	if v := os.Getenv("LIGHT_TO_PPFD_FACTOR"); v != "" {
+		factor, err := strconv.ParseFloat(v, 64)
+		if err != nil || factor <= 0 {
+			return nil, errors.New("LIGHT_TO_PPFD_FACTOR must be a positive number")
+		}
+		derivedConfig.LuxToPPFD = factor
+	}
+
+	offlineAfter := service.DefaultDeviceOfflineAfter
+	if v := os.Getenv("DEVICE_OFFLINE_AFTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, errors.New("DEVICE_OFFLINE_AFTER must be a positive duration such as 5m")
+		}
+		offlineAfter = d
+	}
+
+	claimTTL := service.DefaultClaimTTL
+	if v := os.Getenv("PROVISIONING_CLAIM_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, errors.New("PROVISIONING_CLAIM_TTL must be a positive duration such as 24h")
+		}
+		claimTTL = d
+	}
+
+	// Models without tool support need LLM_TOOLS=false
+	toolCalling := true
+	if v := os.Getenv("LLM_TOOLS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("LLM_TOOLS must be true or false")
+		}
+		toolCalling = b
+	}
+
+	adviceCacheTTL := service.DefaultAdviceCacheTTL
+	if v := os.Getenv("LLM_ADVICE_CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, errors.New("LLM_ADVICE_CACHE_TTL must be a duration such as 10m, or 0 to turn caching off")
+		}
+		adviceCacheTTL = d
+	}
+
+	userStore := stores.NewUsers(db.New(app.db))
+	userService := service.NewUserService(userStore)
+	adminOnly := internalhttp.AdminMiddleware(userService)
+
+	r := stores.NewSensorReadings(app.db)
+	qualityService := service.NewSensorQuality(stores.NewSensorQuality(db.New(app.db)), r)
+	handler.NewQualityHandler(qualityService).RegisterRoutes(app.Echo)
+
+	calibrationService := service.NewSensorCalibrations(stores.NewSensorCalibrations(db.New(app.db)), r, qualityService)
+	handler.NewCalibrationHandler(calibrationService).RegisterRoutes(app.Echo)
+
+	notificationService := service.NewNotifications(stores.NewNotificationChannels(db.New(app.db)), notify.Settings{
+		SMTP: notify.SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		},
+		TelegramToken: os.Getenv("TELEGRAM_BOT_TOKEN"),
+	})
+	handler.NewNotificationHandler(notificationService).RegisterRoutes(app.Echo)
+
+	silenceService := service.NewSilences(stores.NewSilences(db.New(app.db)))
+	handler.NewSilenceHandler(silenceService).RegisterRoutes(app.Echo)
+
+	deviceService := service.NewDevices(stores.NewDevices(app.db), offlineAfter)
+	handler.NewDeviceHandler(deviceService).RegisterRoutes(app.Echo)
+
+	alertService := service.NewAlerts(stores.NewAlerts(app.db), r,
+		service.WithAlertNotifier(notificationService),
+		service.WithAlertSilencer(silenceService),
+		service.WithCommandTracker(deviceService),
+	)
+	handler.NewAlertHandler(alertService).RegisterRoutes(app.Echo)
+
+	s := service.NewSensorReadings(r,
+		service.WithDerivedConfig(derivedConfig),
+		service.WithCalibrator(calibrationService),
+		service.WithQualityChecker(qualityService),
+		service.WithDeviceTracker(deviceService),
+	)
+	h := handler.NewSensorReadings(s, adminOnly)
 	h.RegisterRoutes(app.Echo)
 
 	// LLM Service and Handler
-	openaiAPIKey := os.Getenv("OPENAI_API_KEY")
-	llmService := service.NewLLMService(r, openaiAPIKey)
+	llmProvider, err := newLLMProvider()
+	if err != nil {
+		return nil, err
+	}
+	llmBudget, llmPricing, err := llmUsageConfig()
+	if err != nil {
+		return nil, err
+	}
+	llmUsageService := service.NewLLMUsage(stores.NewLLMUsage(app.db), llmBudget, llmPricing)
+	handler.NewLLMUsageHandler(llmUsageService, adminOnly).RegisterRoutes(app.Echo)
+
+	handler.NewLoginHandler(userService).RegisterRoutes(app.Echo)
+	handler.NewUserHandler(userService).RegisterRoutes(app.Echo)
+
+	llmService := service.NewLLMService(r, llmProvider,
+		service.WithAdviceRules(alertService),
+		service.WithUsageTracker(llmUsageService),
+		service.WithAdviceCache(adviceCacheTTL),
+		service.WithUserLocales(userService),
+	)
 	handler.NewLLMHandler(llmService).RegisterRoutes(app.Echo)
 
 	handler.NewHealthHandler().RegisterRoutes(app.Echo)
 	handler.NewThresholdHandler().RegisterRoutes(app.Echo)
 
-	controlStore := stores.NewSensorControls(db.New(app.db))
-	controlService := service.NewSensorControlsService(controlStore)
-	handler.NewControlHandler(controlService).RegisterRoutes(app.Echo)
+	deviceConfigService := service.NewDeviceConfigs(stores.NewDeviceConfigs(app.db), deviceService)
+	handler.NewDeviceConfigHandler(deviceConfigService).RegisterRoutes(app.Echo)
 
-	userStore := stores.NewUsers(db.New(app.db))
-	userService := service.NewUserService(userStore)
-	handler.NewLoginHandler(userService).RegisterRoutes(app.Echo)
+	provisioningService := service.NewProvisioning(stores.NewProvisioning(app.db), deviceConfigService, claimTTL)
+	handler.NewProvisioningHandler(provisioningService, adminOnly).RegisterRoutes(app.Echo)
+
+	auditStore := stores.NewAuditLog(db.New(app.db))
+	controlStore := stores.NewSensorControls(app.db)
+	controlService := service.NewSensorControlsService(controlStore,
+		service.WithControlSuppressor(silenceService),
+		service.WithControlAuditor(auditStore),
+	)
+	handler.NewControlHandler(controlService, deviceConfigService).RegisterRoutes(app.Echo)
+
+	controlProposalService := service.NewControlProposals(stores.NewControlProposals(app.db), controlService)
+	handler.NewControlProposalHandler(controlProposalService).RegisterRoutes(app.Echo)
+
+	assistantService := service.NewAssistant(stores.NewAssistant(app.db), r, llmProvider, controlService, controlProposalService,
+		service.WithAdviceRules(alertService),
+		service.WithToolCalling(toolCalling),
+		service.WithUsageTracker(llmUsageService),
+		service.WithUserLocales(userService),
+	)
+	handler.NewAssistantHandler(assistantService).RegisterRoutes(app.Echo)
+
+	firmwareBlobs, err := newFirmwareBlobStore()
+	if err != nil {
+		return nil, err
+	}
+	firmwareService := service.NewFirmware(stores.NewFirmware(app.db), firmwareBlobs, deviceService)
+	handler.NewFirmwareHandler(firmwareService).RegisterRoutes(app.Echo)
+
+	auditService := service.NewAuditLog(auditStore)
+	handler.NewAuditHandler(auditService).RegisterRoutes(app.Echo)
+
+	webhookService := service.NewWebhooks(stores.NewWebhooks(db.New(app.db)), nil)
+	handler.NewWebhookHandler(webhookService).RegisterRoutes(app.Echo)
+
+	app.jobs = jobs.NewRunner(psql.NewAdvisoryLocker(app.db),
+		jobs.Job{Name: "flatline-detector", Interval: flatlineCheckInterval, Run: qualityService.DetectFlatlines},
+		jobs.Job{Name: "alert-evaluator", Interval: alertEvaluationInterval, Run: alertService.EvaluateAlertRules},
+		jobs.Job{Name: "webhook-dispatcher", Interval: webhookDispatchInterval, Run: webhookService.DispatchWebhooks},
+		jobs.Job{Name: "webhook-pruner", Interval: webhookPruneInterval, Run: webhookService.PruneWebhookOutbox},
+		jobs.Job{Name: "device-offline-detector", Interval: deviceOfflineCheckInterval, Run: deviceService.DetectOfflineDevices},
+		jobs.Job{Name: "device-claim-expirer", Interval: claimExpiryInterval, Run: provisioningService.ExpireDeviceClaims},
+		jobs.Job{Name: "suppression-auditor", Interval: suppressionAuditInterval, Run: controlService.AuditSuppressions},
+	)
 
 	//  NOTE: Middlewares should be added after all options are applied
 	e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
 		Timeout:      60 * time.Second,
 		ErrorMessage: "Request timed out",
 		Skipper: func(c echo.Context) bool {
-			return c.Path() == "/api/llm/plant-advice"
+			// Streaming, bulk and long-poll endpoints must not be cut off or
+			// buffered by the timeout handler
+			switch c.Path() {
+			case "/api/llm/plant-advice", "/api/readings/export", "/api/admin/readings/import", "/api/calibrations/:id/recompute", "/api/control",
+				"/api/firmware/releases", "/api/firmware/releases/:id/download", "/api/assistant/conversations/:id/messages":
+				return true
+			}
+			return false
 		},
 	}))
 
 	e.Use(middleware.Logger())
 
+	e.Use(internalhttp.DeviceAuthMiddleware(provisioningService))
+
 	e.Validator = NewGreenValidator()
 
 	e.HTTPErrorHandler = greenHTTPErrorHandler
@@ -114,7 +298,134 @@ func New(opts ...AppOption) (*App, error) {
 	return app, nil
 }
 
+// newLLMProvider configures the language model behind plant advice.
+// LLM_PROVIDER picks openai (the default, or any compatible endpoint via
+// LLM_BASE_URL), ollama or fake.
+func newLLMProvider() (llm.Provider, error) {
+	cfg := llm.Config{
+		Provider: os.Getenv("LLM_PROVIDER"),
+		Model:    os.Getenv("LLM_MODEL"),
+		BaseURL:  os.Getenv("LLM_BASE_URL"),
+		APIKey:   os.Getenv("LLM_API_KEY"),
+		CAFile:   os.Getenv("LLM_CA_FILE"),
+	}
+	if cfg.APIKey == "" {
+		cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+	}
+	if v := os.Getenv("LLM_TLS_INSECURE_SKIP_VERIFY"); v != "" {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("LLM_TLS_INSECURE_SKIP_VERIFY must be true or false")
+		}
+		if insecure {
+			slog.Warn("TLS verification is disabled for the LLM provider")
+		}
+		cfg.InsecureSkipVerify = insecure
+	}
+
+	provider, err := llm.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Using LLM provider", "provider", provider.Name(), "model", provider.Model())
+
+	return provider, nil
+}
+
+// llmUsageConfig reads the daily token budgets, LLM_DAILY_TOKEN_BUDGET
+// overall, LLM_USER_DAILY_TOKEN_BUDGET per user and
+// LLM_ANONYMOUS_DAILY_TOKEN_BUDGET shared by everyone who has not signed
+// in, and the provider's prices in US dollars per million tokens,
+// LLM_PROMPT_COST_PER_MILLION and LLM_COMPLETION_COST_PER_MILLION. Unset
+// budgets are unlimited, except that the anonymous budget defaults to the
+// per-user one, and unset prices are free.
+func llmUsageConfig() (internal.LLMBudget, service.LLMPricing, error) {
+	var budget internal.LLMBudget
+	var pricing service.LLMPricing
+
+	for _, b := range []struct {
+		env string
+		dst *int64
+	}{
+		{"LLM_DAILY_TOKEN_BUDGET", &budget.DailyTokens},
+		{"LLM_USER_DAILY_TOKEN_BUDGET", &budget.UserDailyTokens},
+		{"LLM_ANONYMOUS_DAILY_TOKEN_BUDGET", &budget.AnonymousDailyTokens},
+	} {
+		if v := os.Getenv(b.env); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return budget, pricing, fmt.Errorf("%s must be a whole number of tokens, or 0 for no limit", b.env)
+			}
+			*b.dst = n
+		}
+	}
+	if os.Getenv("LLM_ANONYMOUS_DAILY_TOKEN_BUDGET") == "" {
+		budget.AnonymousDailyTokens = budget.UserDailyTokens
+	}
+
+	for _, p := range []struct {
+		env string
+		dst *float64
+	}{
+		{"LLM_PROMPT_COST_PER_MILLION", &pricing.PromptPerMillion},
+		{"LLM_COMPLETION_COST_PER_MILLION", &pricing.CompletionPerMillion},
+	} {
+		if v := os.Getenv(p.env); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 {
+				return budget, pricing, fmt.Errorf("%s must be a price in US dollars, such as 0.15", p.env)
+			}
+			*p.dst = f
+		}
+	}
+
+	return budget, pricing, nil
+}
+
+// newFirmwareBlobStore picks where firmware images live: a local directory
+// by default, or an S3-compatible bucket such as MinIO when FIRMWARE_STORAGE
+// is "s3"
+func newFirmwareBlobStore() (blob.Store, error) {
+	switch storage := os.Getenv("FIRMWARE_STORAGE"); storage {
+	case "", "disk":
+		dir := os.Getenv("FIRMWARE_DIR")
+		if dir == "" {
+			dir = "data/firmware"
+		}
+		return blob.NewDisk(dir)
+	case "s3":
+		useSSL := false
+		if v := os.Getenv("S3_USE_SSL"); v != "" {
+			var err error
+			if useSSL, err = strconv.ParseBool(v); err != nil {
+				return nil, errors.New("S3_USE_SSL must be true or false")
+			}
+		}
+		bucket := os.Getenv("S3_BUCKET")
+		if bucket == "" {
+			bucket = "firmware"
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return blob.NewS3(ctx, blob.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			Bucket:    bucket,
+			UseSSL:    useSSL,
+			Region:    os.Getenv("S3_REGION"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown FIRMWARE_STORAGE %q, want disk or s3", storage)
+	}
+}
+
 func (a *App) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopJobs = cancel
+	a.jobs.Start(ctx)
+
 	return a.Echo.Start(a.addr)
 }
 
@@ -147,6 +458,9 @@ func WithTimeout(d time.Duration) AppOption {
 }
 
 func (a *App) Close() {
+	if a.stopJobs != nil {
+		a.stopJobs()
+	}
 	a.db.Close()
 }
 
@@ -1,9 +1,11 @@
 package app
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
 )
 
 type APIError struct {
@@ -24,9 +26,19 @@ func greenHTTPErrorHandler(err error, c echo.Context) {
 		return
 	}
 
+	var ie internal.InputError
 	if he, ok := err.(*echo.HTTPError); ok {
 		code = he.Code
 		message = he.Message
+	} else if errors.Is(err, internal.ErrNotFound) {
+		code = http.StatusNotFound
+		message = "Not found"
+	} else if errors.Is(err, internal.ErrConflict) {
+		code = http.StatusConflict
+		message = "Already exists"
+	} else if errors.As(err, &ie) {
+		code = http.StatusBadRequest
+		message = ie.Message
 	}
 	c.Logger().Error(err)
 
@@ -1,11 +1,13 @@
 package http
 
 import (
-	// "net/http"
-	// "strings"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
 
 	"github.com/labstack/echo/v4"
-	// "github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal"
 )
 
 func JWTAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
@@ -25,3 +27,159 @@ func JWTAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 		return next(c)
 	}
 }
+
+// UserID returns the ID of the user making the request, as set by
+// JWTAuthMiddleware or else read from the bearer token. Per-user endpoints
+// use it even while the middleware is not enforcing authentication.
+func UserID(c echo.Context) (int, error) {
+	if id, ok := c.Get("user_id").(int); ok {
+		return id, nil
+	}
+
+	authHeader := c.Request().Header.Get("Authorization")
+	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+		return 0, echo.NewHTTPError(http.StatusUnauthorized, "missing or invalid Authorization header")
+	}
+	claims, err := internal.ParseJWT(strings.TrimPrefix(authHeader, "Bearer "))
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired token")
+	}
+
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+
+	return claims.UserID, nil
+}
+
+// Username returns the name of the user making the request, from the same
+// bearer token as UserID
+func Username(c echo.Context) (string, error) {
+	if _, err := UserID(c); err != nil {
+		return "", err
+	}
+
+	username, _ := c.Get("username").(string)
+	if username == "" {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "token has no username")
+	}
+
+	return username, nil
+}
+
+// UserRoleChecker looks up the role of a user
+type UserRoleChecker interface {
+	GetUserRole(ctx context.Context, userID int) (string, error)
+}
+
+// AdminMiddleware only lets users with the admin role through. Unlike
+// JWTAuthMiddleware it is always enforced, and the role is looked up on every
+// request so that revoking it takes effect at once.
+func AdminMiddleware(roles UserRoleChecker) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userID, err := UserID(c)
+			if err != nil {
+				return err
+			}
+
+			role, err := roles.GetUserRole(c.Request().Context(), userID)
+			if errors.Is(err, internal.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusUnauthorized, "unknown user")
+			}
+			if err != nil {
+				return err
+			}
+			if role != internal.RoleAdmin {
+				return echo.NewHTTPError(http.StatusForbidden, "admin role required")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// DeviceAuthenticator resolves a device API key to the device it was issued
+// to, and tells which devices have one
+type DeviceAuthenticator interface {
+	AuthenticateDevice(ctx context.Context, key string) (string, error)
+	DeviceHasAPIKey(ctx context.Context, deviceID string) (bool, error)
+}
+
+// DeviceAuthMiddleware authenticates provisioned devices by their API key.
+// The device the key belongs to is set as "device_id", and handlers find out
+// which device is calling with DeviceID. Requests without a key pass through
+// so that boards provisioned by hand keep working, but not when they claim
+// to be a device that has been issued a key.
+func DeviceAuthMiddleware(auth DeviceAuthenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(internal.DeviceAPIKeyHeader)
+			if key == "" {
+				c.Set("device_auth", auth)
+				if claimed := c.Request().Header.Get(internal.DeviceIDHeader); claimed != "" {
+					if err := requireKeyless(c, auth, claimed); err != nil {
+						return err
+					}
+				}
+				return next(c)
+			}
+
+			deviceID, err := auth.AuthenticateDevice(c.Request().Context(), key)
+			if errors.Is(err, internal.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid API key")
+			}
+			if err != nil {
+				return err
+			}
+
+			if claimed := c.Request().Header.Get(internal.DeviceIDHeader); claimed != "" && claimed != deviceID {
+				return echo.NewHTTPError(http.StatusForbidden, "API key belongs to another device")
+			}
+			c.Set("device_id", deviceID)
+
+			return next(c)
+		}
+	}
+}
+
+// DeviceID returns the device a request comes from. For a request with an
+// API key that is the device the key was issued to, and claimed, if given,
+// must be the same device. Otherwise it is claimed or the X-Device-ID
+// header, as long as that device has not been issued a key. It is empty
+// when the request does not say.
+func DeviceID(c echo.Context, claimed string) (string, error) {
+	if deviceID, ok := c.Get("device_id").(string); ok {
+		if claimed != "" && claimed != deviceID {
+			return "", echo.NewHTTPError(http.StatusForbidden, "API key belongs to another device")
+		}
+		return deviceID, nil
+	}
+
+	if claimed == "" {
+		claimed = c.Request().Header.Get(internal.DeviceIDHeader)
+	}
+	if claimed == "" {
+		return "", nil
+	}
+	if auth, ok := c.Get("device_auth").(DeviceAuthenticator); ok {
+		if err := requireKeyless(c, auth, claimed); err != nil {
+			return "", err
+		}
+	}
+
+	return claimed, nil
+}
+
+// requireKeyless turns away requests without an API key that claim to be a
+// device which has one
+func requireKeyless(c echo.Context, auth DeviceAuthenticator, deviceID string) error {
+	hasKey, err := auth.DeviceHasAPIKey(c.Request().Context(), deviceID)
+	if err != nil {
+		return err
+	}
+	if hasKey {
+		return echo.NewHTTPError(http.StatusUnauthorized, "API key required")
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+)
+
+type Alert struct {
+	service AlertService
+}
+
+type AlertService interface {
+	ListAlerts(ctx context.Context, status string) ([]internal.Alert, error)
+	AcknowledgeAlert(ctx context.Context, id int64, by string) (internal.Alert, error)
+	ListAlertRules(ctx context.Context) ([]internal.AlertRule, error)
+	CreateAlertRule(ctx context.Context, params internal.AlertRuleParams) (internal.AlertRule, error)
+	UpdateAlertRule(ctx context.Context, id int64, params internal.AlertRuleParams) (internal.AlertRule, error)
+	DeleteAlertRule(ctx context.Context, id int64) error
+}
+
+func NewAlertHandler(s AlertService) *Alert {
+	return &Alert{service: s}
+}
+
+func (h *Alert) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/alerts", h.Index)
+	e.POST("/api/alerts/:id/acknowledge", internalhttp.JWTAuthMiddleware(h.Acknowledge))
+
+	e.GET("/api/alert-rules", h.IndexRules)
+	e.POST("/api/alert-rules", internalhttp.JWTAuthMiddleware(h.CreateRule))
+	e.PUT("/api/alert-rules/:id", internalhttp.JWTAuthMiddleware(h.UpdateRule))
+	e.DELETE("/api/alert-rules/:id", internalhttp.JWTAuthMiddleware(h.DeleteRule))
+}
+
+type alertRuleRequest struct {
+	Name            string  `json:"name"`
+	SensorType      string  `json:"sensor_type"`
+	Kind            string  `json:"kind"`       // "threshold", "rate_of_change" or "missing_data"
+	Comparison      string  `json:"comparison"` // "above" or "below", for threshold rules
+	Threshold       float64 `json:"threshold"`
+	DurationSeconds int     `json:"duration_seconds"`
+	Severity        string  `json:"severity"`
+	Enabled         *bool   `json:"enabled,omitempty"` // defaults to true
+	Zone            *string `json:"zone,omitempty"`
+}
+
+func (r alertRuleRequest) params() internal.AlertRuleParams {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return internal.AlertRuleParams{
+		Name:            r.Name,
+		SensorType:      r.SensorType,
+		Kind:            r.Kind,
+		Comparison:      r.Comparison,
+		Threshold:       r.Threshold,
+		DurationSeconds: r.DurationSeconds,
+		Severity:        r.Severity,
+		Enabled:         enabled,
+		Zone:            r.Zone,
+	}
+}
+
+type acknowledgeAlertRequest struct {
+	AcknowledgedBy string `json:"acknowledged_by"`
+}
+
+// Index lists recent alerts, optionally filtered with ?status=open,
+// acknowledged or resolved
+func (h *Alert) Index(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	alerts, err := h.service.ListAlerts(c.Request().Context(), c.QueryParam("status"))
+	if err != nil {
+		slog.Error("Failed to list alerts", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": alerts})
+}
+
+func (h *Alert) Acknowledge(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid alert id")
+	}
+
+	var req acknowledgeAlertRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	alert, err := h.service.AcknowledgeAlert(c.Request().Context(), id, req.AcknowledgedBy)
+	if err != nil {
+		slog.Error("Failed to acknowledge alert", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Acknowledged alert", "id", id, "by", req.AcknowledgedBy, "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": alert})
+}
+
+func (h *Alert) IndexRules(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	rules, err := h.service.ListAlertRules(c.Request().Context())
+	if err != nil {
+		slog.Error("Failed to list alert rules", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": rules})
+}
+
+func (h *Alert) CreateRule(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var req alertRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	rule, err := h.service.CreateAlertRule(c.Request().Context(), req.params())
+	if err != nil {
+		slog.Error("Failed to create alert rule", "error", err, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Created alert rule", "id", rule.ID, "kind", rule.Kind, "sensor_type", rule.SensorType, "request_id", reqID)
+
+	return c.JSON(http.StatusCreated, echo.Map{"data": rule})
+}
+
+func (h *Alert) UpdateRule(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid alert rule id")
+	}
+
+	var req alertRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	rule, err := h.service.UpdateAlertRule(c.Request().Context(), id, req.params())
+	if err != nil {
+		slog.Error("Failed to update alert rule", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Updated alert rule", "id", id, "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": rule})
+}
+
+func (h *Alert) DeleteRule(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid alert rule id")
+	}
+
+	if err := h.service.DeleteAlertRule(c.Request().Context(), id); err != nil {
+		slog.Error("Failed to delete alert rule", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Deleted alert rule", "id", id, "request_id", reqID)
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+	"github.com/lulzshadowwalker/green-backend/internal/service"
+)
+
+type Assistant struct {
+	service AssistantService
+}
+
+type AssistantService interface {
+	ListConversations(ctx context.Context, userID int) ([]internal.Conversation, error)
+	GetConversation(ctx context.Context, userID int, id int64) (internal.Conversation, error)
+	ListConversationMessages(ctx context.Context, userID int, id int64) ([]internal.ConversationMessage, error)
+	CreateConversation(ctx context.Context, userID int, params internal.CreateConversationParams) (internal.Conversation, error)
+	DeleteConversation(ctx context.Context, userID int, id int64) error
+	SendMessage(ctx context.Context, userID int, id int64, content string, locale internal.Locale, w io.Writer) (internal.ConversationMessage, error)
+	Query(ctx context.Context, userID int, question, timezone string, locale internal.Locale) (internal.QueryAnswer, error)
+}
+
+func NewAssistantHandler(s AssistantService) *Assistant {
+	return &Assistant{service: s}
+}
+
+func (h *Assistant) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/assistant/conversations", h.Index)
+	e.POST("/api/assistant/conversations", h.Create)
+	e.GET("/api/assistant/conversations/:id", h.Show)
+	e.DELETE("/api/assistant/conversations/:id", h.Delete)
+	e.POST("/api/assistant/conversations/:id/messages", h.Send)
+	e.POST("/api/assistant/query", h.Query)
+}
+
+type conversationRequest struct {
+	Title string `json:"title"`
+	Plant string `json:"plant"` // defaults to strawberry
+}
+
+type conversationMessageRequest struct {
+	Content string `json:"content"`
+}
+
+type assistantQueryRequest struct {
+	Question string `json:"question"`
+	Timezone string `json:"timezone"` // defaults to UTC
+}
+
+func (h *Assistant) Index(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	userID, err := internalhttp.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	conversations, err := h.service.ListConversations(c.Request().Context(), userID)
+	if err != nil {
+		slog.Error("Failed to list conversations", "error", err, "user_id", userID, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": conversations})
+}
+
+func (h *Assistant) Create(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	userID, err := internalhttp.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req conversationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	conversation, err := h.service.CreateConversation(c.Request().Context(), userID, internal.CreateConversationParams{
+		Title: req.Title,
+		Plant: req.Plant,
+	})
+	if err != nil {
+		slog.Error("Failed to create conversation", "error", err, "user_id", userID, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Created conversation", "id", conversation.ID, "plant", conversation.Plant, "user_id", userID, "request_id", reqID)
+
+	return c.JSON(http.StatusCreated, echo.Map{"data": conversation})
+}
+
+// Show returns the conversation with all of its messages
+func (h *Assistant) Show(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	userID, err := internalhttp.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid conversation id")
+	}
+
+	conversation, err := h.service.GetConversation(c.Request().Context(), userID, id)
+	if err != nil {
+		slog.Error("Failed to get conversation", "error", err, "id", id, "user_id", userID, "request_id", reqID)
+		return err
+	}
+
+	messages, err := h.service.ListConversationMessages(c.Request().Context(), userID, id)
+	if err != nil {
+		slog.Error("Failed to list conversation messages", "error", err, "id", id, "user_id", userID, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": struct {
+		internal.Conversation
+		Messages []internal.ConversationMessage `json:"messages"`
+	}{conversation, messages}})
+}
+
+func (h *Assistant) Delete(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	userID, err := internalhttp.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid conversation id")
+	}
+
+	if err := h.service.DeleteConversation(c.Request().Context(), userID, id); err != nil {
+		slog.Error("Failed to delete conversation", "error", err, "id", id, "user_id", userID, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Deleted conversation", "id", id, "user_id", userID, "request_id", reqID)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Send asks the assistant a question. The reply streams as the same events
+// as plant advice, with "done" carrying the saved reply, or comes back as
+// JSON with ?stream=false. ?lang= and ?temperature_unit= override the
+// user's preferences.
+func (h *Assistant) Send(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	userID, err := internalhttp.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid conversation id")
+	}
+
+	var req conversationMessageRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	locale, err := parseLocale(c)
+	if err != nil {
+		return err
+	}
+
+	stream, err := wantsAdviceStream(c)
+	if err != nil {
+		return err
+	}
+
+	// The request context is cancelled when the client disconnects
+	ctx, cancel := context.WithTimeout(c.Request().Context(), adviceTimeout)
+	defer cancel()
+
+	if !stream {
+		var buf adviceBuffer
+		reply, err := h.service.SendMessage(ctx, userID, id, req.Content, locale, &buf)
+		if err != nil {
+			slog.Error("Failed to answer message", "error", err, "id", id, "user_id", userID, "request_id", reqID)
+			return err
+		}
+
+		buf.setHeaders(c)
+		return c.JSON(http.StatusOK, echo.Map{"data": withAdviceLocale(echo.Map{
+			"message": reply,
+			"usage":   buf.usage,
+		}, buf.locale)})
+	}
+
+	return streamAdvice(c, func(w io.Writer) (echo.Map, error) {
+		reply, err := h.service.SendMessage(ctx, userID, id, req.Content, locale, w)
+		if err != nil {
+			return nil, err
+		}
+		return echo.Map{"message": reply}, nil
+	}, "id", id, "user_id", userID)
+}
+
+// Query answers a question about sensor history, such as "what was the
+// highest temperature yesterday afternoon?", with the numbers and the query
+// plan that produced them, in the language and units asked for with ?lang=
+// and ?temperature_unit= or else the user's preferred ones
+func (h *Assistant) Query(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	start := time.Now()
+
+	userID, err := internalhttp.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req assistantQueryRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	locale, err := parseLocale(c)
+	if err != nil {
+		return err
+	}
+
+	answer, err := h.service.Query(c.Request().Context(), userID, req.Question, req.Timezone, locale)
+	if err != nil {
+		slog.Error("Failed to answer history question", "error", err, "user_id", userID, "request_id", reqID)
+		switch {
+		case errors.Is(err, service.ErrQueryUnavailable):
+			return echo.NewHTTPError(http.StatusServiceUnavailable, service.ErrQueryUnavailable.Error())
+		case errors.Is(err, service.ErrLLMBudgetExceeded):
+			return echo.NewHTTPError(http.StatusTooManyRequests, err.Error())
+		}
+		return err
+	}
+
+	slog.Info("Answered history question",
+		"sensor_type", answer.Plan.SensorType,
+		"aggregate", answer.Plan.Aggregate,
+		"from", answer.Plan.From,
+		"to", answer.Plan.To,
+		"count", answer.Count,
+		"user_id", userID,
+		"request_id", reqID,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": answer})
+}
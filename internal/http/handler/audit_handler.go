@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+)
+
+type Audit struct {
+	service AuditService
+}
+
+type AuditService interface {
+	ListAuditEntries(ctx context.Context, filter internal.AuditFilter) ([]internal.AuditEntry, error)
+}
+
+func NewAuditHandler(s AuditService) *Audit {
+	return &Audit{service: s}
+}
+
+func (h *Audit) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/audit-log", internalhttp.JWTAuthMiddleware(h.Index))
+}
+
+// Index lists recent changes, optionally narrowed with ?entity_type= and
+// ?entity_id=
+func (h *Audit) Index(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	entries, err := h.service.ListAuditEntries(c.Request().Context(), internal.AuditFilter{
+		EntityType: c.QueryParam("entity_type"),
+		EntityID:   c.QueryParam("entity_id"),
+	})
+	if err != nil {
+		slog.Error("Failed to list audit entries", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": entries})
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+)
+
+type Calibration struct {
+	service CalibrationService
+}
+
+type CalibrationService interface {
+	ListSensorCalibrations(ctx context.Context) ([]internal.SensorCalibration, error)
+	CreateSensorCalibration(ctx context.Context, params internal.CreateSensorCalibrationParams) (internal.SensorCalibration, error)
+	UpdateSensorCalibration(ctx context.Context, id int64, params internal.UpdateSensorCalibrationParams) (internal.SensorCalibration, error)
+	RecomputeSensorCalibration(ctx context.Context, id int64) (int, error)
+}
+
+func NewCalibrationHandler(s CalibrationService) *Calibration {
+	return &Calibration{service: s}
+}
+
+func (h *Calibration) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/calibrations", h.Index)
+	e.POST("/api/calibrations", internalhttp.JWTAuthMiddleware(h.Create))
+	e.PUT("/api/calibrations/:id", internalhttp.JWTAuthMiddleware(h.Update))
+	e.POST("/api/calibrations/:id/recompute", internalhttp.JWTAuthMiddleware(h.Recompute))
+}
+
+type calibrationPoints struct {
+	RawLow  float64 `json:"raw_low"`
+	RefLow  float64 `json:"ref_low"`
+	RawHigh float64 `json:"raw_high"`
+	RefHigh float64 `json:"ref_high"`
+}
+
+type calibrationRequest struct {
+	SensorType string  `json:"sensor_type"`
+	DeviceID   *string `json:"device_id,omitempty"`
+	Method     string  `json:"method"` // "linear", "two_point" or "polynomial"
+	// Scale and Offset describe a linear calibration
+	Scale  *float64 `json:"scale,omitempty"`
+	Offset *float64 `json:"offset,omitempty"`
+	// Points describe a two-point calibration
+	Points *calibrationPoints `json:"points,omitempty"`
+	// Coefficients describe a polynomial calibration, in ascending powers
+	Coefficients  []float64  `json:"coefficients,omitempty"`
+	EffectiveFrom *time.Time `json:"effective_from,omitempty"`
+	Note          string     `json:"note"`
+}
+
+func (r calibrationRequest) coefficients() ([]float64, error) {
+	switch r.Method {
+	case internal.CalibrationLinear:
+		scale, offset := 1.0, 0.0
+		if r.Scale != nil {
+			scale = *r.Scale
+		}
+		if r.Offset != nil {
+			offset = *r.Offset
+		}
+		return internal.LinearCoefficients(scale, offset), nil
+	case internal.CalibrationTwoPoint:
+		if r.Points == nil {
+			return nil, internal.NewInputError("two_point calibration requires points")
+		}
+		c, err := internal.TwoPointCoefficients(r.Points.RawLow, r.Points.RefLow, r.Points.RawHigh, r.Points.RefHigh)
+		if err != nil {
+			return nil, internal.NewInputError("%s", err.Error())
+		}
+		return c, nil
+	default:
+		return r.Coefficients, nil
+	}
+}
+
+func (r calibrationRequest) effectiveFrom() time.Time {
+	if r.EffectiveFrom != nil {
+		return *r.EffectiveFrom
+	}
+	return time.Now()
+}
+
+func (h *Calibration) Index(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	calibrations, err := h.service.ListSensorCalibrations(c.Request().Context())
+	if err != nil {
+		slog.Error("Failed to list sensor calibrations", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": calibrations})
+}
+
+func (h *Calibration) Create(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var req calibrationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	coefficients, err := req.coefficients()
+	if err != nil {
+		return err
+	}
+
+	calibration, err := h.service.CreateSensorCalibration(c.Request().Context(), internal.CreateSensorCalibrationParams{
+		SensorType:    req.SensorType,
+		DeviceID:      req.DeviceID,
+		Method:        req.Method,
+		Coefficients:  coefficients,
+		EffectiveFrom: req.effectiveFrom(),
+		Note:          req.Note,
+	})
+	if err != nil {
+		slog.Error("Failed to create sensor calibration", "error", err, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Created sensor calibration",
+		"id", calibration.ID,
+		"sensor_type", calibration.SensorType,
+		"method", calibration.Method,
+		"request_id", reqID,
+	)
+
+	return c.JSON(http.StatusCreated, echo.Map{"data": calibration})
+}
+
+func (h *Calibration) Update(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid calibration id")
+	}
+
+	var req calibrationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	coefficients, err := req.coefficients()
+	if err != nil {
+		return err
+	}
+
+	calibration, err := h.service.UpdateSensorCalibration(c.Request().Context(), id, internal.UpdateSensorCalibrationParams{
+		Method:        req.Method,
+		Coefficients:  coefficients,
+		EffectiveFrom: req.EffectiveFrom,
+		Note:          req.Note,
+	})
+	if err != nil {
+		slog.Error("Failed to update sensor calibration", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Updated sensor calibration", "id", id, "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": calibration})
+}
+
+// Recompute rewrites stored values from their raw values after a calibration
+// has been corrected
+func (h *Calibration) Recompute(c echo.Context) error {
+	start := time.Now()
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid calibration id")
+	}
+
+	updated, err := h.service.RecomputeSensorCalibration(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to recompute sensor calibration",
+			"error", err,
+			"id", id,
+			"updated", updated,
+			"request_id", reqID,
+		)
+		return err
+	}
+
+	slog.Info("Recomputed calibrated readings",
+		"id", id,
+		"updated", updated,
+		"request_id", reqID,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": echo.Map{"updated": updated}})
+}
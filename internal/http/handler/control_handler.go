@@ -2,8 +2,14 @@ package handler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -13,17 +19,28 @@ import (
 
 type Control struct {
 	service ControlService
+	configs DeviceConfigVersions
 }
 
 type ControlService interface {
 	GetAllSensorControls(ctx context.Context) ([]internal.SensorControl, error)
 	SetSensorControlMode(ctx context.Context, sensorType, mode string, manualUntil *time.Time) (internal.SensorControl, error)
 	SetSensorControlModeWithValue(ctx context.Context, sensorType, mode string, manualUntil *time.Time, manualIntValue *int, manualBoolValue *bool) (internal.SensorControl, error)
+	ControlsChanged() <-chan struct{}
 }
 
-func NewControlHandler(c ControlService) *Control {
+// DeviceConfigVersions lets devices polling the controls notice that their
+// configuration changed
+type DeviceConfigVersions interface {
+	DeviceConfigVersion(ctx context.Context, deviceID string) (int64, error)
+	ConfigsChanged() <-chan struct{}
+}
+
+// NewControlHandler serves the controls. configs may be nil.
+func NewControlHandler(c ControlService, configs DeviceConfigVersions) *Control {
 	return &Control{
 		service: c,
+		configs: configs,
 	}
 }
 
@@ -84,6 +101,11 @@ func (c *Control) Set(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, control)
 }
 
+// Index serves the controls as a flat map with an ETag. A request carrying
+// If-None-Match and ?wait=30s is held until the controls change or the wait
+// runs out, answering 304 Not Modified if nothing changed. Devices naming
+// themselves in X-Device-ID also get config_version, so that a configuration
+// change wakes them like a control change.
 func (c *Control) Index(ctx echo.Context) error {
 	start := time.Now()
 	reqID := ctx.Response().Header().Get(echo.HeaderXRequestID)
@@ -94,17 +116,138 @@ func (c *Control) Index(ctx echo.Context) error {
 		"request_id", reqID,
 	)
 
-	controls, err := c.service.GetAllSensorControls(ctx.Request().Context())
+	wait, err := parseControlWait(ctx.QueryParam("wait"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, echo.Map{"error": "wait must be a duration such as 30s"})
+	}
+
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	recheck := time.NewTicker(controlRecheckInterval)
+	defer recheck.Stop()
+
+	ifNoneMatch := ctx.Request().Header.Get("If-None-Match")
+	deviceID, err := internalhttp.DeviceID(ctx, "")
 	if err != nil {
-		slog.Error("Failed to get sensor controls", "error", err, "request_id", reqID)
-		return ctx.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to get control status"})
+		return err
+	}
+	for {
+		// Taken before reading so that a change in between is not missed
+		changed := c.service.ControlsChanged()
+		var configChanged <-chan struct{}
+		if c.configs != nil {
+			configChanged = c.configs.ConfigsChanged()
+		}
+
+		controls, err := c.service.GetAllSensorControls(ctx.Request().Context())
+		if err != nil {
+			slog.Error("Failed to get sensor controls", "error", err, "request_id", reqID)
+			return ctx.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to get control status"})
+		}
+
+		result := controlStatus(controls)
+		if c.configs != nil && deviceID != "" {
+			version, err := c.configs.DeviceConfigVersion(ctx.Request().Context(), deviceID)
+			if err != nil {
+				slog.Error("Failed to get device config version", "error", err, "device_id", deviceID, "request_id", reqID)
+				return ctx.JSON(http.StatusInternalServerError, echo.Map{"error": "failed to get control status"})
+			}
+			result["config_version"] = version
+		}
+
+		body, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		etag := controlETag(body)
+		ctx.Response().Header().Set("ETag", etag)
+		ctx.Response().Header().Set("Cache-Control", "no-cache")
+
+		if !etagMatches(ifNoneMatch, etag) {
+			slog.Info("Returning control status",
+				"result", result,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"request_id", reqID,
+			)
+			return ctx.JSONBlob(http.StatusOK, body)
+		}
+		if wait == 0 {
+			return ctx.NoContent(http.StatusNotModified)
+		}
+
+		select {
+		case <-changed:
+		case <-configChanged:
+		case <-recheck.C:
+		case <-timeout.C:
+			return ctx.NoContent(http.StatusNotModified)
+		case <-ctx.Request().Context().Done():
+			// The device hung up
+			return nil
+		}
 	}
+}
 
+const (
+	// maxControlWait caps how long a long-poll is held, staying under the
+	// idle timeouts of the proxies and carriers in front of devices
+	maxControlWait = 60 * time.Second
+	// controlRecheckInterval is how often a held long-poll reads the controls
+	// again, catching changes made through other replicas and suppressions
+	// starting or ending
+	controlRecheckInterval = 2 * time.Second
+)
+
+// parseControlWait reads ?wait as a duration such as 30s, or as plain
+// seconds
+func parseControlWait(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		secs, convErr := strconv.Atoi(v)
+		if convErr != nil {
+			return 0, err
+		}
+		d = time.Duration(secs) * time.Second
+	}
+	if d < 0 {
+		return 0, errors.New("negative wait")
+	}
+
+	return min(d, maxControlWait), nil
+}
+
+func controlETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header names etag, comparing
+// weakly as RFC 9110 requires
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// controlStatus flattens controls into the map devices read
+func controlStatus(controls []internal.SensorControl) map[string]interface{} {
 	// Flat JSON: { "fan_mode": "manual", "fan": 255, ... }
 	result := make(map[string]interface{})
 	for _, ctrl := range controls {
 		modeKey := ctrl.SensorType + "_mode"
 		result[modeKey] = ctrl.Mode
+		if ctrl.Suppressed {
+			// Automation is paused for maintenance
+			result[ctrl.SensorType+"_suppressed"] = true
+		}
 
 		// Only one value per sensor, no _int_value/_bool_value suffix
 		switch ctrl.SensorType {
@@ -125,11 +268,5 @@ func (c *Control) Index(ctx echo.Context) error {
 		}
 	}
 
-	slog.Info("Returning control status",
-		"result", result,
-		"duration_ms", time.Since(start).Milliseconds(),
-		"request_id", reqID,
-	)
-
-	return ctx.JSON(http.StatusOK, result)
+	return result
 }
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+)
+
+type ControlProposal struct {
+	service ControlProposalService
+}
+
+type ControlProposalService interface {
+	ListControlProposals(ctx context.Context, status string) ([]internal.ControlProposal, error)
+	GetControlProposal(ctx context.Context, id int64) (internal.ControlProposal, error)
+	ApproveControlProposal(ctx context.Context, id int64, by string) (internal.ControlProposal, error)
+	RejectControlProposal(ctx context.Context, id int64, by string) (internal.ControlProposal, error)
+}
+
+func NewControlProposalHandler(s ControlProposalService) *ControlProposal {
+	return &ControlProposal{service: s}
+}
+
+func (h *ControlProposal) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/control/proposals", h.Index)
+	e.GET("/api/control/proposals/:id", h.Show)
+	e.POST("/api/control/proposals/:id/approve", internalhttp.JWTAuthMiddleware(h.Approve))
+	e.POST("/api/control/proposals/:id/reject", internalhttp.JWTAuthMiddleware(h.Reject))
+}
+
+// Index lists proposals, newest first, optionally filtered by ?status=
+func (h *ControlProposal) Index(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	proposals, err := h.service.ListControlProposals(c.Request().Context(), c.QueryParam("status"))
+	if err != nil {
+		slog.Error("Failed to list control proposals", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": proposals})
+}
+
+func (h *ControlProposal) Show(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid proposal id")
+	}
+
+	proposal, err := h.service.GetControlProposal(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to get control proposal", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": proposal})
+}
+
+// Approve applies the proposed change to the controls. The signed in user
+// is recorded as the reviewer.
+func (h *ControlProposal) Approve(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid proposal id")
+	}
+
+	reviewer, err := internalhttp.Username(c)
+	if err != nil {
+		return err
+	}
+
+	proposal, err := h.service.ApproveControlProposal(c.Request().Context(), id, reviewer)
+	if err != nil {
+		slog.Error("Failed to approve control proposal", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Approved control proposal",
+		"id", id,
+		"sensor_type", proposal.SensorType,
+		"mode", proposal.Mode,
+		"by", proposal.ReviewedBy,
+		"request_id", reqID,
+	)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": proposal})
+}
+
+func (h *ControlProposal) Reject(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid proposal id")
+	}
+
+	reviewer, err := internalhttp.Username(c)
+	if err != nil {
+		return err
+	}
+
+	proposal, err := h.service.RejectControlProposal(c.Request().Context(), id, reviewer)
+	if err != nil {
+		slog.Error("Failed to reject control proposal", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Rejected control proposal", "id", id, "by", proposal.ReviewedBy, "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": proposal})
+}
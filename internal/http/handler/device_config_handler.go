@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+)
+
+type DeviceConfig struct {
+	service DeviceConfigService
+}
+
+type DeviceConfigService interface {
+	GetDeviceConfig(ctx context.Context, deviceID string) (internal.DeviceConfigDocument, error)
+	UpdateDeviceConfig(ctx context.Context, deviceID string, config internal.DeviceConfig, by string) (internal.DeviceConfigDocument, error)
+}
+
+func NewDeviceConfigHandler(s DeviceConfigService) *DeviceConfig {
+	return &DeviceConfig{service: s}
+}
+
+func (h *DeviceConfig) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/devices/:id/config", h.Show)
+	e.PUT("/api/devices/:id/config", internalhttp.JWTAuthMiddleware(h.Update))
+}
+
+type deviceConfigRequest struct {
+	internal.DeviceConfig
+	UpdatedBy string `json:"updated_by"`
+}
+
+func deviceConfigETag(version int64) string {
+	return `"config-` + strconv.FormatInt(version, 10) + `"`
+}
+
+// Show serves a device its configuration. Devices learn that it changed from
+// config_version in the control status, and can revalidate with
+// If-None-Match.
+func (h *DeviceConfig) Show(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	id := c.Param("id")
+
+	doc, err := h.service.GetDeviceConfig(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to get device config", "error", err, "device_id", id, "request_id", reqID)
+		return err
+	}
+
+	etag := deviceConfigETag(doc.Version)
+	c.Response().Header().Set("ETag", etag)
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": doc})
+}
+
+func (h *DeviceConfig) Update(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	id := c.Param("id")
+
+	req := deviceConfigRequest{DeviceConfig: internal.DefaultDeviceConfig()}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	doc, err := h.service.UpdateDeviceConfig(c.Request().Context(), id, req.DeviceConfig, req.UpdatedBy)
+	if err != nil {
+		slog.Error("Failed to update device config", "error", err, "device_id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Updated device config", "device_id", id, "version", doc.Version, "by", req.UpdatedBy, "request_id", reqID)
+
+	c.Response().Header().Set("ETag", deviceConfigETag(doc.Version))
+	return c.JSON(http.StatusOK, echo.Map{"data": doc})
+}
@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+)
+
+type Device struct {
+	service DeviceService
+}
+
+type DeviceService interface {
+	ListDevices(ctx context.Context) ([]internal.Device, error)
+	GetDevice(ctx context.Context, id string) (internal.Device, error)
+	CreateDevice(ctx context.Context, id string, params internal.DeviceParams) (internal.Device, error)
+	UpdateDevice(ctx context.Context, id string, params internal.DeviceParams) (internal.Device, error)
+	DeleteDevice(ctx context.Context, id string) error
+	RecordDeviceHeartbeat(ctx context.Context, id string, hb internal.DeviceHeartbeat) (internal.Device, error)
+	GetDeviceTwin(ctx context.Context, id string) (internal.DeviceTwin, error)
+	ReportActuatorStates(ctx context.Context, id string, reports []internal.ActuatorReport) (internal.DeviceTwin, error)
+}
+
+func NewDeviceHandler(s DeviceService) *Device {
+	return &Device{service: s}
+}
+
+func (h *Device) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/devices", h.Index)
+	e.GET("/api/devices/:id", h.Show)
+	e.POST("/api/devices", internalhttp.JWTAuthMiddleware(h.Create))
+	e.PUT("/api/devices/:id", internalhttp.JWTAuthMiddleware(h.Update))
+	e.DELETE("/api/devices/:id", internalhttp.JWTAuthMiddleware(h.Delete))
+	e.POST("/api/devices/:id/heartbeat", internalhttp.JWTAuthMiddleware(h.Heartbeat))
+	e.GET("/api/devices/:id/twin", h.Twin)
+	e.PATCH("/api/devices/:id/reported", internalhttp.JWTAuthMiddleware(h.Reported))
+}
+
+type deviceRequest struct {
+	ID   string  `json:"id"` // only read on create
+	Name string  `json:"name"`
+	Zone *string `json:"zone,omitempty"`
+}
+
+func (r deviceRequest) params() internal.DeviceParams {
+	return internal.DeviceParams{
+		Name: r.Name,
+		Zone: r.Zone,
+	}
+}
+
+type heartbeatRequest struct {
+	FirmwareVersion string `json:"firmware_version"`
+	// IPAddress defaults to the address the heartbeat came from
+	IPAddress string   `json:"ip_address"`
+	Sensors   []string `json:"sensors"`
+}
+
+// reportedRequest maps each actuator to its state after the device applied
+// desired version Version, e.g. { "fan": { "state": { "mode": "manual",
+// "value": 255 }, "version": 4 } }
+type reportedRequest map[string]struct {
+	State   json.RawMessage `json:"state"`
+	Version int64           `json:"version"`
+}
+
+func (h *Device) Index(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	devices, err := h.service.ListDevices(c.Request().Context())
+	if err != nil {
+		slog.Error("Failed to list devices", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": devices})
+}
+
+func (h *Device) Show(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	id := c.Param("id")
+
+	device, err := h.service.GetDevice(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to get device", "error", err, "device_id", id, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": device})
+}
+
+func (h *Device) Create(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var req deviceRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	device, err := h.service.CreateDevice(c.Request().Context(), req.ID, req.params())
+	if err != nil {
+		slog.Error("Failed to register device", "error", err, "device_id", req.ID, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Registered device", "device_id", device.ID, "request_id", reqID)
+
+	return c.JSON(http.StatusCreated, echo.Map{"data": device})
+}
+
+func (h *Device) Update(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	id := c.Param("id")
+
+	var req deviceRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	device, err := h.service.UpdateDevice(c.Request().Context(), id, req.params())
+	if err != nil {
+		slog.Error("Failed to update device", "error", err, "device_id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Updated device", "device_id", id, "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": device})
+}
+
+func (h *Device) Delete(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	id := c.Param("id")
+
+	if err := h.service.DeleteDevice(c.Request().Context(), id); err != nil {
+		slog.Error("Failed to delete device", "error", err, "device_id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Deleted device", "device_id", id, "request_id", reqID)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Heartbeat records that a device is alive, registering it on first contact
+func (h *Device) Heartbeat(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	id, err := internalhttp.DeviceID(c, c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	var req heartbeatRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if req.IPAddress == "" {
+		req.IPAddress = c.RealIP()
+	}
+
+	device, err := h.service.RecordDeviceHeartbeat(c.Request().Context(), id, internal.DeviceHeartbeat{
+		FirmwareVersion: req.FirmwareVersion,
+		IPAddress:       req.IPAddress,
+		Sensors:         req.Sensors,
+	})
+	if err != nil {
+		slog.Error("Failed to record device heartbeat", "error", err, "device_id", id, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": device})
+}
+
+// Twin returns the desired and reported state of the device's actuators
+func (h *Device) Twin(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	id := c.Param("id")
+
+	twin, err := h.service.GetDeviceTwin(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to get device twin", "error", err, "device_id", id, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": twin})
+}
+
+// Reported records the state a device has put its actuators in and
+// acknowledges the desired versions it applied
+func (h *Device) Reported(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	id, err := internalhttp.DeviceID(c, c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	var req reportedRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	reports := make([]internal.ActuatorReport, 0, len(req))
+	for actuator, r := range req {
+		reports = append(reports, internal.ActuatorReport{
+			Actuator: actuator,
+			State:    r.State,
+			Version:  r.Version,
+		})
+	}
+
+	twin, err := h.service.ReportActuatorStates(c.Request().Context(), id, reports)
+	if err != nil {
+		slog.Error("Failed to record reported actuator state", "error", err, "device_id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Recorded reported actuator state", "device_id", id, "actuators", len(reports), "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": twin})
+}
@@ -0,0 +1,427 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+	"github.com/lulzshadowwalker/green-backend/internal/service"
+)
+
+type Firmware struct {
+	service FirmwareService
+}
+
+type FirmwareService interface {
+	ListFirmwareReleases(ctx context.Context) ([]internal.FirmwareRelease, error)
+	UploadFirmwareRelease(ctx context.Context, version, notes, checksum string, r io.Reader, size int64) (internal.FirmwareRelease, error)
+	DeleteFirmwareRelease(ctx context.Context, id int64) error
+	OpenFirmwareRelease(ctx context.Context, id int64) (internal.FirmwareRelease, io.ReadCloser, error)
+	ListRolloutGroups(ctx context.Context) ([]internal.RolloutGroup, error)
+	GetRolloutGroup(ctx context.Context, id int64) (internal.RolloutGroup, error)
+	CreateRolloutGroup(ctx context.Context, params internal.RolloutGroupParams) (internal.RolloutGroup, error)
+	UpdateRolloutGroup(ctx context.Context, id int64, params internal.RolloutGroupParams) (internal.RolloutGroup, error)
+	DeleteRolloutGroup(ctx context.Context, id int64) error
+	ListFirmwareRollouts(ctx context.Context) ([]internal.FirmwareRollout, error)
+	GetFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error)
+	CreateFirmwareRollout(ctx context.Context, params internal.FirmwareRolloutParams) (internal.FirmwareRollout, error)
+	AdvanceFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error)
+	PauseFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error)
+	ResumeFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error)
+	ListDeviceFirmwareUpdates(ctx context.Context, rolloutID int64) ([]internal.DeviceFirmwareUpdate, error)
+	GetFirmwareUpdate(ctx context.Context, deviceID string) (internal.FirmwareOffer, error)
+	ReportFirmwareUpdateStatus(ctx context.Context, deviceID string, rolloutID int64, status, errMsg string) (internal.DeviceFirmwareUpdate, error)
+}
+
+func NewFirmwareHandler(s FirmwareService) *Firmware {
+	return &Firmware{service: s}
+}
+
+func (h *Firmware) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/firmware/releases", h.IndexReleases)
+	e.POST("/api/firmware/releases", internalhttp.JWTAuthMiddleware(h.Upload))
+	e.DELETE("/api/firmware/releases/:id", internalhttp.JWTAuthMiddleware(h.DeleteRelease))
+	e.GET("/api/firmware/releases/:id/download", h.Download)
+
+	e.GET("/api/firmware/groups", h.IndexGroups)
+	e.GET("/api/firmware/groups/:id", h.ShowGroup)
+	e.POST("/api/firmware/groups", internalhttp.JWTAuthMiddleware(h.CreateGroup))
+	e.PUT("/api/firmware/groups/:id", internalhttp.JWTAuthMiddleware(h.UpdateGroup))
+	e.DELETE("/api/firmware/groups/:id", internalhttp.JWTAuthMiddleware(h.DeleteGroup))
+
+	e.GET("/api/firmware/rollouts", h.IndexRollouts)
+	e.GET("/api/firmware/rollouts/:id", h.ShowRollout)
+	e.POST("/api/firmware/rollouts", internalhttp.JWTAuthMiddleware(h.CreateRollout))
+	e.POST("/api/firmware/rollouts/:id/advance", internalhttp.JWTAuthMiddleware(h.Advance))
+	e.POST("/api/firmware/rollouts/:id/pause", internalhttp.JWTAuthMiddleware(h.Pause))
+	e.POST("/api/firmware/rollouts/:id/resume", internalhttp.JWTAuthMiddleware(h.Resume))
+	e.GET("/api/firmware/rollouts/:id/devices", h.RolloutDevices)
+
+	e.GET("/api/devices/:id/firmware", h.Check)
+	e.POST("/api/devices/:id/firmware/status", h.ReportStatus)
+}
+
+type rolloutGroupRequest struct {
+	Name      string   `json:"name"`
+	DeviceIDs []string `json:"device_ids"`
+}
+
+type rolloutRequest struct {
+	ReleaseID        int64  `json:"release_id"`
+	GroupID          *int64 `json:"group_id,omitempty"` // omit to target every device
+	Stages           []int  `json:"stages,omitempty"`   // defaults to 10, 50, 100
+	FailureThreshold int    `json:"failure_threshold"`  // defaults to 1
+}
+
+type firmwareStatusRequest struct {
+	RolloutID int64  `json:"rollout_id"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (h *Firmware) IndexReleases(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	releases, err := h.service.ListFirmwareReleases(c.Request().Context())
+	if err != nil {
+		slog.Error("Failed to list firmware releases", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": releases})
+}
+
+// Upload takes a multipart form with the image in "file", its "version" and
+// optionally "notes" and the expected "sha256"
+func (h *Firmware) Upload(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, service.MaxFirmwareSize+1<<20)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "file is required")
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	release, err := h.service.UploadFirmwareRelease(c.Request().Context(),
+		c.FormValue("version"),
+		c.FormValue("notes"),
+		c.FormValue("sha256"),
+		f,
+		fh.Size,
+	)
+	if err != nil {
+		slog.Error("Failed to upload firmware release", "error", err, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Uploaded firmware release",
+		"id", release.ID,
+		"version", release.Version,
+		"size", release.Size,
+		"sha256", release.SHA256,
+		"request_id", reqID,
+	)
+
+	return c.JSON(http.StatusCreated, echo.Map{"data": release})
+}
+
+func (h *Firmware) DeleteRelease(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid release id")
+	}
+
+	if err := h.service.DeleteFirmwareRelease(c.Request().Context(), id); err != nil {
+		slog.Error("Failed to delete firmware release", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Deleted firmware release", "id", id, "request_id", reqID)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Download streams a release's image. Devices should check it against the
+// X-Checksum-SHA256 header before installing.
+func (h *Firmware) Download(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid release id")
+	}
+
+	release, r, err := h.service.OpenFirmwareRelease(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to open firmware release", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+	defer r.Close()
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(release.Size, 10))
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", "firmware-"+release.Version+".bin"))
+	header.Set("X-Checksum-SHA256", release.SHA256)
+	header.Set("ETag", `"`+release.SHA256+`"`)
+
+	return c.Stream(http.StatusOK, echo.MIMEOctetStream, r)
+}
+
+func (h *Firmware) IndexGroups(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	groups, err := h.service.ListRolloutGroups(c.Request().Context())
+	if err != nil {
+		slog.Error("Failed to list rollout groups", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": groups})
+}
+
+func (h *Firmware) ShowGroup(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid rollout group id")
+	}
+
+	group, err := h.service.GetRolloutGroup(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to get rollout group", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": group})
+}
+
+func (h *Firmware) CreateGroup(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var req rolloutGroupRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	group, err := h.service.CreateRolloutGroup(c.Request().Context(), internal.RolloutGroupParams(req))
+	if err != nil {
+		slog.Error("Failed to create rollout group", "error", err, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Created rollout group", "id", group.ID, "name", group.Name, "devices", len(group.DeviceIDs), "request_id", reqID)
+
+	return c.JSON(http.StatusCreated, echo.Map{"data": group})
+}
+
+func (h *Firmware) UpdateGroup(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid rollout group id")
+	}
+
+	var req rolloutGroupRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	group, err := h.service.UpdateRolloutGroup(c.Request().Context(), id, internal.RolloutGroupParams(req))
+	if err != nil {
+		slog.Error("Failed to update rollout group", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Updated rollout group", "id", id, "devices", len(group.DeviceIDs), "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": group})
+}
+
+func (h *Firmware) DeleteGroup(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid rollout group id")
+	}
+
+	if err := h.service.DeleteRolloutGroup(c.Request().Context(), id); err != nil {
+		slog.Error("Failed to delete rollout group", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Deleted rollout group", "id", id, "request_id", reqID)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Firmware) IndexRollouts(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	rollouts, err := h.service.ListFirmwareRollouts(c.Request().Context())
+	if err != nil {
+		slog.Error("Failed to list firmware rollouts", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": rollouts})
+}
+
+func (h *Firmware) ShowRollout(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid rollout id")
+	}
+
+	rollout, err := h.service.GetFirmwareRollout(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to get firmware rollout", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": rollout})
+}
+
+func (h *Firmware) CreateRollout(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var req rolloutRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	rollout, err := h.service.CreateFirmwareRollout(c.Request().Context(), internal.FirmwareRolloutParams(req))
+	if err != nil {
+		slog.Error("Failed to create firmware rollout", "error", err, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Created firmware rollout",
+		"id", rollout.ID,
+		"version", rollout.Version,
+		"stages", rollout.Stages,
+		"request_id", reqID,
+	)
+
+	return c.JSON(http.StatusCreated, echo.Map{"data": rollout})
+}
+
+// transition applies an operator action to a rollout
+func (h *Firmware) transition(c echo.Context, action string, apply func(ctx context.Context, id int64) (internal.FirmwareRollout, error)) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid rollout id")
+	}
+
+	rollout, err := apply(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to "+action+" firmware rollout", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Firmware rollout "+action+"d",
+		"id", id,
+		"status", rollout.Status,
+		"percent", rollout.Percent(),
+		"request_id", reqID,
+	)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": rollout})
+}
+
+// Advance offers the update to the next stage's share of devices
+func (h *Firmware) Advance(c echo.Context) error {
+	return h.transition(c, "advance", h.service.AdvanceFirmwareRollout)
+}
+
+func (h *Firmware) Pause(c echo.Context) error {
+	return h.transition(c, "pause", h.service.PauseFirmwareRollout)
+}
+
+// Resume reactivates a paused or halted rollout
+func (h *Firmware) Resume(c echo.Context) error {
+	return h.transition(c, "resume", h.service.ResumeFirmwareRollout)
+}
+
+// RolloutDevices lists each device's progress through a rollout
+func (h *Firmware) RolloutDevices(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid rollout id")
+	}
+
+	updates, err := h.service.ListDeviceFirmwareUpdates(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to list firmware updates", "error", err, "rollout_id", id, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": updates})
+}
+
+// Check tells a device whether there is firmware for it to install
+func (h *Firmware) Check(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	id, err := internalhttp.DeviceID(c, c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	offer, err := h.service.GetFirmwareUpdate(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to check for firmware update", "error", err, "device_id", id, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": offer})
+}
+
+// ReportStatus records a device's progress with an offered update
+func (h *Firmware) ReportStatus(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	id, err := internalhttp.DeviceID(c, c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	var req firmwareStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	update, err := h.service.ReportFirmwareUpdateStatus(c.Request().Context(), id, req.RolloutID, req.Status, req.Error)
+	if err != nil {
+		slog.Error("Failed to record firmware update status", "error", err, "device_id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Recorded firmware update status",
+		"device_id", id,
+		"rollout_id", req.RolloutID,
+		"status", update.Status,
+		"request_id", reqID,
+	)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": update})
+}
@@ -2,14 +2,36 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
 	"github.com/lulzshadowwalker/green-backend/internal/service"
 )
 
+// AdviceSourceHeader says whether advice came from the language model or
+// the offline advisor
+const AdviceSourceHeader = "X-Advice-Source"
+
+// Events sent on the plant advice stream
+const (
+	AdviceEventDelta  = "delta"
+	AdviceEventSource = "source"
+	AdviceEventUsage  = "usage"
+	AdviceEventError  = "error"
+	AdviceEventDone   = "done"
+)
+
+const adviceTimeout = 60 * time.Second
+
 type LLMHandler struct {
 	service service.LLMService
 }
@@ -22,43 +44,234 @@ func (h *LLMHandler) RegisterRoutes(e *echo.Echo) {
 	e.GET("/api/llm/plant-advice", h.StreamPlantAdvice)
 }
 
+// adviceEvents sends advice to the client as server-sent events
+type adviceEvents struct {
+	sse    *sseStream
+	source string
+	locale *internal.Locale
+	// pending is the start of a character split across writes
+	pending []byte
+}
+
+func (w *adviceEvents) SetAdviceLocale(locale internal.Locale) {
+	w.locale = &locale
+	w.sse.SetHeader("Content-Language", locale.Language)
+}
+
+func (w *adviceEvents) SetAdviceSource(source string) {
+	w.source = source
+	w.sse.Event(AdviceEventSource, withAdviceLocale(echo.Map{"source": source}, w.locale))
+}
+
+func (w *adviceEvents) ReportAdviceUsage(usage service.AdviceUsage) {
+	w.sse.Event(AdviceEventUsage, usage)
+}
+
+// Write sends p as a delta. A character split across writes is held back
+// until it is whole, as either half alone would be garbled in the JSON,
+// which Arabic text, with two bytes to a letter, runs into often.
+func (w *adviceEvents) Write(p []byte) (int, error) {
+	text := append(w.pending, p...)
+	n := completeUTF8(text)
+	w.pending = append([]byte(nil), text[n:]...)
+	if n == 0 {
+		return len(p), nil
+	}
+
+	if err := w.sse.Event(AdviceEventDelta, echo.Map{"text": string(text[:n])}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// flush sends whatever is held back, which is only left over if the text
+// was not valid UTF-8 to begin with
+func (w *adviceEvents) flush() {
+	if len(w.pending) > 0 {
+		w.sse.Event(AdviceEventDelta, echo.Map{"text": string(w.pending)})
+		w.pending = nil
+	}
+}
+
+// completeUTF8 is the length of b without a character cut off at its end
+func completeUTF8(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
+
+// withAdviceLocale adds the language advice is in, and the direction it is
+// written in, to an event or reply
+func withAdviceLocale(m echo.Map, locale *internal.Locale) echo.Map {
+	if locale != nil {
+		m["language"] = locale.Language
+		m["direction"] = locale.Direction()
+		m["temperature_unit"] = locale.TemperatureUnit
+	}
+	return m
+}
+
+// adviceBuffer collects advice for clients that want a single JSON reply
+type adviceBuffer struct {
+	strings.Builder
+	source string
+	locale *internal.Locale
+	usage  *service.AdviceUsage
+}
+
+func (w *adviceBuffer) SetAdviceLocale(locale internal.Locale) {
+	w.locale = &locale
+}
+
+func (w *adviceBuffer) SetAdviceSource(source string) {
+	w.source = source
+}
+
+// setHeaders sets the advice source and language headers of a JSON reply
+func (w *adviceBuffer) setHeaders(c echo.Context) {
+	c.Response().Header().Set(AdviceSourceHeader, w.source)
+	if w.locale != nil {
+		c.Response().Header().Set("Content-Language", w.locale.Language)
+	}
+}
+
+// parseLocale reads the lang and temperature_unit query parameters. Either
+// may be left out, to use the user's preference or the default.
+func parseLocale(c echo.Context) (internal.Locale, error) {
+	var locale internal.Locale
+	var err error
+	if v := c.QueryParam("lang"); v != "" {
+		if locale.Language, err = internal.ParseLanguage(v); err != nil {
+			return internal.Locale{}, err
+		}
+	}
+	if v := c.QueryParam("temperature_unit"); v != "" {
+		if locale.TemperatureUnit, err = internal.ParseTemperatureUnit(v); err != nil {
+			return internal.Locale{}, err
+		}
+	}
+	return locale, nil
+}
+
+func (w *adviceBuffer) ReportAdviceUsage(usage service.AdviceUsage) {
+	w.usage = &usage
+}
+
+// wantsAdviceStream reports whether the client asked for an event stream.
+// Streaming is the default; ?stream=false or an Accept header of
+// application/json gets a single JSON reply instead.
+func wantsAdviceStream(c echo.Context) (bool, error) {
+	if v := c.QueryParam("stream"); v != "" {
+		stream, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, echo.NewHTTPError(http.StatusBadRequest, "invalid stream")
+		}
+		return stream, nil
+	}
+
+	accept := c.Request().Header.Get(echo.HeaderAccept)
+	return !strings.HasPrefix(accept, echo.MIMEApplicationJSON), nil
+}
+
+// streamAdvice streams what generate writes as advice events, ending with
+// "done", which carries the advice source on top of whatever generate
+// returns, or with "error". Comments are sent as heartbeats while the model
+// is thinking. If generate fails before anything was sent, its error is
+// returned so the client gets a proper status instead.
+func streamAdvice(c echo.Context, generate func(w io.Writer) (echo.Map, error), logAttrs ...any) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	logAttrs = append(logAttrs, "request_id", reqID)
+
+	sse := newSSEStream(c.Response())
+	stopHeartbeat := sse.startHeartbeat(sseHeartbeatInterval)
+	defer stopHeartbeat()
+
+	events := &adviceEvents{sse: sse}
+	done, err := generate(events)
+	stopHeartbeat()
+	events.flush()
+	if err != nil {
+		if errors.Is(c.Request().Context().Err(), context.Canceled) {
+			slog.Info("Client went away during advice stream", logAttrs...)
+			return nil
+		}
+
+		slog.Error("Failed to stream advice", append([]any{"error", err}, logAttrs...)...)
+		if !sse.Started() {
+			return err
+		}
+		sse.Event(AdviceEventError, echo.Map{"message": "failed to generate advice"})
+		return nil
+	}
+
+	if done == nil {
+		done = echo.Map{}
+	}
+	done["source"] = events.source
+	sse.Event(AdviceEventDone, withAdviceLocale(done, events.locale))
+	return nil
+}
+
+// StreamPlantAdvice streams advice as server-sent events: "source" once it
+// is known which advisor is answering, "delta" for each piece of text,
+// "usage" after a language model reply, then "done", or "error" if no
+// advice could be given. The model request is cancelled when the client
+// goes away. Signing in is optional; signed in users get their own daily
+// budget on top of the overall one, and advice in their preferred language
+// and units unless ?lang= or ?temperature_unit= say otherwise.
 func (h *LLMHandler) StreamPlantAdvice(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var userID *int
+	if c.Request().Header.Get(echo.HeaderAuthorization) != "" {
+		id, err := internalhttp.UserID(c)
+		if err != nil {
+			return err
+		}
+		userID = &id
+	}
+
 	plant := c.QueryParam("plant")
 	if plant == "" {
 		plant = "strawberry"
 	}
 
-	c.Response().Header().Set(echo.HeaderContentType, "text/plain")
-	c.Response().WriteHeader(http.StatusOK)
+	locale, err := parseLocale(c)
+	if err != nil {
+		return err
+	}
+
+	stream, err := wantsAdviceStream(c)
+	if err != nil {
+		return err
+	}
 
-	pr, pw := io.Pipe()
-	
-	ctx, cancel := context.WithTimeout(c.Request().Context(), 60*time.Second)
+	// The request context is cancelled when the client disconnects
+	ctx, cancel := context.WithTimeout(c.Request().Context(), adviceTimeout)
 	defer cancel()
 
-	go func() {
-		defer pw.Close()
-		err := h.service.StreamPlantAdvice(ctx, plant, pw)
-		if err != nil {
-			pw.CloseWithError(err)
-		}
-	}()
-
-	buf := make([]byte, 1024)
-	for {
-		n, err := pr.Read(buf)
-		if n > 0 {
-			_, writeErr := c.Response().Write(buf[:n])
-			if writeErr != nil {
-				return writeErr
-			}
-			c.Response().Flush()
-		}
-		if err != nil {
-			if err == io.EOF {
-				return nil
-			}
+	if !stream {
+		var buf adviceBuffer
+		if err := h.service.StreamPlantAdvice(ctx, userID, plant, locale, &buf); err != nil {
+			slog.Error("Failed to get plant advice", "error", err, "plant", plant, "request_id", reqID)
 			return err
 		}
+
+		buf.setHeaders(c)
+		return c.JSON(http.StatusOK, echo.Map{"data": withAdviceLocale(echo.Map{
+			"advice": buf.String(),
+			"source": buf.source,
+			"usage":  buf.usage,
+		}, buf.locale)})
 	}
-}
\ No newline at end of file
+
+	return streamAdvice(c, func(w io.Writer) (echo.Map, error) {
+		return nil, h.service.StreamPlantAdvice(ctx, userID, plant, locale, w)
+	}, "plant", plant)
+}
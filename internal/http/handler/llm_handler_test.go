@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCompleteUTF8HoldsBackSplitCharacters(t *testing.T) {
+	text := []byte("اسقِ النبات صباحًا 🌱")
+
+	// However the text is cut, the complete part is valid and the rest is
+	// only the start of the character that was split
+	for cut := 0; cut <= len(text); cut++ {
+		n := completeUTF8(text[:cut])
+		if !utf8.Valid(text[:n]) {
+			t.Fatalf("completeUTF8(%q) = %d, which splits a character", text[:cut], n)
+		}
+		if cut-n >= utf8.UTFMax || (n < cut && utf8.FullRune(text[n:cut])) {
+			t.Fatalf("completeUTF8(%q) = %d, holding back more than a split character", text[:cut], n)
+		}
+	}
+
+	// Arabic letters are two bytes; half of one is held back
+	alef := []byte("ا")
+	if n := completeUTF8(append([]byte("مرحب"), alef[0])); n != len("مرحب") {
+		t.Errorf("completeUTF8 with half an alef = %d, want %d", n, len("مرحب"))
+	}
+
+	// Text that was never valid is passed on rather than held back forever
+	if n := completeUTF8([]byte{'a', 0xff}); n != 2 {
+		t.Errorf("completeUTF8 with an invalid byte = %d, want 2", n)
+	}
+}
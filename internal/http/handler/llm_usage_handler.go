@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+type LLMUsage struct {
+	service LLMUsageService
+	admin   echo.MiddlewareFunc
+}
+
+type LLMUsageService interface {
+	LLMUsageReport(ctx context.Context, params internal.SummarizeLLMUsageParams) (internal.LLMUsageReport, error)
+}
+
+func NewLLMUsageHandler(s LLMUsageService, admin echo.MiddlewareFunc) *LLMUsage {
+	return &LLMUsage{service: s, admin: admin}
+}
+
+func (h *LLMUsage) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/admin/llm/usage", h.Index, h.admin)
+}
+
+// Index reports language model usage per day, feature and user between
+// ?from= and ?to= (the last 7 days by default), optionally for one
+// ?user_id=, along with what has been spent of today's budget
+func (h *LLMUsage) Index(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	params := internal.SummarizeLLMUsageParams{From: from, To: to}
+	if v := c.QueryParam("user_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid user_id")
+		}
+		params.UserID = &id
+	}
+
+	report, err := h.service.LLMUsageReport(c.Request().Context(), params)
+	if err != nil {
+		slog.Error("Failed to report LLM usage", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": report})
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+)
+
+type Notification struct {
+	service NotificationService
+}
+
+type NotificationService interface {
+	ListNotificationChannels(ctx context.Context, userID int) ([]internal.NotificationChannel, error)
+	CreateNotificationChannel(ctx context.Context, userID int, params internal.NotificationChannelParams) (internal.NotificationChannel, error)
+	UpdateNotificationChannel(ctx context.Context, userID int, id int64, params internal.NotificationChannelParams) (internal.NotificationChannel, error)
+	DeleteNotificationChannel(ctx context.Context, userID int, id int64) error
+	TestNotificationChannel(ctx context.Context, userID int, id int64) error
+}
+
+func NewNotificationHandler(s NotificationService) *Notification {
+	return &Notification{service: s}
+}
+
+func (h *Notification) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/notification-channels", h.Index)
+	e.POST("/api/notification-channels", h.Create)
+	e.PUT("/api/notification-channels/:id", h.Update)
+	e.DELETE("/api/notification-channels/:id", h.Delete)
+	e.POST("/api/notification-channels/:id/test", h.Test)
+}
+
+type notificationChannelRequest struct {
+	Kind            string            `json:"kind"` // "webhook", "email", "ntfy" or "telegram"
+	Name            string            `json:"name"`
+	Config          map[string]string `json:"config"`
+	MinSeverity     string            `json:"min_severity"`
+	QuietHoursStart *string           `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd   *string           `json:"quiet_hours_end,omitempty"`
+	Timezone        string            `json:"timezone"`
+	Enabled         *bool             `json:"enabled,omitempty"` // defaults to true
+}
+
+func (r notificationChannelRequest) params() internal.NotificationChannelParams {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return internal.NotificationChannelParams{
+		Kind:            r.Kind,
+		Name:            r.Name,
+		Config:          r.Config,
+		MinSeverity:     r.MinSeverity,
+		QuietHoursStart: r.QuietHoursStart,
+		QuietHoursEnd:   r.QuietHoursEnd,
+		Timezone:        r.Timezone,
+		Enabled:         enabled,
+	}
+}
+
+func (h *Notification) resource(c internal.NotificationChannel) internal.NotificationChannel {
+	config := make(map[string]string, len(c.Config))
+	for k, v := range c.Config {
+		config[k] = v
+	}
+	for _, k := range internal.RedactedConfigKeys {
+		if _, ok := config[k]; ok {
+			config[k] = internal.RedactedConfigValue
+		}
+	}
+	c.Config = config
+
+	return c
+}
+
+func (h *Notification) Index(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	userID, err := internalhttp.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	channels, err := h.service.ListNotificationChannels(c.Request().Context(), userID)
+	if err != nil {
+		slog.Error("Failed to list notification channels", "error", err, "user_id", userID, "request_id", reqID)
+		return err
+	}
+
+	res := make([]internal.NotificationChannel, len(channels))
+	for i, channel := range channels {
+		res[i] = h.resource(channel)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": res})
+}
+
+func (h *Notification) Create(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	userID, err := internalhttp.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req notificationChannelRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	channel, err := h.service.CreateNotificationChannel(c.Request().Context(), userID, req.params())
+	if err != nil {
+		slog.Error("Failed to create notification channel", "error", err, "user_id", userID, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Created notification channel", "id", channel.ID, "kind", channel.Kind, "user_id", userID, "request_id", reqID)
+
+	return c.JSON(http.StatusCreated, echo.Map{"data": h.resource(channel)})
+}
+
+func (h *Notification) Update(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	userID, err := internalhttp.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid notification channel id")
+	}
+
+	var req notificationChannelRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	channel, err := h.service.UpdateNotificationChannel(c.Request().Context(), userID, id, req.params())
+	if err != nil {
+		slog.Error("Failed to update notification channel", "error", err, "id", id, "user_id", userID, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Updated notification channel", "id", id, "user_id", userID, "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": h.resource(channel)})
+}
+
+func (h *Notification) Delete(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	userID, err := internalhttp.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid notification channel id")
+	}
+
+	if err := h.service.DeleteNotificationChannel(c.Request().Context(), userID, id); err != nil {
+		slog.Error("Failed to delete notification channel", "error", err, "id", id, "user_id", userID, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Deleted notification channel", "id", id, "user_id", userID, "request_id", reqID)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Test sends a test message through the channel right away
+func (h *Notification) Test(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	userID, err := internalhttp.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid notification channel id")
+	}
+
+	if err := h.service.TestNotificationChannel(c.Request().Context(), userID, id); err != nil {
+		slog.Error("Test notification failed", "error", err, "id", id, "user_id", userID, "request_id", reqID)
+		var ie internal.InputError
+		if errors.As(err, &ie) || errors.Is(err, internal.ErrNotFound) {
+			return err
+		}
+		return echo.NewHTTPError(http.StatusBadGateway, "delivery failed: "+err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
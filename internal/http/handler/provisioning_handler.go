@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+)
+
+type Provisioning struct {
+	service ProvisioningService
+	admin   echo.MiddlewareFunc
+}
+
+type ProvisioningService interface {
+	Provision(ctx context.Context, req internal.ProvisionRequest) (internal.ProvisionResult, error)
+	ListDeviceClaims(ctx context.Context, status string) ([]internal.DeviceClaim, error)
+	GetDeviceClaim(ctx context.Context, id int64) (internal.DeviceClaim, error)
+	ApproveDeviceClaim(ctx context.Context, id int64, approval internal.ClaimApproval) (internal.DeviceClaim, error)
+	RejectDeviceClaim(ctx context.Context, id int64, by string) (internal.DeviceClaim, error)
+	RevokeDeviceAPIKey(ctx context.Context, deviceID string) error
+}
+
+func NewProvisioningHandler(s ProvisioningService, admin echo.MiddlewareFunc) *Provisioning {
+	return &Provisioning{service: s, admin: admin}
+}
+
+func (h *Provisioning) RegisterRoutes(e *echo.Echo) {
+	e.POST("/api/provision", h.Provision)
+
+	// Claims show device ids, addresses and code hints, and approving one
+	// hands out an API key, so all of it is for admins
+	e.GET("/api/provisioning/claims", h.Index, h.admin)
+	e.GET("/api/provisioning/claims/:id", h.Show, h.admin)
+	e.POST("/api/provisioning/claims/:id/approve", h.Approve, h.admin)
+	e.POST("/api/provisioning/claims/:id/reject", h.Reject, h.admin)
+
+	e.DELETE("/api/devices/:id/api-key", h.RevokeKey, h.admin)
+}
+
+type approveClaimRequest struct {
+	Name string  `json:"name"`
+	Zone *string `json:"zone,omitempty"`
+}
+
+// Provision is called by unprovisioned boards with their factory claim code.
+// It answers 202 while the claim awaits approval, and 200 with the device's
+// API key and config once approved. The key is only ever sent once.
+func (h *Provisioning) Provision(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var req internal.ProvisionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	req.IPAddress = c.RealIP()
+
+	res, err := h.service.Provision(c.Request().Context(), req)
+	if err != nil {
+		slog.Error("Failed to provision device", "error", err, "device_id", req.DeviceID, "request_id", reqID)
+		return err
+	}
+
+	if res.Status != internal.ClaimClaimed {
+		return c.JSON(http.StatusAccepted, echo.Map{"data": res})
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, echo.Map{"data": res})
+}
+
+// Index lists provisioning claims, optionally only those with ?status=
+func (h *Provisioning) Index(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	claims, err := h.service.ListDeviceClaims(c.Request().Context(), c.QueryParam("status"))
+	if err != nil {
+		slog.Error("Failed to list device claims", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": claims})
+}
+
+func (h *Provisioning) Show(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid claim id")
+	}
+
+	claim, err := h.service.GetDeviceClaim(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to get device claim", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": claim})
+}
+
+// Approve admits a device and assigns it a name and zone
+func (h *Provisioning) Approve(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid claim id")
+	}
+
+	var req approveClaimRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	reviewer, err := internalhttp.Username(c)
+	if err != nil {
+		return err
+	}
+
+	claim, err := h.service.ApproveDeviceClaim(c.Request().Context(), id, internal.ClaimApproval{
+		Name:       req.Name,
+		Zone:       req.Zone,
+		ReviewedBy: reviewer,
+	})
+	if err != nil {
+		slog.Error("Failed to approve device claim", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Approved device claim",
+		"id", id,
+		"device_id", claim.DeviceID,
+		"by", claim.ReviewedBy,
+		"request_id", reqID,
+	)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": claim})
+}
+
+func (h *Provisioning) Reject(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid claim id")
+	}
+
+	reviewer, err := internalhttp.Username(c)
+	if err != nil {
+		return err
+	}
+
+	claim, err := h.service.RejectDeviceClaim(c.Request().Context(), id, reviewer)
+	if err != nil {
+		slog.Error("Failed to reject device claim", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Rejected device claim", "id", id, "device_id", claim.DeviceID, "by", claim.ReviewedBy, "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": claim})
+}
+
+// RevokeKey withdraws a device's API key
+func (h *Provisioning) RevokeKey(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	id := c.Param("id")
+
+	if err := h.service.RevokeDeviceAPIKey(c.Request().Context(), id); err != nil {
+		slog.Error("Failed to revoke device API key", "error", err, "device_id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Revoked device API key", "device_id", id, "request_id", reqID)
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+)
+
+type Quality struct {
+	service QualityService
+}
+
+type QualityService interface {
+	ListSensorQualityRules(ctx context.Context) ([]internal.SensorQualityRule, error)
+	UpsertSensorQualityRule(ctx context.Context, rule internal.SensorQualityRule) (internal.SensorQualityRule, error)
+	ListDeviceHealthEvents(ctx context.Context) ([]internal.DeviceHealthEvent, error)
+}
+
+func NewQualityHandler(s QualityService) *Quality {
+	return &Quality{service: s}
+}
+
+func (h *Quality) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/quality-rules", h.Index)
+	e.PUT("/api/quality-rules/:sensor_type", internalhttp.JWTAuthMiddleware(h.Upsert))
+	e.GET("/api/device-health-events", h.HealthEvents)
+}
+
+func (h *Quality) Index(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	rules, err := h.service.ListSensorQualityRules(c.Request().Context())
+	if err != nil {
+		slog.Error("Failed to list sensor quality rules", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": rules})
+}
+
+func (h *Quality) Upsert(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var rule internal.SensorQualityRule
+	if err := c.Bind(&rule); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	rule.SensorType = c.Param("sensor_type")
+
+	rule, err := h.service.UpsertSensorQualityRule(c.Request().Context(), rule)
+	if err != nil {
+		slog.Error("Failed to save sensor quality rule", "error", err, "sensor_type", c.Param("sensor_type"), "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Saved sensor quality rule", "sensor_type", rule.SensorType, "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": rule})
+}
+
+func (h *Quality) HealthEvents(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	events, err := h.service.ListDeviceHealthEvents(c.Request().Context())
+	if err != nil {
+		slog.Error("Failed to list device health events", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": events})
+}
@@ -2,31 +2,56 @@ package handler
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/export"
 	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+	"github.com/lulzshadowwalker/green-backend/internal/importer"
+)
+
+const (
+	// defaultReadingsRange is used when a request omits "from"
+	defaultReadingsRange = 7 * 24 * time.Hour
+	// defaultAggregateBucket is used when an aggregate request omits "bucket"
+	defaultAggregateBucket = time.Hour
+	// maxAggregateBuckets guards against requests for absurdly fine buckets
+	maxAggregateBuckets = 10_000
 )
 
 type SensorReadings struct {
 	service SensorReadingsService
+	// admin guards the /api/admin routes
+	admin echo.MiddlewareFunc
 }
 
 type SensorReadingsService interface {
-	GetSensorReadings(ctx context.Context) ([]internal.SensorReading, error)
 	CreateSensorReading(ctx context.Context, params internal.CreateSensorReadingParams) (internal.SensorReading, error)
+	GetSensorReadingsBetween(ctx context.Context, sensorTypes []string, from, to time.Time) ([]internal.SensorReading, error)
+	GetLatestSensorReadings(ctx context.Context) ([]internal.SensorReading, error)
+	AggregateSensorReadings(ctx context.Context, params internal.AggregateSensorReadingsParams) ([]internal.SensorAggregate, error)
+	ExportSensorReadings(ctx context.Context, params internal.ExportSensorReadingsParams, fn func(internal.SensorReading) error) error
+	ImportSensorReadings(ctx context.Context, src io.Reader, opts importer.Options, dryRun bool) (internal.ImportReport, error)
 }
 
-func NewSensorReadings(s SensorReadingsService) *SensorReadings {
-	return &SensorReadings{s}
+func NewSensorReadings(s SensorReadingsService, admin echo.MiddlewareFunc) *SensorReadings {
+	return &SensorReadings{service: s, admin: admin}
 }
 
 func (sr *SensorReadings) RegisterRoutes(a *echo.Echo) {
 	a.GET("/api/readings", sr.Index)
+	a.GET("/api/readings/latest", sr.Latest)
+	a.GET("/api/readings/aggregate", sr.Aggregate)
+	a.GET("/api/readings/export", sr.Export)
 	a.POST("/api/readings", internalhttp.JWTAuthMiddleware(sr.Create))
+	a.POST("/api/admin/readings/import", sr.Import, sr.admin)
 }
 
 func (sr *SensorReadings) Index(c echo.Context) error {
@@ -39,7 +64,19 @@ func (sr *SensorReadings) Index(c echo.Context) error {
 		"request_id", reqID,
 	)
 
-	m, err := sr.service.GetSensorReadings(c.Request().Context())
+	sensorTypes, err := parseSensorTypes(c, true)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	// Every request covers the same default window, whether or not it
+	// names sensors. Virtual sensors such as vpd are only included when
+	// asked for.
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	m, err := sr.service.GetSensorReadingsBetween(c.Request().Context(), sensorTypes, from, to)
 	if err != nil {
 		slog.Error("Failed to get sensor readings",
 			"error", err,
@@ -58,12 +95,115 @@ func (sr *SensorReadings) Index(c echo.Context) error {
 	return c.JSON(http.StatusOK, sr.collection(m))
 }
 
+// Latest returns the most recent reading of every sensor, including the
+// current value of virtual sensors such as vpd and today's dli
+func (sr *SensorReadings) Latest(c echo.Context) error {
+	start := time.Now()
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	slog.Info("SensorReadings Latest request received",
+		"method", c.Request().Method,
+		"path", c.Path(),
+		"remote_addr", c.RealIP(),
+		"request_id", reqID,
+	)
+
+	m, err := sr.service.GetLatestSensorReadings(c.Request().Context())
+	if err != nil {
+		slog.Error("Failed to get latest sensor readings",
+			"error", err,
+			"request_id", reqID,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+		return err
+	}
+
+	slog.Info("Returning latest sensor readings",
+		"count", len(m),
+		"request_id", reqID,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+
+	return c.JSON(http.StatusOK, sr.collection(m))
+}
+
+// Aggregate returns min, max, average and count per sensor per time bucket
+func (sr *SensorReadings) Aggregate(c echo.Context) error {
+	start := time.Now()
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	slog.Info("SensorReadings Aggregate request received",
+		"method", c.Request().Method,
+		"path", c.Path(),
+		"remote_addr", c.RealIP(),
+		"request_id", reqID,
+	)
+
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	sensorTypes, err := parseSensorTypes(c, true)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if sensorTypes == nil {
+		for _, def := range internal.SensorCatalog {
+			if !def.Virtual {
+				sensorTypes = append(sensorTypes, def.Type)
+			}
+		}
+	}
+
+	bucket := defaultAggregateBucket
+	if v := c.QueryParam("bucket"); v != "" {
+		bucket, err = time.ParseDuration(v)
+		if err != nil || bucket < time.Minute {
+			return echo.NewHTTPError(http.StatusBadRequest, "bucket must be a duration of at least 1m, e.g. 15m or 24h")
+		}
+	}
+	if to.Sub(from)/bucket > maxAggregateBuckets {
+		return echo.NewHTTPError(http.StatusBadRequest, "bucket is too small for the requested range")
+	}
+
+	aggregates, err := sr.service.AggregateSensorReadings(c.Request().Context(), internal.AggregateSensorReadingsParams{
+		SensorTypes: sensorTypes,
+		From:        from,
+		To:          to,
+		Bucket:      bucket,
+	})
+	if err != nil {
+		slog.Error("Failed to aggregate sensor readings",
+			"error", err,
+			"request_id", reqID,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+		return err
+	}
+
+	slog.Info("Returning sensor aggregates",
+		"count", len(aggregates),
+		"bucket", bucket.String(),
+		"request_id", reqID,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+
+	res := make([]echo.Map, len(aggregates))
+	for i, a := range aggregates {
+		res[i] = sr.aggregateResource(a)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": res})
+}
+
 type CreateSensorReadingRequest struct {
 	Temperature  float64 `json:"temperature" validate:"number"`
 	Humidity     float64 `json:"humidity" validate:"number"`
 	LightLevel   float64 `json:"lightLevel" validate:"number"`
 	WaterLevel   float64 `json:"waterLevel" validate:"number"`
 	SoilMoisture float64 `json:"soilMoisture" validate:"number"`
+	// DeviceID identifies the reporting board; the X-Device-ID header may be
+	// used instead
+	DeviceID string `json:"deviceId"`
 }
 
 func (sr *SensorReadings) Create(c echo.Context) error {
@@ -95,6 +235,19 @@ func (sr *SensorReadings) Create(c echo.Context) error {
 		return err
 	}
 
+	var deviceID *string
+	id, err := internalhttp.DeviceID(c, req.DeviceID)
+	if err != nil {
+		return err
+	}
+	req.DeviceID = id
+	if req.DeviceID != "" {
+		if err := internal.ValidateDeviceID(req.DeviceID); err != nil {
+			return err
+		}
+		deviceID = &req.DeviceID
+	}
+
 	//  TODO: Move this into the service class with a transaction
 	readings := make([]internal.SensorReading, 0)
 	createdTypes := []string{}
@@ -103,6 +256,7 @@ func (sr *SensorReadings) Create(c echo.Context) error {
 		m, err := sr.service.CreateSensorReading(c.Request().Context(), internal.CreateSensorReadingParams{
 			SensorType: "temperature",
 			Value:      req.Temperature,
+			DeviceID:   deviceID,
 		})
 		if err != nil {
 			slog.Error("Failed to create temperature reading",
@@ -119,6 +273,7 @@ func (sr *SensorReadings) Create(c echo.Context) error {
 		m, err := sr.service.CreateSensorReading(c.Request().Context(), internal.CreateSensorReadingParams{
 			SensorType: "humidity",
 			Value:      req.Humidity,
+			DeviceID:   deviceID,
 		})
 		if err != nil {
 			slog.Error("Failed to create humidity reading",
@@ -135,6 +290,7 @@ func (sr *SensorReadings) Create(c echo.Context) error {
 		m, err := sr.service.CreateSensorReading(c.Request().Context(), internal.CreateSensorReadingParams{
 			SensorType: "light",
 			Value:      req.LightLevel,
+			DeviceID:   deviceID,
 		})
 		if err != nil {
 			slog.Error("Failed to create light reading",
@@ -151,6 +307,7 @@ func (sr *SensorReadings) Create(c echo.Context) error {
 		m, err := sr.service.CreateSensorReading(c.Request().Context(), internal.CreateSensorReadingParams{
 			SensorType: "water",
 			Value:      req.WaterLevel,
+			DeviceID:   deviceID,
 		})
 		if err != nil {
 			slog.Error("Failed to create water reading",
@@ -167,6 +324,7 @@ func (sr *SensorReadings) Create(c echo.Context) error {
 		m, err := sr.service.CreateSensorReading(c.Request().Context(), internal.CreateSensorReadingParams{
 			SensorType: "soil",
 			Value:      req.SoilMoisture,
+			DeviceID:   deviceID,
 		})
 		if err != nil {
 			slog.Error("Failed to create soil reading",
@@ -181,6 +339,7 @@ func (sr *SensorReadings) Create(c echo.Context) error {
 
 	slog.Info("Created sensor readings",
 		"types", createdTypes,
+		"device_id", req.DeviceID,
 		"count", len(readings),
 		"request_id", reqID,
 		"duration_ms", time.Since(start).Milliseconds(),
@@ -189,19 +348,285 @@ func (sr *SensorReadings) Create(c echo.Context) error {
 	return c.JSON(http.StatusOK, sr.collection(readings))
 }
 
-func (sr *SensorReadings) resource(r internal.SensorReading) echo.Map {
-	return echo.Map{
-		"id":   r.ID,
-		"type": "sensor-reading",
-		"attributes": echo.Map{
-			"type":      r.SensorType,
-			"value":     r.Value,
-			"timestamp": r.Timestamp,
+// Export streams raw readings as CSV, NDJSON or Parquet. The format comes from
+// the "format" query parameter, falling back to the Accept header and then CSV.
+// A failure part way through aborts the connection, so that a truncated
+// export is never mistaken for a complete one.
+func (sr *SensorReadings) Export(c echo.Context) error {
+	start := time.Now()
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	slog.Info("SensorReadings Export request received",
+		"method", c.Request().Method,
+		"path", c.Path(),
+		"remote_addr", c.RealIP(),
+		"request_id", reqID,
+	)
+
+	format := export.FormatCSV
+	if q := c.QueryParam("format"); q != "" {
+		f, err := export.ParseFormat(q)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		format = f
+	} else if f, ok := export.FormatFromAccept(c.Request().Header.Get(echo.HeaderAccept)); ok {
+		format = f
+	}
+
+	params, err := parseExportParams(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, format.ContentType())
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q",
+		fmt.Sprintf("readings-%s-%s.%s", params.From.Format("20060102T150405Z"), params.To.Format("20060102T150405Z"), format.Extension())))
+	res.WriteHeader(http.StatusOK)
+
+	w, err := export.NewWriter(format, res)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	err = sr.service.ExportSensorReadings(c.Request().Context(), params, func(r internal.SensorReading) error {
+		count++
+		return w.Write(r)
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		slog.Error("Failed to export sensor readings",
+			"error", err,
+			"format", format,
+			"count", count,
+			"request_id", reqID,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+		// The status has been sent, so the only way left to tell the client
+		// is to cut the connection before the body ends. Returning would end
+		// it cleanly and pass the truncated file off as complete.
+		panic(http.ErrAbortHandler)
+	}
+	res.Flush()
+
+	slog.Info("Exported sensor readings",
+		"format", format,
+		"count", count,
+		"request_id", reqID,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+
+	return nil
+}
+
+func parseExportParams(c echo.Context) (internal.ExportSensorReadingsParams, error) {
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		return internal.ExportSensorReadingsParams{}, err
+	}
+
+	sensorTypes, err := parseSensorTypes(c, false)
+	if err != nil {
+		return internal.ExportSensorReadingsParams{}, err
+	}
+
+	return internal.ExportSensorReadingsParams{
+		From:        from,
+		To:          to,
+		SensorTypes: sensorTypes,
+	}, nil
+}
+
+// parseTimeRange reads the "from" and "to" query parameters, defaulting to
+// the week leading up to now
+func parseTimeRange(c echo.Context) (from, to time.Time, err error) {
+	to = time.Now().UTC()
+	if v := c.QueryParam("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return from, to, fmt.Errorf("to must be an RFC 3339 timestamp")
+		}
+		to = t.UTC()
+	}
+
+	from = to.Add(-defaultReadingsRange)
+	if v := c.QueryParam("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return from, to, fmt.Errorf("from must be an RFC 3339 timestamp")
+		}
+		from = t.UTC()
+	}
+
+	if !to.After(from) {
+		return from, to, fmt.Errorf("to must be after from")
+	}
+
+	return from, to, nil
+}
+
+// parseSensorTypes resolves the comma separated "sensors" query parameter to
+// canonical sensor types. It returns nil when the parameter is absent.
+func parseSensorTypes(c echo.Context, allowVirtual bool) ([]string, error) {
+	sensors := c.QueryParam("sensors")
+	if sensors == "" {
+		return nil, nil
+	}
+
+	var types []string
+	for _, name := range strings.Split(sensors, ",") {
+		def, ok := internal.LookupSensor(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown sensor %q", strings.TrimSpace(name))
+		}
+		if def.Virtual && !allowVirtual {
+			return nil, fmt.Errorf("virtual sensor %q is not supported here", def.Type)
+		}
+		types = append(types, def.Type)
+	}
+
+	return types, nil
+}
+
+// Import bulk loads historical readings. The source is either the raw request
+// body or a multipart "file" field; mapping options mirror the cli import flags.
+// With ?dry_run=true nothing is written, and the report says what would be.
+func (sr *SensorReadings) Import(c echo.Context) error {
+	start := time.Now()
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	slog.Info("SensorReadings Import request received",
+		"method", c.Request().Method,
+		"path", c.Path(),
+		"remote_addr", c.RealIP(),
+		"request_id", reqID,
+	)
+
+	opts, err := parseImportOptions(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	dryRun := false
+	if v := c.QueryParam("dry_run"); v != "" {
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "dry_run must be a boolean")
+		}
+	}
+
+	var src io.Reader = c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "file is required")
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src = f
+
+		if c.QueryParam("format") == "" && strings.HasSuffix(strings.ToLower(fh.Filename), ".ndjson") {
+			opts.Format = importer.FormatNDJSON
+		}
+	}
+
+	report, err := sr.service.ImportSensorReadings(c.Request().Context(), src, opts, dryRun)
+	if err != nil {
+		slog.Error("Failed to import sensor readings",
+			"error", err,
+			"request_id", reqID,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+		return err
+	}
+
+	slog.Info("Imported sensor readings",
+		"dry_run", report.DryRun,
+		"rows", report.RowsRead,
+		"inserted", report.Inserted,
+		"duplicates", report.Duplicates,
+		"invalid", report.InvalidRows,
+		"request_id", reqID,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": report})
+}
+
+func parseImportOptions(c echo.Context) (importer.Options, error) {
+	opts := importer.Options{
+		Format: importer.FormatCSV,
+		Mapping: importer.Mapping{
+			TimestampColumn: c.QueryParam("timestamp_column"),
+			SensorColumn:    c.QueryParam("sensor_column"),
+			ValueColumn:     c.QueryParam("value_column"),
+			UnitColumn:      c.QueryParam("unit_column"),
 		},
+		TimeLayout: c.QueryParam("time_layout"),
+	}
+
+	if f := c.QueryParam("format"); f != "" {
+		format, err := importer.ParseFormat(f)
+		if err != nil {
+			return opts, err
+		}
+		opts.Format = format
+	} else if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), "application/x-ndjson") {
+		opts.Format = importer.FormatNDJSON
+	}
+
+	qs := c.QueryParams()
+	var err error
+	if opts.Mapping.Columns, err = importer.ParseAssignments(qs["map"]); err != nil {
+		return opts, err
+	}
+	if opts.Mapping.Units, err = importer.ParseAssignments(qs["unit"]); err != nil {
+		return opts, err
+	}
+
+	if tz := c.QueryParam("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return opts, fmt.Errorf("unknown time zone %q", tz)
+		}
+		opts.Location = loc
+	}
+
+	return opts, nil
+}
+
+// resource renders a reading. Virtual readings are computed on request, so
+// they have no id and their values may still change while partial.
+func (sr *SensorReadings) resource(r internal.SensorReading) echo.Map {
+	attributes := echo.Map{
+		"type":      r.SensorType,
+		"value":     r.Value,
+		"raw_value": r.RawValue,
+		"quality":   r.Quality,
+		"device_id": r.DeviceID,
+		"timestamp": r.Timestamp,
+	}
+	if r.Partial {
+		attributes["partial"] = true
+	}
+
+	res := echo.Map{
+		"type":          "sensor-reading",
+		"attributes":    attributes,
 		"relationships": echo.Map{},
 		"includes":      echo.Map{},
 		"links":         echo.Map{},
 	}
+	if r.ID != "" {
+		res["id"] = r.ID
+	}
+
+	return res
 }
 
 func (sr *SensorReadings) collection(r []internal.SensorReading) echo.Map {
@@ -214,3 +639,21 @@ func (sr *SensorReadings) collection(r []internal.SensorReading) echo.Map {
 		"data": res,
 	}
 }
+
+func (sr *SensorReadings) aggregateResource(a internal.SensorAggregate) echo.Map {
+	return echo.Map{
+		"id":   fmt.Sprintf("%s-%d", a.SensorType, a.Bucket.Unix()),
+		"type": "sensor-aggregate",
+		"attributes": echo.Map{
+			"type":   a.SensorType,
+			"bucket": a.Bucket,
+			"min":    a.Min,
+			"max":    a.Max,
+			"avg":    a.Avg,
+			"count":  a.Count,
+		},
+		"relationships": echo.Map{},
+		"includes":      echo.Map{},
+		"links":         echo.Map{},
+	}
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// failingExport streams a few thousand readings and then fails, as a
+// dropped database connection would
+type failingExport struct {
+	SensorReadingsService
+}
+
+func (failingExport) ExportSensorReadings(ctx context.Context, params internal.ExportSensorReadingsParams, fn func(internal.SensorReading) error) error {
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < 5000; i++ {
+		err := fn(internal.SensorReading{
+			ID:         strconv.Itoa(i + 1),
+			SensorType: "temperature",
+			Value:      21.5,
+			RawValue:   21.5,
+			Quality:    internal.QualityGood,
+			Timestamp:  start.Add(time.Duration(i) * time.Second),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return errors.New("connection reset by peer")
+}
+
+func TestExportAbortsWhenStreamingFails(t *testing.T) {
+	e := echo.New()
+	allow := func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	NewSensorReadings(failingExport{}, allow).RegisterRoutes(e)
+
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	for _, format := range []string{"csv", "ndjson"} {
+		t.Run(format, func(t *testing.T) {
+			res, err := http.Get(srv.URL + "/api/readings/export?format=" + format)
+			if err != nil {
+				// Cut off before the headers arrived, which is just as good
+				return
+			}
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			if err == nil {
+				t.Fatalf("read %d bytes of a failed export without an error, want the connection aborted", len(body))
+			}
+		})
+	}
+}
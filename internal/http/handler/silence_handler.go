@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+)
+
+type Silence struct {
+	service SilenceService
+}
+
+type SilenceService interface {
+	ListAlertSilences(ctx context.Context, activeOnly bool) ([]internal.AlertSilence, error)
+	CreateAlertSilence(ctx context.Context, params internal.AlertSilenceParams) (internal.AlertSilence, error)
+	ExpireAlertSilence(ctx context.Context, id int64) (internal.AlertSilence, error)
+	ListMaintenanceWindows(ctx context.Context) ([]internal.MaintenanceWindow, error)
+	CreateMaintenanceWindow(ctx context.Context, params internal.MaintenanceWindowParams) (internal.MaintenanceWindow, error)
+	UpdateMaintenanceWindow(ctx context.Context, id int64, params internal.MaintenanceWindowParams) (internal.MaintenanceWindow, error)
+	DeleteMaintenanceWindow(ctx context.Context, id int64) error
+	ActiveSuppressions(ctx context.Context, at time.Time) ([]internal.Suppression, error)
+}
+
+func NewSilenceHandler(s SilenceService) *Silence {
+	return &Silence{service: s}
+}
+
+func (h *Silence) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/silences", h.Index)
+	e.POST("/api/silences", internalhttp.JWTAuthMiddleware(h.Create))
+	e.DELETE("/api/silences/:id", internalhttp.JWTAuthMiddleware(h.Expire))
+
+	e.GET("/api/maintenance-windows", h.IndexWindows)
+	e.POST("/api/maintenance-windows", internalhttp.JWTAuthMiddleware(h.CreateWindow))
+	e.PUT("/api/maintenance-windows/:id", internalhttp.JWTAuthMiddleware(h.UpdateWindow))
+	e.DELETE("/api/maintenance-windows/:id", internalhttp.JWTAuthMiddleware(h.DeleteWindow))
+
+	e.GET("/api/suppressions", h.Active)
+}
+
+type silenceRequest struct {
+	internal.SilenceMatcher
+	StartsAt  *time.Time `json:"starts_at,omitempty"` // defaults to now
+	EndsAt    time.Time  `json:"ends_at"`
+	Reason    string     `json:"reason"`
+	CreatedBy string     `json:"created_by"`
+}
+
+type maintenanceWindowRequest struct {
+	Name string `json:"name"`
+	internal.SilenceMatcher
+	Weekdays  []int  `json:"weekdays"`   // 0 is Sunday; empty means every day
+	StartTime string `json:"start_time"` // HH:MM
+	EndTime   string `json:"end_time"`   // HH:MM
+	Timezone  string `json:"timezone"`
+	Reason    string `json:"reason"`
+	Enabled   *bool  `json:"enabled,omitempty"` // defaults to true
+}
+
+func (r maintenanceWindowRequest) params() internal.MaintenanceWindowParams {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return internal.MaintenanceWindowParams{
+		Name:           r.Name,
+		SilenceMatcher: r.SilenceMatcher,
+		Weekdays:       r.Weekdays,
+		StartTime:      r.StartTime,
+		EndTime:        r.EndTime,
+		Timezone:       r.Timezone,
+		Reason:         r.Reason,
+		Enabled:        enabled,
+	}
+}
+
+// Index lists recent silences, or only current and upcoming ones with
+// ?active=true
+func (h *Silence) Index(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	activeOnly := false
+	if v := c.QueryParam("active"); v != "" {
+		var err error
+		if activeOnly, err = strconv.ParseBool(v); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "active must be true or false")
+		}
+	}
+
+	silences, err := h.service.ListAlertSilences(c.Request().Context(), activeOnly)
+	if err != nil {
+		slog.Error("Failed to list silences", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": silences})
+}
+
+func (h *Silence) Create(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var req silenceRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	params := internal.AlertSilenceParams{
+		SilenceMatcher: req.SilenceMatcher,
+		EndsAt:         req.EndsAt,
+		Reason:         req.Reason,
+		CreatedBy:      req.CreatedBy,
+	}
+	if req.StartsAt != nil {
+		params.StartsAt = *req.StartsAt
+	}
+
+	silence, err := h.service.CreateAlertSilence(c.Request().Context(), params)
+	if err != nil {
+		slog.Error("Failed to create silence", "error", err, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Created silence",
+		"id", silence.ID,
+		"starts_at", silence.StartsAt,
+		"ends_at", silence.EndsAt,
+		"reason", silence.Reason,
+		"request_id", reqID,
+	)
+
+	return c.JSON(http.StatusCreated, echo.Map{"data": silence})
+}
+
+// Expire ends a silence early
+func (h *Silence) Expire(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid silence id")
+	}
+
+	silence, err := h.service.ExpireAlertSilence(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to expire silence", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Expired silence", "id", id, "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": silence})
+}
+
+func (h *Silence) IndexWindows(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	windows, err := h.service.ListMaintenanceWindows(c.Request().Context())
+	if err != nil {
+		slog.Error("Failed to list maintenance windows", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": windows})
+}
+
+func (h *Silence) CreateWindow(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var req maintenanceWindowRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	window, err := h.service.CreateMaintenanceWindow(c.Request().Context(), req.params())
+	if err != nil {
+		slog.Error("Failed to create maintenance window", "error", err, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Created maintenance window", "id", window.ID, "name", window.Name, "request_id", reqID)
+
+	return c.JSON(http.StatusCreated, echo.Map{"data": window})
+}
+
+func (h *Silence) UpdateWindow(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid maintenance window id")
+	}
+
+	var req maintenanceWindowRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	window, err := h.service.UpdateMaintenanceWindow(c.Request().Context(), id, req.params())
+	if err != nil {
+		slog.Error("Failed to update maintenance window", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Updated maintenance window", "id", id, "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": window})
+}
+
+func (h *Silence) DeleteWindow(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid maintenance window id")
+	}
+
+	if err := h.service.DeleteMaintenanceWindow(c.Request().Context(), id); err != nil {
+		slog.Error("Failed to delete maintenance window", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Deleted maintenance window", "id", id, "request_id", reqID)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Active lists the silences and maintenance windows in effect right now
+func (h *Silence) Active(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	active, err := h.service.ActiveSuppressions(c.Request().Context(), time.Now())
+	if err != nil {
+		slog.Error("Failed to list active suppressions", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": active})
+}
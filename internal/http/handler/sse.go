@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// sseHeartbeatInterval is how often an idle event stream sends a comment,
+// so that proxies keep it open and clients can tell it is alive
+const sseHeartbeatInterval = 15 * time.Second
+
+// sseStream writes server-sent events. The response is committed as an
+// event stream on the first event or heartbeat, so a handler that fails
+// before sending anything can still reply with an error status. It is safe
+// for concurrent use, so that heartbeats can be sent while events are being
+// produced.
+type sseStream struct {
+	mu      sync.Mutex
+	res     *echo.Response
+	id      int
+	started bool
+}
+
+func newSSEStream(res *echo.Response) *sseStream {
+	return &sseStream{res: res}
+}
+
+// start commits the response. The caller must hold mu.
+func (s *sseStream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+
+	s.res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	s.res.Header().Set("Cache-Control", "no-cache")
+	s.res.Header().Set("Connection", "keep-alive")
+	// Stop nginx and similar proxies from buffering the stream
+	s.res.Header().Set("X-Accel-Buffering", "no")
+	s.res.WriteHeader(http.StatusOK)
+}
+
+// SetHeader sets a response header, unless the response has already been
+// committed
+func (s *sseStream) SetHeader(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		s.res.Header().Set(key, value)
+	}
+}
+
+// Started reports whether anything has been sent yet
+func (s *sseStream) Started() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.started
+}
+
+// Event sends an event whose data is v encoded as JSON
+func (s *sseStream) Event(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.start()
+	s.id++
+	if _, err := fmt.Fprintf(s.res, "id: %d\nevent: %s\ndata: %s\n\n", s.id, name, data); err != nil {
+		return err
+	}
+	s.res.Flush()
+	return nil
+}
+
+// startHeartbeat sends a comment every interval until the returned stop
+// function is called. Stop waits for the heartbeat to finish, so nothing is
+// written to the response after the handler returns, and may be called more
+// than once.
+func (s *sseStream) startHeartbeat(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				s.start()
+				_, err := fmt.Fprint(s.res, ": heartbeat\n\n")
+				if err == nil {
+					s.res.Flush()
+				}
+				s.mu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return sync.OnceFunc(func() {
+		close(done)
+		<-stopped
+	})
+}
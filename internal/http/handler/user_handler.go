@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+)
+
+type User struct {
+	service UserService
+}
+
+type UserService interface {
+	GetUserLocale(ctx context.Context, userID int) (internal.Locale, error)
+	UpdateUserLocale(ctx context.Context, userID int, locale internal.Locale) (internal.Locale, error)
+}
+
+func NewUserHandler(s UserService) *User {
+	return &User{service: s}
+}
+
+func (h *User) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/me/locale", internalhttp.JWTAuthMiddleware(h.ShowLocale))
+	e.PUT("/api/me/locale", internalhttp.JWTAuthMiddleware(h.UpdateLocale))
+}
+
+// ShowLocale returns the language and temperature unit the signed in user
+// gets advice and assistant replies in
+func (h *User) ShowLocale(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	userID, err := internalhttp.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	locale, err := h.service.GetUserLocale(c.Request().Context(), userID)
+	if err != nil {
+		slog.Error("Failed to get user locale", "error", err, "user_id", userID, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": locale})
+}
+
+// UpdateLocale changes the signed in user's language ("en" or "ar") and
+// temperature unit ("celsius" or "fahrenheit"). Fields left out are kept.
+func (h *User) UpdateLocale(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	userID, err := internalhttp.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req internal.Locale
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	locale, err := h.service.UpdateUserLocale(c.Request().Context(), userID, req)
+	if err != nil {
+		slog.Error("Failed to update user locale", "error", err, "user_id", userID, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Updated user locale", "language", locale.Language, "temperature_unit", locale.TemperatureUnit, "user_id", userID, "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": locale})
+}
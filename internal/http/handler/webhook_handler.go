@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	internalhttp "github.com/lulzshadowwalker/green-backend/internal/http"
+)
+
+type Webhook struct {
+	service WebhookService
+}
+
+type WebhookService interface {
+	ListWebhookSubscriptions(ctx context.Context) ([]internal.WebhookSubscription, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (internal.WebhookSubscription, error)
+	CreateWebhookSubscription(ctx context.Context, params internal.WebhookSubscriptionParams) (internal.WebhookSubscription, error)
+	UpdateWebhookSubscription(ctx context.Context, id int64, params internal.WebhookSubscriptionParams) (internal.WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, id int64) error
+	ListWebhookDeliveries(ctx context.Context, subscriptionID int64, status string) ([]internal.WebhookDelivery, error)
+	ListWebhookDeliveryAttempts(ctx context.Context, subscriptionID, deliveryID int64) ([]internal.WebhookDeliveryAttempt, error)
+	RetryWebhookDelivery(ctx context.Context, subscriptionID, deliveryID int64) error
+}
+
+func NewWebhookHandler(s WebhookService) *Webhook {
+	return &Webhook{service: s}
+}
+
+func (h *Webhook) RegisterRoutes(e *echo.Echo) {
+	e.GET("/api/webhooks", internalhttp.JWTAuthMiddleware(h.Index))
+	e.POST("/api/webhooks", internalhttp.JWTAuthMiddleware(h.Create))
+	e.GET("/api/webhooks/:id", internalhttp.JWTAuthMiddleware(h.Show))
+	e.PUT("/api/webhooks/:id", internalhttp.JWTAuthMiddleware(h.Update))
+	e.DELETE("/api/webhooks/:id", internalhttp.JWTAuthMiddleware(h.Delete))
+
+	e.GET("/api/webhooks/:id/deliveries", internalhttp.JWTAuthMiddleware(h.Deliveries))
+	e.GET("/api/webhooks/:id/deliveries/:delivery_id/attempts", internalhttp.JWTAuthMiddleware(h.Attempts))
+	e.POST("/api/webhooks/:id/deliveries/:delivery_id/retry", internalhttp.JWTAuthMiddleware(h.Retry))
+}
+
+type webhookRequest struct {
+	URL         string   `json:"url"`
+	EventTypes  []string `json:"event_types"` // "reading.created", "control.changed", "alert.opened" or "alert.resolved"
+	Description string   `json:"description"`
+	Enabled     *bool    `json:"enabled,omitempty"` // defaults to true
+}
+
+func (r webhookRequest) params() internal.WebhookSubscriptionParams {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return internal.WebhookSubscriptionParams{
+		URL:         r.URL,
+		EventTypes:  r.EventTypes,
+		Description: r.Description,
+		Enabled:     enabled,
+	}
+}
+
+func webhookID(c echo.Context) (int64, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid webhook id")
+	}
+	return id, nil
+}
+
+func webhookDeliveryID(c echo.Context) (int64, int64, error) {
+	id, err := webhookID(c)
+	if err != nil {
+		return 0, 0, err
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "invalid delivery id")
+	}
+	return id, deliveryID, nil
+}
+
+func (h *Webhook) Index(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	subs, err := h.service.ListWebhookSubscriptions(c.Request().Context())
+	if err != nil {
+		slog.Error("Failed to list webhook subscriptions", "error", err, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": subs})
+}
+
+func (h *Webhook) Show(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+
+	sub, err := h.service.GetWebhookSubscription(c.Request().Context(), id)
+	if err != nil {
+		slog.Error("Failed to get webhook subscription", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": sub})
+}
+
+// Create registers a subscription. The response carries the signing secret,
+// which cannot be retrieved again.
+func (h *Webhook) Create(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var req webhookRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	sub, err := h.service.CreateWebhookSubscription(c.Request().Context(), req.params())
+	if err != nil {
+		slog.Error("Failed to create webhook subscription", "error", err, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Created webhook subscription", "id", sub.ID, "event_types", sub.EventTypes, "request_id", reqID)
+
+	return c.JSON(http.StatusCreated, echo.Map{"data": sub})
+}
+
+func (h *Webhook) Update(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+
+	var req webhookRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	sub, err := h.service.UpdateWebhookSubscription(c.Request().Context(), id, req.params())
+	if err != nil {
+		slog.Error("Failed to update webhook subscription", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Updated webhook subscription", "id", id, "request_id", reqID)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": sub})
+}
+
+func (h *Webhook) Delete(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.DeleteWebhookSubscription(c.Request().Context(), id); err != nil {
+		slog.Error("Failed to delete webhook subscription", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Deleted webhook subscription", "id", id, "request_id", reqID)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Deliveries lists a subscription's recent deliveries, optionally filtered
+// with ?status=pending, delivered or dead
+func (h *Webhook) Deliveries(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := h.service.ListWebhookDeliveries(c.Request().Context(), id, c.QueryParam("status"))
+	if err != nil {
+		slog.Error("Failed to list webhook deliveries", "error", err, "id", id, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": deliveries})
+}
+
+func (h *Webhook) Attempts(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, deliveryID, err := webhookDeliveryID(c)
+	if err != nil {
+		return err
+	}
+
+	attempts, err := h.service.ListWebhookDeliveryAttempts(c.Request().Context(), id, deliveryID)
+	if err != nil {
+		slog.Error("Failed to list webhook delivery attempts", "error", err, "delivery_id", deliveryID, "request_id", reqID)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": attempts})
+}
+
+// Retry puts a dead delivery back on the queue
+func (h *Webhook) Retry(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	id, deliveryID, err := webhookDeliveryID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.RetryWebhookDelivery(c.Request().Context(), id, deliveryID); err != nil {
+		slog.Error("Failed to retry webhook delivery", "error", err, "delivery_id", deliveryID, "request_id", reqID)
+		return err
+	}
+
+	slog.Info("Requeued webhook delivery", "id", id, "delivery_id", deliveryID, "request_id", reqID)
+
+	return c.NoContent(http.StatusAccepted)
+}
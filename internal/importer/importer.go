@@ -0,0 +1,452 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// Format identifies the serialization of an import source
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// maxReportedErrors caps how many invalid rows are listed in a report
+const maxReportedErrors = 100
+
+// timestampColumns are tried, in order, when no timestamp column is mapped
+var timestampColumns = []string{"timestamp", "time", "datetime", "date", "ts"}
+
+// timeLayouts are tried, in order, when no explicit layout is given
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+}
+
+// ParseFormat resolves a user-supplied format name
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatCSV, FormatNDJSON:
+		return f, nil
+	case "jsonl":
+		return FormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported import format %q", s)
+	}
+}
+
+// Mapping describes how source columns become readings. Sources are either
+// long (one reading per row, with sensor and value columns) or wide (one
+// column per sensor). When neither SensorColumn nor Columns is set, columns
+// whose names are in the sensor catalog are imported as a wide source.
+type Mapping struct {
+	TimestampColumn string
+	// SensorColumn and ValueColumn select the long layout
+	SensorColumn string
+	ValueColumn  string
+	// UnitColumn optionally holds a per-row unit in the long layout
+	UnitColumn string
+	// Columns maps wide source columns to sensor names or aliases
+	Columns map[string]string
+	// Units maps sensor names to the unit the source records them in
+	Units map[string]string
+}
+
+func (m Mapping) validate() error {
+	for col, sensor := range m.Columns {
+		def, ok := internal.LookupSensor(sensor)
+		if !ok {
+			return fmt.Errorf("column %q is mapped to unknown sensor %q", col, sensor)
+		}
+		if def.Virtual {
+			return fmt.Errorf("column %q is mapped to virtual sensor %q, which cannot be imported", col, def.Type)
+		}
+	}
+	for sensor, unit := range m.Units {
+		if _, err := internal.ConvertToSensorUnit(sensor, unit, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type Options struct {
+	Format  Format
+	Mapping Mapping
+	// Location is applied to timestamps without a UTC offset; defaults to UTC
+	Location *time.Location
+	// TimeLayout overrides timestamp detection; "unix" and "unixms" are accepted
+	TimeLayout string
+}
+
+type record struct {
+	row    int
+	fields map[string]string
+}
+
+// Reader turns a CSV or NDJSON source into validated readings, recording
+// rows that cannot be imported in its report rather than failing
+type Reader struct {
+	opts    Options
+	next    func() (record, error)
+	pending []internal.ImportSensorReadingParams
+	report  internal.ImportReport
+	// resolved wide columns, keyed by source column
+	wide map[string]string
+}
+
+func NewReader(r io.Reader, opts Options) (*Reader, error) {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if err := opts.Mapping.validate(); err != nil {
+		return nil, err
+	}
+
+	rd := &Reader{
+		opts: opts,
+		report: internal.ImportReport{
+			SensorCounts: map[string]int{},
+			Errors:       []internal.ImportRowError{},
+		},
+	}
+
+	switch opts.Format {
+	case FormatCSV:
+		if err := rd.initCSV(r); err != nil {
+			return nil, err
+		}
+	case FormatNDJSON:
+		rd.initNDJSON(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", opts.Format)
+	}
+
+	return rd, nil
+}
+
+// Report returns the validation report for the rows read so far
+func (rd *Reader) Report() internal.ImportReport {
+	return rd.report
+}
+
+// Next returns the next valid reading, or io.EOF once the source is exhausted
+func (rd *Reader) Next() (internal.ImportSensorReadingParams, error) {
+	for len(rd.pending) == 0 {
+		rec, err := rd.next()
+		if err != nil {
+			return internal.ImportSensorReadingParams{}, err
+		}
+
+		rd.report.RowsRead++
+		readings, rowErr := rd.convert(rec)
+		if rowErr != nil {
+			rd.invalid(*rowErr)
+			continue
+		}
+		rd.pending = readings
+	}
+
+	p := rd.pending[0]
+	rd.pending = rd.pending[1:]
+
+	rd.report.Readings++
+	rd.report.SensorCounts[p.SensorType]++
+	if rd.report.From == nil || p.Timestamp.Before(*rd.report.From) {
+		t := p.Timestamp
+		rd.report.From = &t
+	}
+	if rd.report.To == nil || p.Timestamp.After(*rd.report.To) {
+		t := p.Timestamp
+		rd.report.To = &t
+	}
+
+	return p, nil
+}
+
+func (rd *Reader) invalid(e internal.ImportRowError) {
+	rd.report.InvalidRows++
+	if len(rd.report.Errors) < maxReportedErrors {
+		rd.report.Errors = append(rd.report.Errors, e)
+	} else {
+		rd.report.ErrorsTruncated = true
+	}
+}
+
+func (rd *Reader) initCSV(r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read csv header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+
+	row := 1
+	rd.next = func() (record, error) {
+		values, err := cr.Read()
+		row++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return record{row: row, fields: map[string]string{}}, nil
+			}
+			return record{}, err
+		}
+
+		fields := make(map[string]string, len(header))
+		for i, col := range header {
+			if i < len(values) {
+				fields[col] = strings.TrimSpace(values[i])
+			}
+		}
+		return record{row: row, fields: fields}, nil
+	}
+
+	return nil
+}
+
+func (rd *Reader) initNDJSON(r io.Reader) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	row := 0
+	rd.next = func() (record, error) {
+		for sc.Scan() {
+			row++
+			line := strings.TrimSpace(sc.Text())
+			if line == "" {
+				continue
+			}
+
+			var obj map[string]any
+			if err := json.Unmarshal([]byte(line), &obj); err != nil {
+				return record{row: row, fields: map[string]string{}}, nil
+			}
+
+			fields := make(map[string]string, len(obj))
+			for k, v := range obj {
+				switch v := v.(type) {
+				case nil:
+				case string:
+					fields[k] = strings.TrimSpace(v)
+				case float64:
+					fields[k] = strconv.FormatFloat(v, 'f', -1, 64)
+				default:
+					fields[k] = fmt.Sprint(v)
+				}
+			}
+			return record{row: row, fields: fields}, nil
+		}
+		if err := sc.Err(); err != nil {
+			return record{}, err
+		}
+		return record{}, io.EOF
+	}
+}
+
+func (rd *Reader) timestampColumn(fields map[string]string) string {
+	if rd.opts.Mapping.TimestampColumn != "" {
+		return rd.opts.Mapping.TimestampColumn
+	}
+
+	for _, candidate := range timestampColumns {
+		for col := range fields {
+			if strings.EqualFold(col, candidate) {
+				return col
+			}
+		}
+	}
+
+	return ""
+}
+
+// wideColumns resolves which source columns hold which sensor for wide sources
+func (rd *Reader) wideColumns(fields map[string]string, tsCol string) map[string]string {
+	if rd.wide != nil && rd.opts.Format == FormatCSV {
+		return rd.wide
+	}
+
+	wide := map[string]string{}
+	if len(rd.opts.Mapping.Columns) > 0 {
+		for col, sensor := range rd.opts.Mapping.Columns {
+			def, _ := internal.LookupSensor(sensor)
+			wide[col] = def.Type
+		}
+	} else {
+		for col := range fields {
+			if col == tsCol {
+				continue
+			}
+			if def, ok := internal.LookupSensor(col); ok && !def.Virtual {
+				wide[col] = def.Type
+			}
+		}
+	}
+
+	rd.wide = wide
+	return wide
+}
+
+func (rd *Reader) convert(rec record) ([]internal.ImportSensorReadingParams, *internal.ImportRowError) {
+	if len(rec.fields) == 0 {
+		return nil, &internal.ImportRowError{Row: rec.row, Message: "row could not be parsed"}
+	}
+
+	tsCol := rd.timestampColumn(rec.fields)
+	if tsCol == "" {
+		return nil, &internal.ImportRowError{Row: rec.row, Message: "no timestamp column found"}
+	}
+	ts, err := rd.parseTime(rec.fields[tsCol])
+	if err != nil {
+		return nil, &internal.ImportRowError{Row: rec.row, Column: tsCol, Message: err.Error()}
+	}
+
+	m := rd.opts.Mapping
+	if m.SensorColumn != "" {
+		valueCol := m.ValueColumn
+		if valueCol == "" {
+			valueCol = "value"
+		}
+
+		def, ok := internal.LookupSensor(rec.fields[m.SensorColumn])
+		if !ok || def.Virtual {
+			return nil, &internal.ImportRowError{Row: rec.row, Column: m.SensorColumn, Message: fmt.Sprintf("unknown sensor %q", rec.fields[m.SensorColumn])}
+		}
+
+		unit := m.Units[def.Type]
+		if m.UnitColumn != "" && rec.fields[m.UnitColumn] != "" {
+			unit = rec.fields[m.UnitColumn]
+		}
+
+		p, rowErr := rd.reading(rec.row, valueCol, def.Type, rec.fields[valueCol], unit, ts)
+		if rowErr != nil {
+			return nil, rowErr
+		}
+		return []internal.ImportSensorReadingParams{p}, nil
+	}
+
+	wide := rd.wideColumns(rec.fields, tsCol)
+	if len(wide) == 0 {
+		return nil, &internal.ImportRowError{Row: rec.row, Message: "no sensor columns found"}
+	}
+
+	readings := make([]internal.ImportSensorReadingParams, 0, len(wide))
+	for col, sensorType := range wide {
+		raw, ok := rec.fields[col]
+		if !ok || raw == "" {
+			// Spreadsheets routinely leave gaps for sensors that did not report
+			continue
+		}
+
+		p, rowErr := rd.reading(rec.row, col, sensorType, raw, rd.unitFor(sensorType), ts)
+		if rowErr != nil {
+			return nil, rowErr
+		}
+		readings = append(readings, p)
+	}
+
+	if len(readings) == 0 {
+		return nil, &internal.ImportRowError{Row: rec.row, Message: "row has no sensor values"}
+	}
+
+	return readings, nil
+}
+
+// unitFor looks up the source unit of a sensor, accepting aliases as keys
+func (rd *Reader) unitFor(sensorType string) string {
+	for name, unit := range rd.opts.Mapping.Units {
+		if def, ok := internal.LookupSensor(name); ok && def.Type == sensorType {
+			return unit
+		}
+	}
+
+	return ""
+}
+
+func (rd *Reader) reading(row int, col, sensorType, raw, unit string, ts time.Time) (internal.ImportSensorReadingParams, *internal.ImportRowError) {
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return internal.ImportSensorReadingParams{}, &internal.ImportRowError{Row: row, Column: col, Message: fmt.Sprintf("value %q is not a number", raw)}
+	}
+
+	v, err = internal.ConvertToSensorUnit(sensorType, unit, v)
+	if err != nil {
+		return internal.ImportSensorReadingParams{}, &internal.ImportRowError{Row: row, Column: col, Message: err.Error()}
+	}
+
+	return internal.ImportSensorReadingParams{
+		SensorType: sensorType,
+		Value:      v,
+		Timestamp:  ts,
+	}, nil
+}
+
+func (rd *Reader) parseTime(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, errors.New("timestamp is empty")
+	}
+
+	switch rd.opts.TimeLayout {
+	case "unix", "unixms":
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("timestamp %q is not a unix time", raw)
+		}
+		if rd.opts.TimeLayout == "unixms" {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	case "":
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, raw, rd.opts.Location); err == nil {
+				return t.UTC(), nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("timestamp %q is not in a recognized format", raw)
+	default:
+		t, err := time.ParseInLocation(rd.opts.TimeLayout, raw, rd.opts.Location)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("timestamp %q does not match layout %q", raw, rd.opts.TimeLayout)
+		}
+		return t.UTC(), nil
+	}
+}
+
+// ParseAssignments turns "key=value" pairs, as given on the command line or
+// in query strings, into a map
+func ParseAssignments(pairs []string) (map[string]string, error) {
+	m := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		for _, part := range strings.Split(pair, ",") {
+			k, v, ok := strings.Cut(part, "=")
+			if !ok || strings.TrimSpace(k) == "" || strings.TrimSpace(v) == "" {
+				return nil, fmt.Errorf("%q must be in the form key=value", part)
+			}
+			m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,72 @@
+// Package jobs runs periodic background work inside the API process.
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Job is a unit of background work run every Interval
+type Job struct {
+	// Name identifies the job in logs and is the key it is locked under
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Locker ensures only one replica runs a job at a time. TryLock runs fn and
+// reports true if it got the lock, or returns false without running fn if
+// another replica holds it.
+type Locker interface {
+	TryLock(ctx context.Context, name string, fn func(ctx context.Context) error) (bool, error)
+}
+
+type Runner struct {
+	locker Locker
+	jobs   []Job
+}
+
+func NewRunner(locker Locker, jobs ...Job) *Runner {
+	return &Runner{
+		locker: locker,
+		jobs:   jobs,
+	}
+}
+
+// Start runs every job on its own ticker until ctx is cancelled
+func (r *Runner) Start(ctx context.Context) {
+	for _, job := range r.jobs {
+		go r.loop(ctx, job)
+	}
+}
+
+func (r *Runner) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.run(ctx, job)
+		}
+	}
+}
+
+func (r *Runner) run(ctx context.Context, job Job) {
+	start := time.Now()
+
+	ran, err := r.locker.TryLock(ctx, job.Name, job.Run)
+	if err != nil {
+		slog.Error("background job failed", "job", job.Name, "error", err)
+		return
+	}
+	if !ran {
+		slog.Debug("background job skipped, locked by another instance", "job", job.Name)
+		return
+	}
+
+	slog.Debug("background job finished", "job", job.Name, "duration_ms", time.Since(start).Milliseconds())
+}
@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fake is a deterministic provider for tests and offline development. It
+// replies with Reply, or else echoes the last user message, streamed a word
+// at a time. Token counts are word counts.
+type Fake struct {
+	ModelName string
+	Reply     string
+	// ToolCalls, if set, are asked for in answer to each user message when
+	// the request offers tools; the reply comes once their results are in
+	ToolCalls []ToolCall
+	// Err, if set, is returned after FailAfter words have been streamed
+	Err       error
+	FailAfter int
+	// Delay holds back the first word, as a slow provider would
+	Delay time.Duration
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+func (f *Fake) Name() string { return KindFake }
+
+func (f *Fake) Model() string {
+	if f.ModelName == "" {
+		return KindFake
+	}
+	return f.ModelName
+}
+
+// Requests returns every request made so far, for assertions
+func (f *Fake) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+func (f *Fake) Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.mu.Unlock()
+
+	prompt := 0
+	for _, m := range req.Messages {
+		prompt += len(strings.Fields(m.Content))
+	}
+
+	if len(f.ToolCalls) > 0 && len(req.Tools) > 0 && len(req.Messages) > 0 && req.Messages[len(req.Messages)-1].Role == RoleUser {
+		return Response{
+			ToolCalls: append([]ToolCall(nil), f.ToolCalls...),
+			Model:     f.Model(),
+			Usage:     Usage{PromptTokens: prompt},
+		}, nil
+	}
+
+	reply := f.Reply
+	if reply == "" {
+		for i := len(req.Messages) - 1; i >= 0; i-- {
+			if req.Messages[i].Role == RoleUser {
+				reply = "You said: " + req.Messages[i].Content
+				break
+			}
+		}
+	}
+
+	words := strings.SplitAfter(reply, " ")
+	if req.MaxTokens > 0 && len(words) > req.MaxTokens {
+		words = words[:req.MaxTokens]
+	}
+
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-ctx.Done():
+			return Response{}, ctx.Err()
+		}
+	}
+
+	var content strings.Builder
+	for i, w := range words {
+		if f.Err != nil && i == f.FailAfter {
+			return Response{}, f.Err
+		}
+		if err := ctx.Err(); err != nil {
+			return Response{}, err
+		}
+		content.WriteString(w)
+		if err := onDelta(w); err != nil {
+			return Response{}, err
+		}
+	}
+	if f.Err != nil {
+		return Response{}, f.Err
+	}
+
+	return Response{
+		Content: content.String(),
+		Model:   f.Model(),
+		Usage:   Usage{PromptTokens: prompt, CompletionTokens: len(words)},
+	}, nil
+}
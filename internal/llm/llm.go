@@ -0,0 +1,162 @@
+// Package llm talks to large language models through interchangeable
+// providers: any OpenAI-compatible endpoint, a local Ollama server, or a
+// deterministic fake for tests.
+package llm
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Provider kinds
+const (
+	KindOpenAI = "openai"
+	KindOllama = "ollama"
+	KindFake   = "fake"
+)
+
+// Message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	// RoleTool carries the result of a tool call back to the model
+	RoleTool = "tool"
+)
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// ToolCalls are the tools an assistant message asked to run
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID is the call a tool message answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// Tool is a function the model may ask to have run instead of replying
+// straight away
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the arguments object
+	Parameters json.RawMessage
+}
+
+// ToolCall is the model asking for a tool to be run. Arguments is a JSON
+// object.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type Request struct {
+	Messages []Message
+	// Tools are offered to the model; a reply may then be tool calls
+	// instead of text
+	Tools []Tool
+	// MaxTokens caps the reply; zero leaves it to the provider
+	MaxTokens int
+}
+
+// Usage is the token count a provider reported for a request
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type Response struct {
+	Content string
+	// ToolCalls, when set, are to be run and their results sent back for
+	// the model to carry on
+	ToolCalls []ToolCall
+	Model     string
+	Usage     Usage
+}
+
+type Provider interface {
+	// Name is the provider kind, such as "ollama"
+	Name() string
+	Model() string
+	// Stream generates a reply, passing each piece to onDelta as it arrives.
+	// An error from onDelta stops the stream and is returned.
+	Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error)
+}
+
+// Complete generates a whole reply at once
+func Complete(ctx context.Context, p Provider, req Request) (Response, error) {
+	return p.Stream(ctx, req, func(string) error { return nil })
+}
+
+// Config selects and configures a provider
+type Config struct {
+	// Provider is the kind of backend; defaults to openai
+	Provider string
+	Model    string
+	// BaseURL points at the API, such as http://localhost:11434 for Ollama
+	// or any OpenAI-compatible /v1 endpoint; empty uses the provider default
+	BaseURL string
+	APIKey  string
+	// CAFile is a PEM bundle trusted on top of the system roots, for
+	// endpoints behind a private CA
+	CAFile string
+	// InsecureSkipVerify disables TLS verification. Only for local testing.
+	InsecureSkipVerify bool
+	// Timeout bounds a whole request, including streaming; zero means none
+	Timeout time.Duration
+}
+
+// New builds the provider described by cfg
+func New(cfg Config) (Provider, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case "", KindOpenAI:
+		client, err := httpClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewOpenAI(cfg, client), nil
+	case KindOllama:
+		client, err := httpClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewOllama(cfg, client), nil
+	case KindFake:
+		return &Fake{ModelName: cfg.Model}, nil
+	default:
+		return nil, fmt.Errorf("unknown llm provider %q, want %s, %s or %s", cfg.Provider, KindOpenAI, KindOllama, KindFake)
+	}
+}
+
+// httpClient verifies TLS against the system roots plus cfg.CAFile
+func httpClient(cfg Config) (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read llm CA bundle because %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("llm CA bundle %s holds no PEM certificates", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	tlsConfig.InsecureSkipVerify = cfg.InsecureSkipVerify
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport, Timeout: cfg.Timeout}, nil
+}
@@ -0,0 +1,185 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultOllamaURL is where a local Ollama server listens
+	DefaultOllamaURL   = "http://localhost:11434"
+	defaultOllamaModel = "llama3.1"
+)
+
+// Ollama talks to an Ollama server through its native chat API
+type Ollama struct {
+	client  *http.Client
+	baseURL string
+	model   string
+}
+
+func NewOllama(cfg Config, client *http.Client) *Ollama {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultOllamaURL
+	}
+	model := cfg.Model
+	if model == "" {
+		model = defaultOllamaModel
+	}
+
+	return &Ollama{client: client, baseURL: strings.TrimRight(baseURL, "/"), model: model}
+}
+
+func (o *Ollama) Name() string  { return KindOllama }
+func (o *Ollama) Model() string { return o.model }
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []ollamaTool    `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+// ollamaMessage differs from Message in how tool calls are passed: Ollama
+// has no call IDs, takes arguments as an object and names the tool that a
+// result is for
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters,omitempty"`
+	} `json:"function"`
+}
+
+// ollamaChunk is one line of a streamed reply. The last one has Done set and
+// carries the token counts.
+type ollamaChunk struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+func ollamaMessages(messages []Message) []ollamaMessage {
+	toolNames := map[string]string{}
+	res := make([]ollamaMessage, len(messages))
+	for i, m := range messages {
+		res[i] = ollamaMessage{Role: m.Role, Content: m.Content, ToolName: toolNames[m.ToolCallID]}
+		for _, call := range m.ToolCalls {
+			toolNames[call.ID] = call.Name
+
+			var c ollamaToolCall
+			c.Function.Name = call.Name
+			c.Function.Arguments = json.RawMessage(call.Arguments)
+			if !json.Valid(c.Function.Arguments) {
+				c.Function.Arguments = json.RawMessage("{}")
+			}
+			res[i].ToolCalls = append(res[i].ToolCalls, c)
+		}
+	}
+	return res
+}
+
+func (o *Ollama) Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error) {
+	body := ollamaRequest{Model: o.model, Messages: ollamaMessages(req.Messages), Stream: true}
+	for _, t := range req.Tools {
+		tool := ollamaTool{Type: "function"}
+		tool.Function.Name = t.Name
+		tool.Function.Description = t.Description
+		tool.Function.Parameters = t.Parameters
+		body.Tools = append(body.Tools, tool)
+	}
+	if req.MaxTokens > 0 {
+		body.Options = map[string]any{"num_predict": req.MaxTokens}
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return Response{}, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/api/chat", bytes.NewReader(payload))
+	if err != nil {
+		return Response{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	res, err := o.client.Do(httpReq)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to reach ollama: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1<<12))
+		return Response{}, fmt.Errorf("ollama returned %s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	out := Response{Model: o.model}
+	var content strings.Builder
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var chunk ollamaChunk
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			return Response{}, fmt.Errorf("invalid ollama stream line: %w", err)
+		}
+		if chunk.Error != "" {
+			return Response{}, fmt.Errorf("ollama: %s", chunk.Error)
+		}
+
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			if err := onDelta(chunk.Message.Content); err != nil {
+				return Response{}, err
+			}
+		}
+		for _, call := range chunk.Message.ToolCalls {
+			out.ToolCalls = append(out.ToolCalls, ToolCall{
+				ID:        fmt.Sprintf("call_%d", len(out.ToolCalls)),
+				Name:      call.Function.Name,
+				Arguments: string(call.Function.Arguments),
+			})
+		}
+		if chunk.Done {
+			out.Usage = Usage{PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount}
+			if chunk.Model != "" {
+				out.Model = chunk.Model
+			}
+			out.Content = content.String()
+			return out, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Response{}, fmt.Errorf("error receiving from ollama stream: %w", err)
+	}
+
+	return Response{}, io.ErrUnexpectedEOF
+}
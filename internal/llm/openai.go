@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const defaultOpenAIModel = openai.GPT3Dot5Turbo
+
+// OpenAI talks to the OpenAI API or any endpoint compatible with it
+type OpenAI struct {
+	client *openai.Client
+	model  string
+}
+
+func NewOpenAI(cfg Config, client *http.Client) *OpenAI {
+	config := openai.DefaultConfig(cfg.APIKey)
+	if cfg.BaseURL != "" {
+		config.BaseURL = cfg.BaseURL
+	}
+	config.HTTPClient = client
+
+	model := cfg.Model
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+
+	return &OpenAI{client: openai.NewClientWithConfig(config), model: model}
+}
+
+func (o *OpenAI) Name() string  { return KindOpenAI }
+func (o *OpenAI) Model() string { return o.model }
+
+func (o *OpenAI) Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error) {
+	messages := make([]openai.ChatCompletionMessage, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = openai.ChatCompletionMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for _, call := range m.ToolCalls {
+			messages[i].ToolCalls = append(messages[i].ToolCalls, openai.ToolCall{
+				ID:       call.ID,
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: call.Name, Arguments: call.Arguments},
+			})
+		}
+	}
+
+	var tools []openai.Tool
+	for _, t := range req.Tools {
+		tools = append(tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.Parameters,
+			},
+		})
+	}
+
+	stream, err := o.client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
+		Model:         o.model,
+		MaxTokens:     req.MaxTokens,
+		Messages:      messages,
+		Tools:         tools,
+		Stream:        true,
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to create openai stream: %w", err)
+	}
+	defer stream.Close()
+
+	res := Response{Model: o.model}
+	var content []byte
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Response{}, fmt.Errorf("error receiving from openai stream: %w", err)
+		}
+
+		if chunk.Model != "" {
+			res.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			res.Usage = Usage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+			}
+		}
+		for _, choice := range chunk.Choices {
+			// Tool calls arrive in pieces: the first names the call, the
+			// rest append to its arguments
+			for _, call := range choice.Delta.ToolCalls {
+				i := len(res.ToolCalls)
+				switch {
+				case call.Index != nil && *call.Index >= 0 && *call.Index < i:
+					i = *call.Index
+				case call.Index == nil && call.ID == "" && i > 0:
+					// Servers that leave out the index continue the last call
+					i--
+				}
+				if i == len(res.ToolCalls) {
+					res.ToolCalls = append(res.ToolCalls, ToolCall{})
+				}
+				if call.ID != "" {
+					res.ToolCalls[i].ID = call.ID
+				}
+				res.ToolCalls[i].Name += call.Function.Name
+				res.ToolCalls[i].Arguments += call.Function.Arguments
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content = append(content, choice.Delta.Content...)
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return Response{}, err
+			}
+		}
+	}
+
+	res.Content = string(content)
+	return res, nil
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"log/slog"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// defaultEncoding counts tokens for models whose tokenizer is not known,
+// such as those served by Ollama. It is close enough to budget prompts by.
+const defaultEncoding = tiktoken.MODEL_CL100K_BASE
+
+// Tokens each message costs on top of its content, for the role and
+// framing, and that priming the reply costs
+const (
+	tokensPerMessage = 4
+	tokensPerReply   = 3
+)
+
+var (
+	// The encodings ship with the binary; nothing is downloaded
+	loadEncodings = sync.OnceFunc(func() {
+		tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+	})
+	encodings sync.Map // model name to *tiktoken.Tiktoken, or nil if none loads
+)
+
+func encodingFor(model string) *tiktoken.Tiktoken {
+	if enc, ok := encodings.Load(model); ok {
+		return enc.(*tiktoken.Tiktoken)
+	}
+	loadEncodings()
+
+	enc, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		if enc, err = tiktoken.GetEncoding(defaultEncoding); err != nil {
+			slog.Warn("Failed to load tokenizer, estimating tokens instead", "model", model, "error", err)
+			enc = nil
+		}
+	}
+	encodings.Store(model, enc)
+	return enc
+}
+
+// CountTokens counts the tokens text takes up for model, with the model's
+// own tokenizer when it is known and cl100k_base otherwise
+func CountTokens(model, text string) int {
+	if text == "" {
+		return 0
+	}
+	if enc := encodingFor(model); enc != nil {
+		return len(enc.EncodeOrdinary(text))
+	}
+	// About four characters a token in English
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// CountMessageTokens counts the prompt tokens messages take up for model,
+// including what each message's role and framing cost
+func CountMessageTokens(model string, messages []Message) int {
+	tokens := tokensPerReply
+	for _, m := range messages {
+		tokens += tokensPerMessage + CountTokens(model, m.Content)
+		for _, call := range m.ToolCalls {
+			tokens += CountTokens(model, call.Name) + CountTokens(model, call.Arguments)
+		}
+	}
+	return tokens
+}
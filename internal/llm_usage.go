@@ -0,0 +1,78 @@
+package internal
+
+import "time"
+
+// Features language model usage is recorded against
+const (
+	LLMFeaturePlantAdvice = "plant_advice"
+	LLMFeatureAssistant   = "assistant"
+	LLMFeatureSummary     = "assistant_summary"
+	LLMFeatureQuery       = "assistant_query"
+)
+
+// LLMUsage is one request to the language model and what it cost. Cached
+// requests were answered from the advice cache without reaching the model.
+type LLMUsage struct {
+	ID               int64     `json:"id"`
+	UserID           *int      `json:"user_id"`
+	Feature          string    `json:"feature"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	LatencyMS        int64     `json:"latency_ms"`
+	CostUSD          float64   `json:"cost_usd"`
+	Cached           bool      `json:"cached"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type CreateLLMUsageParams struct {
+	UserID           *int
+	Feature          string
+	Provider         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	LatencyMS        int64
+	CostUSD          float64
+	Cached           bool
+}
+
+// LLMUsageTotal is the usage of one feature by one user over a UTC day.
+// UserID is nil for requests made without signing in.
+type LLMUsageTotal struct {
+	Day              time.Time `json:"day"`
+	Feature          string    `json:"feature"`
+	UserID           *int      `json:"user_id"`
+	Requests         int64     `json:"requests"`
+	CachedRequests   int64     `json:"cached_requests"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	CostUSD          float64   `json:"cost_usd"`
+}
+
+type SummarizeLLMUsageParams struct {
+	From   time.Time
+	To     time.Time
+	UserID *int
+}
+
+// LLMBudget caps the tokens spent on the language model per UTC day. Zero
+// means no cap.
+type LLMBudget struct {
+	DailyTokens     int64 `json:"daily_tokens"`
+	UserDailyTokens int64 `json:"user_daily_tokens"`
+	// AnonymousDailyTokens is shared by everyone who has not signed in
+	AnonymousDailyTokens int64 `json:"anonymous_daily_tokens"`
+}
+
+// LLMUsageReport is usage over a period, and how much of today's budget is
+// left
+type LLMUsageReport struct {
+	From            time.Time       `json:"from"`
+	To              time.Time       `json:"to"`
+	Totals          []LLMUsageTotal `json:"totals"`
+	Budget          LLMBudget       `json:"budget"`
+	TokensToday     int64           `json:"tokens_today"`
+	UserTokensToday *int64          `json:"user_tokens_today,omitempty"`
+}
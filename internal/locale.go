@@ -0,0 +1,106 @@
+package internal
+
+import "strings"
+
+// Languages advice and the assistant can answer in
+const (
+	LanguageEnglish = "en"
+	LanguageArabic  = "ar"
+)
+
+// Units temperatures can be given in
+const (
+	TemperatureCelsius    = "celsius"
+	TemperatureFahrenheit = "fahrenheit"
+)
+
+// Locale is how a user wants advice written: in which language, and with
+// temperatures in which unit. Empty fields mean no preference.
+type Locale struct {
+	Language        string `json:"language"`
+	TemperatureUnit string `json:"temperature_unit"`
+}
+
+// DefaultLocale is used for whatever neither the request nor the user's
+// preferences say
+var DefaultLocale = Locale{Language: LanguageEnglish, TemperatureUnit: TemperatureCelsius}
+
+// ParseLanguage accepts a language code or tag such as "ar" or "ar-JO" and
+// returns the language advice can be given in
+func ParseLanguage(s string) (string, error) {
+	tag := strings.ToLower(strings.TrimSpace(s))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+
+	switch tag {
+	case LanguageEnglish, LanguageArabic:
+		return tag, nil
+	default:
+		return "", NewInputError("unsupported language %q, use en or ar", s)
+	}
+}
+
+// ParseTemperatureUnit accepts the names readings use for Celsius and
+// Fahrenheit, such as "C", "°F" or "fahrenheit"
+func ParseTemperatureUnit(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "°c", "c", "celsius", "degc":
+		return TemperatureCelsius, nil
+	case "°f", "f", "fahrenheit", "degf":
+		return TemperatureFahrenheit, nil
+	default:
+		return "", NewInputError("unsupported temperature unit %q, use celsius or fahrenheit", s)
+	}
+}
+
+// Or fills in l's empty fields from fallback
+func (l Locale) Or(fallback Locale) Locale {
+	if l.Language == "" {
+		l.Language = fallback.Language
+	}
+	if l.TemperatureUnit == "" {
+		l.TemperatureUnit = fallback.TemperatureUnit
+	}
+	return l
+}
+
+// Direction is "rtl" for languages written right to left and "ltr" for the
+// rest
+func (l Locale) Direction() string {
+	if l.Language == LanguageArabic {
+		return "rtl"
+	}
+	return "ltr"
+}
+
+// Unit is the unit values of sensorType are shown in
+func (l Locale) Unit(sensorType string) string {
+	def, _ := LookupSensor(sensorType)
+	if def.Unit == "°C" && l.TemperatureUnit == TemperatureFahrenheit {
+		return "°F"
+	}
+	return def.Unit
+}
+
+// Value converts a stored value of sensorType into the unit it is shown in
+func (l Locale) Value(sensorType string, v float64) float64 {
+	if l.Unit(sensorType) == "°F" {
+		return v*9/5 + 32
+	}
+	return v
+}
+
+// Delta converts a difference or rate of change of sensorType, which unlike
+// a value is not offset, into the unit it is shown in
+func (l Locale) Delta(sensorType string, d float64) float64 {
+	if l.Unit(sensorType) == "°F" {
+		return d * 9 / 5
+	}
+	return d
+}
+
+// Range converts a range of sensorType into the unit it is shown in
+func (l Locale) Range(sensorType string, r Range) Range {
+	return Range{Min: l.Value(sensorType, r.Min), Max: l.Value(sensorType, r.Max)}
+}
@@ -0,0 +1,42 @@
+package internal
+
+import "time"
+
+// RedactedConfigKeys are channel settings never echoed back to clients, who
+// see RedactedConfigValue in their place
+var RedactedConfigKeys = []string{"secret", "token"}
+
+// RedactedConfigValue stands in for a redacted setting. Sending it back in
+// an update keeps the stored value.
+const RedactedConfigValue = "********"
+
+// NotificationChannel is where one user wants alerts delivered. Config holds
+// the kind-specific settings, e.g. url and secret for webhooks, to for email,
+// server, topic and token for ntfy, and chat_id for Telegram.
+type NotificationChannel struct {
+	ID          int64             `json:"id"`
+	UserID      int               `json:"user_id"`
+	Kind        string            `json:"kind"`
+	Name        string            `json:"name"`
+	Config      map[string]string `json:"config"`
+	MinSeverity string            `json:"min_severity"`
+	// QuietHoursStart and QuietHoursEnd are HH:MM in Timezone. Only critical
+	// alerts are delivered during quiet hours.
+	QuietHoursStart *string   `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd   *string   `json:"quiet_hours_end,omitempty"`
+	Timezone        string    `json:"timezone"`
+	Enabled         bool      `json:"enabled"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type NotificationChannelParams struct {
+	Kind            string
+	Name            string
+	Config          map[string]string
+	MinSeverity     string
+	QuietHoursStart *string
+	QuietHoursEnd   *string
+	Timezone        string
+	Enabled         bool
+}
@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPConfig is the relay email notifications are sent through. Username may
+// be empty for relays without authentication, such as MailHog.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Email sends notifications as plain-text mail
+type Email struct {
+	SMTP SMTPConfig
+	To   string
+}
+
+func (e *Email) Notify(ctx context.Context, n Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.SMTP.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", e.To)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.At.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(n.Body)
+	msg.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if e.SMTP.Username != "" {
+		auth = smtp.PlainAuth("", e.SMTP.Username, e.SMTP.Password, e.SMTP.Host)
+	}
+
+	return smtp.SendMail(net.JoinHostPort(e.SMTP.Host, e.SMTP.Port), auth, e.SMTP.From, []string{e.To}, msg.Bytes())
+}
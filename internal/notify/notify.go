@@ -0,0 +1,177 @@
+// Package notify delivers alert notifications to webhooks, email, ntfy and
+// Telegram.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// Channel kinds
+const (
+	KindWebhook  = "webhook"
+	KindEmail    = "email"
+	KindNtfy     = "ntfy"
+	KindTelegram = "telegram"
+)
+
+// Notification is a message about an alert, rendered by each channel in its
+// own format
+type Notification struct {
+	Title      string    `json:"title"`
+	Body       string    `json:"body"`
+	Severity   string    `json:"severity"`
+	AlertID    int64     `json:"alert_id,omitempty"`
+	SensorType string    `json:"sensor_type,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Settings holds the server-wide configuration channels share, such as the
+// SMTP relay and the Telegram bot
+type Settings struct {
+	SMTP          SMTPConfig
+	TelegramToken string
+	HTTPClient    *http.Client
+}
+
+func (s Settings) httpClient() *http.Client {
+	if s.HTTPClient != nil {
+		return s.HTTPClient
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// New builds the notifier for a channel of the given kind from its per-user
+// config
+func New(kind string, config map[string]string, settings Settings) (Notifier, error) {
+	required := func(keys ...string) error {
+		for _, k := range keys {
+			if config[k] == "" {
+				return fmt.Errorf("%s channels need %q", kind, k)
+			}
+		}
+		return nil
+	}
+
+	switch kind {
+	case KindWebhook:
+		if err := required("url"); err != nil {
+			return nil, err
+		}
+		return &Webhook{URL: config["url"], Secret: config["secret"], Client: settings.httpClient()}, nil
+	case KindEmail:
+		if err := required("to"); err != nil {
+			return nil, err
+		}
+		if settings.SMTP.Host == "" {
+			return nil, errors.New("email is not configured on this server")
+		}
+		smtp := settings.SMTP
+		if smtp.Port == "" {
+			smtp.Port = "25"
+		}
+		return &Email{SMTP: smtp, To: config["to"]}, nil
+	case KindNtfy:
+		if err := required("topic"); err != nil {
+			return nil, err
+		}
+		return &Ntfy{ServerURL: config["server"], Topic: config["topic"], Token: config["token"], Client: settings.httpClient()}, nil
+	case KindTelegram:
+		if err := required("chat_id"); err != nil {
+			return nil, err
+		}
+		if settings.TelegramToken == "" {
+			return nil, errors.New("telegram is not configured on this server")
+		}
+		return &Telegram{Token: settings.TelegramToken, ChatID: config["chat_id"], Client: settings.httpClient()}, nil
+	default:
+		return nil, fmt.Errorf("unknown channel kind %q", kind)
+	}
+}
+
+// permanentError marks a failure that retrying cannot fix, such as a
+// rejected request
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that Send gives up immediately
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// Backoff controls how Send retries a failed delivery
+type Backoff struct {
+	Attempts int
+	Initial  time.Duration
+	Max      time.Duration
+}
+
+func DefaultBackoff() Backoff {
+	return Backoff{
+		Attempts: 5,
+		Initial:  2 * time.Second,
+		Max:      2 * time.Minute,
+	}
+}
+
+// Send delivers n, retrying transient failures with exponential backoff and
+// jitter until the attempts run out or ctx is done
+func Send(ctx context.Context, notifier Notifier, n Notification, b Backoff) error {
+	delay := b.Initial
+	var err error
+	for attempt := 1; attempt <= b.Attempts; attempt++ {
+		if err = notifier.Notify(ctx, n); err == nil || IsPermanent(err) {
+			return err
+		}
+		if attempt == b.Attempts {
+			break
+		}
+
+		// Full jitter keeps replicas retrying the same outage apart
+		wait := time.Duration(rand.Int64N(int64(delay) + 1))
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+
+		delay *= 2
+		if delay > b.Max {
+			delay = b.Max
+		}
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %w", b.Attempts, err)
+}
+
+// checkResponse turns an unsuccessful HTTP response into an error, marking
+// client errors other than rate limiting as permanent
+func checkResponse(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+
+	err := fmt.Errorf("unexpected status %s", res.Status)
+	if res.StatusCode >= 400 && res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultNtfyServer is used when a channel does not name its own server
+const DefaultNtfyServer = "https://ntfy.sh"
+
+// Ntfy publishes notifications to an ntfy topic
+type Ntfy struct {
+	ServerURL string
+	Topic     string
+	Token     string
+	Client    *http.Client
+}
+
+func ntfyPriority(severity string) string {
+	switch severity {
+	case "critical":
+		return "urgent"
+	case "warning":
+		return "high"
+	default:
+		return "default"
+	}
+}
+
+func (n *Ntfy) Notify(ctx context.Context, msg Notification) error {
+	server := n.ServerURL
+	if server == "" {
+		server = DefaultNtfyServer
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimRight(server, "/")+"/"+url.PathEscape(n.Topic), strings.NewReader(msg.Body))
+	if err != nil {
+		return Permanent(err)
+	}
+	// Header values must be ASCII; ntfy decodes RFC 2047 encoded words
+	req.Header.Set("Title", mime.QEncoding.Encode("utf-8", msg.Title))
+	req.Header.Set("Priority", ntfyPriority(msg.Severity))
+	req.Header.Set("Tags", "seedling,"+msg.Severity)
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+
+	res, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	return checkResponse(res)
+}
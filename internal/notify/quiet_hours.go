@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuietHours is a daily window, such as 22:00 to 07:00, during which only
+// critical notifications are delivered
+type QuietHours struct {
+	Start    time.Duration // offset from local midnight
+	End      time.Duration
+	Location *time.Location
+}
+
+// ParseQuietHours parses HH:MM bounds in the named time zone
+func ParseQuietHours(start, end, tz string) (QuietHours, error) {
+	s, err := parseClock(start)
+	if err != nil {
+		return QuietHours{}, err
+	}
+	e, err := parseClock(end)
+	if err != nil {
+		return QuietHours{}, err
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return QuietHours{}, fmt.Errorf("unknown time zone %q", tz)
+	}
+
+	return QuietHours{Start: s, End: e, Location: loc}, nil
+}
+
+func parseClock(v string) (time.Duration, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", v)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether t falls within the quiet hours. Windows where End
+// is before Start wrap past midnight.
+func (q QuietHours) Contains(t time.Time) bool {
+	if q.Start == q.End {
+		return false
+	}
+
+	local := t.In(q.Location)
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+
+	if q.Start < q.End {
+		return clock >= q.Start && clock < q.End
+	}
+	return clock >= q.Start || clock < q.End
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultTelegramAPI is the Bot API endpoint used when BaseURL is empty
+const DefaultTelegramAPI = "https://api.telegram.org"
+
+// Telegram sends notifications through a bot to a chat
+type Telegram struct {
+	Token   string
+	ChatID  string
+	BaseURL string
+	Client  *http.Client
+}
+
+func (t *Telegram) Notify(ctx context.Context, n Notification) error {
+	base := t.BaseURL
+	if base == "" {
+		base = DefaultTelegramAPI
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"chat_id":              t.ChatID,
+		"text":                 n.Title + "\n\n" + n.Body,
+		"disable_notification": n.Severity == "info",
+	})
+	if err != nil {
+		return Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimRight(base, "/")+"/bot"+t.Token+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	return checkResponse(res)
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of the timestamp, a dot
+	// and the body, keyed with the channel secret
+	SignatureHeader = "X-Green-Signature"
+	// TimestampHeader carries the unix time the request was signed at, so
+	// receivers can reject replays
+	TimestampHeader = "X-Green-Timestamp"
+)
+
+// Webhook POSTs notifications as JSON
+type Webhook struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+// Sign returns the signature of body sent at ts
+func Sign(secret string, ts time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *Webhook) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	_, err = PostSigned(ctx, w.Client, w.URL, w.Secret, body, nil)
+	return err
+}
+
+// PostSigned POSTs a JSON body with any extra headers, signed when secret is
+// set. It returns the response status code, or zero if no response arrived.
+func PostSigned(ctx context.Context, client *http.Client, url, secret string, body []byte, header http.Header) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, Permanent(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if secret != "" {
+		now := time.Now()
+		req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+		req.Header.Set(SignatureHeader, Sign(secret, now, body))
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	return res.StatusCode, checkResponse(res)
+}
@@ -0,0 +1,121 @@
+package internal
+
+import "strings"
+
+// Range is an inclusive band of acceptable values
+type Range struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// PlantProfile holds the growing conditions a crop does well in, keyed by
+// sensor type
+type PlantProfile struct {
+	Name    string           `json:"name"`
+	Aliases []string         `json:"aliases,omitempty"`
+	Ranges  map[string]Range `json:"ranges"`
+}
+
+// reservoirRange keeps the water tank from running dry whatever is grown
+var reservoirRange = Range{Min: 20, Max: 100}
+
+// PlantProfiles lists the crops advice knows about. Light ranges are for
+// daytime.
+var PlantProfiles = []PlantProfile{
+	{
+		Name:    "strawberry",
+		Aliases: []string{"strawberries"},
+		Ranges: map[string]Range{
+			"temperature": {Min: 15, Max: 26},
+			"humidity":    {Min: 60, Max: 80},
+			"soil":        {Min: 40, Max: 70},
+			"light":       {Min: 10000, Max: 50000},
+			"water":       reservoirRange,
+		},
+	},
+	{
+		Name:    "tomato",
+		Aliases: []string{"tomatoes"},
+		Ranges: map[string]Range{
+			"temperature": {Min: 18, Max: 29},
+			"humidity":    {Min: 60, Max: 80},
+			"soil":        {Min: 40, Max: 70},
+			"light":       {Min: 15000, Max: 70000},
+			"water":       reservoirRange,
+		},
+	},
+	{
+		Name:    "cucumber",
+		Aliases: []string{"cucumbers"},
+		Ranges: map[string]Range{
+			"temperature": {Min: 20, Max: 30},
+			"humidity":    {Min: 70, Max: 90},
+			"soil":        {Min: 50, Max: 80},
+			"light":       {Min: 15000, Max: 60000},
+			"water":       reservoirRange,
+		},
+	},
+	{
+		Name:    "lettuce",
+		Aliases: []string{"lettuces"},
+		Ranges: map[string]Range{
+			"temperature": {Min: 10, Max: 22},
+			"humidity":    {Min: 50, Max: 70},
+			"soil":        {Min: 45, Max: 70},
+			"light":       {Min: 8000, Max: 40000},
+			"water":       reservoirRange,
+		},
+	},
+	{
+		Name:    "pepper",
+		Aliases: []string{"peppers", "bell pepper", "chili", "chilli"},
+		Ranges: map[string]Range{
+			"temperature": {Min: 18, Max: 30},
+			"humidity":    {Min: 60, Max: 75},
+			"soil":        {Min: 40, Max: 65},
+			"light":       {Min: 15000, Max: 60000},
+			"water":       reservoirRange,
+		},
+	},
+	{
+		Name: "basil",
+		Ranges: map[string]Range{
+			"temperature": {Min: 18, Max: 30},
+			"humidity":    {Min: 40, Max: 60},
+			"soil":        {Min: 35, Max: 60},
+			"light":       {Min: 15000, Max: 50000},
+			"water":       reservoirRange,
+		},
+	},
+}
+
+// GenericPlantProfile is a broad band most greenhouse crops tolerate, for
+// plants missing from the catalog
+var GenericPlantProfile = PlantProfile{
+	Name: "plant",
+	Ranges: map[string]Range{
+		"temperature": {Min: 15, Max: 30},
+		"humidity":    {Min: 50, Max: 85},
+		"soil":        {Min: 35, Max: 75},
+		"light":       {Min: 10000, Max: 60000},
+		"water":       reservoirRange,
+	},
+}
+
+// LookupPlantProfile resolves a plant name or alias (case-insensitive) to
+// its profile
+func LookupPlantProfile(name string) (PlantProfile, bool) {
+	name = strings.TrimSpace(name)
+	for _, p := range PlantProfiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+		for _, alias := range p.Aliases {
+			if strings.EqualFold(alias, name) {
+				return p, true
+			}
+		}
+	}
+
+	return PlantProfile{}, false
+}
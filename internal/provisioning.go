@@ -0,0 +1,61 @@
+package internal
+
+import "time"
+
+// DeviceAPIKeyHeader carries the API key a provisioned device authenticates
+// with
+const DeviceAPIKeyHeader = "X-API-Key"
+
+// Claim statuses. A claim waits for an admin while pending, then waits for
+// the device to collect its key once approved. Claims nobody finishes in
+// time expire, and the device may then ask again.
+const (
+	ClaimPending  = "pending"
+	ClaimApproved = "approved"
+	ClaimRejected = "rejected"
+	ClaimClaimed  = "claimed"
+	ClaimExpired  = "expired"
+)
+
+// DeviceClaim is a board asking to join with its factory claim code
+type DeviceClaim struct {
+	ID       int64  `json:"id"`
+	DeviceID string `json:"device_id"`
+	// CodeHint is the end of the claim code, for matching a request to the
+	// sticker on a board
+	CodeHint        string    `json:"code_hint"`
+	FirmwareVersion string    `json:"firmware_version"`
+	IPAddress       string    `json:"ip_address"`
+	Status          string    `json:"status"`
+	Name            string    `json:"name"`
+	Zone            *string   `json:"zone,omitempty"`
+	ReviewedBy      string    `json:"reviewed_by,omitempty"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ProvisionRequest is what a board sends when it boots unprovisioned
+type ProvisionRequest struct {
+	ClaimCode       string `json:"claim_code"`
+	DeviceID        string `json:"device_id"`
+	FirmwareVersion string `json:"firmware_version"`
+	IPAddress       string `json:"-"`
+}
+
+// ClaimApproval is how an admin admits a device
+type ClaimApproval struct {
+	Name       string
+	Zone       *string
+	ReviewedBy string
+}
+
+// ProvisionResult answers a provisioning request. The API key and config are
+// only sent once, when the device collects them after approval.
+type ProvisionResult struct {
+	Status    string                `json:"status"`
+	DeviceID  string                `json:"device_id"`
+	ExpiresAt *time.Time            `json:"expires_at,omitempty"`
+	APIKey    string                `json:"api_key,omitempty"`
+	Config    *DeviceConfigDocument `json:"config,omitempty"`
+}
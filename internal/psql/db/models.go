@@ -8,6 +8,269 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type Alert struct {
+	ID             int64
+	RuleID         int64
+	SensorType     string
+	Severity       string
+	Status         string
+	Message        string
+	Value          pgtype.Float8
+	OpenedAt       pgtype.Timestamptz
+	AcknowledgedAt pgtype.Timestamptz
+	AcknowledgedBy pgtype.Text
+	ResolvedAt     pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	Silenced       bool
+}
+
+type AlertRule struct {
+	ID              int64
+	Name            string
+	SensorType      string
+	Kind            string
+	Comparison      string
+	Threshold       float64
+	DurationSeconds int32
+	Severity        string
+	Enabled         bool
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+	Zone            pgtype.Text
+}
+
+type AlertSilence struct {
+	ID         int64
+	SensorType pgtype.Text
+	Zone       pgtype.Text
+	RuleID     pgtype.Int8
+	StartsAt   pgtype.Timestamptz
+	EndsAt     pgtype.Timestamptz
+	Reason     string
+	CreatedBy  string
+	CreatedAt  pgtype.Timestamptz
+}
+
+type AssistantConversation struct {
+	ID                int64
+	UserID            int32
+	Title             string
+	Plant             string
+	Summary           string
+	SummarizedThrough int64
+	CreatedAt         pgtype.Timestamptz
+	UpdatedAt         pgtype.Timestamptz
+}
+
+type AssistantMessage struct {
+	ID             int64
+	ConversationID int64
+	Role           string
+	Content        string
+	Source         pgtype.Text
+	CreatedAt      pgtype.Timestamptz
+}
+
+type AuditLog struct {
+	ID         int64
+	EntityType string
+	EntityID   string
+	Action     string
+	Actor      string
+	Data       []byte
+	CreatedAt  pgtype.Timestamptz
+}
+
+type ControlProposal struct {
+	ID              int64
+	SensorType      string
+	Mode            string
+	ManualIntValue  pgtype.Int4
+	ManualBoolValue pgtype.Bool
+	DurationMinutes pgtype.Int4
+	Reason          string
+	Status          string
+	ProposedBy      string
+	UserID          pgtype.Int4
+	ConversationID  pgtype.Int8
+	ReviewedBy      string
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+}
+
+type DesiredActuatorState struct {
+	Actuator string
+	Desired  []byte
+}
+
+type Device struct {
+	ID              string
+	Name            string
+	FirmwareVersion string
+	IpAddress       string
+	Zone            pgtype.Text
+	Sensors         []string
+	Status          string
+	LastSeenAt      pgtype.Timestamptz
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+}
+
+type DeviceActuatorState struct {
+	DeviceID        string
+	Actuator        string
+	Desired         []byte
+	DesiredVersion  int64
+	DesiredAt       pgtype.Timestamptz
+	Reported        []byte
+	ReportedVersion int64
+	ReportedAt      pgtype.Timestamptz
+}
+
+type DeviceApiKey struct {
+	DeviceID   string
+	KeyHash    string
+	CreatedAt  pgtype.Timestamptz
+	LastUsedAt pgtype.Timestamptz
+}
+
+type DeviceClaim struct {
+	ID              int64
+	CodeHash        string
+	CodeHint        string
+	DeviceID        string
+	FirmwareVersion string
+	IpAddress       string
+	Status          string
+	Name            string
+	Zone            pgtype.Text
+	ReviewedBy      string
+	ExpiresAt       pgtype.Timestamptz
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+}
+
+type DeviceConfig struct {
+	DeviceID  string
+	Version   int64
+	Config    []byte
+	UpdatedBy string
+	UpdatedAt pgtype.Timestamptz
+}
+
+type DeviceFirmwareUpdate struct {
+	DeviceID  string
+	RolloutID int64
+	Status    string
+	Error     string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+}
+
+type DeviceHealthEvent struct {
+	ID         int64
+	SensorType string
+	DeviceID   pgtype.Text
+	Kind       string
+	Message    string
+	StartedAt  pgtype.Timestamptz
+	ResolvedAt pgtype.Timestamptz
+}
+
+type FirmwareRelease struct {
+	ID         int64
+	Version    string
+	Sha256     string
+	Size       int64
+	StorageKey string
+	Notes      string
+	CreatedAt  pgtype.Timestamptz
+}
+
+type FirmwareRollout struct {
+	ID               int64
+	ReleaseID        int64
+	GroupID          pgtype.Int8
+	Stages           []int32
+	Stage            int32
+	Status           string
+	FailureThreshold int32
+	HaltReason       pgtype.Text
+	ResumedAt        pgtype.Timestamptz
+	CreatedAt        pgtype.Timestamptz
+	UpdatedAt        pgtype.Timestamptz
+}
+
+type FirmwareRolloutGroup struct {
+	ID        int64
+	Name      string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+}
+
+type FirmwareRolloutGroupDevice struct {
+	GroupID  int64
+	DeviceID string
+}
+
+type LlmUsage struct {
+	ID               int64
+	UserID           pgtype.Int4
+	Feature          string
+	Provider         string
+	Model            string
+	PromptTokens     int32
+	CompletionTokens int32
+	LatencyMs        int64
+	CostUsd          float64
+	Cached           bool
+	CreatedAt        pgtype.Timestamptz
+}
+
+type MaintenanceWindow struct {
+	ID         int64
+	Name       string
+	SensorType pgtype.Text
+	Zone       pgtype.Text
+	RuleID     pgtype.Int8
+	Weekdays   []int32
+	StartTime  string
+	EndTime    string
+	Timezone   string
+	Reason     string
+	Enabled    bool
+	CreatedAt  pgtype.Timestamptz
+	UpdatedAt  pgtype.Timestamptz
+}
+
+type NotificationChannel struct {
+	ID              int64
+	UserID          int32
+	Kind            string
+	Name            string
+	Config          []byte
+	MinSeverity     string
+	QuietHoursStart pgtype.Text
+	QuietHoursEnd   pgtype.Text
+	Timezone        string
+	Enabled         bool
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+}
+
+type SensorCalibration struct {
+	ID            int64
+	SensorType    string
+	DeviceID      pgtype.Text
+	Method        string
+	Coefficients  []float64
+	EffectiveFrom pgtype.Timestamptz
+	Note          string
+	CreatedAt     pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
+	RecomputeFrom pgtype.Timestamptz
+}
+
 type SensorControl struct {
 	ID              int32
 	SensorType      string
@@ -19,16 +282,74 @@ type SensorControl struct {
 	ManualIntValue  pgtype.Int4
 }
 
+type SensorQualityRule struct {
+	SensorType         string
+	MinValue           float64
+	MaxValue           float64
+	MaxChangePerMinute pgtype.Float8
+	FlatlineMinutes    int32
+	FlatlineTolerance  float64
+	UpdatedAt          pgtype.Timestamptz
+}
+
 type SensorReading struct {
-	ID         int64
-	SensorType string
-	Value      float64
-	Timestamp  pgtype.Timestamptz
+	ID            int64
+	SensorType    string
+	Value         float64
+	Timestamp     pgtype.Timestamptz
+	RawValue      float64
+	CalibrationID pgtype.Int8
+	Quality       string
+	QualityReason string
+	DeviceID      pgtype.Text
 }
 
 type User struct {
-	ID           int32
-	Username     string
-	PasswordHash string
-	CreatedAt    pgtype.Timestamptz
+	ID              int32
+	Username        string
+	PasswordHash    string
+	CreatedAt       pgtype.Timestamptz
+	Language        string
+	TemperatureUnit string
+	Role            string
+}
+
+type WebhookDelivery struct {
+	ID             int64
+	SubscriptionID int64
+	EventID        int64
+	Status         string
+	Attempts       int32
+	NextAttemptAt  pgtype.Timestamptz
+	LastError      string
+	LastStatusCode pgtype.Int4
+	CreatedAt      pgtype.Timestamptz
+	DeliveredAt    pgtype.Timestamptz
+}
+
+type WebhookDeliveryAttempt struct {
+	ID          int64
+	DeliveryID  int64
+	AttemptedAt pgtype.Timestamptz
+	StatusCode  pgtype.Int4
+	Error       string
+	DurationMs  int32
+}
+
+type WebhookOutbox struct {
+	ID        int64
+	EventType string
+	Payload   []byte
+	CreatedAt pgtype.Timestamptz
+}
+
+type WebhookSubscription struct {
+	ID          int64
+	Url         string
+	EventTypes  []string
+	Secret      string
+	Description string
+	Enabled     bool
+	CreatedAt   pgtype.Timestamptz
+	UpdatedAt   pgtype.Timestamptz
 }
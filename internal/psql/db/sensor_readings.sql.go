@@ -11,31 +11,236 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const aggregateSensorReadings = `-- name: AggregateSensorReadings :many
+SELECT sensor_type,
+       date_bin($1::interval, timestamp, $2::timestamptz)::timestamptz AS bucket_start,
+       min(value)::double precision AS min_value,
+       max(value)::double precision AS max_value,
+       avg(value)::double precision AS avg_value,
+       count(*) AS reading_count
+FROM sensor_readings
+WHERE sensor_type = ANY($3::text[])
+  AND timestamp >= $2
+  AND timestamp < $4
+  AND quality <> 'bad'
+GROUP BY sensor_type, bucket_start
+ORDER BY bucket_start ASC, sensor_type ASC
+`
+
+type AggregateSensorReadingsParams struct {
+	Bucket      pgtype.Interval
+	FromTime    pgtype.Timestamptz
+	SensorTypes []string
+	ToTime      pgtype.Timestamptz
+}
+
+type AggregateSensorReadingsRow struct {
+	SensorType   string
+	BucketStart  pgtype.Timestamptz
+	MinValue     float64
+	MaxValue     float64
+	AvgValue     float64
+	ReadingCount int64
+}
+
+func (q *Queries) AggregateSensorReadings(ctx context.Context, arg AggregateSensorReadingsParams) ([]AggregateSensorReadingsRow, error) {
+	rows, err := q.db.Query(ctx, aggregateSensorReadings,
+		arg.Bucket,
+		arg.FromTime,
+		arg.SensorTypes,
+		arg.ToTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AggregateSensorReadingsRow
+	for rows.Next() {
+		var i AggregateSensorReadingsRow
+		if err := rows.Scan(
+			&i.SensorType,
+			&i.BucketStart,
+			&i.MinValue,
+			&i.MaxValue,
+			&i.AvgValue,
+			&i.ReadingCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createSensorReading = `-- name: CreateSensorReading :one
-INSERT INTO sensor_readings (sensor_type, value)
-VALUES ($1, $2)
-RETURNING id, sensor_type, value, timestamp
+INSERT INTO sensor_readings (sensor_type, value, raw_value, calibration_id, quality, quality_reason, device_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id
 `
 
 type CreateSensorReadingParams struct {
-	SensorType string
-	Value      float64
+	SensorType    string
+	Value         float64
+	RawValue      float64
+	CalibrationID pgtype.Int8
+	Quality       string
+	QualityReason string
+	DeviceID      pgtype.Text
 }
 
 func (q *Queries) CreateSensorReading(ctx context.Context, arg CreateSensorReadingParams) (SensorReading, error) {
-	row := q.db.QueryRow(ctx, createSensorReading, arg.SensorType, arg.Value)
+	row := q.db.QueryRow(ctx, createSensorReading,
+		arg.SensorType,
+		arg.Value,
+		arg.RawValue,
+		arg.CalibrationID,
+		arg.Quality,
+		arg.QualityReason,
+		arg.DeviceID,
+	)
+	var i SensorReading
+	err := row.Scan(
+		&i.ID,
+		&i.SensorType,
+		&i.Value,
+		&i.Timestamp,
+		&i.RawValue,
+		&i.CalibrationID,
+		&i.Quality,
+		&i.QualityReason,
+		&i.DeviceID,
+	)
+	return i, err
+}
+
+const getLatestSensorReadings = `-- name: GetLatestSensorReadings :many
+SELECT DISTINCT ON (sensor_type) id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
+WHERE quality <> 'bad'
+ORDER BY sensor_type, timestamp DESC
+`
+
+func (q *Queries) GetLatestSensorReadings(ctx context.Context) ([]SensorReading, error) {
+	rows, err := q.db.Query(ctx, getLatestSensorReadings)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SensorReading
+	for rows.Next() {
+		var i SensorReading
+		if err := rows.Scan(
+			&i.ID,
+			&i.SensorType,
+			&i.Value,
+			&i.Timestamp,
+			&i.RawValue,
+			&i.CalibrationID,
+			&i.Quality,
+			&i.QualityReason,
+			&i.DeviceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLatestUsableSensorReadingByDevice = `-- name: GetLatestUsableSensorReadingByDevice :one
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
+WHERE sensor_type = $1
+  AND device_id IS NOT DISTINCT FROM $2
+  AND quality <> 'bad'
+ORDER BY timestamp DESC
+LIMIT 1
+`
+
+type GetLatestUsableSensorReadingByDeviceParams struct {
+	SensorType string
+	DeviceID   pgtype.Text
+}
+
+func (q *Queries) GetLatestUsableSensorReadingByDevice(ctx context.Context, arg GetLatestUsableSensorReadingByDeviceParams) (SensorReading, error) {
+	row := q.db.QueryRow(ctx, getLatestUsableSensorReadingByDevice, arg.SensorType, arg.DeviceID)
+	var i SensorReading
+	err := row.Scan(
+		&i.ID,
+		&i.SensorType,
+		&i.Value,
+		&i.Timestamp,
+		&i.RawValue,
+		&i.CalibrationID,
+		&i.Quality,
+		&i.QualityReason,
+		&i.DeviceID,
+	)
+	return i, err
+}
+
+const getLatestUsableSensorReadingByType = `-- name: GetLatestUsableSensorReadingByType :one
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
+WHERE sensor_type = $1
+  AND quality <> 'bad'
+ORDER BY timestamp DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestUsableSensorReadingByType(ctx context.Context, sensorType string) (SensorReading, error) {
+	row := q.db.QueryRow(ctx, getLatestUsableSensorReadingByType, sensorType)
 	var i SensorReading
 	err := row.Scan(
 		&i.ID,
 		&i.SensorType,
 		&i.Value,
 		&i.Timestamp,
+		&i.RawValue,
+		&i.CalibrationID,
+		&i.Quality,
+		&i.QualityReason,
+		&i.DeviceID,
+	)
+	return i, err
+}
+
+const getLatestUsableSensorReadingInZone = `-- name: GetLatestUsableSensorReadingInZone :one
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
+WHERE sensor_type = $1
+  AND device_id IN (SELECT id FROM devices WHERE zone = $2)
+  AND quality <> 'bad'
+ORDER BY timestamp DESC
+LIMIT 1
+`
+
+type GetLatestUsableSensorReadingInZoneParams struct {
+	SensorType string
+	Zone       pgtype.Text
+}
+
+func (q *Queries) GetLatestUsableSensorReadingInZone(ctx context.Context, arg GetLatestUsableSensorReadingInZoneParams) (SensorReading, error) {
+	row := q.db.QueryRow(ctx, getLatestUsableSensorReadingInZone, arg.SensorType, arg.Zone)
+	var i SensorReading
+	err := row.Scan(
+		&i.ID,
+		&i.SensorType,
+		&i.Value,
+		&i.Timestamp,
+		&i.RawValue,
+		&i.CalibrationID,
+		&i.Quality,
+		&i.QualityReason,
+		&i.DeviceID,
 	)
 	return i, err
 }
 
 const getSensorReading = `-- name: GetSensorReading :one
-SELECT id, sensor_type, value, timestamp from sensor_readings
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
 WHERE id = $1
 `
 
@@ -47,12 +252,70 @@ func (q *Queries) GetSensorReading(ctx context.Context, id int64) (SensorReading
 		&i.SensorType,
 		&i.Value,
 		&i.Timestamp,
+		&i.RawValue,
+		&i.CalibrationID,
+		&i.Quality,
+		&i.QualityReason,
+		&i.DeviceID,
 	)
 	return i, err
 }
 
+const getSensorReadingStatsByDeviceSince = `-- name: GetSensorReadingStatsByDeviceSince :many
+SELECT device_id,
+       count(*) AS reading_count,
+       min(value)::double precision AS min_value,
+       max(value)::double precision AS max_value,
+       min(timestamp)::timestamptz AS first_timestamp
+FROM sensor_readings
+WHERE sensor_type = $1
+  AND timestamp >= $2
+  AND quality <> 'bad'
+GROUP BY device_id
+ORDER BY device_id NULLS FIRST
+`
+
+type GetSensorReadingStatsByDeviceSinceParams struct {
+	SensorType string
+	Timestamp  pgtype.Timestamptz
+}
+
+type GetSensorReadingStatsByDeviceSinceRow struct {
+	DeviceID       pgtype.Text
+	ReadingCount   int64
+	MinValue       float64
+	MaxValue       float64
+	FirstTimestamp pgtype.Timestamptz
+}
+
+func (q *Queries) GetSensorReadingStatsByDeviceSince(ctx context.Context, arg GetSensorReadingStatsByDeviceSinceParams) ([]GetSensorReadingStatsByDeviceSinceRow, error) {
+	rows, err := q.db.Query(ctx, getSensorReadingStatsByDeviceSince, arg.SensorType, arg.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSensorReadingStatsByDeviceSinceRow
+	for rows.Next() {
+		var i GetSensorReadingStatsByDeviceSinceRow
+		if err := rows.Scan(
+			&i.DeviceID,
+			&i.ReadingCount,
+			&i.MinValue,
+			&i.MaxValue,
+			&i.FirstTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getSensorReadings = `-- name: GetSensorReadings :many
-SELECT id, sensor_type, value, timestamp from sensor_readings
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
 `
 
 func (q *Queries) GetSensorReadings(ctx context.Context) ([]SensorReading, error) {
@@ -69,6 +332,11 @@ func (q *Queries) GetSensorReadings(ctx context.Context) ([]SensorReading, error
 			&i.SensorType,
 			&i.Value,
 			&i.Timestamp,
+			&i.RawValue,
+			&i.CalibrationID,
+			&i.Quality,
+			&i.QualityReason,
+			&i.DeviceID,
 		); err != nil {
 			return nil, err
 		}
@@ -81,7 +349,7 @@ func (q *Queries) GetSensorReadings(ctx context.Context) ([]SensorReading, error
 }
 
 const getSensorReadingsByTime = `-- name: GetSensorReadingsByTime :many
-SELECT id, sensor_type, value, timestamp from sensor_readings
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
 WHERE timestamp >= $1
   AND timestamp <= $2
 ORDER BY timestamp DESC
@@ -114,6 +382,11 @@ func (q *Queries) GetSensorReadingsByTime(ctx context.Context, arg GetSensorRead
 			&i.SensorType,
 			&i.Value,
 			&i.Timestamp,
+			&i.RawValue,
+			&i.CalibrationID,
+			&i.Quality,
+			&i.QualityReason,
+			&i.DeviceID,
 		); err != nil {
 			return nil, err
 		}
@@ -126,7 +399,7 @@ func (q *Queries) GetSensorReadingsByTime(ctx context.Context, arg GetSensorRead
 }
 
 const getSensorReadingsByType = `-- name: GetSensorReadingsByType :many
-SELECT id, sensor_type, value, timestamp from sensor_readings
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
 WHERE sensor_type = $1
 ORDER BY timestamp DESC
 LIMIT $2 OFFSET $3
@@ -152,6 +425,11 @@ func (q *Queries) GetSensorReadingsByType(ctx context.Context, arg GetSensorRead
 			&i.SensorType,
 			&i.Value,
 			&i.Timestamp,
+			&i.RawValue,
+			&i.CalibrationID,
+			&i.Quality,
+			&i.QualityReason,
+			&i.DeviceID,
 		); err != nil {
 			return nil, err
 		}
@@ -164,7 +442,7 @@ func (q *Queries) GetSensorReadingsByType(ctx context.Context, arg GetSensorRead
 }
 
 const getSensorReadingsByTypeAndTime = `-- name: GetSensorReadingsByTypeAndTime :many
-SELECT id, sensor_type, value, timestamp from sensor_readings
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
 WHERE sensor_type = $1
   AND timestamp >= $2
   AND timestamp <= $3
@@ -200,6 +478,158 @@ func (q *Queries) GetSensorReadingsByTypeAndTime(ctx context.Context, arg GetSen
 			&i.SensorType,
 			&i.Value,
 			&i.Timestamp,
+			&i.RawValue,
+			&i.CalibrationID,
+			&i.Quality,
+			&i.QualityReason,
+			&i.DeviceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSensorReadingsByTypesAndTime = `-- name: GetSensorReadingsByTypesAndTime :many
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
+WHERE sensor_type = ANY($1::text[])
+  AND timestamp >= $2
+  AND timestamp < $3
+  AND quality <> 'bad'
+ORDER BY timestamp ASC
+`
+
+type GetSensorReadingsByTypesAndTimeParams struct {
+	SensorTypes []string
+	FromTime    pgtype.Timestamptz
+	ToTime      pgtype.Timestamptz
+}
+
+func (q *Queries) GetSensorReadingsByTypesAndTime(ctx context.Context, arg GetSensorReadingsByTypesAndTimeParams) ([]SensorReading, error) {
+	rows, err := q.db.Query(ctx, getSensorReadingsByTypesAndTime, arg.SensorTypes, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SensorReading
+	for rows.Next() {
+		var i SensorReading
+		if err := rows.Scan(
+			&i.ID,
+			&i.SensorType,
+			&i.Value,
+			&i.Timestamp,
+			&i.RawValue,
+			&i.CalibrationID,
+			&i.Quality,
+			&i.QualityReason,
+			&i.DeviceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSensorReadingsForRecalibration = `-- name: GetSensorReadingsForRecalibration :many
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
+WHERE sensor_type = $1
+  AND (timestamp, id) > ($2::timestamptz, $3::bigint)
+ORDER BY timestamp ASC, id ASC
+LIMIT $4
+`
+
+type GetSensorReadingsForRecalibrationParams struct {
+	SensorType string
+	AfterTime  pgtype.Timestamptz
+	AfterID    int64
+	RowLimit   int32
+}
+
+func (q *Queries) GetSensorReadingsForRecalibration(ctx context.Context, arg GetSensorReadingsForRecalibrationParams) ([]SensorReading, error) {
+	rows, err := q.db.Query(ctx, getSensorReadingsForRecalibration,
+		arg.SensorType,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SensorReading
+	for rows.Next() {
+		var i SensorReading
+		if err := rows.Scan(
+			&i.ID,
+			&i.SensorType,
+			&i.Value,
+			&i.Timestamp,
+			&i.RawValue,
+			&i.CalibrationID,
+			&i.Quality,
+			&i.QualityReason,
+			&i.DeviceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSensorReadingsInZoneByTypeAndTime = `-- name: GetSensorReadingsInZoneByTypeAndTime :many
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
+WHERE sensor_type = $1
+  AND device_id IN (SELECT id FROM devices WHERE zone = $2)
+  AND timestamp >= $3
+  AND timestamp < $4
+  AND quality <> 'bad'
+ORDER BY timestamp ASC
+`
+
+type GetSensorReadingsInZoneByTypeAndTimeParams struct {
+	SensorType string
+	Zone       pgtype.Text
+	FromTime   pgtype.Timestamptz
+	ToTime     pgtype.Timestamptz
+}
+
+func (q *Queries) GetSensorReadingsInZoneByTypeAndTime(ctx context.Context, arg GetSensorReadingsInZoneByTypeAndTimeParams) ([]SensorReading, error) {
+	rows, err := q.db.Query(ctx, getSensorReadingsInZoneByTypeAndTime,
+		arg.SensorType,
+		arg.Zone,
+		arg.FromTime,
+		arg.ToTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SensorReading
+	for rows.Next() {
+		var i SensorReading
+		if err := rows.Scan(
+			&i.ID,
+			&i.SensorType,
+			&i.Value,
+			&i.Timestamp,
+			&i.RawValue,
+			&i.CalibrationID,
+			&i.Quality,
+			&i.QualityReason,
+			&i.DeviceID,
 		); err != nil {
 			return nil, err
 		}
@@ -212,8 +642,9 @@ func (q *Queries) GetSensorReadingsByTypeAndTime(ctx context.Context, arg GetSen
 }
 
 const getSensorReadingsPastDays = `-- name: GetSensorReadingsPastDays :many
-SELECT id, sensor_type, value, timestamp from sensor_readings
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
 WHERE timestamp >= NOW() - INTERVAL '1 day' * $1
+  AND quality <> 'bad'
 ORDER BY timestamp DESC
 `
 
@@ -231,6 +662,48 @@ func (q *Queries) GetSensorReadingsPastDays(ctx context.Context, dollar_1 interf
 			&i.SensorType,
 			&i.Value,
 			&i.Timestamp,
+			&i.RawValue,
+			&i.CalibrationID,
+			&i.Quality,
+			&i.QualityReason,
+			&i.DeviceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsableSensorReadingsSince = `-- name: GetUsableSensorReadingsSince :many
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id from sensor_readings
+WHERE timestamp >= $1
+  AND quality <> 'bad'
+ORDER BY timestamp ASC, id ASC
+`
+
+func (q *Queries) GetUsableSensorReadingsSince(ctx context.Context, timestamp pgtype.Timestamptz) ([]SensorReading, error) {
+	rows, err := q.db.Query(ctx, getUsableSensorReadingsSince, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SensorReading
+	for rows.Next() {
+		var i SensorReading
+		if err := rows.Scan(
+			&i.ID,
+			&i.SensorType,
+			&i.Value,
+			&i.Timestamp,
+			&i.RawValue,
+			&i.CalibrationID,
+			&i.Quality,
+			&i.QualityReason,
+			&i.DeviceID,
 		); err != nil {
 			return nil, err
 		}
@@ -241,3 +714,38 @@ func (q *Queries) GetSensorReadingsPastDays(ctx context.Context, dollar_1 interf
 	}
 	return items, nil
 }
+
+const updateSensorReadingCalibratedValues = `-- name: UpdateSensorReadingCalibratedValues :exec
+UPDATE sensor_readings AS r
+SET value = u.value,
+    calibration_id = NULLIF(u.calibration_id, 0),
+    quality = u.quality,
+    quality_reason = u.quality_reason
+FROM unnest(
+    $1::bigint[],
+    $2::double precision[],
+    $3::bigint[],
+    $4::text[],
+    $5::text[]
+) AS u(id, value, calibration_id, quality, quality_reason)
+WHERE r.id = u.id
+`
+
+type UpdateSensorReadingCalibratedValuesParams struct {
+	Ids              []int64
+	CalibratedValues []float64
+	CalibrationIds   []int64
+	Qualities        []string
+	QualityReasons   []string
+}
+
+func (q *Queries) UpdateSensorReadingCalibratedValues(ctx context.Context, arg UpdateSensorReadingCalibratedValuesParams) error {
+	_, err := q.db.Exec(ctx, updateSensorReadingCalibratedValues,
+		arg.Ids,
+		arg.CalibratedValues,
+		arg.CalibrationIds,
+		arg.Qualities,
+		arg.QualityReasons,
+	)
+	return err
+}
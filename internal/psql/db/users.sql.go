@@ -25,3 +25,71 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (GetUs
 	err := row.Scan(&i.ID, &i.Username, &i.PasswordHash)
 	return i, err
 }
+
+const getUserLocale = `-- name: GetUserLocale :one
+SELECT language, temperature_unit FROM users WHERE id = $1
+`
+
+type GetUserLocaleRow struct {
+	Language        string
+	TemperatureUnit string
+}
+
+func (q *Queries) GetUserLocale(ctx context.Context, id int32) (GetUserLocaleRow, error) {
+	row := q.db.QueryRow(ctx, getUserLocale, id)
+	var i GetUserLocaleRow
+	err := row.Scan(&i.Language, &i.TemperatureUnit)
+	return i, err
+}
+
+const getUserRole = `-- name: GetUserRole :one
+SELECT role FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserRole(ctx context.Context, id int32) (string, error) {
+	row := q.db.QueryRow(ctx, getUserRole, id)
+	var role string
+	err := row.Scan(&role)
+	return role, err
+}
+
+const updateUserLocale = `-- name: UpdateUserLocale :one
+UPDATE users
+SET
+    language = $2,
+    temperature_unit = $3
+WHERE id = $1
+RETURNING language, temperature_unit
+`
+
+type UpdateUserLocaleParams struct {
+	ID              int32
+	Language        string
+	TemperatureUnit string
+}
+
+type UpdateUserLocaleRow struct {
+	Language        string
+	TemperatureUnit string
+}
+
+func (q *Queries) UpdateUserLocale(ctx context.Context, arg UpdateUserLocaleParams) (UpdateUserLocaleRow, error) {
+	row := q.db.QueryRow(ctx, updateUserLocale, arg.ID, arg.Language, arg.TemperatureUnit)
+	var i UpdateUserLocaleRow
+	err := row.Scan(&i.Language, &i.TemperatureUnit)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :exec
+UPDATE users SET role = $2 WHERE id = $1
+`
+
+type UpdateUserRoleParams struct {
+	ID   int32
+	Role string
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) error {
+	_, err := q.db.Exec(ctx, updateUserRole, arg.ID, arg.Role)
+	return err
+}
@@ -0,0 +1,42 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdvisoryLocker serializes work across replicas with transaction-scoped
+// Postgres advisory locks, so a lock is released even if its holder dies.
+type AdvisoryLocker struct {
+	pool *pgxpool.Pool
+}
+
+func NewAdvisoryLocker(pool *pgxpool.Pool) *AdvisoryLocker {
+	return &AdvisoryLocker{pool: pool}
+}
+
+// TryLock runs fn while holding the advisory lock named name. It returns false
+// without running fn if another session holds the lock.
+func (l *AdvisoryLocker) TryLock(ctx context.Context, name string, fn func(ctx context.Context) error) (bool, error) {
+	tx, err := l.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin lock transaction because %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock(hashtext($1))", name).Scan(&locked); err != nil {
+		return false, fmt.Errorf("failed to acquire advisory lock because %w", err)
+	}
+	if !locked {
+		return false, nil
+	}
+
+	if err := fn(ctx); err != nil {
+		return true, err
+	}
+
+	return true, tx.Commit(ctx)
+}
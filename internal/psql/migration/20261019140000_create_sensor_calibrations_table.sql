@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS sensor_calibrations (
+    id             BIGSERIAL          PRIMARY KEY,
+    sensor_type    TEXT               NOT NULL,
+    device_id      TEXT,              -- NULL applies to every device reporting this sensor
+    method         VARCHAR(16)        NOT NULL, -- 'linear', 'two_point' or 'polynomial'
+    coefficients   DOUBLE PRECISION[] NOT NULL, -- ascending powers: value = c0 + c1*raw + c2*raw^2 ...
+    effective_from TIMESTAMPTZ        NOT NULL,
+    note           TEXT               NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ        NOT NULL DEFAULT NOW (),
+    updated_at     TIMESTAMPTZ        NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_sensor_calibrations_sensor_time
+  ON sensor_calibrations (sensor_type, effective_from DESC);
+
+ALTER TABLE sensor_readings
+  ADD COLUMN raw_value DOUBLE PRECISION,
+  ADD COLUMN calibration_id BIGINT REFERENCES sensor_calibrations (id) ON DELETE SET NULL;
+
+UPDATE sensor_readings SET raw_value = value;
+
+ALTER TABLE sensor_readings ALTER COLUMN raw_value SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sensor_readings
+  DROP COLUMN IF EXISTS calibration_id,
+  DROP COLUMN IF EXISTS raw_value;
+
+DROP INDEX IF EXISTS idx_sensor_calibrations_sensor_time;
+DROP TABLE IF EXISTS sensor_calibrations;
+-- +goose StatementEnd
@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sensor_readings
+  ADD COLUMN quality VARCHAR(16) NOT NULL DEFAULT 'good', -- 'good', 'suspect' or 'bad'
+  ADD COLUMN quality_reason TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS sensor_quality_rules (
+    sensor_type             TEXT             PRIMARY KEY,
+    min_value               DOUBLE PRECISION NOT NULL,
+    max_value               DOUBLE PRECISION NOT NULL,
+    max_change_per_minute   DOUBLE PRECISION, -- NULL disables the spike check
+    flatline_minutes        INTEGER          NOT NULL DEFAULT 60,
+    flatline_tolerance      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at              TIMESTAMPTZ      NOT NULL DEFAULT NOW ()
+);
+
+-- Defaults for supported sensors. Light has no spike check because grow
+-- lights switch on and off instantly.
+INSERT INTO
+    sensor_quality_rules (sensor_type, min_value, max_value, max_change_per_minute, flatline_minutes, flatline_tolerance)
+VALUES
+    ('temperature', -20, 60, 5, 120, 0.05),
+    ('humidity', 0, 100, 20, 120, 0.1),
+    ('light', 0, 200000, NULL, 720, 0),
+    ('water', 0, 100, 25, 1440, 0),
+    ('soil', 0, 100, 10, 720, 0) ON CONFLICT (sensor_type) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS device_health_events (
+    id          BIGSERIAL    PRIMARY KEY,
+    sensor_type TEXT         NOT NULL,
+    device_id   TEXT,
+    kind        VARCHAR(32)  NOT NULL, -- 'flatline' or 'stuck'
+    message     TEXT         NOT NULL,
+    started_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW (),
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_device_health_events_open
+  ON device_health_events (sensor_type, kind) WHERE resolved_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_device_health_events_open;
+DROP TABLE IF EXISTS device_health_events;
+DROP TABLE IF EXISTS sensor_quality_rules;
+
+ALTER TABLE sensor_readings
+  DROP COLUMN IF EXISTS quality_reason,
+  DROP COLUMN IF EXISTS quality;
+-- +goose StatementEnd
@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id               BIGSERIAL        PRIMARY KEY,
+    name             TEXT             NOT NULL,
+    sensor_type      TEXT             NOT NULL,
+    kind             VARCHAR(32)      NOT NULL, -- 'threshold', 'rate_of_change' or 'missing_data'
+    comparison       VARCHAR(8)       NOT NULL DEFAULT 'above', -- 'above' or 'below', for threshold rules
+    threshold        DOUBLE PRECISION NOT NULL DEFAULT 0,
+    duration_seconds INTEGER          NOT NULL DEFAULT 0,
+    severity         VARCHAR(16)      NOT NULL DEFAULT 'warning', -- 'info', 'warning' or 'critical'
+    enabled          BOOLEAN          NOT NULL DEFAULT TRUE,
+    created_at       TIMESTAMPTZ      NOT NULL DEFAULT NOW (),
+    updated_at       TIMESTAMPTZ      NOT NULL DEFAULT NOW ()
+);
+
+CREATE TABLE IF NOT EXISTS alerts (
+    id              BIGSERIAL        PRIMARY KEY,
+    rule_id         BIGINT           NOT NULL REFERENCES alert_rules (id) ON DELETE CASCADE,
+    sensor_type     TEXT             NOT NULL,
+    severity        VARCHAR(16)      NOT NULL,
+    status          VARCHAR(16)      NOT NULL DEFAULT 'open', -- 'open', 'acknowledged' or 'resolved'
+    message         TEXT             NOT NULL,
+    value           DOUBLE PRECISION,
+    opened_at       TIMESTAMPTZ      NOT NULL DEFAULT NOW (),
+    acknowledged_at TIMESTAMPTZ,
+    acknowledged_by TEXT,
+    resolved_at     TIMESTAMPTZ,
+    updated_at      TIMESTAMPTZ      NOT NULL DEFAULT NOW ()
+);
+
+-- A rule has at most one alert that is not yet resolved, which also stops
+-- replicas evaluating the same reading from opening duplicates
+CREATE UNIQUE INDEX idx_alerts_unresolved_rule
+  ON alerts (rule_id) WHERE status <> 'resolved';
+
+CREATE INDEX idx_alerts_opened_at ON alerts (opened_at DESC);
+
+-- Defaults matching the thresholds served to clients
+INSERT INTO
+    alert_rules (name, sensor_type, kind, comparison, threshold, duration_seconds, severity)
+VALUES
+    ('Greenhouse too hot', 'temperature', 'threshold', 'above', 30, 600, 'critical'),
+    ('Greenhouse too cold', 'temperature', 'threshold', 'below', 15, 600, 'critical'),
+    ('Temperature sensor silent', 'temperature', 'missing_data', 'above', 0, 900, 'warning');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_alerts_opened_at;
+DROP INDEX IF EXISTS idx_alerts_unresolved_rule;
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS alert_rules;
+-- +goose StatementEnd
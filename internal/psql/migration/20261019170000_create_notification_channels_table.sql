@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id                BIGSERIAL    PRIMARY KEY,
+    user_id           INTEGER      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind              VARCHAR(16)  NOT NULL, -- 'webhook', 'email', 'ntfy' or 'telegram'
+    name              TEXT         NOT NULL,
+    config            JSONB        NOT NULL DEFAULT '{}',
+    min_severity      VARCHAR(16)  NOT NULL DEFAULT 'warning',
+    quiet_hours_start TEXT, -- HH:MM, in timezone
+    quiet_hours_end   TEXT,
+    timezone          TEXT         NOT NULL DEFAULT 'UTC',
+    enabled           BOOLEAN      NOT NULL DEFAULT TRUE,
+    created_at        TIMESTAMPTZ  NOT NULL DEFAULT NOW (),
+    updated_at        TIMESTAMPTZ  NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_notification_channels_user_id ON notification_channels (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_notification_channels_user_id;
+DROP TABLE IF EXISTS notification_channels;
+-- +goose StatementEnd
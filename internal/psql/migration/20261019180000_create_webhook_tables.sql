@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id          BIGSERIAL    PRIMARY KEY,
+    url         TEXT         NOT NULL,
+    event_types TEXT[]       NOT NULL,
+    secret      TEXT         NOT NULL,
+    description TEXT         NOT NULL DEFAULT '',
+    enabled     BOOLEAN      NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW (),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW ()
+);
+
+-- Events are written here in the same transaction as the change they
+-- describe, so an event is delivered if and only if the change committed
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+    id          BIGSERIAL    PRIMARY KEY,
+    event_type  VARCHAR(64)  NOT NULL,
+    payload     JSONB        NOT NULL,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW ()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               BIGSERIAL    PRIMARY KEY,
+    subscription_id  BIGINT       NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id         BIGINT       NOT NULL REFERENCES webhook_outbox (id) ON DELETE CASCADE,
+    status           VARCHAR(16)  NOT NULL DEFAULT 'pending', -- 'pending', 'delivered' or 'dead'
+    attempts         INTEGER      NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW (),
+    last_error       TEXT         NOT NULL DEFAULT '',
+    last_status_code INTEGER,
+    created_at       TIMESTAMPTZ  NOT NULL DEFAULT NOW (),
+    delivered_at     TIMESTAMPTZ
+);
+
+CREATE INDEX idx_webhook_deliveries_due
+  ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_subscription
+  ON webhook_deliveries (subscription_id, created_at DESC);
+CREATE INDEX idx_webhook_deliveries_event ON webhook_deliveries (event_id);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id           BIGSERIAL    PRIMARY KEY,
+    delivery_id  BIGINT       NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    attempted_at TIMESTAMPTZ  NOT NULL DEFAULT NOW (),
+    status_code  INTEGER,
+    error        TEXT         NOT NULL DEFAULT '',
+    duration_ms  INTEGER      NOT NULL
+);
+
+CREATE INDEX idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts (delivery_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS webhook_delivery_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_outbox;
+DROP TABLE IF EXISTS webhook_subscriptions;
+-- +goose StatementEnd
@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE alert_rules ADD COLUMN zone TEXT;
+
+-- Alerts raised while silenced are still recorded, but nobody is notified
+ALTER TABLE alerts ADD COLUMN silenced BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- A silence or maintenance window matches alerts and controls by sensor type,
+-- zone and rule; matchers left null match anything, so one with none set
+-- silences everything
+CREATE TABLE IF NOT EXISTS alert_silences (
+    id          BIGSERIAL   PRIMARY KEY,
+    sensor_type TEXT,
+    zone        TEXT,
+    rule_id     BIGINT      REFERENCES alert_rules (id) ON DELETE CASCADE,
+    starts_at   TIMESTAMPTZ NOT NULL,
+    ends_at     TIMESTAMPTZ NOT NULL,
+    reason      TEXT        NOT NULL,
+    created_by  TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+    CHECK (ends_at >= starts_at)
+);
+
+CREATE INDEX idx_alert_silences_ends_at ON alert_silences (ends_at);
+
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id          BIGSERIAL   PRIMARY KEY,
+    name        TEXT        NOT NULL,
+    sensor_type TEXT,
+    zone        TEXT,
+    rule_id     BIGINT      REFERENCES alert_rules (id) ON DELETE CASCADE,
+    weekdays    INTEGER[]   NOT NULL DEFAULT '{}', -- 0 is Sunday; empty means every day
+    start_time  TEXT        NOT NULL, -- HH:MM
+    end_time    TEXT        NOT NULL, -- HH:MM, before start_time to wrap past midnight
+    timezone    TEXT        NOT NULL DEFAULT 'UTC',
+    reason      TEXT        NOT NULL DEFAULT '',
+    enabled     BOOLEAN     NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS maintenance_windows;
+DROP INDEX IF EXISTS idx_alert_silences_ends_at;
+DROP TABLE IF EXISTS alert_silences;
+ALTER TABLE alerts DROP COLUMN IF EXISTS silenced;
+ALTER TABLE alert_rules DROP COLUMN IF EXISTS zone;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS devices (
+    id               TEXT        PRIMARY KEY, -- chosen by the board, such as its MAC address
+    name             TEXT        NOT NULL DEFAULT '',
+    firmware_version TEXT        NOT NULL DEFAULT '',
+    ip_address       TEXT        NOT NULL DEFAULT '',
+    zone             TEXT,
+    sensors          TEXT[]      NOT NULL DEFAULT '{}', -- sensor types the device reports
+    status           VARCHAR(16) NOT NULL DEFAULT 'offline', -- 'online' or 'offline'
+    last_seen_at     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_devices_online_last_seen
+  ON devices (last_seen_at) WHERE status = 'online';
+
+-- Readings from before the registry, and from boards that do not identify
+-- themselves, have no device
+ALTER TABLE sensor_readings ADD COLUMN device_id TEXT;
+
+CREATE INDEX idx_sensor_readings_device_timestamp
+  ON sensor_readings (device_id, timestamp DESC) WHERE device_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_sensor_readings_device_timestamp;
+ALTER TABLE sensor_readings DROP COLUMN IF EXISTS device_id;
+DROP INDEX IF EXISTS idx_devices_online_last_seen;
+DROP TABLE IF EXISTS devices;
+-- +goose StatementEnd
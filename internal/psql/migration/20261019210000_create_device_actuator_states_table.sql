@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The state the server wants each actuator in, in the shape devices report
+-- theirs
+CREATE VIEW desired_actuator_states AS
+SELECT
+    sensor_type AS actuator,
+    jsonb_build_object(
+        'mode', mode,
+        'value', COALESCE(to_jsonb(manual_int_value), to_jsonb(manual_bool_value))
+    ) AS desired
+FROM sensor_controls;
+
+-- One row per actuator a device has reported, pairing what the server wants
+-- with what the device last said it applied
+CREATE TABLE IF NOT EXISTS device_actuator_states (
+    device_id        TEXT        NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    actuator         VARCHAR(32) NOT NULL,
+    desired          JSONB,
+    desired_version  BIGINT      NOT NULL DEFAULT 0, -- bumped whenever desired changes
+    desired_at       TIMESTAMPTZ,
+    reported         JSONB,
+    reported_version BIGINT      NOT NULL DEFAULT 0, -- the desired_version the device acknowledged
+    reported_at      TIMESTAMPTZ,
+    PRIMARY KEY (device_id, actuator)
+);
+
+CREATE INDEX idx_device_actuator_states_pending
+  ON device_actuator_states (actuator, desired_at) WHERE reported_version < desired_version;
+
+-- Commands that devices have not acknowledged within two minutes
+INSERT INTO
+    alert_rules (name, sensor_type, kind, comparison, threshold, duration_seconds, severity)
+VALUES
+    ('Fan command not applied', 'fan', 'command_timeout', 'above', 0, 120, 'warning'),
+    ('Heater command not applied', 'heat', 'command_timeout', 'above', 0, 120, 'warning'),
+    ('Light command not applied', 'light', 'command_timeout', 'above', 0, 120, 'warning'),
+    ('Door command not applied', 'door', 'command_timeout', 'above', 0, 120, 'warning'),
+    ('Pump command not applied', 'pump', 'command_timeout', 'above', 0, 120, 'warning');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM alert_rules WHERE kind = 'command_timeout';
+DROP INDEX IF EXISTS idx_device_actuator_states_pending;
+DROP TABLE IF EXISTS device_actuator_states;
+DROP VIEW IF EXISTS desired_actuator_states;
+-- +goose StatementEnd
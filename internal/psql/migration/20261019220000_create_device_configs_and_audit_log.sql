@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The configuration document each device fetches, versioned so that devices
+-- can tell when theirs has changed
+CREATE TABLE IF NOT EXISTS device_configs (
+    device_id  TEXT        PRIMARY KEY REFERENCES devices (id) ON DELETE CASCADE,
+    version    BIGINT      NOT NULL DEFAULT 1,
+    config     JSONB       NOT NULL,
+    updated_by TEXT        NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+);
+
+-- Who changed what, and when
+CREATE TABLE IF NOT EXISTS audit_log (
+    id          BIGSERIAL   PRIMARY KEY,
+    entity_type VARCHAR(32) NOT NULL, -- e.g. 'device_config'
+    entity_id   TEXT        NOT NULL,
+    action      VARCHAR(32) NOT NULL, -- e.g. 'update'
+    actor       TEXT        NOT NULL DEFAULT '',
+    data        JSONB       NOT NULL, -- the entity after the change
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_audit_log_entity_created_at
+  ON audit_log (entity_type, entity_id, created_at DESC);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log (created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_audit_log_created_at;
+DROP INDEX IF EXISTS idx_audit_log_entity_created_at;
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS device_configs;
+-- +goose StatementEnd
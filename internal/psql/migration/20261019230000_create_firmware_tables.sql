@@ -0,0 +1,70 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS firmware_releases (
+    id          BIGSERIAL   PRIMARY KEY,
+    version     TEXT        NOT NULL UNIQUE, -- semantic version, without a leading v
+    sha256      CHAR(64)    NOT NULL,
+    size        BIGINT      NOT NULL,
+    storage_key TEXT        NOT NULL, -- where the image is kept on disk or in the bucket
+    notes       TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+);
+
+CREATE TABLE IF NOT EXISTS firmware_rollout_groups (
+    id         BIGSERIAL   PRIMARY KEY,
+    name       TEXT        NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+);
+
+CREATE TABLE IF NOT EXISTS firmware_rollout_group_devices (
+    group_id  BIGINT NOT NULL REFERENCES firmware_rollout_groups (id) ON DELETE CASCADE,
+    device_id TEXT   NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, device_id)
+);
+
+CREATE INDEX idx_firmware_rollout_group_devices_device
+  ON firmware_rollout_group_devices (device_id);
+
+CREATE TABLE IF NOT EXISTS firmware_rollouts (
+    id                BIGSERIAL   PRIMARY KEY,
+    release_id        BIGINT      NOT NULL REFERENCES firmware_releases (id),
+    group_id          BIGINT      REFERENCES firmware_rollout_groups (id), -- NULL targets every device
+    stages            INTEGER[]   NOT NULL, -- percentages of target devices, e.g. {10,50,100}
+    stage             INTEGER     NOT NULL DEFAULT 0, -- index into stages
+    status            VARCHAR(16) NOT NULL DEFAULT 'active', -- 'active', 'paused' or 'halted'
+    failure_threshold INTEGER     NOT NULL DEFAULT 1,
+    halt_reason       TEXT,
+    resumed_at        TIMESTAMPTZ, -- failures before this no longer count towards halting
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_firmware_rollouts_active
+  ON firmware_rollouts (group_id) WHERE status = 'active';
+
+CREATE TABLE IF NOT EXISTS device_firmware_updates (
+    device_id  TEXT        NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    rollout_id BIGINT      NOT NULL REFERENCES firmware_rollouts (id) ON DELETE CASCADE,
+    status     VARCHAR(16) NOT NULL DEFAULT 'offered', -- 'offered', 'downloading', 'installing', 'succeeded' or 'failed'
+    error      TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+    PRIMARY KEY (device_id, rollout_id)
+);
+
+CREATE INDEX idx_device_firmware_updates_rollout_status
+  ON device_firmware_updates (rollout_id, status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_device_firmware_updates_rollout_status;
+DROP TABLE IF EXISTS device_firmware_updates;
+DROP INDEX IF EXISTS idx_firmware_rollouts_active;
+DROP TABLE IF EXISTS firmware_rollouts;
+DROP INDEX IF EXISTS idx_firmware_rollout_group_devices_device;
+DROP TABLE IF EXISTS firmware_rollout_group_devices;
+DROP TABLE IF EXISTS firmware_rollout_groups;
+DROP TABLE IF EXISTS firmware_releases;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Boards asking to join with their factory claim code. The code itself is
+-- never stored, only its hash and last few characters.
+CREATE TABLE IF NOT EXISTS device_claims (
+    id               BIGSERIAL   PRIMARY KEY,
+    code_hash        TEXT        NOT NULL UNIQUE,
+    code_hint        TEXT        NOT NULL,
+    device_id        TEXT        NOT NULL,
+    firmware_version TEXT        NOT NULL DEFAULT '',
+    ip_address       TEXT        NOT NULL DEFAULT '',
+    status           VARCHAR(16) NOT NULL DEFAULT 'pending', -- pending, approved, rejected, claimed, expired
+    name             TEXT        NOT NULL DEFAULT '',
+    zone             TEXT,
+    reviewed_by      TEXT        NOT NULL DEFAULT '',
+    expires_at       TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_device_claims_status_expires_at ON device_claims (status, expires_at);
+
+-- The API key each provisioned device authenticates with, stored hashed
+CREATE TABLE IF NOT EXISTS device_api_keys (
+    device_id    TEXT        PRIMARY KEY REFERENCES devices (id) ON DELETE CASCADE,
+    key_hash     TEXT        NOT NULL UNIQUE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+    last_used_at TIMESTAMPTZ
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS device_api_keys;
+DROP INDEX IF EXISTS idx_device_claims_status_expires_at;
+DROP TABLE IF EXISTS device_claims;
+-- +goose StatementEnd
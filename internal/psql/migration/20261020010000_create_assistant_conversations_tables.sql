@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS assistant_conversations (
+    id                 BIGSERIAL    PRIMARY KEY,
+    user_id            INTEGER      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title              TEXT         NOT NULL DEFAULT '',
+    plant              TEXT         NOT NULL,
+    summary            TEXT         NOT NULL DEFAULT '', -- older turns, condensed
+    summarized_through BIGINT       NOT NULL DEFAULT 0, -- last message folded into summary
+    created_at         TIMESTAMPTZ  NOT NULL DEFAULT NOW (),
+    updated_at         TIMESTAMPTZ  NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_assistant_conversations_user_id ON assistant_conversations (user_id, updated_at DESC);
+
+CREATE TABLE IF NOT EXISTS assistant_messages (
+    id              BIGSERIAL    PRIMARY KEY,
+    conversation_id BIGINT       NOT NULL REFERENCES assistant_conversations (id) ON DELETE CASCADE,
+    role            VARCHAR(16)  NOT NULL, -- 'user' or 'assistant'
+    content         TEXT         NOT NULL,
+    source          VARCHAR(16), -- 'llm' or 'rules', for assistant replies
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_assistant_messages_conversation_id ON assistant_messages (conversation_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_assistant_messages_conversation_id;
+DROP TABLE IF EXISTS assistant_messages;
+DROP INDEX IF EXISTS idx_assistant_conversations_user_id;
+DROP TABLE IF EXISTS assistant_conversations;
+-- +goose StatementEnd
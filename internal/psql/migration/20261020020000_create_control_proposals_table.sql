@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS control_proposals (
+    id                BIGSERIAL    PRIMARY KEY,
+    sensor_type       TEXT         NOT NULL,
+    mode              VARCHAR(16)  NOT NULL, -- 'automatic' or 'manual'
+    manual_int_value  INTEGER,
+    manual_bool_value BOOLEAN,
+    duration_minutes  INTEGER, -- how long a manual override lasts once approved
+    reason            TEXT         NOT NULL DEFAULT '',
+    status            VARCHAR(16)  NOT NULL DEFAULT 'pending', -- 'pending', 'approved' or 'rejected'
+    proposed_by       TEXT         NOT NULL,
+    user_id           INTEGER      REFERENCES users (id) ON DELETE SET NULL,
+    conversation_id   BIGINT       REFERENCES assistant_conversations (id) ON DELETE SET NULL,
+    reviewed_by       TEXT         NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ  NOT NULL DEFAULT NOW (),
+    updated_at        TIMESTAMPTZ  NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_control_proposals_status ON control_proposals (status, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_control_proposals_status;
+DROP TABLE IF EXISTS control_proposals;
+-- +goose StatementEnd
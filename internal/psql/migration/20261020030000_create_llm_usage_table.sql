@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS llm_usage (
+    id                BIGSERIAL         PRIMARY KEY,
+    user_id           INTEGER           REFERENCES users (id) ON DELETE SET NULL,
+    feature           VARCHAR(32)       NOT NULL, -- 'plant_advice', 'assistant', 'assistant_summary' or 'assistant_query'
+    provider          VARCHAR(32)       NOT NULL,
+    model             TEXT              NOT NULL DEFAULT '',
+    prompt_tokens     INTEGER           NOT NULL DEFAULT 0,
+    completion_tokens INTEGER           NOT NULL DEFAULT 0,
+    latency_ms        BIGINT            NOT NULL DEFAULT 0,
+    cost_usd          DOUBLE PRECISION  NOT NULL DEFAULT 0,
+    cached            BOOLEAN           NOT NULL DEFAULT FALSE, -- answered from the advice cache at no cost
+    created_at        TIMESTAMPTZ       NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_llm_usage_created_at ON llm_usage (created_at);
+CREATE INDEX idx_llm_usage_user_created_at ON llm_usage (user_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_llm_usage_user_created_at;
+DROP INDEX IF EXISTS idx_llm_usage_created_at;
+DROP TABLE IF EXISTS llm_usage;
+-- +goose StatementEnd
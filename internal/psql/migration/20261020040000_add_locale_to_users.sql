@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS language VARCHAR(8) NOT NULL DEFAULT 'en',
+    ADD COLUMN IF NOT EXISTS temperature_unit VARCHAR(16) NOT NULL DEFAULT 'celsius';
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN IF EXISTS temperature_unit,
+    DROP COLUMN IF EXISTS language;
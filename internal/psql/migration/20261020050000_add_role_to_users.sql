@@ -0,0 +1,9 @@
+-- +goose Up
+-- Admin routes such as bulk import and LLM usage need the admin role, which
+-- is granted with cmd/usercli
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user'; -- 'user' or 'admin'
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN IF EXISTS role;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Moving a calibration's effective_from changes readings on both sides of
+-- the move, so the earliest one is kept until they have been recomputed
+ALTER TABLE sensor_calibrations
+    ADD COLUMN IF NOT EXISTS recompute_from TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE sensor_calibrations
+    DROP COLUMN IF EXISTS recompute_from;
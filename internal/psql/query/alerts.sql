@@ -0,0 +1,82 @@
+-- name: ListAlertRules :many
+SELECT * FROM alert_rules
+ORDER BY id;
+
+-- name: ListEnabledAlertRules :many
+SELECT * FROM alert_rules
+WHERE enabled
+ORDER BY id;
+
+-- name: ListEnabledAlertRulesBySensorType :many
+SELECT * FROM alert_rules
+WHERE enabled
+  AND sensor_type = $1
+ORDER BY id;
+
+-- name: GetAlertRule :one
+SELECT * FROM alert_rules
+WHERE id = $1;
+
+-- name: CreateAlertRule :one
+INSERT INTO alert_rules (name, sensor_type, kind, comparison, threshold, duration_seconds, severity, enabled, zone)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: UpdateAlertRule :one
+UPDATE alert_rules
+SET name = $2,
+    sensor_type = $3,
+    kind = $4,
+    comparison = $5,
+    threshold = $6,
+    duration_seconds = $7,
+    severity = $8,
+    enabled = $9,
+    zone = $10,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteAlertRule :execrows
+DELETE FROM alert_rules
+WHERE id = $1;
+
+-- name: ListAlerts :many
+SELECT * FROM alerts
+WHERE (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'))
+ORDER BY opened_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetAlert :one
+SELECT * FROM alerts
+WHERE id = $1;
+
+-- name: GetUnresolvedAlertByRule :one
+SELECT * FROM alerts
+WHERE rule_id = $1
+  AND status <> 'resolved';
+
+-- name: CreateAlert :one
+INSERT INTO alerts (rule_id, sensor_type, severity, message, value, silenced)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (rule_id) WHERE status <> 'resolved' DO NOTHING
+RETURNING *;
+
+-- name: AcknowledgeAlert :one
+UPDATE alerts
+SET status = 'acknowledged',
+    acknowledged_at = NOW(),
+    acknowledged_by = $2,
+    updated_at = NOW()
+WHERE id = $1
+  AND status = 'open'
+RETURNING *;
+
+-- name: ResolveAlert :one
+UPDATE alerts
+SET status = 'resolved',
+    resolved_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+  AND status <> 'resolved'
+RETURNING *;
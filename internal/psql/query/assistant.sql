@@ -0,0 +1,43 @@
+-- name: ListAssistantConversations :many
+SELECT * FROM assistant_conversations
+WHERE user_id = $1
+ORDER BY updated_at DESC, id DESC;
+
+-- name: GetAssistantConversation :one
+SELECT * FROM assistant_conversations
+WHERE id = $1
+  AND user_id = $2;
+
+-- name: CreateAssistantConversation :one
+INSERT INTO assistant_conversations (user_id, title, plant)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: DeleteAssistantConversation :execrows
+DELETE FROM assistant_conversations
+WHERE id = $1
+  AND user_id = $2;
+
+-- name: ListAssistantMessages :many
+SELECT * FROM assistant_messages
+WHERE conversation_id = $1
+  AND id > $2
+ORDER BY id;
+
+-- name: CreateAssistantMessage :one
+INSERT INTO assistant_messages (conversation_id, role, content, source)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: TouchAssistantConversation :exec
+UPDATE assistant_conversations
+SET title = CASE WHEN title = '' THEN sqlc.arg('title') ELSE title END,
+    updated_at = NOW()
+WHERE id = sqlc.arg('id');
+
+-- name: SummarizeAssistantConversation :execrows
+UPDATE assistant_conversations
+SET summary = sqlc.arg('summary'),
+    summarized_through = sqlc.arg('summarized_through')
+WHERE id = sqlc.arg('id')
+  AND summarized_through < sqlc.arg('summarized_through');
@@ -0,0 +1,10 @@
+-- name: CreateAuditEntry :exec
+INSERT INTO audit_log (entity_type, entity_id, action, actor, data)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ListAuditEntries :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg('entity_type')::text IS NULL OR entity_type = sqlc.narg('entity_type'))
+  AND (sqlc.narg('entity_id')::text IS NULL OR entity_id = sqlc.narg('entity_id'))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,30 @@
+-- name: ListControlProposals :many
+SELECT * FROM control_proposals
+WHERE (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'))
+ORDER BY created_at DESC, id DESC;
+
+-- name: GetControlProposal :one
+SELECT * FROM control_proposals
+WHERE id = $1;
+
+-- name: CreateControlProposal :one
+INSERT INTO control_proposals (sensor_type, mode, manual_int_value, manual_bool_value, duration_minutes, reason, proposed_by, user_id, conversation_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: ReviewControlProposal :one
+UPDATE control_proposals
+SET status = sqlc.arg('status'),
+    reviewed_by = sqlc.arg('reviewed_by'),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+  AND status = 'pending'
+RETURNING *;
+
+-- name: FailControlProposal :one
+UPDATE control_proposals
+SET status = 'failed',
+    updated_at = NOW()
+WHERE id = $1
+  AND status = 'approved'
+RETURNING *;
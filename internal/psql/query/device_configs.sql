@@ -0,0 +1,15 @@
+-- name: GetDeviceConfig :one
+SELECT * FROM device_configs
+WHERE device_id = $1;
+
+-- name: UpsertDeviceConfig :one
+INSERT INTO device_configs AS c (device_id, config, updated_by)
+SELECT id, sqlc.arg('config')::jsonb, sqlc.arg('updated_by')
+FROM devices
+WHERE id = sqlc.arg('device_id')
+ON CONFLICT (device_id) DO UPDATE
+SET version = c.version + 1,
+    config = EXCLUDED.config,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = NOW()
+RETURNING *;
@@ -0,0 +1,42 @@
+-- name: ListDeviceActuatorStates :many
+SELECT * FROM device_actuator_states
+WHERE device_id = $1
+ORDER BY actuator;
+
+-- name: ReportActuatorState :one
+INSERT INTO device_actuator_states AS s (device_id, actuator, desired, desired_version, desired_at, reported, reported_version, reported_at)
+SELECT
+    d.id,
+    sqlc.arg('actuator')::text,
+    v.desired,
+    CASE WHEN v.desired IS NULL THEN 0 ELSE 1 END,
+    CASE WHEN v.desired IS NULL THEN NULL ELSE NOW() END,
+    sqlc.arg('reported')::jsonb,
+    LEAST(sqlc.arg('reported_version')::bigint, CASE WHEN v.desired IS NULL THEN 0 ELSE 1 END),
+    NOW()
+FROM devices d
+LEFT JOIN desired_actuator_states v ON v.actuator = sqlc.arg('actuator')::text
+WHERE d.id = sqlc.arg('device_id')
+ON CONFLICT (device_id, actuator) DO UPDATE
+SET reported = EXCLUDED.reported,
+    -- Never acknowledge a version that has not been issued, nor go back
+    reported_version = GREATEST(s.reported_version, LEAST(sqlc.arg('reported_version')::bigint, s.desired_version)),
+    reported_at = NOW()
+RETURNING *;
+
+-- name: SyncDesiredActuatorStates :execrows
+UPDATE device_actuator_states s
+SET desired = v.desired,
+    desired_version = s.desired_version + 1,
+    desired_at = NOW()
+FROM desired_actuator_states v
+WHERE s.actuator = $1
+  AND v.actuator = s.actuator
+  AND s.desired IS DISTINCT FROM v.desired;
+
+-- name: ListPendingActuatorCommands :many
+SELECT * FROM device_actuator_states
+WHERE actuator = $1
+  AND reported_version < desired_version
+  AND desired_at < $2
+ORDER BY desired_at, device_id;
@@ -0,0 +1,64 @@
+-- name: ListDevices :many
+SELECT * FROM devices
+ORDER BY id;
+
+-- name: GetDevice :one
+SELECT * FROM devices
+WHERE id = $1;
+
+-- name: CreateDevice :one
+INSERT INTO devices (id, name, zone)
+VALUES ($1, $2, $3)
+ON CONFLICT (id) DO NOTHING
+RETURNING *;
+
+-- name: UpdateDevice :one
+UPDATE devices
+SET name = $2,
+    zone = $3,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteDevice :execrows
+DELETE FROM devices
+WHERE id = $1;
+
+-- name: RecordDeviceHeartbeat :one
+WITH prev AS (
+    SELECT status FROM devices WHERE id = sqlc.arg('id')
+)
+INSERT INTO devices (id, firmware_version, ip_address, sensors, status, last_seen_at)
+VALUES (sqlc.arg('id'), sqlc.arg('firmware_version'), sqlc.arg('ip_address'), sqlc.arg('sensors')::text[], 'online', NOW())
+ON CONFLICT (id) DO UPDATE
+SET firmware_version = COALESCE(NULLIF(EXCLUDED.firmware_version, ''), devices.firmware_version),
+    ip_address = COALESCE(NULLIF(EXCLUDED.ip_address, ''), devices.ip_address),
+    sensors = CASE WHEN cardinality(EXCLUDED.sensors) > 0 THEN EXCLUDED.sensors ELSE devices.sensors END,
+    status = 'online',
+    last_seen_at = NOW(),
+    updated_at = NOW()
+RETURNING devices.*, COALESCE((SELECT status FROM prev), '')::text AS previous_status;
+
+-- name: TouchDevice :one
+WITH prev AS (
+    SELECT status FROM devices WHERE id = sqlc.arg('id')
+)
+INSERT INTO devices (id, sensors, status, last_seen_at)
+VALUES (sqlc.arg('id'), ARRAY[sqlc.arg('sensor_type')::text], 'online', NOW())
+ON CONFLICT (id) DO UPDATE
+SET sensors = CASE
+        WHEN sqlc.arg('sensor_type')::text = ANY(devices.sensors) THEN devices.sensors
+        ELSE array_append(devices.sensors, sqlc.arg('sensor_type')::text)
+    END,
+    status = 'online',
+    last_seen_at = NOW(),
+    updated_at = NOW()
+RETURNING devices.*, COALESCE((SELECT status FROM prev), '')::text AS previous_status;
+
+-- name: MarkStaleDevicesOffline :many
+UPDATE devices
+SET status = 'offline',
+    updated_at = NOW()
+WHERE status = 'online'
+  AND last_seen_at < $1
+RETURNING *;
@@ -0,0 +1,161 @@
+-- name: ListFirmwareReleases :many
+SELECT * FROM firmware_releases
+ORDER BY created_at DESC, id DESC;
+
+-- name: GetFirmwareRelease :one
+SELECT * FROM firmware_releases
+WHERE id = $1;
+
+-- name: CreateFirmwareRelease :one
+INSERT INTO firmware_releases (version, sha256, size, storage_key, notes)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (version) DO NOTHING
+RETURNING *;
+
+-- name: DeleteFirmwareRelease :one
+DELETE FROM firmware_releases
+WHERE id = $1
+RETURNING *;
+
+-- name: ListRolloutGroups :many
+SELECT g.id, g.name, g.created_at, g.updated_at,
+       COALESCE(array_agg(d.device_id ORDER BY d.device_id) FILTER (WHERE d.device_id IS NOT NULL), '{}')::text[] AS device_ids
+FROM firmware_rollout_groups g
+LEFT JOIN firmware_rollout_group_devices d ON d.group_id = g.id
+GROUP BY g.id
+ORDER BY g.name;
+
+-- name: GetRolloutGroup :one
+SELECT g.id, g.name, g.created_at, g.updated_at,
+       COALESCE(array_agg(d.device_id ORDER BY d.device_id) FILTER (WHERE d.device_id IS NOT NULL), '{}')::text[] AS device_ids
+FROM firmware_rollout_groups g
+LEFT JOIN firmware_rollout_group_devices d ON d.group_id = g.id
+WHERE g.id = $1
+GROUP BY g.id;
+
+-- name: CreateRolloutGroup :one
+INSERT INTO firmware_rollout_groups (name)
+VALUES ($1)
+ON CONFLICT (name) DO NOTHING
+RETURNING *;
+
+-- name: UpdateRolloutGroup :one
+UPDATE firmware_rollout_groups
+SET name = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteRolloutGroup :execrows
+DELETE FROM firmware_rollout_groups
+WHERE id = $1;
+
+-- name: ClearRolloutGroupDevices :exec
+DELETE FROM firmware_rollout_group_devices
+WHERE group_id = $1;
+
+-- name: AddRolloutGroupDevices :execrows
+INSERT INTO firmware_rollout_group_devices (group_id, device_id)
+SELECT sqlc.arg('group_id'), id
+FROM devices
+WHERE id = ANY(sqlc.arg('device_ids')::text[])
+ON CONFLICT DO NOTHING;
+
+-- name: ListFirmwareRollouts :many
+SELECT r.*, f.version,
+       (SELECT count(*) FROM device_firmware_updates u
+        WHERE u.rollout_id = r.id
+          AND u.status = 'failed'
+          AND u.updated_at > COALESCE(r.resumed_at, '-infinity'))::int AS failures
+FROM firmware_rollouts r
+JOIN firmware_releases f ON f.id = r.release_id
+ORDER BY r.created_at DESC, r.id DESC;
+
+-- name: GetFirmwareRollout :one
+SELECT r.*, f.version,
+       (SELECT count(*) FROM device_firmware_updates u
+        WHERE u.rollout_id = r.id
+          AND u.status = 'failed'
+          AND u.updated_at > COALESCE(r.resumed_at, '-infinity'))::int AS failures
+FROM firmware_rollouts r
+JOIN firmware_releases f ON f.id = r.release_id
+WHERE r.id = $1;
+
+-- name: CreateFirmwareRollout :one
+INSERT INTO firmware_rollouts (release_id, group_id, stages, failure_threshold)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: AdvanceFirmwareRollout :one
+UPDATE firmware_rollouts
+SET stage = stage + 1,
+    updated_at = NOW()
+WHERE id = $1
+  AND stage < cardinality(stages) - 1
+RETURNING *;
+
+-- name: PauseFirmwareRollout :one
+UPDATE firmware_rollouts
+SET status = 'paused',
+    updated_at = NOW()
+WHERE id = $1
+  AND status = 'active'
+RETURNING *;
+
+-- name: ResumeFirmwareRollout :one
+UPDATE firmware_rollouts
+SET status = 'active',
+    halt_reason = NULL,
+    resumed_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+  AND status IN ('paused', 'halted')
+RETURNING *;
+
+-- name: HaltFirmwareRolloutOnFailures :one
+UPDATE firmware_rollouts r
+SET status = 'halted',
+    halt_reason = sqlc.arg('halt_reason'),
+    updated_at = NOW()
+WHERE r.id = sqlc.arg('id')
+  AND r.status = 'active'
+  AND (SELECT count(*) FROM device_firmware_updates u
+       WHERE u.rollout_id = r.id
+         AND u.status = 'failed'
+         AND u.updated_at > COALESCE(r.resumed_at, '-infinity')) >= r.failure_threshold
+RETURNING *;
+
+-- name: ListActiveFirmwareRolloutsForDevice :many
+SELECT r.*,
+       f.version, f.sha256, f.size, f.storage_key, f.notes, f.created_at AS release_created_at,
+       u.status AS update_status
+FROM firmware_rollouts r
+JOIN firmware_releases f ON f.id = r.release_id
+LEFT JOIN device_firmware_updates u ON u.rollout_id = r.id AND u.device_id = sqlc.arg('device_id')
+WHERE r.status = 'active'
+  AND (r.group_id IS NULL OR EXISTS (
+      SELECT 1 FROM firmware_rollout_group_devices g
+      WHERE g.group_id = r.group_id AND g.device_id = sqlc.arg('device_id')
+  ))
+ORDER BY r.id;
+
+-- name: OfferFirmwareUpdate :one
+INSERT INTO device_firmware_updates (device_id, rollout_id)
+VALUES ($1, $2)
+ON CONFLICT (device_id, rollout_id) DO UPDATE
+SET device_id = EXCLUDED.device_id
+RETURNING *;
+
+-- name: SetDeviceFirmwareUpdateStatus :one
+UPDATE device_firmware_updates
+SET status = $3,
+    error = $4,
+    updated_at = NOW()
+WHERE device_id = $1
+  AND rollout_id = $2
+RETURNING *;
+
+-- name: ListDeviceFirmwareUpdates :many
+SELECT * FROM device_firmware_updates
+WHERE rollout_id = $1
+ORDER BY updated_at DESC, device_id;
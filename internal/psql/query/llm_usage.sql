@@ -0,0 +1,32 @@
+-- name: CreateLLMUsage :one
+INSERT INTO llm_usage (user_id, feature, provider, model, prompt_tokens, completion_tokens, latency_ms, cost_usd, cached)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: SumAnonymousLLMTokensSince :one
+SELECT COALESCE(SUM(prompt_tokens + completion_tokens), 0)::bigint AS tokens
+FROM llm_usage
+WHERE created_at >= $1
+  AND user_id IS NULL;
+
+-- name: SumLLMTokensSince :one
+SELECT COALESCE(SUM(prompt_tokens + completion_tokens), 0)::bigint AS tokens
+FROM llm_usage
+WHERE created_at >= sqlc.arg('since')
+  AND (sqlc.narg('user_id')::integer IS NULL OR user_id = sqlc.narg('user_id'));
+
+-- name: SummarizeLLMUsage :many
+SELECT date_bin('1 day'::interval, created_at, TIMESTAMPTZ 'epoch')::timestamptz AS day,
+       feature,
+       user_id,
+       count(*) AS requests,
+       count(*) FILTER (WHERE cached) AS cached_requests,
+       COALESCE(SUM(prompt_tokens), 0)::bigint AS prompt_tokens,
+       COALESCE(SUM(completion_tokens), 0)::bigint AS completion_tokens,
+       COALESCE(SUM(cost_usd), 0)::double precision AS cost_usd
+FROM llm_usage
+WHERE created_at >= sqlc.arg('from_time')
+  AND created_at < sqlc.arg('to_time')
+  AND (sqlc.narg('user_id')::integer IS NULL OR user_id = sqlc.narg('user_id'))
+GROUP BY day, feature, user_id
+ORDER BY day ASC, feature ASC, user_id ASC NULLS FIRST;
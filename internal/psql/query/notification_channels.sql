@@ -0,0 +1,39 @@
+-- name: ListNotificationChannelsByUser :many
+SELECT * FROM notification_channels
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: ListEnabledNotificationChannels :many
+SELECT * FROM notification_channels
+WHERE enabled
+ORDER BY id;
+
+-- name: GetNotificationChannel :one
+SELECT * FROM notification_channels
+WHERE id = $1
+  AND user_id = $2;
+
+-- name: CreateNotificationChannel :one
+INSERT INTO notification_channels (user_id, kind, name, config, min_severity, quiet_hours_start, quiet_hours_end, timezone, enabled)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: UpdateNotificationChannel :one
+UPDATE notification_channels
+SET kind = $3,
+    name = $4,
+    config = $5,
+    min_severity = $6,
+    quiet_hours_start = $7,
+    quiet_hours_end = $8,
+    timezone = $9,
+    enabled = $10,
+    updated_at = NOW()
+WHERE id = $1
+  AND user_id = $2
+RETURNING *;
+
+-- name: DeleteNotificationChannel :execrows
+DELETE FROM notification_channels
+WHERE id = $1
+  AND user_id = $2;
@@ -0,0 +1,103 @@
+-- name: ListDeviceClaims :many
+SELECT * FROM device_claims
+WHERE (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'))
+ORDER BY created_at DESC, id DESC;
+
+-- name: GetDeviceClaim :one
+SELECT * FROM device_claims
+WHERE id = $1;
+
+-- name: GetDeviceClaimByCode :one
+SELECT * FROM device_claims
+WHERE code_hash = $1;
+
+-- name: CreateDeviceClaim :one
+INSERT INTO device_claims (code_hash, code_hint, device_id, firmware_version, ip_address, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (code_hash) DO NOTHING
+RETURNING *;
+
+-- name: ReopenDeviceClaim :one
+UPDATE device_claims
+SET device_id = sqlc.arg('device_id'),
+    firmware_version = sqlc.arg('firmware_version'),
+    ip_address = sqlc.arg('ip_address'),
+    status = 'pending',
+    name = '',
+    zone = NULL,
+    reviewed_by = '',
+    expires_at = sqlc.arg('expires_at'),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+  AND (status = 'expired' OR (status IN ('pending', 'approved') AND expires_at <= NOW()))
+RETURNING *;
+
+-- name: ApproveDeviceClaim :one
+UPDATE device_claims
+SET status = 'approved',
+    name = sqlc.arg('name'),
+    zone = sqlc.narg('zone'),
+    reviewed_by = sqlc.arg('reviewed_by'),
+    expires_at = sqlc.arg('expires_at'),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+  AND status = 'pending'
+  AND expires_at > NOW()
+RETURNING *;
+
+-- name: RejectDeviceClaim :one
+UPDATE device_claims
+SET status = 'rejected',
+    reviewed_by = sqlc.arg('reviewed_by'),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+  AND status IN ('pending', 'approved')
+RETURNING *;
+
+-- name: CompleteDeviceClaim :one
+UPDATE device_claims
+SET status = 'claimed',
+    updated_at = NOW()
+WHERE id = $1
+  AND status = 'approved'
+  AND expires_at > NOW()
+RETURNING *;
+
+-- name: ExpireDeviceClaims :many
+UPDATE device_claims
+SET status = 'expired',
+    updated_at = NOW()
+WHERE status IN ('pending', 'approved')
+  AND expires_at <= NOW()
+RETURNING *;
+
+-- name: ProvisionDevice :exec
+INSERT INTO devices (id, name, zone)
+VALUES ($1, $2, $3)
+ON CONFLICT (id) DO UPDATE
+SET name = EXCLUDED.name,
+    zone = EXCLUDED.zone,
+    updated_at = NOW();
+
+-- name: UpsertDeviceAPIKey :exec
+INSERT INTO device_api_keys (device_id, key_hash)
+VALUES ($1, $2)
+ON CONFLICT (device_id) DO UPDATE
+SET key_hash = EXCLUDED.key_hash,
+    created_at = NOW(),
+    last_used_at = NULL;
+
+-- name: AuthenticateDeviceAPIKey :one
+UPDATE device_api_keys
+SET last_used_at = NOW()
+WHERE key_hash = $1
+RETURNING device_id;
+
+-- name: DeviceHasAPIKey :one
+SELECT EXISTS (
+    SELECT 1 FROM device_api_keys WHERE device_id = $1
+) AS has_key;
+
+-- name: DeleteDeviceAPIKey :execrows
+DELETE FROM device_api_keys
+WHERE device_id = $1;
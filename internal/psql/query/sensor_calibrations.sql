@@ -0,0 +1,34 @@
+-- name: ListSensorCalibrations :many
+SELECT * FROM sensor_calibrations
+ORDER BY sensor_type, effective_from DESC;
+
+-- name: ListSensorCalibrationsBySensorType :many
+SELECT * FROM sensor_calibrations
+WHERE sensor_type = $1
+ORDER BY effective_from DESC;
+
+-- name: GetSensorCalibration :one
+SELECT * FROM sensor_calibrations
+WHERE id = $1;
+
+-- name: ClearSensorCalibrationRecomputeFrom :exec
+UPDATE sensor_calibrations
+SET recompute_from = NULL
+WHERE id = $1
+  AND updated_at = $2;
+
+-- name: CreateSensorCalibration :one
+INSERT INTO sensor_calibrations (sensor_type, device_id, method, coefficients, effective_from, note)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: UpdateSensorCalibration :one
+UPDATE sensor_calibrations
+SET method = $2,
+    coefficients = $3,
+    effective_from = COALESCE(sqlc.narg(effective_from)::timestamptz, effective_from),
+    note = $5,
+    recompute_from = LEAST(COALESCE(recompute_from, effective_from), COALESCE(sqlc.narg(effective_from)::timestamptz, effective_from)),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
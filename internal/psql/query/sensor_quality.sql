@@ -0,0 +1,50 @@
+-- name: ListSensorQualityRules :many
+SELECT * FROM sensor_quality_rules
+ORDER BY sensor_type;
+
+-- name: GetSensorQualityRule :one
+SELECT * FROM sensor_quality_rules
+WHERE sensor_type = $1;
+
+-- name: UpsertSensorQualityRule :one
+INSERT INTO sensor_quality_rules (sensor_type, min_value, max_value, max_change_per_minute, flatline_minutes, flatline_tolerance)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (sensor_type) DO UPDATE
+    SET min_value = EXCLUDED.min_value,
+        max_value = EXCLUDED.max_value,
+        max_change_per_minute = EXCLUDED.max_change_per_minute,
+        flatline_minutes = EXCLUDED.flatline_minutes,
+        flatline_tolerance = EXCLUDED.flatline_tolerance,
+        updated_at = NOW()
+RETURNING *;
+
+-- name: ListDeviceHealthEvents :many
+SELECT * FROM device_health_events
+ORDER BY started_at DESC
+LIMIT $1;
+
+-- name: ListOpenDeviceHealthEventsBySensorType :many
+SELECT * FROM device_health_events
+WHERE sensor_type = $1
+  AND resolved_at IS NULL
+ORDER BY started_at ASC;
+
+-- name: GetOpenDeviceHealthEvent :one
+SELECT * FROM device_health_events
+WHERE sensor_type = sqlc.arg(sensor_type)
+  AND device_id IS NOT DISTINCT FROM sqlc.narg(device_id)
+  AND kind = sqlc.arg(kind)
+  AND resolved_at IS NULL
+ORDER BY started_at DESC
+LIMIT 1;
+
+-- name: CreateDeviceHealthEvent :one
+INSERT INTO device_health_events (sensor_type, device_id, kind, message)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ResolveDeviceHealthEvent :exec
+UPDATE device_health_events
+SET resolved_at = NOW()
+WHERE id = $1
+  AND resolved_at IS NULL;
@@ -22,6 +22,7 @@ LIMIT $4 OFFSET $5;
 -- name: GetSensorReadingsPastDays :many
 SELECT * from sensor_readings
 WHERE timestamp >= NOW() - INTERVAL '1 day' * $1
+  AND quality <> 'bad'
 ORDER BY timestamp DESC;
 
 -- name: GetSensorReadingsByTime :many
@@ -31,7 +32,108 @@ WHERE timestamp >= $1
 ORDER BY timestamp DESC
 LIMIT $3 OFFSET $4;
 
+-- name: GetUsableSensorReadingsSince :many
+SELECT * from sensor_readings
+WHERE timestamp >= $1
+  AND quality <> 'bad'
+ORDER BY timestamp ASC, id ASC;
+
 -- name: CreateSensorReading :one
-INSERT INTO sensor_readings (sensor_type, value)
-VALUES ($1, $2)
+INSERT INTO sensor_readings (sensor_type, value, raw_value, calibration_id, quality, quality_reason, device_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
+
+-- name: GetLatestSensorReadings :many
+SELECT DISTINCT ON (sensor_type) * from sensor_readings
+WHERE quality <> 'bad'
+ORDER BY sensor_type, timestamp DESC;
+
+-- name: GetSensorReadingsByTypesAndTime :many
+SELECT * from sensor_readings
+WHERE sensor_type = ANY(sqlc.arg(sensor_types)::text[])
+  AND timestamp >= sqlc.arg(from_time)
+  AND timestamp < sqlc.arg(to_time)
+  AND quality <> 'bad'
+ORDER BY timestamp ASC;
+
+-- name: AggregateSensorReadings :many
+SELECT sensor_type,
+       date_bin(sqlc.arg(bucket)::interval, timestamp, sqlc.arg(from_time)::timestamptz)::timestamptz AS bucket_start,
+       min(value)::double precision AS min_value,
+       max(value)::double precision AS max_value,
+       avg(value)::double precision AS avg_value,
+       count(*) AS reading_count
+FROM sensor_readings
+WHERE sensor_type = ANY(sqlc.arg(sensor_types)::text[])
+  AND timestamp >= sqlc.arg(from_time)
+  AND timestamp < sqlc.arg(to_time)
+  AND quality <> 'bad'
+GROUP BY sensor_type, bucket_start
+ORDER BY bucket_start ASC, sensor_type ASC;
+
+-- name: GetSensorReadingsForRecalibration :many
+SELECT * from sensor_readings
+WHERE sensor_type = sqlc.arg(sensor_type)
+  AND (timestamp, id) > (sqlc.arg(after_time)::timestamptz, sqlc.arg(after_id)::bigint)
+ORDER BY timestamp ASC, id ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: UpdateSensorReadingCalibratedValues :exec
+UPDATE sensor_readings AS r
+SET value = u.value,
+    calibration_id = NULLIF(u.calibration_id, 0),
+    quality = u.quality,
+    quality_reason = u.quality_reason
+FROM unnest(
+    sqlc.arg(ids)::bigint[],
+    sqlc.arg(calibrated_values)::double precision[],
+    sqlc.arg(calibration_ids)::bigint[],
+    sqlc.arg(qualities)::text[],
+    sqlc.arg(quality_reasons)::text[]
+) AS u(id, value, calibration_id, quality, quality_reason)
+WHERE r.id = u.id;
+
+-- name: GetLatestUsableSensorReadingByDevice :one
+SELECT * from sensor_readings
+WHERE sensor_type = sqlc.arg(sensor_type)
+  AND device_id IS NOT DISTINCT FROM sqlc.narg(device_id)
+  AND quality <> 'bad'
+ORDER BY timestamp DESC
+LIMIT 1;
+
+-- name: GetLatestUsableSensorReadingByType :one
+SELECT * from sensor_readings
+WHERE sensor_type = $1
+  AND quality <> 'bad'
+ORDER BY timestamp DESC
+LIMIT 1;
+
+-- name: GetLatestUsableSensorReadingInZone :one
+SELECT * from sensor_readings
+WHERE sensor_type = sqlc.arg(sensor_type)
+  AND device_id IN (SELECT id FROM devices WHERE zone = sqlc.arg(zone))
+  AND quality <> 'bad'
+ORDER BY timestamp DESC
+LIMIT 1;
+
+-- name: GetSensorReadingStatsByDeviceSince :many
+SELECT device_id,
+       count(*) AS reading_count,
+       min(value)::double precision AS min_value,
+       max(value)::double precision AS max_value,
+       min(timestamp)::timestamptz AS first_timestamp
+FROM sensor_readings
+WHERE sensor_type = $1
+  AND timestamp >= $2
+  AND quality <> 'bad'
+GROUP BY device_id
+ORDER BY device_id NULLS FIRST;
+
+-- name: GetSensorReadingsInZoneByTypeAndTime :many
+SELECT * from sensor_readings
+WHERE sensor_type = sqlc.arg(sensor_type)
+  AND device_id IN (SELECT id FROM devices WHERE zone = sqlc.arg(zone))
+  AND timestamp >= sqlc.arg(from_time)
+  AND timestamp < sqlc.arg(to_time)
+  AND quality <> 'bad'
+ORDER BY timestamp ASC;
@@ -0,0 +1,58 @@
+-- name: ListAlertSilences :many
+SELECT * FROM alert_silences
+WHERE (NOT sqlc.arg('active_only')::boolean OR ends_at > NOW())
+ORDER BY starts_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListActiveAlertSilences :many
+SELECT * FROM alert_silences
+WHERE starts_at <= sqlc.arg('at')::timestamptz
+  AND ends_at > sqlc.arg('at')::timestamptz
+ORDER BY id;
+
+-- name: CreateAlertSilence :one
+INSERT INTO alert_silences (sensor_type, zone, rule_id, starts_at, ends_at, reason, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: ExpireAlertSilence :one
+UPDATE alert_silences
+SET starts_at = LEAST(starts_at, NOW()),
+    ends_at = NOW()
+WHERE id = $1
+  AND ends_at > NOW()
+RETURNING *;
+
+-- name: ListMaintenanceWindows :many
+SELECT * FROM maintenance_windows
+ORDER BY id;
+
+-- name: ListEnabledMaintenanceWindows :many
+SELECT * FROM maintenance_windows
+WHERE enabled
+ORDER BY id;
+
+-- name: CreateMaintenanceWindow :one
+INSERT INTO maintenance_windows (name, sensor_type, zone, rule_id, weekdays, start_time, end_time, timezone, reason, enabled)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: UpdateMaintenanceWindow :one
+UPDATE maintenance_windows
+SET name = $2,
+    sensor_type = $3,
+    zone = $4,
+    rule_id = $5,
+    weekdays = $6,
+    start_time = $7,
+    end_time = $8,
+    timezone = $9,
+    reason = $10,
+    enabled = $11,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteMaintenanceWindow :execrows
+DELETE FROM maintenance_windows
+WHERE id = $1;
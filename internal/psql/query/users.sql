@@ -1,2 +1,19 @@
 -- name: GetUserByUsername :one
-SELECT id, username, password_hash FROM users WHERE username = $1;
\ No newline at end of file
+SELECT id, username, password_hash FROM users WHERE username = $1;
+
+-- name: GetUserRole :one
+SELECT role FROM users WHERE id = $1;
+
+-- name: GetUserLocale :one
+SELECT language, temperature_unit FROM users WHERE id = $1;
+
+-- name: UpdateUserLocale :one
+UPDATE users
+SET
+    language = $2,
+    temperature_unit = $3
+WHERE id = $1
+RETURNING language, temperature_unit;
+
+-- name: UpdateUserRole :exec
+UPDATE users SET role = $2 WHERE id = $1;
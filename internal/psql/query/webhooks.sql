@@ -0,0 +1,110 @@
+-- name: ListWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+ORDER BY id;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1;
+
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (url, event_types, secret, description, enabled)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: UpdateWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET url = $2,
+    event_types = $3,
+    description = $4,
+    enabled = $5,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions
+WHERE id = $1;
+
+-- name: EnqueueWebhookEvent :exec
+WITH event AS (
+    INSERT INTO webhook_outbox (event_type, payload)
+    SELECT sqlc.arg('event_type')::text, sqlc.arg('payload')::jsonb
+    WHERE EXISTS (
+        SELECT 1 FROM webhook_subscriptions
+        WHERE enabled
+          AND sqlc.arg('event_type')::text = ANY(event_types)
+    )
+    RETURNING id
+)
+INSERT INTO webhook_deliveries (subscription_id, event_id)
+SELECT s.id, event.id
+FROM webhook_subscriptions s, event
+WHERE s.enabled
+  AND sqlc.arg('event_type')::text = ANY(s.event_types);
+
+-- name: ListDueWebhookDeliveries :many
+SELECT d.id, d.subscription_id, d.attempts, e.id AS event_id, e.event_type, e.payload, e.created_at AS event_created_at, s.url, s.secret
+FROM webhook_deliveries d
+JOIN webhook_outbox e ON e.id = d.event_id
+JOIN webhook_subscriptions s ON s.id = d.subscription_id
+WHERE d.status = 'pending'
+  AND d.next_attempt_at <= NOW()
+  AND s.enabled
+ORDER BY d.next_attempt_at
+LIMIT $1;
+
+-- name: MarkWebhookDeliveryDelivered :exec
+UPDATE webhook_deliveries
+SET status = 'delivered',
+    attempts = attempts + 1,
+    last_error = '',
+    last_status_code = $2,
+    delivered_at = NOW()
+WHERE id = $1;
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET status = $2,
+    attempts = attempts + 1,
+    next_attempt_at = $3,
+    last_error = $4,
+    last_status_code = $5
+WHERE id = $1;
+
+-- name: CreateWebhookDeliveryAttempt :exec
+INSERT INTO webhook_delivery_attempts (delivery_id, status_code, error, duration_ms)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListWebhookDeliveries :many
+SELECT d.*, e.event_type
+FROM webhook_deliveries d
+JOIN webhook_outbox e ON e.id = d.event_id
+WHERE d.subscription_id = sqlc.arg('subscription_id')
+  AND (sqlc.narg('status')::text IS NULL OR d.status = sqlc.narg('status'))
+ORDER BY d.created_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListWebhookDeliveryAttempts :many
+SELECT a.* FROM webhook_delivery_attempts a
+JOIN webhook_deliveries d ON d.id = a.delivery_id
+WHERE a.delivery_id = $1
+  AND d.subscription_id = $2
+ORDER BY a.attempted_at;
+
+-- name: RetryWebhookDelivery :execrows
+UPDATE webhook_deliveries
+SET status = 'pending',
+    attempts = 0,
+    next_attempt_at = NOW()
+WHERE id = $1
+  AND subscription_id = $2
+  AND status = 'dead';
+
+-- name: PruneWebhookOutbox :execrows
+DELETE FROM webhook_outbox e
+WHERE e.created_at < $1
+  AND NOT EXISTS (
+      SELECT 1 FROM webhook_deliveries d
+      WHERE d.event_id = e.id
+        AND d.status = 'pending'
+  );
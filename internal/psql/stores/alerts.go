@@ -0,0 +1,287 @@
+package stores
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+type Alerts struct {
+	q    *db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewAlerts(pool *pgxpool.Pool) *Alerts {
+	return &Alerts{q: db.New(pool), pool: pool}
+}
+
+func (a *Alerts) toRule(r db.AlertRule) internal.AlertRule {
+	return internal.AlertRule{
+		ID:              r.ID,
+		Name:            r.Name,
+		SensorType:      r.SensorType,
+		Kind:            r.Kind,
+		Comparison:      r.Comparison,
+		Threshold:       r.Threshold,
+		DurationSeconds: int(r.DurationSeconds),
+		Severity:        r.Severity,
+		Enabled:         r.Enabled,
+		Zone:            textPtr(r.Zone),
+		CreatedAt:       r.CreatedAt.Time,
+		UpdatedAt:       r.UpdatedAt.Time,
+	}
+}
+
+func (a *Alerts) toAlert(r db.Alert) internal.Alert {
+	alert := internal.Alert{
+		ID:         r.ID,
+		RuleID:     r.RuleID,
+		SensorType: r.SensorType,
+		Severity:   r.Severity,
+		Status:     r.Status,
+		Message:    r.Message,
+		OpenedAt:   r.OpenedAt.Time,
+		UpdatedAt:  r.UpdatedAt.Time,
+		Silenced:   r.Silenced,
+	}
+	if r.Value.Valid {
+		v := r.Value.Float64
+		alert.Value = &v
+	}
+	if r.AcknowledgedAt.Valid {
+		t := r.AcknowledgedAt.Time
+		alert.AcknowledgedAt = &t
+	}
+	if r.AcknowledgedBy.Valid {
+		by := r.AcknowledgedBy.String
+		alert.AcknowledgedBy = &by
+	}
+	if r.ResolvedAt.Valid {
+		t := r.ResolvedAt.Time
+		alert.ResolvedAt = &t
+	}
+
+	return alert
+}
+
+func (a *Alerts) toRules(rows []db.AlertRule) []internal.AlertRule {
+	res := make([]internal.AlertRule, len(rows))
+	for i, row := range rows {
+		res[i] = a.toRule(row)
+	}
+	return res
+}
+
+func (a *Alerts) ListAlertRules(ctx context.Context) ([]internal.AlertRule, error) {
+	rows, err := a.q.ListAlertRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.toRules(rows), nil
+}
+
+func (a *Alerts) ListEnabledAlertRules(ctx context.Context) ([]internal.AlertRule, error) {
+	rows, err := a.q.ListEnabledAlertRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.toRules(rows), nil
+}
+
+func (a *Alerts) ListEnabledAlertRulesBySensorType(ctx context.Context, sensorType string) ([]internal.AlertRule, error) {
+	rows, err := a.q.ListEnabledAlertRulesBySensorType(ctx, sensorType)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.toRules(rows), nil
+}
+
+func (a *Alerts) GetAlertRule(ctx context.Context, id int64) (internal.AlertRule, error) {
+	row, err := a.q.GetAlertRule(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.AlertRule{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.AlertRule{}, err
+	}
+
+	return a.toRule(row), nil
+}
+
+func (a *Alerts) CreateAlertRule(ctx context.Context, params internal.AlertRuleParams) (internal.AlertRule, error) {
+	row, err := a.q.CreateAlertRule(ctx, db.CreateAlertRuleParams{
+		Name:            params.Name,
+		SensorType:      params.SensorType,
+		Kind:            params.Kind,
+		Comparison:      params.Comparison,
+		Threshold:       params.Threshold,
+		DurationSeconds: int32(params.DurationSeconds),
+		Severity:        params.Severity,
+		Enabled:         params.Enabled,
+		Zone:            ptrText(params.Zone),
+	})
+	if err != nil {
+		return internal.AlertRule{}, err
+	}
+
+	return a.toRule(row), nil
+}
+
+func (a *Alerts) UpdateAlertRule(ctx context.Context, id int64, params internal.AlertRuleParams) (internal.AlertRule, error) {
+	row, err := a.q.UpdateAlertRule(ctx, db.UpdateAlertRuleParams{
+		ID:              id,
+		Name:            params.Name,
+		SensorType:      params.SensorType,
+		Kind:            params.Kind,
+		Comparison:      params.Comparison,
+		Threshold:       params.Threshold,
+		DurationSeconds: int32(params.DurationSeconds),
+		Severity:        params.Severity,
+		Enabled:         params.Enabled,
+		Zone:            ptrText(params.Zone),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.AlertRule{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.AlertRule{}, err
+	}
+
+	return a.toRule(row), nil
+}
+
+func (a *Alerts) DeleteAlertRule(ctx context.Context, id int64) error {
+	n, err := a.q.DeleteAlertRule(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return internal.ErrNotFound
+	}
+
+	return nil
+}
+
+// ListAlerts returns the most recent alerts, optionally only those in status
+func (a *Alerts) ListAlerts(ctx context.Context, status string, limit int) ([]internal.Alert, error) {
+	rows, err := a.q.ListAlerts(ctx, db.ListAlertsParams{
+		Status: pgtype.Text{String: status, Valid: status != ""},
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.Alert, len(rows))
+	for i, row := range rows {
+		res[i] = a.toAlert(row)
+	}
+
+	return res, nil
+}
+
+func (a *Alerts) GetAlert(ctx context.Context, id int64) (internal.Alert, error) {
+	row, err := a.q.GetAlert(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.Alert{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.Alert{}, err
+	}
+
+	return a.toAlert(row), nil
+}
+
+func (a *Alerts) GetUnresolvedAlertByRule(ctx context.Context, ruleID int64) (internal.Alert, error) {
+	row, err := a.q.GetUnresolvedAlertByRule(ctx, ruleID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.Alert{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.Alert{}, err
+	}
+
+	return a.toAlert(row), nil
+}
+
+// CreateAlert opens an alert for a rule. It returns false if the rule already
+// has an unresolved alert, for instance one opened by another replica.
+func (a *Alerts) CreateAlert(ctx context.Context, params internal.CreateAlertParams) (internal.Alert, bool, error) {
+	var value pgtype.Float8
+	if params.Value != nil {
+		value.Valid = true
+		value.Float64 = *params.Value
+	}
+
+	var alert internal.Alert
+	err := pgx.BeginFunc(ctx, a.pool, func(tx pgx.Tx) error {
+		q := a.q.WithTx(tx)
+		row, err := q.CreateAlert(ctx, db.CreateAlertParams{
+			RuleID:     params.RuleID,
+			SensorType: params.SensorType,
+			Severity:   params.Severity,
+			Message:    params.Message,
+			Value:      value,
+			Silenced:   params.Silenced,
+		})
+		if err != nil {
+			return err
+		}
+
+		alert = a.toAlert(row)
+		return enqueueWebhookEvent(ctx, q, internal.EventAlertOpened, alert)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.Alert{}, false, nil
+	}
+	if err != nil {
+		return internal.Alert{}, false, err
+	}
+
+	return alert, true, nil
+}
+
+func (a *Alerts) AcknowledgeAlert(ctx context.Context, id int64, by string) (internal.Alert, error) {
+	row, err := a.q.AcknowledgeAlert(ctx, db.AcknowledgeAlertParams{
+		ID:             id,
+		AcknowledgedBy: pgtype.Text{String: by, Valid: by != ""},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.Alert{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.Alert{}, err
+	}
+
+	return a.toAlert(row), nil
+}
+
+func (a *Alerts) ResolveAlert(ctx context.Context, id int64) (internal.Alert, error) {
+	var alert internal.Alert
+	err := pgx.BeginFunc(ctx, a.pool, func(tx pgx.Tx) error {
+		q := a.q.WithTx(tx)
+		row, err := q.ResolveAlert(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		alert = a.toAlert(row)
+		return enqueueWebhookEvent(ctx, q, internal.EventAlertResolved, alert)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.Alert{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.Alert{}, err
+	}
+
+	return alert, nil
+}
@@ -0,0 +1,150 @@
+package stores
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+type Assistant struct {
+	q    *db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewAssistant(pool *pgxpool.Pool) *Assistant {
+	return &Assistant{q: db.New(pool), pool: pool}
+}
+
+func (a *Assistant) toConversation(c db.AssistantConversation) internal.Conversation {
+	return internal.Conversation{
+		ID:                c.ID,
+		UserID:            int(c.UserID),
+		Title:             c.Title,
+		Plant:             c.Plant,
+		Summary:           c.Summary,
+		SummarizedThrough: c.SummarizedThrough,
+		CreatedAt:         c.CreatedAt.Time,
+		UpdatedAt:         c.UpdatedAt.Time,
+	}
+}
+
+func (a *Assistant) toMessage(m db.AssistantMessage) internal.ConversationMessage {
+	return internal.ConversationMessage{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		Role:           m.Role,
+		Content:        m.Content,
+		Source:         textPtr(m.Source),
+		CreatedAt:      m.CreatedAt.Time,
+	}
+}
+
+func (a *Assistant) ListConversations(ctx context.Context, userID int) ([]internal.Conversation, error) {
+	rows, err := a.q.ListAssistantConversations(ctx, int32(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.Conversation, len(rows))
+	for i, row := range rows {
+		res[i] = a.toConversation(row)
+	}
+	return res, nil
+}
+
+func (a *Assistant) GetConversation(ctx context.Context, userID int, id int64) (internal.Conversation, error) {
+	row, err := a.q.GetAssistantConversation(ctx, db.GetAssistantConversationParams{ID: id, UserID: int32(userID)})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.Conversation{}, internal.ErrNotFound
+		}
+		return internal.Conversation{}, err
+	}
+
+	return a.toConversation(row), nil
+}
+
+func (a *Assistant) CreateConversation(ctx context.Context, userID int, params internal.CreateConversationParams) (internal.Conversation, error) {
+	row, err := a.q.CreateAssistantConversation(ctx, db.CreateAssistantConversationParams{
+		UserID: int32(userID),
+		Title:  params.Title,
+		Plant:  params.Plant,
+	})
+	if err != nil {
+		return internal.Conversation{}, err
+	}
+
+	return a.toConversation(row), nil
+}
+
+func (a *Assistant) DeleteConversation(ctx context.Context, userID int, id int64) error {
+	n, err := a.q.DeleteAssistantConversation(ctx, db.DeleteAssistantConversationParams{ID: id, UserID: int32(userID)})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return internal.ErrNotFound
+	}
+	return nil
+}
+
+// ListConversationMessages returns the conversation's messages after the
+// message afterID, oldest first
+func (a *Assistant) ListConversationMessages(ctx context.Context, conversationID, afterID int64) ([]internal.ConversationMessage, error) {
+	rows, err := a.q.ListAssistantMessages(ctx, db.ListAssistantMessagesParams{ConversationID: conversationID, ID: afterID})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.ConversationMessage, len(rows))
+	for i, row := range rows {
+		res[i] = a.toMessage(row)
+	}
+	return res, nil
+}
+
+// AddConversationMessage saves a message and marks the conversation as
+// updated
+func (a *Assistant) AddConversationMessage(ctx context.Context, params internal.CreateConversationMessageParams) (internal.ConversationMessage, error) {
+	var msg internal.ConversationMessage
+	err := pgx.BeginFunc(ctx, a.pool, func(tx pgx.Tx) error {
+		q := a.q.WithTx(tx)
+
+		row, err := q.CreateAssistantMessage(ctx, db.CreateAssistantMessageParams{
+			ConversationID: params.ConversationID,
+			Role:           params.Role,
+			Content:        params.Content,
+			Source:         ptrText(params.Source),
+		})
+		if err != nil {
+			return err
+		}
+		msg = a.toMessage(row)
+
+		return q.TouchAssistantConversation(ctx, db.TouchAssistantConversationParams{
+			Title: params.Title,
+			ID:    params.ConversationID,
+		})
+	})
+	if err != nil {
+		return internal.ConversationMessage{}, err
+	}
+
+	return msg, nil
+}
+
+// SummarizeConversation replaces the conversation's summary with one that
+// covers every message up to through. An older summary never replaces a
+// newer one.
+func (a *Assistant) SummarizeConversation(ctx context.Context, id int64, summary string, through int64) error {
+	_, err := a.q.SummarizeAssistantConversation(ctx, db.SummarizeAssistantConversationParams{
+		Summary:           summary,
+		SummarizedThrough: through,
+		ID:                id,
+	})
+	return err
+}
@@ -0,0 +1,72 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+type AuditLog struct {
+	q *db.Queries
+}
+
+func NewAuditLog(q *db.Queries) *AuditLog {
+	return &AuditLog{q: q}
+}
+
+// recordAudit logs a change to an entity. Run it in the transaction making
+// the change, so that the log never misses or invents one.
+func recordAudit(ctx context.Context, q *db.Queries, entityType, entityID, action, actor string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s audit entry because %w", entityType, err)
+	}
+
+	if err := q.CreateAuditEntry(ctx, db.CreateAuditEntryParams{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		Actor:      actor,
+		Data:       payload,
+	}); err != nil {
+		return fmt.Errorf("failed to record %s audit entry because %w", entityType, err)
+	}
+
+	return nil
+}
+
+// RecordAuditEntry logs a change that is not made in a transaction of its
+// own, such as a maintenance window starting
+func (a *AuditLog) RecordAuditEntry(ctx context.Context, entityType, entityID, action, actor string, data any) error {
+	return recordAudit(ctx, a.q, entityType, entityID, action, actor, data)
+}
+
+func (a *AuditLog) ListAuditEntries(ctx context.Context, filter internal.AuditFilter, limit int) ([]internal.AuditEntry, error) {
+	rows, err := a.q.ListAuditEntries(ctx, db.ListAuditEntriesParams{
+		EntityType: pgtype.Text{String: filter.EntityType, Valid: filter.EntityType != ""},
+		EntityID:   pgtype.Text{String: filter.EntityID, Valid: filter.EntityID != ""},
+		Limit:      int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.AuditEntry, len(rows))
+	for i, row := range rows {
+		res[i] = internal.AuditEntry{
+			ID:         row.ID,
+			EntityType: row.EntityType,
+			EntityID:   row.EntityID,
+			Action:     row.Action,
+			Actor:      row.Actor,
+			Data:       row.Data,
+			CreatedAt:  row.CreatedAt.Time,
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,192 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+type ControlProposals struct {
+	q    *db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewControlProposals(pool *pgxpool.Pool) *ControlProposals {
+	return &ControlProposals{q: db.New(pool), pool: pool}
+}
+
+func boolPtr(v pgtype.Bool) *bool {
+	if !v.Valid {
+		return nil
+	}
+	b := v.Bool
+	return &b
+}
+
+func ptrBool(v *bool) pgtype.Bool {
+	if v == nil {
+		return pgtype.Bool{}
+	}
+	return pgtype.Bool{Bool: *v, Valid: true}
+}
+
+func (p *ControlProposals) toEntity(r db.ControlProposal) internal.ControlProposal {
+	return internal.ControlProposal{
+		ID:              r.ID,
+		SensorType:      r.SensorType,
+		Mode:            r.Mode,
+		ManualIntValue:  int4Ptr(r.ManualIntValue),
+		ManualBoolValue: boolPtr(r.ManualBoolValue),
+		DurationMinutes: int4Ptr(r.DurationMinutes),
+		Reason:          r.Reason,
+		Status:          r.Status,
+		ProposedBy:      r.ProposedBy,
+		UserID:          int4Ptr(r.UserID),
+		ConversationID:  int8Ptr(r.ConversationID),
+		ReviewedBy:      r.ReviewedBy,
+		CreatedAt:       r.CreatedAt.Time,
+		UpdatedAt:       r.UpdatedAt.Time,
+	}
+}
+
+// ListControlProposals returns proposals newest first, optionally only those
+// with the given status
+func (p *ControlProposals) ListControlProposals(ctx context.Context, status string) ([]internal.ControlProposal, error) {
+	rows, err := p.q.ListControlProposals(ctx, pgtype.Text{String: status, Valid: status != ""})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.ControlProposal, len(rows))
+	for i, row := range rows {
+		res[i] = p.toEntity(row)
+	}
+	return res, nil
+}
+
+func (p *ControlProposals) GetControlProposal(ctx context.Context, id int64) (internal.ControlProposal, error) {
+	row, err := p.q.GetControlProposal(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.ControlProposal{}, internal.ErrNotFound
+		}
+		return internal.ControlProposal{}, err
+	}
+
+	return p.toEntity(row), nil
+}
+
+// CreateControlProposal saves a pending proposal and records it in the audit
+// log
+func (p *ControlProposals) CreateControlProposal(ctx context.Context, params internal.CreateControlProposalParams) (internal.ControlProposal, error) {
+	var proposal internal.ControlProposal
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		q := p.q.WithTx(tx)
+
+		row, err := q.CreateControlProposal(ctx, db.CreateControlProposalParams{
+			SensorType:      params.SensorType,
+			Mode:            params.Mode,
+			ManualIntValue:  ptrInt4(params.ManualIntValue),
+			ManualBoolValue: ptrBool(params.ManualBoolValue),
+			DurationMinutes: ptrInt4(params.DurationMinutes),
+			Reason:          params.Reason,
+			ProposedBy:      params.ProposedBy,
+			UserID:          ptrInt4(params.UserID),
+			ConversationID:  ptrInt8(params.ConversationID),
+		})
+		if err != nil {
+			return err
+		}
+		proposal = p.toEntity(row)
+
+		return recordAudit(ctx, q, internal.AuditControlProposal, strconv.FormatInt(proposal.ID, 10), internal.AuditPropose, proposal.ProposedBy, proposal)
+	})
+	if err != nil {
+		return internal.ControlProposal{}, err
+	}
+
+	return proposal, nil
+}
+
+// ClaimControlProposal marks a pending proposal approved before its change
+// is made, so that no concurrent review can approve or reject it as well.
+// It returns ErrConflict if the proposal is no longer pending.
+func (p *ControlProposals) ClaimControlProposal(ctx context.Context, id int64, by string) (internal.ControlProposal, error) {
+	return p.review(ctx, id, internal.ProposalApproved, internal.AuditApprove, by)
+}
+
+func (p *ControlProposals) RejectControlProposal(ctx context.Context, id int64, by string) (internal.ControlProposal, error) {
+	return p.review(ctx, id, internal.ProposalRejected, internal.AuditReject, by)
+}
+
+// RecordControlProposalApplied records the control change made for a claimed
+// proposal
+func (p *ControlProposals) RecordControlProposalApplied(ctx context.Context, id int64, by string, control internal.SensorControl) error {
+	return recordAudit(ctx, p.q, internal.AuditControlProposal, strconv.FormatInt(id, 10), internal.AuditApply, by, struct {
+		Control internal.SensorControl `json:"control"`
+	}{control})
+}
+
+// FailControlProposal marks a claimed proposal failed when its change could
+// not be made, recording why
+func (p *ControlProposals) FailControlProposal(ctx context.Context, id int64, by string, cause error) (internal.ControlProposal, error) {
+	var proposal internal.ControlProposal
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		q := p.q.WithTx(tx)
+
+		row, err := q.FailControlProposal(ctx, id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return internal.ErrConflict
+			}
+			return err
+		}
+		proposal = p.toEntity(row)
+
+		return recordAudit(ctx, q, internal.AuditControlProposal, strconv.FormatInt(id, 10), internal.AuditFail, by, struct {
+			Proposal internal.ControlProposal `json:"proposal"`
+			Error    string                   `json:"error"`
+		}{proposal, cause.Error()})
+	})
+	if err != nil {
+		return internal.ControlProposal{}, err
+	}
+
+	return proposal, nil
+}
+
+func (p *ControlProposals) review(ctx context.Context, id int64, status, action, by string) (internal.ControlProposal, error) {
+	var proposal internal.ControlProposal
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		q := p.q.WithTx(tx)
+
+		row, err := q.ReviewControlProposal(ctx, db.ReviewControlProposalParams{
+			Status:     status,
+			ReviewedBy: by,
+			ID:         id,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				// Reviewed by someone else in the meantime
+				return internal.ErrConflict
+			}
+			return err
+		}
+		proposal = p.toEntity(row)
+
+		return recordAudit(ctx, q, internal.AuditControlProposal, strconv.FormatInt(id, 10), action, by, struct {
+			Proposal internal.ControlProposal `json:"proposal"`
+		}{proposal})
+	})
+	if err != nil {
+		return internal.ControlProposal{}, err
+	}
+
+	return proposal, nil
+}
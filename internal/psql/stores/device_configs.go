@@ -0,0 +1,90 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+type DeviceConfigs struct {
+	q    *db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewDeviceConfigs(pool *pgxpool.Pool) *DeviceConfigs {
+	return &DeviceConfigs{q: db.New(pool), pool: pool}
+}
+
+func (dc *DeviceConfigs) toEntity(r db.DeviceConfig) (internal.DeviceConfigDocument, error) {
+	config := internal.DefaultDeviceConfig()
+	if err := json.Unmarshal(r.Config, &config); err != nil {
+		return internal.DeviceConfigDocument{}, fmt.Errorf("failed to decode config of device %s because %w", r.DeviceID, err)
+	}
+
+	updatedAt := r.UpdatedAt.Time
+	return internal.DeviceConfigDocument{
+		DeviceID:  r.DeviceID,
+		Version:   r.Version,
+		Config:    config,
+		UpdatedBy: r.UpdatedBy,
+		UpdatedAt: &updatedAt,
+	}, nil
+}
+
+// GetDeviceConfig returns the device's configuration, or ErrNotFound if it
+// has never been configured
+func (dc *DeviceConfigs) GetDeviceConfig(ctx context.Context, deviceID string) (internal.DeviceConfigDocument, error) {
+	row, err := dc.q.GetDeviceConfig(ctx, deviceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.DeviceConfigDocument{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.DeviceConfigDocument{}, err
+	}
+
+	return dc.toEntity(row)
+}
+
+// UpdateDeviceConfig replaces the device's configuration with the next
+// version, recording the change in the audit log in the same transaction
+func (dc *DeviceConfigs) UpdateDeviceConfig(ctx context.Context, deviceID string, config internal.DeviceConfig, by string) (internal.DeviceConfigDocument, error) {
+	payload, err := json.Marshal(config)
+	if err != nil {
+		return internal.DeviceConfigDocument{}, err
+	}
+
+	var doc internal.DeviceConfigDocument
+	err = pgx.BeginFunc(ctx, dc.pool, func(tx pgx.Tx) error {
+		q := dc.q.WithTx(tx)
+		row, err := q.UpsertDeviceConfig(ctx, db.UpsertDeviceConfigParams{
+			Config:    payload,
+			UpdatedBy: by,
+			DeviceID:  deviceID,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if doc, err = dc.toEntity(row); err != nil {
+			return err
+		}
+		return recordAudit(ctx, q, internal.AuditDeviceConfig, deviceID, internal.AuditUpdate, by, map[string]any{
+			"version": doc.Version,
+			"config":  doc.Config,
+		})
+	})
+	if err != nil {
+		return internal.DeviceConfigDocument{}, err
+	}
+
+	return doc, nil
+}
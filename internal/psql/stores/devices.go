@@ -0,0 +1,332 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+type Devices struct {
+	q    *db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewDevices(pool *pgxpool.Pool) *Devices {
+	return &Devices{q: db.New(pool), pool: pool}
+}
+
+func (d *Devices) toEntity(r db.Device) internal.Device {
+	var lastSeen *time.Time
+	if r.LastSeenAt.Valid {
+		t := r.LastSeenAt.Time
+		lastSeen = &t
+	}
+	sensors := r.Sensors
+	if sensors == nil {
+		sensors = []string{}
+	}
+	return internal.Device{
+		ID:              r.ID,
+		Name:            r.Name,
+		FirmwareVersion: r.FirmwareVersion,
+		IPAddress:       r.IpAddress,
+		Zone:            textPtr(r.Zone),
+		Sensors:         sensors,
+		Status:          r.Status,
+		LastSeenAt:      lastSeen,
+		CreatedAt:       r.CreatedAt.Time,
+		UpdatedAt:       r.UpdatedAt.Time,
+	}
+}
+
+func (d *Devices) ListDevices(ctx context.Context) ([]internal.Device, error) {
+	rows, err := d.q.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.Device, len(rows))
+	for i, row := range rows {
+		res[i] = d.toEntity(row)
+	}
+
+	return res, nil
+}
+
+func (d *Devices) GetDevice(ctx context.Context, id string) (internal.Device, error) {
+	row, err := d.q.GetDevice(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.Device{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.Device{}, err
+	}
+
+	return d.toEntity(row), nil
+}
+
+// CreateDevice registers a device ahead of its first contact
+func (d *Devices) CreateDevice(ctx context.Context, id string, params internal.DeviceParams) (internal.Device, error) {
+	row, err := d.q.CreateDevice(ctx, db.CreateDeviceParams{
+		ID:   id,
+		Name: params.Name,
+		Zone: ptrText(params.Zone),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.Device{}, internal.ErrConflict
+	}
+	if err != nil {
+		return internal.Device{}, err
+	}
+
+	return d.toEntity(row), nil
+}
+
+func (d *Devices) UpdateDevice(ctx context.Context, id string, params internal.DeviceParams) (internal.Device, error) {
+	row, err := d.q.UpdateDevice(ctx, db.UpdateDeviceParams{
+		ID:   id,
+		Name: params.Name,
+		Zone: ptrText(params.Zone),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.Device{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.Device{}, err
+	}
+
+	return d.toEntity(row), nil
+}
+
+func (d *Devices) DeleteDevice(ctx context.Context, id string) error {
+	n, err := d.q.DeleteDevice(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return internal.ErrNotFound
+	}
+
+	return nil
+}
+
+// seen runs a write that marks a device online and, if it was not online
+// before, enqueues device.online in the same transaction
+func (d *Devices) seen(ctx context.Context, write func(q *db.Queries) (db.Device, string, error)) (internal.Device, error) {
+	var device internal.Device
+	err := pgx.BeginFunc(ctx, d.pool, func(tx pgx.Tx) error {
+		q := d.q.WithTx(tx)
+		row, previous, err := write(q)
+		if err != nil {
+			return err
+		}
+
+		device = d.toEntity(row)
+		if previous == internal.DeviceOnline {
+			return nil
+		}
+		return enqueueWebhookEvent(ctx, q, internal.EventDeviceOnline, device)
+	})
+	if err != nil {
+		return internal.Device{}, err
+	}
+
+	return device, nil
+}
+
+// RecordDeviceHeartbeat marks a device as seen now with what it reported,
+// registering it if it is new
+func (d *Devices) RecordDeviceHeartbeat(ctx context.Context, id string, hb internal.DeviceHeartbeat) (internal.Device, error) {
+	sensors := hb.Sensors
+	if sensors == nil {
+		sensors = []string{}
+	}
+
+	return d.seen(ctx, func(q *db.Queries) (db.Device, string, error) {
+		row, err := q.RecordDeviceHeartbeat(ctx, db.RecordDeviceHeartbeatParams{
+			ID:              id,
+			FirmwareVersion: hb.FirmwareVersion,
+			IpAddress:       hb.IPAddress,
+			Sensors:         sensors,
+		})
+		return db.Device{
+			ID:              row.ID,
+			Name:            row.Name,
+			FirmwareVersion: row.FirmwareVersion,
+			IpAddress:       row.IpAddress,
+			Zone:            row.Zone,
+			Sensors:         row.Sensors,
+			Status:          row.Status,
+			LastSeenAt:      row.LastSeenAt,
+			CreatedAt:       row.CreatedAt,
+			UpdatedAt:       row.UpdatedAt,
+		}, row.PreviousStatus, err
+	})
+}
+
+// TouchDevice marks a device as seen now because it sent a reading of
+// sensorType, registering it if it is new
+func (d *Devices) TouchDevice(ctx context.Context, id, sensorType string) (internal.Device, error) {
+	return d.seen(ctx, func(q *db.Queries) (db.Device, string, error) {
+		row, err := q.TouchDevice(ctx, db.TouchDeviceParams{
+			ID:         id,
+			SensorType: sensorType,
+		})
+		return db.Device{
+			ID:              row.ID,
+			Name:            row.Name,
+			FirmwareVersion: row.FirmwareVersion,
+			IpAddress:       row.IpAddress,
+			Zone:            row.Zone,
+			Sensors:         row.Sensors,
+			Status:          row.Status,
+			LastSeenAt:      row.LastSeenAt,
+			CreatedAt:       row.CreatedAt,
+			UpdatedAt:       row.UpdatedAt,
+		}, row.PreviousStatus, err
+	})
+}
+
+// MarkStaleDevicesOffline marks online devices not seen since before as
+// offline, enqueueing device.offline for each in the same transaction
+func (d *Devices) MarkStaleDevicesOffline(ctx context.Context, before time.Time) ([]internal.Device, error) {
+	var devices []internal.Device
+	err := pgx.BeginFunc(ctx, d.pool, func(tx pgx.Tx) error {
+		q := d.q.WithTx(tx)
+		rows, err := q.MarkStaleDevicesOffline(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+		if err != nil {
+			return err
+		}
+
+		devices = make([]internal.Device, len(rows))
+		for i, row := range rows {
+			devices[i] = d.toEntity(row)
+			if err := enqueueWebhookEvent(ctx, q, internal.EventDeviceOffline, devices[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+func (d *Devices) toActuatorState(r db.DeviceActuatorState) internal.ActuatorState {
+	s := internal.ActuatorState{
+		DeviceID:        r.DeviceID,
+		Actuator:        r.Actuator,
+		Desired:         r.Desired,
+		DesiredVersion:  r.DesiredVersion,
+		Reported:        r.Reported,
+		ReportedVersion: r.ReportedVersion,
+		Pending:         r.ReportedVersion < r.DesiredVersion,
+		Delta:           r.Desired != nil && !sameJSON(r.Desired, r.Reported),
+	}
+	if r.DesiredAt.Valid {
+		t := r.DesiredAt.Time
+		s.DesiredAt = &t
+	}
+	if r.ReportedAt.Valid {
+		t := r.ReportedAt.Time
+		s.ReportedAt = &t
+	}
+
+	return s
+}
+
+// sameJSON compares two JSON documents ignoring key order and whitespace
+func sameJSON(a, b []byte) bool {
+	var av, bv any
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+func (d *Devices) twin(ctx context.Context, q *db.Queries, id string) (internal.DeviceTwin, error) {
+	rows, err := q.ListDeviceActuatorStates(ctx, id)
+	if err != nil {
+		return internal.DeviceTwin{}, err
+	}
+
+	twin := internal.DeviceTwin{
+		DeviceID:  id,
+		Actuators: make(map[string]internal.ActuatorState, len(rows)),
+	}
+	for _, row := range rows {
+		twin.Actuators[row.Actuator] = d.toActuatorState(row)
+	}
+
+	return twin, nil
+}
+
+func (d *Devices) GetDeviceTwin(ctx context.Context, id string) (internal.DeviceTwin, error) {
+	if _, err := d.GetDevice(ctx, id); err != nil {
+		return internal.DeviceTwin{}, err
+	}
+
+	return d.twin(ctx, d.q, id)
+}
+
+// ReportActuatorStates records what a device says its actuators are in,
+// acknowledging the desired versions it applied
+func (d *Devices) ReportActuatorStates(ctx context.Context, id string, reports []internal.ActuatorReport) (internal.DeviceTwin, error) {
+	var twin internal.DeviceTwin
+	err := pgx.BeginFunc(ctx, d.pool, func(tx pgx.Tx) error {
+		q := d.q.WithTx(tx)
+		for _, r := range reports {
+			_, err := q.ReportActuatorState(ctx, db.ReportActuatorStateParams{
+				Actuator:        r.Actuator,
+				Reported:        r.State,
+				ReportedVersion: r.Version,
+				DeviceID:        id,
+			})
+			if errors.Is(err, pgx.ErrNoRows) {
+				return internal.ErrNotFound
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		twin, err = d.twin(ctx, q, id)
+		return err
+	})
+	if err != nil {
+		return internal.DeviceTwin{}, err
+	}
+
+	return twin, nil
+}
+
+// ListPendingActuatorCommands returns the unacknowledged desired states of
+// actuator issued before before, oldest first
+func (d *Devices) ListPendingActuatorCommands(ctx context.Context, actuator string, before time.Time) ([]internal.ActuatorState, error) {
+	rows, err := d.q.ListPendingActuatorCommands(ctx, db.ListPendingActuatorCommandsParams{
+		Actuator:  actuator,
+		DesiredAt: pgtype.Timestamptz{Time: before, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.ActuatorState, len(rows))
+	for i, row := range rows {
+		res[i] = d.toActuatorState(row)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,518 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+// Postgres error codes
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+func int8Ptr(v pgtype.Int8) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	i := v.Int64
+	return &i
+}
+
+type Firmware struct {
+	q    *db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewFirmware(pool *pgxpool.Pool) *Firmware {
+	return &Firmware{q: db.New(pool), pool: pool}
+}
+
+func (f *Firmware) toRelease(r db.FirmwareRelease) internal.FirmwareRelease {
+	return internal.FirmwareRelease{
+		ID:         r.ID,
+		Version:    r.Version,
+		SHA256:     r.Sha256,
+		Size:       r.Size,
+		StorageKey: r.StorageKey,
+		Notes:      r.Notes,
+		CreatedAt:  r.CreatedAt.Time,
+	}
+}
+
+func (f *Firmware) toGroup(id int64, name string, deviceIDs []string, createdAt, updatedAt pgtype.Timestamptz) internal.RolloutGroup {
+	if deviceIDs == nil {
+		deviceIDs = []string{}
+	}
+	return internal.RolloutGroup{
+		ID:        id,
+		Name:      name,
+		DeviceIDs: deviceIDs,
+		CreatedAt: createdAt.Time,
+		UpdatedAt: updatedAt.Time,
+	}
+}
+
+func (f *Firmware) toRollout(r db.FirmwareRollout, version string, failures int32) internal.FirmwareRollout {
+	stages := make([]int, len(r.Stages))
+	for i, s := range r.Stages {
+		stages[i] = int(s)
+	}
+	var resumedAt *time.Time
+	if r.ResumedAt.Valid {
+		t := r.ResumedAt.Time
+		resumedAt = &t
+	}
+	return internal.FirmwareRollout{
+		ID:               r.ID,
+		ReleaseID:        r.ReleaseID,
+		Version:          version,
+		GroupID:          int8Ptr(r.GroupID),
+		Stages:           stages,
+		Stage:            int(r.Stage),
+		Status:           r.Status,
+		FailureThreshold: int(r.FailureThreshold),
+		Failures:         int(failures),
+		HaltReason:       textPtr(r.HaltReason),
+		ResumedAt:        resumedAt,
+		CreatedAt:        r.CreatedAt.Time,
+		UpdatedAt:        r.UpdatedAt.Time,
+	}
+}
+
+func (f *Firmware) toUpdate(r db.DeviceFirmwareUpdate) internal.DeviceFirmwareUpdate {
+	return internal.DeviceFirmwareUpdate{
+		DeviceID:  r.DeviceID,
+		RolloutID: r.RolloutID,
+		Status:    r.Status,
+		Error:     r.Error,
+		CreatedAt: r.CreatedAt.Time,
+		UpdatedAt: r.UpdatedAt.Time,
+	}
+}
+
+func (f *Firmware) ListFirmwareReleases(ctx context.Context) ([]internal.FirmwareRelease, error) {
+	rows, err := f.q.ListFirmwareReleases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.FirmwareRelease, len(rows))
+	for i, row := range rows {
+		res[i] = f.toRelease(row)
+	}
+
+	return res, nil
+}
+
+func (f *Firmware) GetFirmwareRelease(ctx context.Context, id int64) (internal.FirmwareRelease, error) {
+	row, err := f.q.GetFirmwareRelease(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.FirmwareRelease{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.FirmwareRelease{}, err
+	}
+
+	return f.toRelease(row), nil
+}
+
+func (f *Firmware) CreateFirmwareRelease(ctx context.Context, params internal.FirmwareReleaseParams) (internal.FirmwareRelease, error) {
+	row, err := f.q.CreateFirmwareRelease(ctx, db.CreateFirmwareReleaseParams{
+		Version:    params.Version,
+		Sha256:     params.SHA256,
+		Size:       params.Size,
+		StorageKey: params.StorageKey,
+		Notes:      params.Notes,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.FirmwareRelease{}, internal.ErrConflict
+	}
+	if err != nil {
+		return internal.FirmwareRelease{}, err
+	}
+
+	return f.toRelease(row), nil
+}
+
+// DeleteFirmwareRelease deletes a release no rollout uses, returning it so
+// that its image can be removed too
+func (f *Firmware) DeleteFirmwareRelease(ctx context.Context, id int64) (internal.FirmwareRelease, error) {
+	row, err := f.q.DeleteFirmwareRelease(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.FirmwareRelease{}, internal.ErrNotFound
+	}
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return internal.FirmwareRelease{}, internal.NewInputError("release %d is used by a rollout", id)
+	}
+	if err != nil {
+		return internal.FirmwareRelease{}, err
+	}
+
+	return f.toRelease(row), nil
+}
+
+func (f *Firmware) ListRolloutGroups(ctx context.Context) ([]internal.RolloutGroup, error) {
+	rows, err := f.q.ListRolloutGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.RolloutGroup, len(rows))
+	for i, row := range rows {
+		res[i] = f.toGroup(row.ID, row.Name, row.DeviceIds, row.CreatedAt, row.UpdatedAt)
+	}
+
+	return res, nil
+}
+
+func (f *Firmware) getRolloutGroup(ctx context.Context, q *db.Queries, id int64) (internal.RolloutGroup, error) {
+	row, err := q.GetRolloutGroup(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.RolloutGroup{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.RolloutGroup{}, err
+	}
+
+	return f.toGroup(row.ID, row.Name, row.DeviceIds, row.CreatedAt, row.UpdatedAt), nil
+}
+
+func (f *Firmware) GetRolloutGroup(ctx context.Context, id int64) (internal.RolloutGroup, error) {
+	return f.getRolloutGroup(ctx, f.q, id)
+}
+
+// setGroupDevices replaces the devices in a group. deviceIDs must be unique.
+func (f *Firmware) setGroupDevices(ctx context.Context, q *db.Queries, groupID int64, deviceIDs []string) error {
+	if err := q.ClearRolloutGroupDevices(ctx, groupID); err != nil {
+		return err
+	}
+
+	n, err := q.AddRolloutGroupDevices(ctx, db.AddRolloutGroupDevicesParams{
+		GroupID:   groupID,
+		DeviceIds: deviceIDs,
+	})
+	if err != nil {
+		return err
+	}
+	if int(n) != len(deviceIDs) {
+		return internal.NewInputError("device_ids names %d unknown devices", len(deviceIDs)-int(n))
+	}
+
+	return nil
+}
+
+func (f *Firmware) CreateRolloutGroup(ctx context.Context, params internal.RolloutGroupParams) (internal.RolloutGroup, error) {
+	var group internal.RolloutGroup
+	err := pgx.BeginFunc(ctx, f.pool, func(tx pgx.Tx) error {
+		q := f.q.WithTx(tx)
+		row, err := q.CreateRolloutGroup(ctx, params.Name)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+		if err := f.setGroupDevices(ctx, q, row.ID, params.DeviceIDs); err != nil {
+			return err
+		}
+
+		group, err = f.getRolloutGroup(ctx, q, row.ID)
+		return err
+	})
+	if err != nil {
+		return internal.RolloutGroup{}, err
+	}
+
+	return group, nil
+}
+
+func (f *Firmware) UpdateRolloutGroup(ctx context.Context, id int64, params internal.RolloutGroupParams) (internal.RolloutGroup, error) {
+	var group internal.RolloutGroup
+	err := pgx.BeginFunc(ctx, f.pool, func(tx pgx.Tx) error {
+		q := f.q.WithTx(tx)
+		_, err := q.UpdateRolloutGroup(ctx, db.UpdateRolloutGroupParams{
+			ID:   id,
+			Name: params.Name,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.ErrNotFound
+		}
+		if pgErrorCode(err) == pgUniqueViolation {
+			return internal.ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+		if err := f.setGroupDevices(ctx, q, id, params.DeviceIDs); err != nil {
+			return err
+		}
+
+		group, err = f.getRolloutGroup(ctx, q, id)
+		return err
+	})
+	if err != nil {
+		return internal.RolloutGroup{}, err
+	}
+
+	return group, nil
+}
+
+func (f *Firmware) DeleteRolloutGroup(ctx context.Context, id int64) error {
+	n, err := f.q.DeleteRolloutGroup(ctx, id)
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return internal.NewInputError("rollout group %d is used by a rollout", id)
+	}
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return internal.ErrNotFound
+	}
+
+	return nil
+}
+
+func (f *Firmware) ListFirmwareRollouts(ctx context.Context) ([]internal.FirmwareRollout, error) {
+	rows, err := f.q.ListFirmwareRollouts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.FirmwareRollout, len(rows))
+	for i, row := range rows {
+		res[i] = f.toRollout(db.FirmwareRollout{
+			ID:               row.ID,
+			ReleaseID:        row.ReleaseID,
+			GroupID:          row.GroupID,
+			Stages:           row.Stages,
+			Stage:            row.Stage,
+			Status:           row.Status,
+			FailureThreshold: row.FailureThreshold,
+			HaltReason:       row.HaltReason,
+			ResumedAt:        row.ResumedAt,
+			CreatedAt:        row.CreatedAt,
+			UpdatedAt:        row.UpdatedAt,
+		}, row.Version, row.Failures)
+	}
+
+	return res, nil
+}
+
+func (f *Firmware) getFirmwareRollout(ctx context.Context, q *db.Queries, id int64) (internal.FirmwareRollout, error) {
+	row, err := q.GetFirmwareRollout(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.FirmwareRollout{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.FirmwareRollout{}, err
+	}
+
+	return f.toRollout(db.FirmwareRollout{
+		ID:               row.ID,
+		ReleaseID:        row.ReleaseID,
+		GroupID:          row.GroupID,
+		Stages:           row.Stages,
+		Stage:            row.Stage,
+		Status:           row.Status,
+		FailureThreshold: row.FailureThreshold,
+		HaltReason:       row.HaltReason,
+		ResumedAt:        row.ResumedAt,
+		CreatedAt:        row.CreatedAt,
+		UpdatedAt:        row.UpdatedAt,
+	}, row.Version, row.Failures), nil
+}
+
+func (f *Firmware) GetFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error) {
+	return f.getFirmwareRollout(ctx, f.q, id)
+}
+
+func (f *Firmware) CreateFirmwareRollout(ctx context.Context, params internal.FirmwareRolloutParams) (internal.FirmwareRollout, error) {
+	stages := make([]int32, len(params.Stages))
+	for i, s := range params.Stages {
+		stages[i] = int32(s)
+	}
+
+	row, err := f.q.CreateFirmwareRollout(ctx, db.CreateFirmwareRolloutParams{
+		ReleaseID:        params.ReleaseID,
+		GroupID:          ptrInt8(params.GroupID),
+		Stages:           stages,
+		FailureThreshold: int32(params.FailureThreshold),
+	})
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return internal.FirmwareRollout{}, internal.NewInputError("unknown release or rollout group")
+	}
+	if err != nil {
+		return internal.FirmwareRollout{}, err
+	}
+
+	return f.GetFirmwareRollout(ctx, row.ID)
+}
+
+// transition applies a rollout status or stage change, which matches no row
+// if the rollout is missing or in the wrong state
+func (f *Firmware) transition(ctx context.Context, id int64, change func(ctx context.Context, id int64) (db.FirmwareRollout, error)) (internal.FirmwareRollout, error) {
+	if _, err := change(ctx, id); errors.Is(err, pgx.ErrNoRows) {
+		return internal.FirmwareRollout{}, internal.ErrNotFound
+	} else if err != nil {
+		return internal.FirmwareRollout{}, err
+	}
+
+	return f.GetFirmwareRollout(ctx, id)
+}
+
+// AdvanceFirmwareRollout moves a rollout to its next stage
+func (f *Firmware) AdvanceFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error) {
+	return f.transition(ctx, id, f.q.AdvanceFirmwareRollout)
+}
+
+func (f *Firmware) PauseFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error) {
+	return f.transition(ctx, id, f.q.PauseFirmwareRollout)
+}
+
+// ResumeFirmwareRollout reactivates a paused or halted rollout. Failures
+// from before it resumed no longer count towards halting it.
+func (f *Firmware) ResumeFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error) {
+	return f.transition(ctx, id, f.q.ResumeFirmwareRollout)
+}
+
+// ListFirmwareRolloutCandidates returns the active rollouts targeting a
+// device, with their releases and the device's progress through each
+func (f *Firmware) ListFirmwareRolloutCandidates(ctx context.Context, deviceID string) ([]internal.FirmwareRolloutCandidate, error) {
+	rows, err := f.q.ListActiveFirmwareRolloutsForDevice(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.FirmwareRolloutCandidate, len(rows))
+	for i, row := range rows {
+		res[i] = internal.FirmwareRolloutCandidate{
+			Rollout: f.toRollout(db.FirmwareRollout{
+				ID:               row.ID,
+				ReleaseID:        row.ReleaseID,
+				GroupID:          row.GroupID,
+				Stages:           row.Stages,
+				Stage:            row.Stage,
+				Status:           row.Status,
+				FailureThreshold: row.FailureThreshold,
+				HaltReason:       row.HaltReason,
+				ResumedAt:        row.ResumedAt,
+				CreatedAt:        row.CreatedAt,
+				UpdatedAt:        row.UpdatedAt,
+			}, row.Version, 0),
+			Release: f.toRelease(db.FirmwareRelease{
+				ID:         row.ReleaseID,
+				Version:    row.Version,
+				Sha256:     row.Sha256,
+				Size:       row.Size,
+				StorageKey: row.StorageKey,
+				Notes:      row.Notes,
+				CreatedAt:  row.ReleaseCreatedAt,
+			}),
+			UpdateStatus: textPtr(row.UpdateStatus),
+		}
+	}
+
+	return res, nil
+}
+
+// OfferFirmwareUpdate starts tracking a device's update through a rollout,
+// keeping its progress if it was offered the update before
+func (f *Firmware) OfferFirmwareUpdate(ctx context.Context, deviceID string, rolloutID int64) (internal.DeviceFirmwareUpdate, error) {
+	row, err := f.q.OfferFirmwareUpdate(ctx, db.OfferFirmwareUpdateParams{
+		DeviceID:  deviceID,
+		RolloutID: rolloutID,
+	})
+	if err != nil {
+		return internal.DeviceFirmwareUpdate{}, err
+	}
+
+	return f.toUpdate(row), nil
+}
+
+// RecordFirmwareUpdateStatus records a device's progress through a rollout.
+// A failure that takes the rollout to its failure threshold halts it and
+// enqueues firmware.rollout_halted in the same transaction; the halted
+// rollout is returned.
+func (f *Firmware) RecordFirmwareUpdateStatus(ctx context.Context, deviceID string, rolloutID int64, status, errMsg string) (internal.DeviceFirmwareUpdate, *internal.FirmwareRollout, error) {
+	var update internal.DeviceFirmwareUpdate
+	var halted *internal.FirmwareRollout
+	err := pgx.BeginFunc(ctx, f.pool, func(tx pgx.Tx) error {
+		q := f.q.WithTx(tx)
+		row, err := q.SetDeviceFirmwareUpdateStatus(ctx, db.SetDeviceFirmwareUpdateStatusParams{
+			DeviceID:  deviceID,
+			RolloutID: rolloutID,
+			Status:    status,
+			Error:     errMsg,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		update = f.toUpdate(row)
+
+		if status != internal.UpdateFailed {
+			return nil
+		}
+
+		reason := fmt.Sprintf("failure threshold reached; last failure on device %s", deviceID)
+		if errMsg != "" {
+			reason += ": " + errMsg
+		}
+		_, err = q.HaltFirmwareRolloutOnFailures(ctx, db.HaltFirmwareRolloutOnFailuresParams{
+			HaltReason: pgtype.Text{String: reason, Valid: true},
+			ID:         rolloutID,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		rollout, err := f.getFirmwareRollout(ctx, q, rolloutID)
+		if err != nil {
+			return err
+		}
+		halted = &rollout
+		return enqueueWebhookEvent(ctx, q, internal.EventFirmwareRolloutHalted, rollout)
+	})
+	if err != nil {
+		return internal.DeviceFirmwareUpdate{}, nil, err
+	}
+
+	return update, halted, nil
+}
+
+func (f *Firmware) ListDeviceFirmwareUpdates(ctx context.Context, rolloutID int64) ([]internal.DeviceFirmwareUpdate, error) {
+	rows, err := f.q.ListDeviceFirmwareUpdates(ctx, rolloutID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.DeviceFirmwareUpdate, len(rows))
+	for i, row := range rows {
+		res[i] = f.toUpdate(row)
+	}
+
+	return res, nil
+}
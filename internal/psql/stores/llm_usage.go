@@ -0,0 +1,91 @@
+package stores
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+type LLMUsage struct {
+	q *db.Queries
+}
+
+func NewLLMUsage(pool *pgxpool.Pool) *LLMUsage {
+	return &LLMUsage{q: db.New(pool)}
+}
+
+func (u *LLMUsage) CreateLLMUsage(ctx context.Context, params internal.CreateLLMUsageParams) (internal.LLMUsage, error) {
+	row, err := u.q.CreateLLMUsage(ctx, db.CreateLLMUsageParams{
+		UserID:           ptrInt4(params.UserID),
+		Feature:          params.Feature,
+		Provider:         params.Provider,
+		Model:            params.Model,
+		PromptTokens:     int32(params.PromptTokens),
+		CompletionTokens: int32(params.CompletionTokens),
+		LatencyMs:        params.LatencyMS,
+		CostUsd:          params.CostUSD,
+		Cached:           params.Cached,
+	})
+	if err != nil {
+		return internal.LLMUsage{}, err
+	}
+
+	return internal.LLMUsage{
+		ID:               row.ID,
+		UserID:           int4Ptr(row.UserID),
+		Feature:          row.Feature,
+		Provider:         row.Provider,
+		Model:            row.Model,
+		PromptTokens:     int(row.PromptTokens),
+		CompletionTokens: int(row.CompletionTokens),
+		LatencyMS:        row.LatencyMs,
+		CostUSD:          row.CostUsd,
+		Cached:           row.Cached,
+		CreatedAt:        row.CreatedAt.Time,
+	}, nil
+}
+
+// SumLLMTokens returns the tokens spent since the given time, by userID or,
+// when it is nil, by everyone
+func (u *LLMUsage) SumLLMTokens(ctx context.Context, since time.Time, userID *int) (int64, error) {
+	return u.q.SumLLMTokensSince(ctx, db.SumLLMTokensSinceParams{
+		Since:  pgtype.Timestamptz{Time: since, Valid: true},
+		UserID: ptrInt4(userID),
+	})
+}
+
+// SumAnonymousLLMTokens returns the tokens spent since the given time by
+// requests made without signing in
+func (u *LLMUsage) SumAnonymousLLMTokens(ctx context.Context, since time.Time) (int64, error) {
+	return u.q.SumAnonymousLLMTokensSince(ctx, pgtype.Timestamptz{Time: since, Valid: true})
+}
+
+func (u *LLMUsage) SummarizeLLMUsage(ctx context.Context, params internal.SummarizeLLMUsageParams) ([]internal.LLMUsageTotal, error) {
+	rows, err := u.q.SummarizeLLMUsage(ctx, db.SummarizeLLMUsageParams{
+		FromTime: pgtype.Timestamptz{Time: params.From, Valid: true},
+		ToTime:   pgtype.Timestamptz{Time: params.To, Valid: true},
+		UserID:   ptrInt4(params.UserID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.LLMUsageTotal, len(rows))
+	for i, row := range rows {
+		res[i] = internal.LLMUsageTotal{
+			Day:              row.Day.Time,
+			Feature:          row.Feature,
+			UserID:           int4Ptr(row.UserID),
+			Requests:         row.Requests,
+			CachedRequests:   row.CachedRequests,
+			PromptTokens:     row.PromptTokens,
+			CompletionTokens: row.CompletionTokens,
+			CostUSD:          row.CostUsd,
+		}
+	}
+	return res, nil
+}
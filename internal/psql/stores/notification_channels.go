@@ -0,0 +1,170 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+type NotificationChannels struct {
+	q *db.Queries
+}
+
+func NewNotificationChannels(q *db.Queries) *NotificationChannels {
+	return &NotificationChannels{q: q}
+}
+
+func textPtr(t pgtype.Text) *string {
+	if !t.Valid {
+		return nil
+	}
+	s := t.String
+	return &s
+}
+
+func ptrText(s *string) pgtype.Text {
+	if s == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *s, Valid: true}
+}
+
+func (nc *NotificationChannels) toEntity(c db.NotificationChannel) (internal.NotificationChannel, error) {
+	config := map[string]string{}
+	if err := json.Unmarshal(c.Config, &config); err != nil {
+		return internal.NotificationChannel{}, fmt.Errorf("invalid config on notification channel %d because %w", c.ID, err)
+	}
+
+	return internal.NotificationChannel{
+		ID:              c.ID,
+		UserID:          int(c.UserID),
+		Kind:            c.Kind,
+		Name:            c.Name,
+		Config:          config,
+		MinSeverity:     c.MinSeverity,
+		QuietHoursStart: textPtr(c.QuietHoursStart),
+		QuietHoursEnd:   textPtr(c.QuietHoursEnd),
+		Timezone:        c.Timezone,
+		Enabled:         c.Enabled,
+		CreatedAt:       c.CreatedAt.Time,
+		UpdatedAt:       c.UpdatedAt.Time,
+	}, nil
+}
+
+func (nc *NotificationChannels) toEntities(rows []db.NotificationChannel) ([]internal.NotificationChannel, error) {
+	res := make([]internal.NotificationChannel, len(rows))
+	for i, row := range rows {
+		c, err := nc.toEntity(row)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = c
+	}
+	return res, nil
+}
+
+func (nc *NotificationChannels) ListNotificationChannels(ctx context.Context, userID int) ([]internal.NotificationChannel, error) {
+	rows, err := nc.q.ListNotificationChannelsByUser(ctx, int32(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	return nc.toEntities(rows)
+}
+
+func (nc *NotificationChannels) ListEnabledNotificationChannels(ctx context.Context) ([]internal.NotificationChannel, error) {
+	rows, err := nc.q.ListEnabledNotificationChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return nc.toEntities(rows)
+}
+
+func (nc *NotificationChannels) GetNotificationChannel(ctx context.Context, userID int, id int64) (internal.NotificationChannel, error) {
+	row, err := nc.q.GetNotificationChannel(ctx, db.GetNotificationChannelParams{
+		ID:     id,
+		UserID: int32(userID),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.NotificationChannel{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.NotificationChannel{}, err
+	}
+
+	return nc.toEntity(row)
+}
+
+func (nc *NotificationChannels) CreateNotificationChannel(ctx context.Context, userID int, params internal.NotificationChannelParams) (internal.NotificationChannel, error) {
+	config, err := json.Marshal(params.Config)
+	if err != nil {
+		return internal.NotificationChannel{}, err
+	}
+
+	row, err := nc.q.CreateNotificationChannel(ctx, db.CreateNotificationChannelParams{
+		UserID:          int32(userID),
+		Kind:            params.Kind,
+		Name:            params.Name,
+		Config:          config,
+		MinSeverity:     params.MinSeverity,
+		QuietHoursStart: ptrText(params.QuietHoursStart),
+		QuietHoursEnd:   ptrText(params.QuietHoursEnd),
+		Timezone:        params.Timezone,
+		Enabled:         params.Enabled,
+	})
+	if err != nil {
+		return internal.NotificationChannel{}, err
+	}
+
+	return nc.toEntity(row)
+}
+
+func (nc *NotificationChannels) UpdateNotificationChannel(ctx context.Context, userID int, id int64, params internal.NotificationChannelParams) (internal.NotificationChannel, error) {
+	config, err := json.Marshal(params.Config)
+	if err != nil {
+		return internal.NotificationChannel{}, err
+	}
+
+	row, err := nc.q.UpdateNotificationChannel(ctx, db.UpdateNotificationChannelParams{
+		ID:              id,
+		UserID:          int32(userID),
+		Kind:            params.Kind,
+		Name:            params.Name,
+		Config:          config,
+		MinSeverity:     params.MinSeverity,
+		QuietHoursStart: ptrText(params.QuietHoursStart),
+		QuietHoursEnd:   ptrText(params.QuietHoursEnd),
+		Timezone:        params.Timezone,
+		Enabled:         params.Enabled,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.NotificationChannel{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.NotificationChannel{}, err
+	}
+
+	return nc.toEntity(row)
+}
+
+func (nc *NotificationChannels) DeleteNotificationChannel(ctx context.Context, userID int, id int64) error {
+	n, err := nc.q.DeleteNotificationChannel(ctx, db.DeleteNotificationChannelParams{
+		ID:     id,
+		UserID: int32(userID),
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return internal.ErrNotFound
+	}
+
+	return nil
+}
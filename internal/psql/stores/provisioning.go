@@ -0,0 +1,249 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+type Provisioning struct {
+	q    *db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewProvisioning(pool *pgxpool.Pool) *Provisioning {
+	return &Provisioning{q: db.New(pool), pool: pool}
+}
+
+func (p *Provisioning) toEntity(r db.DeviceClaim) internal.DeviceClaim {
+	return internal.DeviceClaim{
+		ID:              r.ID,
+		DeviceID:        r.DeviceID,
+		CodeHint:        r.CodeHint,
+		FirmwareVersion: r.FirmwareVersion,
+		IPAddress:       r.IpAddress,
+		Status:          r.Status,
+		Name:            r.Name,
+		Zone:            textPtr(r.Zone),
+		ReviewedBy:      r.ReviewedBy,
+		ExpiresAt:       r.ExpiresAt.Time,
+		CreatedAt:       r.CreatedAt.Time,
+		UpdatedAt:       r.UpdatedAt.Time,
+	}
+}
+
+func (p *Provisioning) toEntities(rows []db.DeviceClaim) []internal.DeviceClaim {
+	res := make([]internal.DeviceClaim, len(rows))
+	for i, row := range rows {
+		res[i] = p.toEntity(row)
+	}
+	return res
+}
+
+// ListDeviceClaims lists claims, newest first. An empty status lists all.
+func (p *Provisioning) ListDeviceClaims(ctx context.Context, status string) ([]internal.DeviceClaim, error) {
+	rows, err := p.q.ListDeviceClaims(ctx, pgtype.Text{String: status, Valid: status != ""})
+	if err != nil {
+		return nil, err
+	}
+
+	return p.toEntities(rows), nil
+}
+
+func (p *Provisioning) GetDeviceClaim(ctx context.Context, id int64) (internal.DeviceClaim, error) {
+	row, err := p.q.GetDeviceClaim(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.DeviceClaim{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.DeviceClaim{}, err
+	}
+
+	return p.toEntity(row), nil
+}
+
+func (p *Provisioning) GetDeviceClaimByCode(ctx context.Context, codeHash string) (internal.DeviceClaim, error) {
+	row, err := p.q.GetDeviceClaimByCode(ctx, codeHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.DeviceClaim{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.DeviceClaim{}, err
+	}
+
+	return p.toEntity(row), nil
+}
+
+func (p *Provisioning) CreateDeviceClaim(ctx context.Context, codeHash, codeHint string, req internal.ProvisionRequest, expiresAt time.Time) (internal.DeviceClaim, error) {
+	row, err := p.q.CreateDeviceClaim(ctx, db.CreateDeviceClaimParams{
+		CodeHash:        codeHash,
+		CodeHint:        codeHint,
+		DeviceID:        req.DeviceID,
+		FirmwareVersion: req.FirmwareVersion,
+		IpAddress:       req.IPAddress,
+		ExpiresAt:       pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.DeviceClaim{}, internal.ErrConflict
+	}
+	if err != nil {
+		return internal.DeviceClaim{}, err
+	}
+
+	return p.toEntity(row), nil
+}
+
+// ReopenDeviceClaim starts an expired claim over as a fresh request
+func (p *Provisioning) ReopenDeviceClaim(ctx context.Context, id int64, req internal.ProvisionRequest, expiresAt time.Time) (internal.DeviceClaim, error) {
+	row, err := p.q.ReopenDeviceClaim(ctx, db.ReopenDeviceClaimParams{
+		DeviceID:        req.DeviceID,
+		FirmwareVersion: req.FirmwareVersion,
+		IpAddress:       req.IPAddress,
+		ExpiresAt:       pgtype.Timestamptz{Time: expiresAt, Valid: true},
+		ID:              id,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.DeviceClaim{}, internal.ErrConflict
+	}
+	if err != nil {
+		return internal.DeviceClaim{}, err
+	}
+
+	return p.toEntity(row), nil
+}
+
+// ApproveDeviceClaim admits a pending claim and registers its device under
+// the approved name and zone
+func (p *Provisioning) ApproveDeviceClaim(ctx context.Context, id int64, approval internal.ClaimApproval, expiresAt time.Time) (internal.DeviceClaim, error) {
+	var claim internal.DeviceClaim
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		q := p.q.WithTx(tx)
+		row, err := q.ApproveDeviceClaim(ctx, db.ApproveDeviceClaimParams{
+			Name:       approval.Name,
+			Zone:       ptrText(approval.Zone),
+			ReviewedBy: approval.ReviewedBy,
+			ExpiresAt:  pgtype.Timestamptz{Time: expiresAt, Valid: true},
+			ID:         id,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		claim = p.toEntity(row)
+
+		if err := q.ProvisionDevice(ctx, db.ProvisionDeviceParams{
+			ID:   claim.DeviceID,
+			Name: claim.Name,
+			Zone: row.Zone,
+		}); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, internal.AuditDeviceClaim, claim.DeviceID, internal.AuditApprove, approval.ReviewedBy, claim)
+	})
+	if err != nil {
+		return internal.DeviceClaim{}, err
+	}
+
+	return claim, nil
+}
+
+func (p *Provisioning) RejectDeviceClaim(ctx context.Context, id int64, by string) (internal.DeviceClaim, error) {
+	var claim internal.DeviceClaim
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		q := p.q.WithTx(tx)
+		row, err := q.RejectDeviceClaim(ctx, db.RejectDeviceClaimParams{
+			ReviewedBy: by,
+			ID:         id,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		claim = p.toEntity(row)
+
+		return recordAudit(ctx, q, internal.AuditDeviceClaim, claim.DeviceID, internal.AuditReject, by, claim)
+	})
+	if err != nil {
+		return internal.DeviceClaim{}, err
+	}
+
+	return claim, nil
+}
+
+// CompleteDeviceClaim marks an approved claim as collected and issues its
+// device the API key hashed as keyHash, replacing any earlier key
+func (p *Provisioning) CompleteDeviceClaim(ctx context.Context, id int64, keyHash string) (internal.DeviceClaim, error) {
+	var claim internal.DeviceClaim
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		q := p.q.WithTx(tx)
+		row, err := q.CompleteDeviceClaim(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return internal.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		claim = p.toEntity(row)
+
+		return q.UpsertDeviceAPIKey(ctx, db.UpsertDeviceAPIKeyParams{
+			DeviceID: claim.DeviceID,
+			KeyHash:  keyHash,
+		})
+	})
+	if err != nil {
+		return internal.DeviceClaim{}, err
+	}
+
+	return claim, nil
+}
+
+// ExpireDeviceClaims expires the claims nobody finished in time
+func (p *Provisioning) ExpireDeviceClaims(ctx context.Context) ([]internal.DeviceClaim, error) {
+	rows, err := p.q.ExpireDeviceClaims(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.toEntities(rows), nil
+}
+
+// AuthenticateDeviceAPIKey returns the device an API key was issued to
+func (p *Provisioning) AuthenticateDeviceAPIKey(ctx context.Context, keyHash string) (string, error) {
+	deviceID, err := p.q.AuthenticateDeviceAPIKey(ctx, keyHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", internal.ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return deviceID, nil
+}
+
+// DeviceHasAPIKey reports whether a device has been issued an API key
+func (p *Provisioning) DeviceHasAPIKey(ctx context.Context, deviceID string) (bool, error) {
+	return p.q.DeviceHasAPIKey(ctx, deviceID)
+}
+
+func (p *Provisioning) RevokeDeviceAPIKey(ctx context.Context, deviceID string) error {
+	n, err := p.q.DeleteDeviceAPIKey(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return internal.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+type SensorCalibrations struct {
+	q *db.Queries
+}
+
+func NewSensorCalibrations(q *db.Queries) *SensorCalibrations {
+	return &SensorCalibrations{q: q}
+}
+
+func (sc *SensorCalibrations) toEntity(c db.SensorCalibration) internal.SensorCalibration {
+	var deviceID *string
+	if c.DeviceID.Valid {
+		id := c.DeviceID.String
+		deviceID = &id
+	}
+	var recomputeFrom *time.Time
+	if c.RecomputeFrom.Valid {
+		from := c.RecomputeFrom.Time
+		recomputeFrom = &from
+	}
+	return internal.SensorCalibration{
+		ID:            c.ID,
+		SensorType:    c.SensorType,
+		DeviceID:      deviceID,
+		Method:        c.Method,
+		Coefficients:  c.Coefficients,
+		EffectiveFrom: c.EffectiveFrom.Time,
+		Note:          c.Note,
+		CreatedAt:     c.CreatedAt.Time,
+		UpdatedAt:     c.UpdatedAt.Time,
+		RecomputeFrom: recomputeFrom,
+	}
+}
+
+func (sc *SensorCalibrations) ListSensorCalibrations(ctx context.Context) ([]internal.SensorCalibration, error) {
+	rows, err := sc.q.ListSensorCalibrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.SensorCalibration, len(rows))
+	for i, row := range rows {
+		res[i] = sc.toEntity(row)
+	}
+
+	return res, nil
+}
+
+func (sc *SensorCalibrations) ListSensorCalibrationsBySensorType(ctx context.Context, sensorType string) ([]internal.SensorCalibration, error) {
+	rows, err := sc.q.ListSensorCalibrationsBySensorType(ctx, sensorType)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.SensorCalibration, len(rows))
+	for i, row := range rows {
+		res[i] = sc.toEntity(row)
+	}
+
+	return res, nil
+}
+
+func (sc *SensorCalibrations) GetSensorCalibration(ctx context.Context, id int64) (internal.SensorCalibration, error) {
+	row, err := sc.q.GetSensorCalibration(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.SensorCalibration{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.SensorCalibration{}, err
+	}
+
+	return sc.toEntity(row), nil
+}
+
+func (sc *SensorCalibrations) CreateSensorCalibration(ctx context.Context, params internal.CreateSensorCalibrationParams) (internal.SensorCalibration, error) {
+	var deviceID pgtype.Text
+	if params.DeviceID != nil {
+		deviceID.Valid = true
+		deviceID.String = *params.DeviceID
+	}
+
+	row, err := sc.q.CreateSensorCalibration(ctx, db.CreateSensorCalibrationParams{
+		SensorType:    params.SensorType,
+		DeviceID:      deviceID,
+		Method:        params.Method,
+		Coefficients:  params.Coefficients,
+		EffectiveFrom: pgtype.Timestamptz{Time: params.EffectiveFrom, Valid: true},
+		Note:          params.Note,
+	})
+	if err != nil {
+		return internal.SensorCalibration{}, err
+	}
+
+	return sc.toEntity(row), nil
+}
+
+func (sc *SensorCalibrations) UpdateSensorCalibration(ctx context.Context, id int64, params internal.UpdateSensorCalibrationParams) (internal.SensorCalibration, error) {
+	var effectiveFrom pgtype.Timestamptz
+	if params.EffectiveFrom != nil {
+		effectiveFrom = pgtype.Timestamptz{Time: *params.EffectiveFrom, Valid: true}
+	}
+
+	row, err := sc.q.UpdateSensorCalibration(ctx, db.UpdateSensorCalibrationParams{
+		ID:            id,
+		Method:        params.Method,
+		Coefficients:  params.Coefficients,
+		EffectiveFrom: effectiveFrom,
+		Note:          params.Note,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.SensorCalibration{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.SensorCalibration{}, err
+	}
+
+	return sc.toEntity(row), nil
+}
+
+// ClearSensorCalibrationRecomputeFrom marks the readings of a calibration as
+// up to date, unless it has been updated since updatedAt
+func (sc *SensorCalibrations) ClearSensorCalibrationRecomputeFrom(ctx context.Context, id int64, updatedAt time.Time) error {
+	return sc.q.ClearSensorCalibrationRecomputeFrom(ctx, db.ClearSensorCalibrationRecomputeFromParams{
+		ID:        id,
+		UpdatedAt: pgtype.Timestamptz{Time: updatedAt, Valid: true},
+	})
+}
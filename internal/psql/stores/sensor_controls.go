@@ -4,21 +4,46 @@ import (
 	"context"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/lulzshadowwalker/green-backend/internal"
 	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
 )
 
 type SensorControls struct {
-	q *db.Queries
+	q    *db.Queries
+	pool *pgxpool.Pool
 }
 
-func NewSensorControls(q *db.Queries) *SensorControls {
+func NewSensorControls(pool *pgxpool.Pool) *SensorControls {
 	return &SensorControls{
-		q: q,
+		q:    db.New(pool),
+		pool: pool,
 	}
 }
 
+// changed wraps a control write in a transaction that also moves the desired
+// state of device twins and enqueues the control.changed webhook event
+func (sc *SensorControls) changed(ctx context.Context, write func(q *db.Queries) (db.SensorControl, error)) (db.SensorControl, error) {
+	var row db.SensorControl
+	err := pgx.BeginFunc(ctx, sc.pool, func(tx pgx.Tx) error {
+		q := sc.q.WithTx(tx)
+		var err error
+		row, err = write(q)
+		if err != nil {
+			return err
+		}
+		if _, err := q.SyncDesiredActuatorStates(ctx, row.SensorType); err != nil {
+			return err
+		}
+
+		return enqueueWebhookEvent(ctx, q, internal.EventControlChanged, sc.toEntity(row))
+	})
+
+	return row, err
+}
+
 func (sc *SensorControls) toEntity(c db.SensorControl) internal.SensorControl {
 	var manualUntil *time.Time
 	if c.ManualUntil.Valid {
@@ -125,37 +150,26 @@ func (sc *SensorControls) UpdateSensorControlMode(ctx context.Context, sensorTyp
 	} else {
 		boolVal.Valid = false
 	}
-	row, err := sc.q.UpdateSensorControlMode(ctx, db.UpdateSensorControlModeParams{
-		SensorType:      sensorType,
-		Mode:            mode,
-		ManualUntil:     mu,
-		ManualIntValue:  intVal,
-		ManualBoolValue: boolVal,
+	row, err := sc.changed(ctx, func(q *db.Queries) (db.SensorControl, error) {
+		row, err := q.UpdateSensorControlMode(ctx, db.UpdateSensorControlModeParams{
+			SensorType:      sensorType,
+			Mode:            mode,
+			ManualUntil:     mu,
+			ManualIntValue:  intVal,
+			ManualBoolValue: boolVal,
+		})
+		return db.SensorControl{
+			SensorType:      row.SensorType,
+			Mode:            row.Mode,
+			ManualUntil:     row.ManualUntil,
+			ManualBoolValue: row.ManualBoolValue,
+			ManualIntValue:  row.ManualIntValue,
+		}, err
 	})
 	if err != nil {
 		return internal.SensorControl{}, err
 	}
-	var muUntil *time.Time
-	if row.ManualUntil.Valid {
-		t := row.ManualUntil.Time
-		muUntil = &t
-	}
-	var manualBoolValueOut *bool
-	if row.ManualBoolValue.Valid {
-		manualBoolValueOut = &row.ManualBoolValue.Bool
-	}
-	var manualIntValueOut *int
-	if row.ManualIntValue.Valid {
-		val := int(row.ManualIntValue.Int32)
-		manualIntValueOut = &val
-	}
-	return internal.SensorControl{
-		SensorType:      row.SensorType,
-		Mode:            row.Mode,
-		ManualUntil:     muUntil,
-		ManualBoolValue: manualBoolValueOut,
-		ManualIntValue:  manualIntValueOut,
-	}, nil
+	return sc.toEntity(row), nil
 }
 
 func (sc *SensorControls) InsertOrUpdateSensorControl(ctx context.Context, sensorType, mode string, manualUntil *time.Time, manualIntValue *int, manualBoolValue *bool) (internal.SensorControl, error) {
@@ -180,35 +194,24 @@ func (sc *SensorControls) InsertOrUpdateSensorControl(ctx context.Context, senso
 	} else {
 		boolVal.Valid = false
 	}
-	row, err := sc.q.InsertSensorControl(ctx, db.InsertSensorControlParams{
-		SensorType:      sensorType,
-		Mode:            mode,
-		ManualUntil:     mu,
-		ManualIntValue:  intVal,
-		ManualBoolValue: boolVal,
+	row, err := sc.changed(ctx, func(q *db.Queries) (db.SensorControl, error) {
+		row, err := q.InsertSensorControl(ctx, db.InsertSensorControlParams{
+			SensorType:      sensorType,
+			Mode:            mode,
+			ManualUntil:     mu,
+			ManualIntValue:  intVal,
+			ManualBoolValue: boolVal,
+		})
+		return db.SensorControl{
+			SensorType:      row.SensorType,
+			Mode:            row.Mode,
+			ManualUntil:     row.ManualUntil,
+			ManualBoolValue: row.ManualBoolValue,
+			ManualIntValue:  row.ManualIntValue,
+		}, err
 	})
 	if err != nil {
 		return internal.SensorControl{}, err
 	}
-	var muUntil *time.Time
-	if row.ManualUntil.Valid {
-		t := row.ManualUntil.Time
-		muUntil = &t
-	}
-	var manualBoolValueOut *bool
-	if row.ManualBoolValue.Valid {
-		manualBoolValueOut = &row.ManualBoolValue.Bool
-	}
-	var manualIntValueOut *int
-	if row.ManualIntValue.Valid {
-		val := int(row.ManualIntValue.Int32)
-		manualIntValueOut = &val
-	}
-	return internal.SensorControl{
-		SensorType:      row.SensorType,
-		Mode:            row.Mode,
-		ManualUntil:     muUntil,
-		ManualBoolValue: manualBoolValueOut,
-		ManualIntValue:  manualIntValueOut,
-	}, nil
+	return sc.toEntity(row), nil
 }
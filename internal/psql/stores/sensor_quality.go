@@ -0,0 +1,173 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+type SensorQuality struct {
+	q *db.Queries
+}
+
+func NewSensorQuality(q *db.Queries) *SensorQuality {
+	return &SensorQuality{q: q}
+}
+
+func (sq *SensorQuality) toRule(r db.SensorQualityRule) internal.SensorQualityRule {
+	var maxChange *float64
+	if r.MaxChangePerMinute.Valid {
+		v := r.MaxChangePerMinute.Float64
+		maxChange = &v
+	}
+	return internal.SensorQualityRule{
+		SensorType:         r.SensorType,
+		Min:                r.MinValue,
+		Max:                r.MaxValue,
+		MaxChangePerMinute: maxChange,
+		FlatlineMinutes:    int(r.FlatlineMinutes),
+		FlatlineTolerance:  r.FlatlineTolerance,
+		UpdatedAt:          r.UpdatedAt.Time,
+	}
+}
+
+func (sq *SensorQuality) toEvent(e db.DeviceHealthEvent) internal.DeviceHealthEvent {
+	var deviceID *string
+	if e.DeviceID.Valid {
+		id := e.DeviceID.String
+		deviceID = &id
+	}
+	var resolvedAt *time.Time
+	if e.ResolvedAt.Valid {
+		t := e.ResolvedAt.Time
+		resolvedAt = &t
+	}
+	return internal.DeviceHealthEvent{
+		ID:         e.ID,
+		SensorType: e.SensorType,
+		DeviceID:   deviceID,
+		Kind:       e.Kind,
+		Message:    e.Message,
+		StartedAt:  e.StartedAt.Time,
+		ResolvedAt: resolvedAt,
+	}
+}
+
+func (sq *SensorQuality) ListSensorQualityRules(ctx context.Context) ([]internal.SensorQualityRule, error) {
+	rows, err := sq.q.ListSensorQualityRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.SensorQualityRule, len(rows))
+	for i, row := range rows {
+		res[i] = sq.toRule(row)
+	}
+
+	return res, nil
+}
+
+func (sq *SensorQuality) GetSensorQualityRule(ctx context.Context, sensorType string) (internal.SensorQualityRule, error) {
+	row, err := sq.q.GetSensorQualityRule(ctx, sensorType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.SensorQualityRule{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.SensorQualityRule{}, err
+	}
+
+	return sq.toRule(row), nil
+}
+
+func (sq *SensorQuality) UpsertSensorQualityRule(ctx context.Context, rule internal.SensorQualityRule) (internal.SensorQualityRule, error) {
+	var maxChange pgtype.Float8
+	if rule.MaxChangePerMinute != nil {
+		maxChange.Valid = true
+		maxChange.Float64 = *rule.MaxChangePerMinute
+	}
+
+	row, err := sq.q.UpsertSensorQualityRule(ctx, db.UpsertSensorQualityRuleParams{
+		SensorType:         rule.SensorType,
+		MinValue:           rule.Min,
+		MaxValue:           rule.Max,
+		MaxChangePerMinute: maxChange,
+		FlatlineMinutes:    int32(rule.FlatlineMinutes),
+		FlatlineTolerance:  rule.FlatlineTolerance,
+	})
+	if err != nil {
+		return internal.SensorQualityRule{}, err
+	}
+
+	return sq.toRule(row), nil
+}
+
+func (sq *SensorQuality) ListDeviceHealthEvents(ctx context.Context, limit int) ([]internal.DeviceHealthEvent, error) {
+	rows, err := sq.q.ListDeviceHealthEvents(ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.DeviceHealthEvent, len(rows))
+	for i, row := range rows {
+		res[i] = sq.toEvent(row)
+	}
+
+	return res, nil
+}
+
+// ListOpenDeviceHealthEvents returns every unresolved event for sensorType,
+// on any device, oldest first
+func (sq *SensorQuality) ListOpenDeviceHealthEvents(ctx context.Context, sensorType string) ([]internal.DeviceHealthEvent, error) {
+	rows, err := sq.q.ListOpenDeviceHealthEventsBySensorType(ctx, sensorType)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.DeviceHealthEvent, len(rows))
+	for i, row := range rows {
+		res[i] = sq.toEvent(row)
+	}
+
+	return res, nil
+}
+
+// GetOpenDeviceHealthEvent returns the unresolved event of kind for
+// sensorType on deviceID, where a nil deviceID means readings from no device
+func (sq *SensorQuality) GetOpenDeviceHealthEvent(ctx context.Context, sensorType string, deviceID *string, kind string) (internal.DeviceHealthEvent, error) {
+	row, err := sq.q.GetOpenDeviceHealthEvent(ctx, db.GetOpenDeviceHealthEventParams{
+		SensorType: sensorType,
+		DeviceID:   ptrText(deviceID),
+		Kind:       kind,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.DeviceHealthEvent{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.DeviceHealthEvent{}, err
+	}
+
+	return sq.toEvent(row), nil
+}
+
+func (sq *SensorQuality) CreateDeviceHealthEvent(ctx context.Context, sensorType string, deviceID *string, kind, message string) (internal.DeviceHealthEvent, error) {
+	row, err := sq.q.CreateDeviceHealthEvent(ctx, db.CreateDeviceHealthEventParams{
+		SensorType: sensorType,
+		DeviceID:   ptrText(deviceID),
+		Kind:       kind,
+		Message:    message,
+	})
+	if err != nil {
+		return internal.DeviceHealthEvent{}, err
+	}
+
+	return sq.toEvent(row), nil
+}
+
+func (sq *SensorQuality) ResolveDeviceHealthEvent(ctx context.Context, id int64) error {
+	return sq.q.ResolveDeviceHealthEvent(ctx, id)
+}
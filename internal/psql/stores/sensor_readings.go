@@ -2,30 +2,100 @@ package stores
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"strconv"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/lulzshadowwalker/green-backend/internal"
 	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
 )
 
+const (
+	// exportCursorName is the server-side cursor used to stream exports
+	exportCursorName = "sensor_readings_export"
+	// exportFetchSize is the number of rows fetched from the cursor per round trip
+	exportFetchSize = 1000
+)
+
+// declareExportCursor is kept out of sqlc because utility statements such as
+// DECLARE cannot have their parameters inferred by the generator.
+const declareExportCursor = `DECLARE ` + exportCursorName + ` NO SCROLL CURSOR FOR
+SELECT id, sensor_type, value, timestamp, raw_value, calibration_id, quality, quality_reason, device_id FROM sensor_readings
+WHERE timestamp >= $1
+  AND timestamp <= $2
+  AND (cardinality($3::text[]) = 0 OR sensor_type = ANY($3::text[]))
+ORDER BY timestamp ASC, id ASC`
+
+// Bulk imports are staged in a temporary table so that duplicates can be
+// filtered against existing rows in a single statement. Like the export
+// cursor, these reference a table sqlc cannot see and so live here.
+const (
+	createImportTable = `CREATE TEMPORARY TABLE sensor_readings_import (
+  sensor_type    TEXT             NOT NULL,
+  value          DOUBLE PRECISION NOT NULL,
+  timestamp      TIMESTAMPTZ      NOT NULL,
+  raw_value      DOUBLE PRECISION NOT NULL,
+  calibration_id BIGINT,
+  quality        VARCHAR(16)      NOT NULL,
+  quality_reason TEXT             NOT NULL
+) ON COMMIT DROP`
+
+	insertImportedReadings = `INSERT INTO sensor_readings (sensor_type, value, raw_value, timestamp, calibration_id, quality, quality_reason)
+SELECT DISTINCT ON (i.sensor_type, i.timestamp) i.sensor_type, i.value, i.raw_value, i.timestamp, i.calibration_id, i.quality, i.quality_reason
+FROM sensor_readings_import i
+WHERE NOT EXISTS (
+  SELECT 1 FROM sensor_readings r
+  WHERE r.sensor_type = i.sensor_type
+    AND r.timestamp = i.timestamp
+)
+ORDER BY i.sensor_type, i.timestamp`
+
+	// countNewImportedReadings is what insertImportedReadings would insert,
+	// for dry runs, which only read sensor_readings
+	countNewImportedReadings = `SELECT count(*) FROM (
+  SELECT DISTINCT i.sensor_type, i.timestamp
+  FROM sensor_readings_import i
+  WHERE NOT EXISTS (
+    SELECT 1 FROM sensor_readings r
+    WHERE r.sensor_type = i.sensor_type
+      AND r.timestamp = i.timestamp
+  )
+) n`
+)
+
 type SensorReadings struct {
-	q *db.Queries
+	q    *db.Queries
+	pool *pgxpool.Pool
 }
 
-func NewSensorReadings(q *db.Queries) *SensorReadings {
+func NewSensorReadings(pool *pgxpool.Pool) *SensorReadings {
 	return &SensorReadings{
-		q: q,
+		q:    db.New(pool),
+		pool: pool,
 	}
 }
 
 func (sr *SensorReadings) toEntity(r db.SensorReading) internal.SensorReading {
+	var calibrationID *int64
+	if r.CalibrationID.Valid {
+		id := r.CalibrationID.Int64
+		calibrationID = &id
+	}
 	return internal.SensorReading{
-		ID:         strconv.Itoa(int(r.ID)),
-		SensorType: r.SensorType,
-		Value:      r.Value,
-		Timestamp:  r.Timestamp.Time,
+		ID:            strconv.Itoa(int(r.ID)),
+		SensorType:    r.SensorType,
+		Value:         r.Value,
+		Timestamp:     r.Timestamp.Time,
+		RawValue:      r.RawValue,
+		CalibrationID: calibrationID,
+		Quality:       r.Quality,
+		QualityReason: r.QualityReason,
+		DeviceID:      textPtr(r.DeviceID),
 	}
 }
 
@@ -43,23 +113,11 @@ func (sr *SensorReadings) GetSensorReadings(ctx context.Context) ([]internal.Sen
 	return res, nil
 }
 
-// GetSensorReadingsSince returns all sensor readings since the given time.
+// GetSensorReadingsSince returns every sensor reading since the given time,
+// oldest first, leaving out readings flagged as bad. Nothing is capped so
+// that summaries cover the whole period however often sensors report.
 func (sr *SensorReadings) GetSensorReadingsSince(ctx context.Context, sinceTime time.Time) ([]internal.SensorReading, error) {
-	// Use GetSensorReadingsByTime with sinceTime and now
-	now := time.Now().UTC()
-	limit := int32(1000) // Arbitrary large limit; adjust as needed
-	offset := int32(0)
-	rows, err := sr.q.GetSensorReadingsByTime(ctx, struct {
-		Timestamp   pgtype.Timestamptz
-		Timestamp_2 pgtype.Timestamptz
-		Limit       int32
-		Offset      int32
-	}{
-		Timestamp:   pgtype.Timestamptz{Time: sinceTime, Valid: true},
-		Timestamp_2: pgtype.Timestamptz{Time: now, Valid: true},
-		Limit:       limit,
-		Offset:      offset,
-	})
+	rows, err := sr.q.GetUsableSensorReadingsSince(ctx, pgtype.Timestamptz{Time: sinceTime, Valid: true})
 	if err != nil {
 		return nil, err
 	}
@@ -73,15 +131,351 @@ func (sr *SensorReadings) GetSensorReadingsSince(ctx context.Context, sinceTime
 }
 
 func (sr *SensorReadings) CreateSensorReading(ctx context.Context, params internal.CreateSensorReadingParams) (internal.SensorReading, error) {
+	var calibrationID pgtype.Int8
+	if params.CalibrationID != nil {
+		calibrationID.Valid = true
+		calibrationID.Int64 = *params.CalibrationID
+	}
+	quality := params.Quality
+	if quality == "" {
+		quality = internal.QualityGood
+	}
 	arg := db.CreateSensorReadingParams{
-		SensorType: params.SensorType,
-		Value:      params.Value,
+		SensorType:    params.SensorType,
+		Value:         params.Value,
+		RawValue:      params.RawValue,
+		CalibrationID: calibrationID,
+		Quality:       quality,
+		QualityReason: params.QualityReason,
+		DeviceID:      ptrText(params.DeviceID),
+	}
+
+	// The reading and its webhook event are committed together so that
+	// subscribers never miss a reading nor hear about one that was rolled back
+	var reading internal.SensorReading
+	err := pgx.BeginFunc(ctx, sr.pool, func(tx pgx.Tx) error {
+		q := sr.q.WithTx(tx)
+		row, err := q.CreateSensorReading(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		reading = sr.toEntity(row)
+		return enqueueWebhookEvent(ctx, q, internal.EventReadingCreated, reading)
+	})
+	if err != nil {
+		return internal.SensorReading{}, err
+	}
+
+	return reading, nil
+}
+
+// StreamSensorReadings walks every reading matching params in timestamp order
+// through a server-side cursor, calling fn for each one so that large exports
+// never have to be held in memory.
+func (sr *SensorReadings) StreamSensorReadings(ctx context.Context, params internal.ExportSensorReadingsParams, fn func(internal.SensorReading) error) error {
+	tx, err := sr.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin export transaction because %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	sensorTypes := params.SensorTypes
+	if sensorTypes == nil {
+		sensorTypes = []string{}
+	}
+
+	_, err = tx.Exec(ctx, declareExportCursor,
+		pgtype.Timestamptz{Time: params.From, Valid: true},
+		pgtype.Timestamptz{Time: params.To, Valid: true},
+		sensorTypes,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare export cursor because %w", err)
+	}
+
+	for {
+		rows, err := tx.Query(ctx, fmt.Sprintf("FETCH FORWARD %d FROM %s", exportFetchSize, exportCursorName))
+		if err != nil {
+			return fmt.Errorf("failed to fetch from export cursor because %w", err)
+		}
+
+		batch, err := pgx.CollectRows(rows, pgx.RowToStructByPos[db.SensorReading])
+		if err != nil {
+			return fmt.Errorf("failed to scan exported readings because %w", err)
+		}
+
+		for _, r := range batch {
+			if err := fn(sr.toEntity(r)); err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < exportFetchSize {
+			return tx.Commit(ctx)
+		}
+	}
+}
+
+// ImportSensorReadings copies every reading produced by next into
+// sensor_readings, skipping any that duplicate an existing reading of the same
+// sensor at the same instant. next returns io.EOF when exhausted. When dryRun
+// is set readings only go as far as the temporary staging table, and the
+// readings that would be inserted are counted instead, so sensor_readings is
+// neither locked nor written and no ids are used up.
+func (sr *SensorReadings) ImportSensorReadings(ctx context.Context, next func() (internal.ImportSensorReadingParams, error), dryRun bool) (copied int, inserted int, err error) {
+	tx, err := sr.pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin import transaction because %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, createImportTable); err != nil {
+		return 0, 0, fmt.Errorf("failed to create import table because %w", err)
+	}
+
+	n, err := tx.CopyFrom(ctx,
+		pgx.Identifier{"sensor_readings_import"},
+		[]string{"sensor_type", "value", "timestamp", "raw_value", "calibration_id", "quality", "quality_reason"},
+		pgx.CopyFromFunc(func() ([]any, error) {
+			p, err := next()
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			quality := p.Quality
+			if quality == "" {
+				quality = internal.QualityGood
+			}
+			return []any{p.SensorType, p.Value, p.Timestamp, p.RawValue, p.CalibrationID, quality, p.QualityReason}, nil
+		}),
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to copy imported readings because %w", err)
+	}
+
+	if dryRun {
+		var inserted int
+		if err := tx.QueryRow(ctx, countNewImportedReadings).Scan(&inserted); err != nil {
+			return int(n), 0, fmt.Errorf("failed to count new imported readings because %w", err)
+		}
+		return int(n), inserted, nil
+	}
+
+	tag, err := tx.Exec(ctx, insertImportedReadings)
+	if err != nil {
+		return int(n), 0, fmt.Errorf("failed to insert imported readings because %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return int(n), 0, fmt.Errorf("failed to commit imported readings because %w", err)
+	}
+
+	return int(n), int(tag.RowsAffected()), nil
+}
+
+// GetLatestUsableSensorReading returns the most recent reading of sensorType
+// that was not flagged as bad
+func (sr *SensorReadings) GetLatestUsableSensorReading(ctx context.Context, sensorType string) (internal.SensorReading, error) {
+	row, err := sr.q.GetLatestUsableSensorReadingByType(ctx, sensorType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.SensorReading{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.SensorReading{}, err
+	}
+
+	return sr.toEntity(row), nil
+}
+
+// GetLatestUsableDeviceReading returns the most recent reading of sensorType
+// from deviceID that was not flagged as bad. A nil deviceID matches readings
+// from no device.
+func (sr *SensorReadings) GetLatestUsableDeviceReading(ctx context.Context, sensorType string, deviceID *string) (internal.SensorReading, error) {
+	row, err := sr.q.GetLatestUsableSensorReadingByDevice(ctx, db.GetLatestUsableSensorReadingByDeviceParams{
+		SensorType: sensorType,
+		DeviceID:   ptrText(deviceID),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.SensorReading{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.SensorReading{}, err
+	}
+
+	return sr.toEntity(row), nil
+}
+
+// GetSensorReadingStatsSince summarizes the usable readings of sensorType
+// taken at or after since, separately for each device
+func (sr *SensorReadings) GetSensorReadingStatsSince(ctx context.Context, sensorType string, since time.Time) ([]internal.SensorReadingStats, error) {
+	rows, err := sr.q.GetSensorReadingStatsByDeviceSince(ctx, db.GetSensorReadingStatsByDeviceSinceParams{
+		SensorType: sensorType,
+		Timestamp:  pgtype.Timestamptz{Time: since, Valid: true},
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	row, err := sr.q.CreateSensorReading(ctx, arg)
+	res := make([]internal.SensorReadingStats, len(rows))
+	for i, row := range rows {
+		res[i] = internal.SensorReadingStats{
+			DeviceID: textPtr(row.DeviceID),
+			Count:    row.ReadingCount,
+			Min:      row.MinValue,
+			Max:      row.MaxValue,
+			First:    row.FirstTimestamp.Time,
+		}
+	}
+
+	return res, nil
+}
+
+func (sr *SensorReadings) GetLatestSensorReadings(ctx context.Context) ([]internal.SensorReading, error) {
+	rows, err := sr.q.GetLatestSensorReadings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.SensorReading, len(rows))
+	for i, rr := range rows {
+		res[i] = sr.toEntity(rr)
+	}
+
+	return res, nil
+}
+
+// GetLatestUsableZoneReading returns the most recent reading of sensorType
+// from a device in zone that was not flagged as bad
+func (sr *SensorReadings) GetLatestUsableZoneReading(ctx context.Context, sensorType, zone string) (internal.SensorReading, error) {
+	row, err := sr.q.GetLatestUsableSensorReadingInZone(ctx, db.GetLatestUsableSensorReadingInZoneParams{
+		SensorType: sensorType,
+		Zone:       pgtype.Text{String: zone, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.SensorReading{}, internal.ErrNotFound
+	}
 	if err != nil {
 		return internal.SensorReading{}, err
 	}
 
 	return sr.toEntity(row), nil
 }
+
+// GetZoneReadingsBetween returns readings of sensorType from devices in zone
+// in [from, to), oldest first, leaving out bad ones
+func (sr *SensorReadings) GetZoneReadingsBetween(ctx context.Context, sensorType, zone string, from, to time.Time) ([]internal.SensorReading, error) {
+	rows, err := sr.q.GetSensorReadingsInZoneByTypeAndTime(ctx, db.GetSensorReadingsInZoneByTypeAndTimeParams{
+		SensorType: sensorType,
+		Zone:       pgtype.Text{String: zone, Valid: true},
+		FromTime:   pgtype.Timestamptz{Time: from, Valid: true},
+		ToTime:     pgtype.Timestamptz{Time: to, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.SensorReading, len(rows))
+	for i, rr := range rows {
+		res[i] = sr.toEntity(rr)
+	}
+
+	return res, nil
+}
+
+// GetSensorReadingsBetween returns readings of the given types in [from, to),
+// oldest first.
+func (sr *SensorReadings) GetSensorReadingsBetween(ctx context.Context, sensorTypes []string, from, to time.Time) ([]internal.SensorReading, error) {
+	rows, err := sr.q.GetSensorReadingsByTypesAndTime(ctx, db.GetSensorReadingsByTypesAndTimeParams{
+		SensorTypes: sensorTypes,
+		FromTime:    pgtype.Timestamptz{Time: from, Valid: true},
+		ToTime:      pgtype.Timestamptz{Time: to, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.SensorReading, len(rows))
+	for i, rr := range rows {
+		res[i] = sr.toEntity(rr)
+	}
+
+	return res, nil
+}
+
+func (sr *SensorReadings) AggregateSensorReadings(ctx context.Context, params internal.AggregateSensorReadingsParams) ([]internal.SensorAggregate, error) {
+	rows, err := sr.q.AggregateSensorReadings(ctx, db.AggregateSensorReadingsParams{
+		Bucket:      pgtype.Interval{Microseconds: params.Bucket.Microseconds(), Valid: true},
+		FromTime:    pgtype.Timestamptz{Time: params.From, Valid: true},
+		SensorTypes: params.SensorTypes,
+		ToTime:      pgtype.Timestamptz{Time: params.To, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.SensorAggregate, len(rows))
+	for i, row := range rows {
+		res[i] = internal.SensorAggregate{
+			SensorType: row.SensorType,
+			Bucket:     row.BucketStart.Time,
+			Min:        row.MinValue,
+			Max:        row.MaxValue,
+			Avg:        row.AvgValue,
+			Count:      row.ReadingCount,
+		}
+	}
+
+	return res, nil
+}
+
+// GetSensorReadingsForRecalibration pages through readings of sensorType in
+// the order they were taken, starting after the one taken at after with id
+// afterID. An afterID of 0 starts at after itself.
+func (sr *SensorReadings) GetSensorReadingsForRecalibration(ctx context.Context, sensorType string, after time.Time, afterID int64, limit int) ([]internal.SensorReading, error) {
+	rows, err := sr.q.GetSensorReadingsForRecalibration(ctx, db.GetSensorReadingsForRecalibrationParams{
+		SensorType: sensorType,
+		AfterTime:  pgtype.Timestamptz{Time: after, Valid: true},
+		AfterID:    afterID,
+		RowLimit:   int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.SensorReading, len(rows))
+	for i, rr := range rows {
+		res[i] = sr.toEntity(rr)
+	}
+
+	return res, nil
+}
+
+// UpdateCalibratedValues stores recalibrated values and their new quality.
+// Readings without a calibration ID fall back to their raw value.
+func (sr *SensorReadings) UpdateCalibratedValues(ctx context.Context, readings []internal.SensorReading) error {
+	arg := db.UpdateSensorReadingCalibratedValuesParams{
+		Ids:              make([]int64, len(readings)),
+		CalibratedValues: make([]float64, len(readings)),
+		CalibrationIds:   make([]int64, len(readings)),
+		Qualities:        make([]string, len(readings)),
+		QualityReasons:   make([]string, len(readings)),
+	}
+	for i, r := range readings {
+		id, err := strconv.ParseInt(r.ID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid reading id %q because %w", r.ID, err)
+		}
+		arg.Ids[i] = id
+		arg.CalibratedValues[i] = r.Value
+		arg.Qualities[i] = r.Quality
+		arg.QualityReasons[i] = r.QualityReason
+		if r.CalibrationID != nil {
+			arg.CalibrationIds[i] = *r.CalibrationID
+		}
+	}
+
+	return sr.q.UpdateSensorReadingCalibratedValues(ctx, arg)
+}
@@ -0,0 +1,58 @@
+package stores
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/advisor"
+)
+
+func TestGetSensorReadingsSinceCoversTheWholeWindow(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	// An hour of heat five hours ago, then 1500 mild readings, so that the
+	// heat is only seen if the oldest rows are read
+	_, err := pool.Exec(ctx, `INSERT INTO sensor_readings (sensor_type, value, raw_value, timestamp)
+SELECT 'temperature', 40, 40, $1::timestamptz + n * interval '6 seconds'
+FROM generate_series(0, 599) AS n`, now.Add(-5*time.Hour))
+	if err != nil {
+		t.Fatalf("failed to seed old readings: %v", err)
+	}
+	_, err = pool.Exec(ctx, `INSERT INTO sensor_readings (sensor_type, value, raw_value, timestamp)
+SELECT 'temperature', 20, 20, $1::timestamptz + n * interval '2 seconds'
+FROM generate_series(0, 1499) AS n`, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("failed to seed recent readings: %v", err)
+	}
+
+	readings, err := NewSensorReadings(pool).GetSensorReadingsSince(ctx, now.Add(-6*time.Hour))
+	if err != nil {
+		t.Fatalf("GetSensorReadingsSince: %v", err)
+	}
+	if len(readings) != 2100 {
+		t.Fatalf("got %d readings, want 2100", len(readings))
+	}
+
+	summaries := advisor.Summarize(advisor.Input{
+		Profile:  internal.GenericPlantProfile,
+		Readings: readings,
+		Now:      now,
+	})
+	if len(summaries) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(summaries))
+	}
+	s := summaries[0]
+	if s.Max != 40 {
+		t.Errorf("max is %v, want the oldest readings' 40", s.Max)
+	}
+	if got := now.Sub(s.From); got < 5*time.Hour-time.Minute {
+		t.Errorf("summary starts %v ago, want about 5h", got)
+	}
+	if s.Latest != 20 {
+		t.Errorf("latest is %v, want 20", s.Latest)
+	}
+}
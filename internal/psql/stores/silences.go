@@ -0,0 +1,223 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+type Silences struct {
+	q *db.Queries
+}
+
+func NewSilences(q *db.Queries) *Silences {
+	return &Silences{q: q}
+}
+
+func toMatcher(sensorType, zone pgtype.Text, ruleID pgtype.Int8) internal.SilenceMatcher {
+	m := internal.SilenceMatcher{
+		SensorType: textPtr(sensorType),
+		Zone:       textPtr(zone),
+	}
+	if ruleID.Valid {
+		id := ruleID.Int64
+		m.RuleID = &id
+	}
+	return m
+}
+
+func ptrInt8(v *int64) pgtype.Int8 {
+	if v == nil {
+		return pgtype.Int8{}
+	}
+	return pgtype.Int8{Int64: *v, Valid: true}
+}
+
+func (s *Silences) toSilence(r db.AlertSilence) internal.AlertSilence {
+	return internal.AlertSilence{
+		ID:             r.ID,
+		SilenceMatcher: toMatcher(r.SensorType, r.Zone, r.RuleID),
+		StartsAt:       r.StartsAt.Time,
+		EndsAt:         r.EndsAt.Time,
+		Reason:         r.Reason,
+		CreatedBy:      r.CreatedBy,
+		CreatedAt:      r.CreatedAt.Time,
+	}
+}
+
+func (s *Silences) toWindow(r db.MaintenanceWindow) internal.MaintenanceWindow {
+	weekdays := make([]int, len(r.Weekdays))
+	for i, d := range r.Weekdays {
+		weekdays[i] = int(d)
+	}
+	return internal.MaintenanceWindow{
+		ID:             r.ID,
+		Name:           r.Name,
+		SilenceMatcher: toMatcher(r.SensorType, r.Zone, r.RuleID),
+		Weekdays:       weekdays,
+		StartTime:      r.StartTime,
+		EndTime:        r.EndTime,
+		Timezone:       r.Timezone,
+		Reason:         r.Reason,
+		Enabled:        r.Enabled,
+		CreatedAt:      r.CreatedAt.Time,
+		UpdatedAt:      r.UpdatedAt.Time,
+	}
+}
+
+func (s *Silences) toSilences(rows []db.AlertSilence) []internal.AlertSilence {
+	res := make([]internal.AlertSilence, len(rows))
+	for i, row := range rows {
+		res[i] = s.toSilence(row)
+	}
+	return res
+}
+
+func (s *Silences) toWindows(rows []db.MaintenanceWindow) []internal.MaintenanceWindow {
+	res := make([]internal.MaintenanceWindow, len(rows))
+	for i, row := range rows {
+		res[i] = s.toWindow(row)
+	}
+	return res
+}
+
+// ListAlertSilences returns the most recent silences, or only those that have
+// not yet ended when activeOnly is set
+func (s *Silences) ListAlertSilences(ctx context.Context, activeOnly bool, limit int) ([]internal.AlertSilence, error) {
+	rows, err := s.q.ListAlertSilences(ctx, db.ListAlertSilencesParams{
+		ActiveOnly: activeOnly,
+		Limit:      int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toSilences(rows), nil
+}
+
+func (s *Silences) ListActiveAlertSilences(ctx context.Context, at time.Time) ([]internal.AlertSilence, error) {
+	rows, err := s.q.ListActiveAlertSilences(ctx, pgtype.Timestamptz{Time: at, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toSilences(rows), nil
+}
+
+func (s *Silences) CreateAlertSilence(ctx context.Context, params internal.AlertSilenceParams) (internal.AlertSilence, error) {
+	row, err := s.q.CreateAlertSilence(ctx, db.CreateAlertSilenceParams{
+		SensorType: ptrText(params.SensorType),
+		Zone:       ptrText(params.Zone),
+		RuleID:     ptrInt8(params.RuleID),
+		StartsAt:   pgtype.Timestamptz{Time: params.StartsAt, Valid: true},
+		EndsAt:     pgtype.Timestamptz{Time: params.EndsAt, Valid: true},
+		Reason:     params.Reason,
+		CreatedBy:  params.CreatedBy,
+	})
+	if err != nil {
+		return internal.AlertSilence{}, err
+	}
+
+	return s.toSilence(row), nil
+}
+
+// ExpireAlertSilence ends a silence early. Past silences are kept as a
+// record of when alerts were muted.
+func (s *Silences) ExpireAlertSilence(ctx context.Context, id int64) (internal.AlertSilence, error) {
+	row, err := s.q.ExpireAlertSilence(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.AlertSilence{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.AlertSilence{}, err
+	}
+
+	return s.toSilence(row), nil
+}
+
+func (s *Silences) ListMaintenanceWindows(ctx context.Context) ([]internal.MaintenanceWindow, error) {
+	rows, err := s.q.ListMaintenanceWindows(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toWindows(rows), nil
+}
+
+func (s *Silences) ListEnabledMaintenanceWindows(ctx context.Context) ([]internal.MaintenanceWindow, error) {
+	rows, err := s.q.ListEnabledMaintenanceWindows(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toWindows(rows), nil
+}
+
+func weekdaysParam(days []int) []int32 {
+	res := make([]int32, len(days))
+	for i, d := range days {
+		res[i] = int32(d)
+	}
+	return res
+}
+
+func (s *Silences) CreateMaintenanceWindow(ctx context.Context, params internal.MaintenanceWindowParams) (internal.MaintenanceWindow, error) {
+	row, err := s.q.CreateMaintenanceWindow(ctx, db.CreateMaintenanceWindowParams{
+		Name:       params.Name,
+		SensorType: ptrText(params.SensorType),
+		Zone:       ptrText(params.Zone),
+		RuleID:     ptrInt8(params.RuleID),
+		Weekdays:   weekdaysParam(params.Weekdays),
+		StartTime:  params.StartTime,
+		EndTime:    params.EndTime,
+		Timezone:   params.Timezone,
+		Reason:     params.Reason,
+		Enabled:    params.Enabled,
+	})
+	if err != nil {
+		return internal.MaintenanceWindow{}, err
+	}
+
+	return s.toWindow(row), nil
+}
+
+func (s *Silences) UpdateMaintenanceWindow(ctx context.Context, id int64, params internal.MaintenanceWindowParams) (internal.MaintenanceWindow, error) {
+	row, err := s.q.UpdateMaintenanceWindow(ctx, db.UpdateMaintenanceWindowParams{
+		ID:         id,
+		Name:       params.Name,
+		SensorType: ptrText(params.SensorType),
+		Zone:       ptrText(params.Zone),
+		RuleID:     ptrInt8(params.RuleID),
+		Weekdays:   weekdaysParam(params.Weekdays),
+		StartTime:  params.StartTime,
+		EndTime:    params.EndTime,
+		Timezone:   params.Timezone,
+		Reason:     params.Reason,
+		Enabled:    params.Enabled,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.MaintenanceWindow{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.MaintenanceWindow{}, err
+	}
+
+	return s.toWindow(row), nil
+}
+
+func (s *Silences) DeleteMaintenanceWindow(ctx context.Context, id int64) error {
+	n, err := s.q.DeleteMaintenanceWindow(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return internal.ErrNotFound
+	}
+
+	return nil
+}
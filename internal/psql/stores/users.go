@@ -2,7 +2,9 @@ package stores
 
 import (
 	"context"
+	"errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/lulzshadowwalker/green-backend/internal"
 	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
 )
@@ -26,3 +28,44 @@ func (u *Users) GetUserByUsername(ctx context.Context, username string) (interna
 		PasswordHash: user.PasswordHash,
 	}, nil
 }
+
+// GetUserRole returns whether the user is a "user" or an "admin"
+func (u *Users) GetUserRole(ctx context.Context, id int) (string, error) {
+	role, err := u.q.GetUserRole(ctx, int32(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", internal.ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (u *Users) GetUserLocale(ctx context.Context, id int) (internal.Locale, error) {
+	row, err := u.q.GetUserLocale(ctx, int32(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.Locale{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.Locale{}, err
+	}
+
+	return internal.Locale{Language: row.Language, TemperatureUnit: row.TemperatureUnit}, nil
+}
+
+func (u *Users) UpdateUserLocale(ctx context.Context, id int, locale internal.Locale) (internal.Locale, error) {
+	row, err := u.q.UpdateUserLocale(ctx, db.UpdateUserLocaleParams{
+		ID:              int32(id),
+		Language:        locale.Language,
+		TemperatureUnit: locale.TemperatureUnit,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.Locale{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.Locale{}, err
+	}
+
+	return internal.Locale{Language: row.Language, TemperatureUnit: row.TemperatureUnit}, nil
+}
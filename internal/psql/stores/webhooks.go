@@ -0,0 +1,283 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/psql/db"
+)
+
+// enqueueWebhookEvent writes an event to the webhook outbox. Callers pass
+// queries bound to the transaction making the change the event describes.
+// Nothing is written when no subscription wants the event.
+func enqueueWebhookEvent(ctx context.Context, q *db.Queries, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event because %w", eventType, err)
+	}
+
+	if err := q.EnqueueWebhookEvent(ctx, db.EnqueueWebhookEventParams{
+		EventType: eventType,
+		Payload:   payload,
+	}); err != nil {
+		return fmt.Errorf("failed to enqueue %s event because %w", eventType, err)
+	}
+
+	return nil
+}
+
+func int4Ptr(v pgtype.Int4) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int32)
+	return &i
+}
+
+func ptrInt4(v *int) pgtype.Int4 {
+	if v == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: int32(*v), Valid: true}
+}
+
+type Webhooks struct {
+	q *db.Queries
+}
+
+func NewWebhooks(q *db.Queries) *Webhooks {
+	return &Webhooks{q: q}
+}
+
+func (w *Webhooks) toSubscription(s db.WebhookSubscription) internal.WebhookSubscription {
+	return internal.WebhookSubscription{
+		ID:          s.ID,
+		URL:         s.Url,
+		EventTypes:  s.EventTypes,
+		Description: s.Description,
+		Enabled:     s.Enabled,
+		CreatedAt:   s.CreatedAt.Time,
+		UpdatedAt:   s.UpdatedAt.Time,
+	}
+}
+
+func (w *Webhooks) ListWebhookSubscriptions(ctx context.Context) ([]internal.WebhookSubscription, error) {
+	rows, err := w.q.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.WebhookSubscription, len(rows))
+	for i, row := range rows {
+		res[i] = w.toSubscription(row)
+	}
+
+	return res, nil
+}
+
+func (w *Webhooks) GetWebhookSubscription(ctx context.Context, id int64) (internal.WebhookSubscription, error) {
+	row, err := w.q.GetWebhookSubscription(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.WebhookSubscription{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.WebhookSubscription{}, err
+	}
+
+	return w.toSubscription(row), nil
+}
+
+// CreateWebhookSubscription stores a subscription along with its signing
+// secret, which is returned this once
+func (w *Webhooks) CreateWebhookSubscription(ctx context.Context, params internal.WebhookSubscriptionParams, secret string) (internal.WebhookSubscription, error) {
+	row, err := w.q.CreateWebhookSubscription(ctx, db.CreateWebhookSubscriptionParams{
+		Url:         params.URL,
+		EventTypes:  params.EventTypes,
+		Secret:      secret,
+		Description: params.Description,
+		Enabled:     params.Enabled,
+	})
+	if err != nil {
+		return internal.WebhookSubscription{}, err
+	}
+
+	sub := w.toSubscription(row)
+	sub.Secret = row.Secret
+
+	return sub, nil
+}
+
+func (w *Webhooks) UpdateWebhookSubscription(ctx context.Context, id int64, params internal.WebhookSubscriptionParams) (internal.WebhookSubscription, error) {
+	row, err := w.q.UpdateWebhookSubscription(ctx, db.UpdateWebhookSubscriptionParams{
+		ID:          id,
+		Url:         params.URL,
+		EventTypes:  params.EventTypes,
+		Description: params.Description,
+		Enabled:     params.Enabled,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return internal.WebhookSubscription{}, internal.ErrNotFound
+	}
+	if err != nil {
+		return internal.WebhookSubscription{}, err
+	}
+
+	return w.toSubscription(row), nil
+}
+
+func (w *Webhooks) DeleteWebhookSubscription(ctx context.Context, id int64) error {
+	n, err := w.q.DeleteWebhookSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return internal.ErrNotFound
+	}
+
+	return nil
+}
+
+func (w *Webhooks) ListDueWebhookDeliveries(ctx context.Context, limit int) ([]internal.DueWebhookDelivery, error) {
+	rows, err := w.q.ListDueWebhookDeliveries(ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.DueWebhookDelivery, len(rows))
+	for i, row := range rows {
+		res[i] = internal.DueWebhookDelivery{
+			ID:             row.ID,
+			SubscriptionID: row.SubscriptionID,
+			Attempts:       int(row.Attempts),
+			EventID:        row.EventID,
+			EventType:      row.EventType,
+			Payload:        row.Payload,
+			EventCreatedAt: row.EventCreatedAt.Time,
+			URL:            row.Url,
+			Secret:         row.Secret,
+		}
+	}
+
+	return res, nil
+}
+
+// RecordWebhookAttempt logs an attempt and moves the delivery to the state the
+// attempt left it in
+func (w *Webhooks) RecordWebhookAttempt(ctx context.Context, deliveryID int64, result internal.WebhookAttemptResult) error {
+	if err := w.q.CreateWebhookDeliveryAttempt(ctx, db.CreateWebhookDeliveryAttemptParams{
+		DeliveryID: deliveryID,
+		StatusCode: ptrInt4(result.StatusCode),
+		Error:      result.Error,
+		DurationMs: int32(result.Duration.Milliseconds()),
+	}); err != nil {
+		return err
+	}
+
+	if result.Status == internal.DeliveryDelivered {
+		return w.q.MarkWebhookDeliveryDelivered(ctx, db.MarkWebhookDeliveryDeliveredParams{
+			ID:             deliveryID,
+			LastStatusCode: ptrInt4(result.StatusCode),
+		})
+	}
+
+	next := result.NextAttemptAt
+	if next.IsZero() {
+		next = time.Now()
+	}
+	return w.q.MarkWebhookDeliveryFailed(ctx, db.MarkWebhookDeliveryFailedParams{
+		ID:             deliveryID,
+		Status:         result.Status,
+		NextAttemptAt:  pgtype.Timestamptz{Time: next, Valid: true},
+		LastError:      result.Error,
+		LastStatusCode: ptrInt4(result.StatusCode),
+	})
+}
+
+// ListWebhookDeliveries returns a subscription's most recent deliveries,
+// optionally only those in status
+func (w *Webhooks) ListWebhookDeliveries(ctx context.Context, subscriptionID int64, status string, limit int) ([]internal.WebhookDelivery, error) {
+	rows, err := w.q.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		SubscriptionID: subscriptionID,
+		Status:         pgtype.Text{String: status, Valid: status != ""},
+		Limit:          int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.WebhookDelivery, len(rows))
+	for i, row := range rows {
+		var deliveredAt *time.Time
+		if row.DeliveredAt.Valid {
+			t := row.DeliveredAt.Time
+			deliveredAt = &t
+		}
+		res[i] = internal.WebhookDelivery{
+			ID:             row.ID,
+			SubscriptionID: row.SubscriptionID,
+			EventID:        row.EventID,
+			EventType:      row.EventType,
+			Status:         row.Status,
+			Attempts:       int(row.Attempts),
+			NextAttemptAt:  row.NextAttemptAt.Time,
+			LastError:      row.LastError,
+			LastStatusCode: int4Ptr(row.LastStatusCode),
+			CreatedAt:      row.CreatedAt.Time,
+			DeliveredAt:    deliveredAt,
+		}
+	}
+
+	return res, nil
+}
+
+func (w *Webhooks) ListWebhookDeliveryAttempts(ctx context.Context, subscriptionID, deliveryID int64) ([]internal.WebhookDeliveryAttempt, error) {
+	rows, err := w.q.ListWebhookDeliveryAttempts(ctx, db.ListWebhookDeliveryAttemptsParams{
+		DeliveryID:     deliveryID,
+		SubscriptionID: subscriptionID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]internal.WebhookDeliveryAttempt, len(rows))
+	for i, row := range rows {
+		res[i] = internal.WebhookDeliveryAttempt{
+			ID:          row.ID,
+			AttemptedAt: row.AttemptedAt.Time,
+			StatusCode:  int4Ptr(row.StatusCode),
+			Error:       row.Error,
+			DurationMs:  int(row.DurationMs),
+		}
+	}
+
+	return res, nil
+}
+
+// RetryWebhookDelivery moves a dead delivery back to the queue with a fresh
+// set of attempts
+func (w *Webhooks) RetryWebhookDelivery(ctx context.Context, subscriptionID, deliveryID int64) error {
+	n, err := w.q.RetryWebhookDelivery(ctx, db.RetryWebhookDeliveryParams{
+		ID:             deliveryID,
+		SubscriptionID: subscriptionID,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return internal.ErrNotFound
+	}
+
+	return nil
+}
+
+// PruneWebhookOutbox deletes events older than before that have nothing left
+// to deliver, along with their delivery logs
+func (w *Webhooks) PruneWebhookOutbox(ctx context.Context, before time.Time) (int64, error) {
+	return w.q.PruneWebhookOutbox(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Reading quality flags. Bad readings are kept for diagnosis, and show up in
+// raw exports, but are excluded from listings, aggregates, alerts,
+// automation, derived sensors and LLM prompts; suspect readings are kept
+// everywhere but flagged.
+const (
+	QualityGood    = "good"
+	QualitySuspect = "suspect"
+	QualityBad     = "bad"
+)
+
+// Device health event kinds
+const (
+	// HealthFlatline is raised when a sensor barely moves for longer than is
+	// plausible
+	HealthFlatline = "flatline"
+	// HealthStuck is raised when a sensor repeats exactly the same value
+	HealthStuck = "stuck"
+)
+
+// maxSpikeGap is how old the previous reading may be for a rate-of-change
+// check to be meaningful
+const maxSpikeGap = time.Hour
+
+// SensorQualityRule holds the plausibility limits of a sensor type
+type SensorQualityRule struct {
+	SensorType string  `json:"sensor_type"`
+	Min        float64 `json:"min_value"`
+	Max        float64 `json:"max_value"`
+	// MaxChangePerMinute flags spikes; nil disables the check
+	MaxChangePerMinute *float64 `json:"max_change_per_minute,omitempty"`
+	// FlatlineMinutes is how long the sensor may hold within FlatlineTolerance
+	// before it is reported
+	FlatlineMinutes   int       `json:"flatline_minutes"`
+	FlatlineTolerance float64   `json:"flatline_tolerance"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Assess grades value taken at at, given the last usable reading of the same
+// sensor on the same device, which may be nil
+func (r SensorQualityRule) Assess(value float64, at time.Time, prev *SensorReading) (quality, reason string) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return QualityBad, "value is not a number"
+	}
+	if value < r.Min || value > r.Max {
+		return QualityBad, fmt.Sprintf("%g is outside the plausible range [%g, %g]", value, r.Min, r.Max)
+	}
+
+	if r.MaxChangePerMinute != nil && prev != nil {
+		gap := at.Sub(prev.Timestamp)
+		if gap > 0 && gap <= maxSpikeGap {
+			// Readings a few seconds apart would otherwise turn jitter into
+			// enormous rates
+			minutes := math.Max(gap.Minutes(), 1)
+			rate := math.Abs(value-prev.Value) / minutes
+			if rate > *r.MaxChangePerMinute {
+				return QualitySuspect, fmt.Sprintf("changed %.2f per minute, more than the limit of %g", rate, *r.MaxChangePerMinute)
+			}
+		}
+	}
+
+	return QualityGood, ""
+}
+
+// DeviceHealthEvent records a sensor that has stopped reporting plausible data
+type DeviceHealthEvent struct {
+	ID         int64      `json:"id"`
+	SensorType string     `json:"sensor_type"`
+	DeviceID   *string    `json:"device_id,omitempty"`
+	Kind       string     `json:"kind"`
+	Message    string     `json:"message"`
+	StartedAt  time.Time  `json:"started_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// SensorReadingStats summarizes the usable readings of a sensor on one device
+// over a window
+type SensorReadingStats struct {
+	// DeviceID is nil for readings from no device
+	DeviceID *string
+	Count    int64
+	Min      float64
+	Max      float64
+	First    time.Time
+}
@@ -0,0 +1,46 @@
+package internal
+
+import "strings"
+
+// SensorDefinition describes a sensor type known to the backend
+type SensorDefinition struct {
+	// Type is the canonical sensor_type stored in sensor_readings
+	Type string
+	// Aliases are alternative names used by devices, clients and spreadsheets
+	Aliases []string
+	// Unit is the unit readings of this type are stored in
+	Unit string
+	// Virtual sensors are computed from other readings and never stored
+	Virtual bool
+}
+
+// SensorCatalog lists every sensor type the backend knows about
+var SensorCatalog = []SensorDefinition{
+	{Type: "temperature", Aliases: []string{"temp"}, Unit: "°C"},
+	{Type: "humidity", Aliases: []string{"rh"}, Unit: "%"},
+	{Type: "light", Aliases: []string{"lightLevel"}, Unit: "lux"},
+	{Type: "water", Aliases: []string{"waterLevel"}, Unit: "%"},
+	{Type: "soil", Aliases: []string{"soilMoisture"}, Unit: "%"},
+	{Type: "vpd", Aliases: []string{"vaporPressureDeficit"}, Unit: "kPa", Virtual: true},
+	{Type: "dew_point", Aliases: []string{"dewPoint"}, Unit: "°C", Virtual: true},
+	{Type: "heat_index", Aliases: []string{"heatIndex"}, Unit: "°C", Virtual: true},
+	{Type: "dli", Aliases: []string{"dailyLightIntegral"}, Unit: "mol/m²/d", Virtual: true},
+}
+
+// LookupSensor resolves a canonical sensor type or one of its aliases
+// (case-insensitive) to its catalog definition
+func LookupSensor(name string) (SensorDefinition, bool) {
+	name = strings.TrimSpace(name)
+	for _, def := range SensorCatalog {
+		if strings.EqualFold(def.Type, name) {
+			return def, true
+		}
+		for _, alias := range def.Aliases {
+			if strings.EqualFold(alias, name) {
+				return def, true
+			}
+		}
+	}
+
+	return SensorDefinition{}, false
+}
@@ -3,15 +3,32 @@ package internal
 import "time"
 
 type SensorReading struct {
-	ID         string
-	SensorType string
-	Value      float64
-	Timestamp  time.Time
+	// ID is empty for virtual sensors, which are computed rather than stored
+	ID         string    `json:"id,omitempty"`
+	SensorType string    `json:"sensor_type"`
+	Value      float64   `json:"value"`
+	Timestamp  time.Time `json:"timestamp"`
+	// RawValue is the value as reported, before calibration
+	RawValue      float64 `json:"raw_value"`
+	CalibrationID *int64  `json:"calibration_id,omitempty"`
+	// Quality is QualityGood, QualitySuspect or QualityBad
+	Quality       string `json:"quality"`
+	QualityReason string `json:"quality_reason,omitempty"`
+	// DeviceID is the board that reported the reading, if it said
+	DeviceID *string `json:"device_id,omitempty"`
+	// Partial is set on virtual readings that are still accumulating, such
+	// as today's DLI
+	Partial bool `json:"partial,omitempty"`
 }
 
 type CreateSensorReadingParams struct {
-	SensorType string
-	Value      float64
+	SensorType    string
+	Value         float64
+	RawValue      float64
+	CalibrationID *int64
+	Quality       string
+	QualityReason string
+	DeviceID      *string
 }
 
 type SensorControl struct {
@@ -20,4 +37,63 @@ type SensorControl struct {
 	ManualUntil     *time.Time `json:"manual_until,omitempty"` // optional, for future timed manual mode
 	ManualBoolValue *bool      `json:"manual_bool_value,omitempty"` // optional, for boolean manual control
 	ManualIntValue  *int       `json:"manual_int_value,omitempty"`  // optional, for int manual control
+	// Suppressed is set on automatic controls while a silence or maintenance
+	// window covers them, telling devices to hold off automation
+	Suppressed bool `json:"suppressed,omitempty"`
+}
+
+type ExportSensorReadingsParams struct {
+	From        time.Time
+	To          time.Time
+	SensorTypes []string // empty means every sensor type
+}
+
+type ImportSensorReadingParams struct {
+	SensorType    string
+	Value         float64
+	Timestamp     time.Time
+	RawValue      float64
+	CalibrationID *int64
+	Quality       string
+	QualityReason string
+}
+
+// ImportRowError describes a source row that could not be imported
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportReport summarizes a bulk import, or what it would do on a dry run
+type ImportReport struct {
+	DryRun       bool             `json:"dry_run"`
+	RowsRead     int              `json:"rows_read"`
+	Readings     int              `json:"readings"`
+	Inserted     int              `json:"inserted"`
+	Duplicates   int              `json:"duplicates"`
+	InvalidRows  int              `json:"invalid_rows"`
+	SensorCounts map[string]int   `json:"sensor_counts"`
+	From         *time.Time       `json:"from,omitempty"`
+	To           *time.Time       `json:"to,omitempty"`
+	Errors       []ImportRowError `json:"errors"`
+	// ErrorsTruncated is set when more rows failed than are listed in Errors
+	ErrorsTruncated bool `json:"errors_truncated"`
+}
+
+// SensorAggregate summarizes the readings of one sensor within a time bucket
+type SensorAggregate struct {
+	SensorType string
+	Bucket     time.Time
+	Min        float64
+	Max        float64
+	Avg        float64
+	Count      int64
+}
+
+type AggregateSensorReadingsParams struct {
+	SensorTypes []string
+	From        time.Time
+	To          time.Time
+	Bucket      time.Duration
 }
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/advisor"
+)
+
+// DefaultAdviceCacheTTL is how long plant advice is reused for the same
+// plant and readings
+const DefaultAdviceCacheTTL = 10 * time.Minute
+
+// maxCachedAdvice bounds the cache; the oldest entry makes room
+const maxCachedAdvice = 256
+
+type cachedAdvice struct {
+	text    string
+	model   string
+	expires time.Time
+}
+
+// adviceCache keeps language model advice for a while, so that refreshing
+// the app does not pay for the same advice again. A nil cache caches
+// nothing.
+type adviceCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedAdvice
+}
+
+func newAdviceCache(ttl time.Duration) *adviceCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &adviceCache{ttl: ttl, entries: map[string]cachedAdvice{}}
+}
+
+func (c *adviceCache) get(key string) (cachedAdvice, bool) {
+	if c == nil {
+		return cachedAdvice{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	advice, ok := c.entries[key]
+	if !ok || time.Now().After(advice.expires) {
+		return cachedAdvice{}, false
+	}
+	return advice, true
+}
+
+func (c *adviceCache) put(key, text, model string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= maxCachedAdvice {
+		oldest := ""
+		for k, a := range c.entries {
+			if now.After(a.expires) {
+				delete(c.entries, k)
+			} else if oldest == "" || a.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		if len(c.entries) >= maxCachedAdvice {
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[key] = cachedAdvice{text: text, model: model, expires: now.Add(c.ttl)}
+}
+
+// adviceCacheKey identifies advice requests that deserve the same answer:
+// the same plant and locale, and summaries of the readings that would put
+// the same picture in the prompt. Statistics are compared to two
+// significant figures, so that small fluctuations still hit the cache, and
+// excursions by which side and how far they went and whether they are
+// still going on.
+func adviceCacheKey(plant string, locale internal.Locale, summaries []advisor.Summary) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(strings.TrimSpace(plant)))
+	b.WriteString("|" + locale.Language + "|" + locale.TemperatureUnit)
+	for _, s := range summaries {
+		b.WriteString("|")
+		b.WriteString(s.SensorType)
+		for _, v := range []float64{s.Latest, s.Min, s.Max, s.Mean, s.TrendPerHour} {
+			b.WriteString(",")
+			b.WriteString(formatBucket(v))
+		}
+		if s.Limit != nil {
+			b.WriteString(",[" + formatBucket(s.Limit.Min) + ":" + formatBucket(s.Limit.Max) + "]")
+		}
+		for _, e := range s.Excursions {
+			b.WriteString("," + e.Problem + formatBucket(e.Peak))
+			if e.Ongoing {
+				b.WriteString("!")
+			}
+		}
+	}
+	return b.String()
+}
+
+func formatBucket(v float64) string {
+	return strconv.FormatFloat(bucketReading(v), 'g', -1, 64)
+}
+
+func bucketReading(v float64) float64 {
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	scale := math.Pow(10, math.Floor(math.Log10(math.Abs(v)))-1)
+	return math.Round(v/scale) * scale
+}
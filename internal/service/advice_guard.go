@@ -0,0 +1,185 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// maxAdviceLength caps plant advice, in characters; the model is stopped and
+// offline advice given instead past it
+const maxAdviceLength = 3000
+
+// Temperatures, in °C, at which advice to cut ventilation or to heat, or to
+// turn heating off, can kill plants within hours
+const (
+	hotTemperature  = 35.0
+	coldTemperature = 5.0
+)
+
+// guardrailError is a reply the guard refused to pass on
+type guardrailError struct {
+	reason string
+}
+
+func (e *guardrailError) Error() string {
+	return "advice rejected: " + e.reason
+}
+
+var (
+	// Markdown and HTML the model is told not to use, and what each becomes
+	markdownLink   = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownMarks  = regexp.MustCompile("\\*\\*|__|```|`|~~")
+	markdownHeader = regexp.MustCompile(`(?m)^[ \t]*#{1,6}[ \t]+`)
+	markdownQuote  = regexp.MustCompile(`(?m)^[ \t]*>[ \t]?`)
+	markdownBullet = regexp.MustCompile(`(?m)^([ \t]*)[*+][ \t]+`)
+	htmlTag        = regexp.MustCompile(`</?[a-zA-Z][^<>]*>`)
+
+	// Advice that cuts cooling, adds heat or turns heating off, in English
+	// and Arabic. Only a few words may come between the verb and what it
+	// acts on, so that "turn off the lights and open the vents" is not
+	// mistaken for closing them.
+	coolingCut = actionPattern(`close|shut|turn off|switch off|disable|stop`, `vents?|ventilation|fans?|windows?|cooling|air ?flow|extractors?`,
+		`أغلق|اغلق|أوقف|اوقف|أطفئ|اطفئ|قل\x{0651}?ل`, `فتحات|التهوية|النوافذ|المراوح|المروحة|التبريد`)
+	heatingOn = actionPattern(`turn on|switch on|increase|raise|use|run`, `heating|heaters?`,
+		`شغ\x{0651}?ل|زِد|زد`, `التدفئة|المدفأة|السخان`)
+	heatingOff = actionPattern(`turn off|switch off|disable|stop`, `heating|heaters?`,
+		`أطفئ|اطفئ|أوقف|اوقف`, `التدفئة|المدفأة|السخان`)
+	negated = regexp.MustCompile(`(?i)\b(don't|do not|never|avoid|without)\b[^.!?\n]{0,20}$`)
+)
+
+// actionPattern matches an English or Arabic instruction to do one of verbs
+// to one of objects
+func actionPattern(verbs, objects, arabicVerbs, arabicObjects string) *regexp.Regexp {
+	filler := `(?:(?:the|all|your|any|some|of|greenhouse|side|roof|top|exhaust|cooling)\s+)*`
+	return regexp.MustCompile(`(?i)\b(?:` + verbs + `)\s+` + filler + `(?:` + objects + `)\b` +
+		`|(?:` + arabicVerbs + `)\s+(?:(?:جميع|كل)\s+)?(?:` + arabicObjects + `)`)
+}
+
+// adviceGuard checks language model advice before it reaches the client.
+// Text is held back until a sentence is complete; each sentence is made
+// plain text and checked against the hard safety limits for the latest
+// readings. A reply that breaks a limit or runs too long is stopped with a
+// guardrailError, so that offline advice can take over from there.
+type adviceGuard struct {
+	w       io.Writer
+	pending strings.Builder
+	// written is how many characters have reached the client
+	written int
+	// temperature is the latest reading, in °C, if there is one
+	temperature *float64
+}
+
+func newAdviceGuard(w io.Writer, readings []internal.SensorReading) *adviceGuard {
+	g := &adviceGuard{w: w}
+	for _, r := range latestReadings(readings) {
+		if r.SensorType == "temperature" {
+			g.temperature = &r.Value
+		}
+	}
+	return g
+}
+
+func (g *adviceGuard) Write(p []byte) (int, error) {
+	g.pending.Write(p)
+
+	text := g.pending.String()
+	end := lastSentenceEnd(text)
+	if end == 0 {
+		return len(p), nil
+	}
+
+	g.pending.Reset()
+	g.pending.WriteString(text[end:])
+	if err := g.pass(text[:end]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// flush checks and passes on whatever is left once the reply has finished
+func (g *adviceGuard) flush() error {
+	text := g.pending.String()
+	g.pending.Reset()
+	if text == "" {
+		return nil
+	}
+	return g.pass(text)
+}
+
+func (g *adviceGuard) pass(text string) error {
+	text = plainText(text)
+	if err := g.check(text); err != nil {
+		return err
+	}
+
+	n := utf8.RuneCountInString(text)
+	if g.written+n > maxAdviceLength {
+		return &guardrailError{reason: fmt.Sprintf("longer than %d characters", maxAdviceLength)}
+	}
+
+	if _, err := io.WriteString(g.w, text); err != nil {
+		return err
+	}
+	g.written += n
+	return nil
+}
+
+// check refuses advice that would make dangerous conditions worse
+func (g *adviceGuard) check(text string) error {
+	if g.temperature == nil {
+		return nil
+	}
+
+	t := *g.temperature
+	switch {
+	case t >= hotTemperature && (matchesUnnegated(coolingCut, text) || matchesUnnegated(heatingOn, text)):
+		return &guardrailError{reason: fmt.Sprintf("cuts cooling or adds heat at %.1f°C", t)}
+	case t <= coldTemperature && matchesUnnegated(heatingOff, text):
+		return &guardrailError{reason: fmt.Sprintf("turns heating off at %.1f°C", t)}
+	}
+	return nil
+}
+
+func matchesUnnegated(re *regexp.Regexp, text string) bool {
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		if !negated.MatchString(text[:loc[0]]) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastSentenceEnd is where the last complete sentence in text ends, or 0 if
+// none has
+func lastSentenceEnd(text string) int {
+	end := 0
+	for i, r := range text {
+		switch r {
+		case '\n':
+			end = i + 1
+		case '.', '!', '?', '؟':
+			// A full stop is only an end once the next character shows it is
+			// not part of a number such as 26.5
+			next := i + utf8.RuneLen(r)
+			if next < len(text) && (text[next] == ' ' || text[next] == '\n') {
+				end = next
+			}
+		}
+	}
+	return end
+}
+
+// plainText strips the Markdown and HTML the model was told not to use
+func plainText(text string) string {
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = htmlTag.ReplaceAllString(text, "")
+	text = markdownMarks.ReplaceAllString(text, "")
+	text = markdownHeader.ReplaceAllString(text, "")
+	text = markdownQuote.ReplaceAllString(text, "")
+	return markdownBullet.ReplaceAllString(text, "$1- ")
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+func hotReadings() []internal.SensorReading {
+	return []internal.SensorReading{{SensorType: "temperature", Value: 45, Timestamp: time.Now()}}
+}
+
+func TestAdviceGuardBlocksCuttingCoolingWhenHot(t *testing.T) {
+	tests := []struct {
+		advice string
+		// unsafe is the part that must not reach the client
+		unsafe string
+	}{
+		{"It is very hot. Close the vents to keep the heat in. ", "Close the vents"},
+		{"Please shut all the side windows. ", "shut all the side windows"},
+		{"أغلق النوافذ الآن. ", "أغلق النوافذ"},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		g := newAdviceGuard(&out, hotReadings())
+
+		_, err := fmt.Fprint(g, tt.advice)
+		if err == nil {
+			err = g.flush()
+		}
+		var rejected *guardrailError
+		if !errors.As(err, &rejected) {
+			t.Errorf("advice %q at 45°C passed the guard, want it rejected", tt.advice)
+		}
+		if strings.Contains(out.String(), tt.unsafe) {
+			t.Errorf("rejected advice reached the client: %q", out.String())
+		}
+	}
+}
+
+func TestAdviceGuardAllowsNegatedAdvice(t *testing.T) {
+	advice := "It is very hot. Do not close the vents, and never turn on the heaters today. Open every window. "
+
+	var out strings.Builder
+	g := newAdviceGuard(&out, hotReadings())
+	if _, err := fmt.Fprint(g, advice); err != nil {
+		t.Fatalf("guard rejected negated advice: %v", err)
+	}
+	if err := g.flush(); err != nil {
+		t.Fatalf("guard rejected negated advice: %v", err)
+	}
+	if out.String() != advice {
+		t.Errorf("guard wrote %q, want %q", out.String(), advice)
+	}
+}
+
+func TestAdviceGuardCapsLength(t *testing.T) {
+	var out strings.Builder
+	g := newAdviceGuard(&out, hotReadings())
+
+	sentence := "Keep the vents open and water early in the morning. "
+	var err error
+	for i := 0; err == nil && i < 2*maxAdviceLength/len(sentence); i++ {
+		_, err = fmt.Fprint(g, sentence)
+	}
+	var rejected *guardrailError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("advice of %d characters was not stopped, error = %v", 2*maxAdviceLength, err)
+	}
+	if n := len([]rune(out.String())); n > maxAdviceLength {
+		t.Errorf("guard wrote %d characters, want at most %d", n, maxAdviceLength)
+	}
+}
@@ -0,0 +1,507 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+const (
+	// defaultAlertsLimit caps how many alerts are listed
+	defaultAlertsLimit = 100
+	// alertLookback is how far before a rule's window readings are fetched to
+	// find the one in effect when the window started
+	alertLookback = 15 * time.Minute
+	// alertClockSkew allows for readings stamped by the database slightly
+	// ahead of this process's clock
+	alertClockSkew = time.Minute
+)
+
+type AlertsStore interface {
+	ListAlertRules(ctx context.Context) ([]internal.AlertRule, error)
+	ListEnabledAlertRules(ctx context.Context) ([]internal.AlertRule, error)
+	GetAlertRule(ctx context.Context, id int64) (internal.AlertRule, error)
+	CreateAlertRule(ctx context.Context, params internal.AlertRuleParams) (internal.AlertRule, error)
+	UpdateAlertRule(ctx context.Context, id int64, params internal.AlertRuleParams) (internal.AlertRule, error)
+	DeleteAlertRule(ctx context.Context, id int64) error
+	ListAlerts(ctx context.Context, status string, limit int) ([]internal.Alert, error)
+	GetAlert(ctx context.Context, id int64) (internal.Alert, error)
+	GetUnresolvedAlertByRule(ctx context.Context, ruleID int64) (internal.Alert, error)
+	CreateAlert(ctx context.Context, params internal.CreateAlertParams) (internal.Alert, bool, error)
+	AcknowledgeAlert(ctx context.Context, id int64, by string) (internal.Alert, error)
+	ResolveAlert(ctx context.Context, id int64) (internal.Alert, error)
+}
+
+// AlertReadingsStore gives alert rules access to the readings they watch,
+// from every device or only those in a zone
+type AlertReadingsStore interface {
+	GetSensorReadingsBetween(ctx context.Context, sensorTypes []string, from, to time.Time) ([]internal.SensorReading, error)
+	GetLatestUsableSensorReading(ctx context.Context, sensorType string) (internal.SensorReading, error)
+	GetZoneReadingsBetween(ctx context.Context, sensorType, zone string, from, to time.Time) ([]internal.SensorReading, error)
+	GetLatestUsableZoneReading(ctx context.Context, sensorType, zone string) (internal.SensorReading, error)
+}
+
+// AlertNotifier is told whenever an alert opens or resolves
+type AlertNotifier interface {
+	NotifyAlert(ctx context.Context, alert internal.Alert) error
+}
+
+// AlertSilencer decides whether a rule's alerts are silenced
+type AlertSilencer interface {
+	RuleSilenced(ctx context.Context, rule internal.AlertRule, at time.Time) (bool, error)
+}
+
+// CommandTracker knows which actuator commands devices have yet to
+// acknowledge
+type CommandTracker interface {
+	ListPendingCommands(ctx context.Context, actuator string, before time.Time) ([]internal.ActuatorState, error)
+}
+
+type Alerts struct {
+	store     AlertsStore
+	readings  AlertReadingsStore
+	notifiers []AlertNotifier
+	silencer  AlertSilencer
+	commands  CommandTracker
+}
+
+type AlertsOption func(*Alerts)
+
+// WithAlertNotifier delivers alerts as they open and resolve
+func WithAlertNotifier(n AlertNotifier) AlertsOption {
+	return func(s *Alerts) {
+		s.notifiers = append(s.notifiers, n)
+	}
+}
+
+// WithAlertSilencer records alerts raised during silences and maintenance
+// windows without notifying anyone
+func WithAlertSilencer(silencer AlertSilencer) AlertsOption {
+	return func(s *Alerts) {
+		s.silencer = silencer
+	}
+}
+
+// WithCommandTracker enables command_timeout rules. Without it they never
+// fire.
+func WithCommandTracker(t CommandTracker) AlertsOption {
+	return func(s *Alerts) {
+		s.commands = t
+	}
+}
+
+func NewAlerts(store AlertsStore, readings AlertReadingsStore, opts ...AlertsOption) *Alerts {
+	s := &Alerts{
+		store:    store,
+		readings: readings,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *Alerts) notify(ctx context.Context, alert internal.Alert) {
+	for _, n := range s.notifiers {
+		if err := n.NotifyAlert(ctx, alert); err != nil {
+			slog.Error("alert notifier failed", "error", err, "alert_id", alert.ID)
+		}
+	}
+}
+
+func (s *Alerts) silenced(ctx context.Context, rule internal.AlertRule, now time.Time) bool {
+	if s.silencer == nil {
+		return false
+	}
+
+	silenced, err := s.silencer.RuleSilenced(ctx, rule, now)
+	if err != nil {
+		// Better a notification during maintenance than a missed alert
+		slog.Error("failed to check alert silences", "error", err, "rule_id", rule.ID)
+		return false
+	}
+
+	return silenced
+}
+
+func validateAlertRule(params *internal.AlertRuleParams) error {
+	if params.Name == "" {
+		return internal.NewInputError("name is required")
+	}
+
+	if params.Kind == internal.AlertCommandTimeout {
+		if !internal.IsActuator(params.SensorType) {
+			return internal.NewInputError("unknown actuator %q", params.SensorType)
+		}
+	} else {
+		def, ok := internal.LookupSensor(params.SensorType)
+		if !ok || def.Virtual {
+			return internal.NewInputError("unknown sensor type %q", params.SensorType)
+		}
+		params.SensorType = def.Type
+	}
+
+	if params.Zone != nil && *params.Zone == "" {
+		params.Zone = nil
+	}
+
+	switch params.Kind {
+	case internal.AlertThreshold:
+		if params.DurationSeconds < 0 {
+			return internal.NewInputError("duration_seconds cannot be negative")
+		}
+	case internal.AlertRateOfChange:
+		if params.Threshold <= 0 {
+			return internal.NewInputError("rate_of_change rules need a positive threshold")
+		}
+		fallthrough
+	case internal.AlertMissingData, internal.AlertCommandTimeout:
+		if params.DurationSeconds <= 0 {
+			return internal.NewInputError("%s rules need a positive duration_seconds", params.Kind)
+		}
+	default:
+		return internal.NewInputError("unknown rule kind %q", params.Kind)
+	}
+
+	if params.Comparison == "" {
+		params.Comparison = internal.ComparisonAbove
+	}
+	if params.Comparison != internal.ComparisonAbove && params.Comparison != internal.ComparisonBelow {
+		return internal.NewInputError("comparison must be %q or %q", internal.ComparisonAbove, internal.ComparisonBelow)
+	}
+
+	if params.Severity == "" {
+		params.Severity = internal.SeverityWarning
+	}
+	switch params.Severity {
+	case internal.SeverityInfo, internal.SeverityWarning, internal.SeverityCritical:
+	default:
+		return internal.NewInputError("unknown severity %q", params.Severity)
+	}
+
+	return nil
+}
+
+func (s *Alerts) ListAlertRules(ctx context.Context) ([]internal.AlertRule, error) {
+	return s.store.ListAlertRules(ctx)
+}
+
+func (s *Alerts) CreateAlertRule(ctx context.Context, params internal.AlertRuleParams) (internal.AlertRule, error) {
+	if err := validateAlertRule(&params); err != nil {
+		return internal.AlertRule{}, err
+	}
+
+	return s.store.CreateAlertRule(ctx, params)
+}
+
+func (s *Alerts) UpdateAlertRule(ctx context.Context, id int64, params internal.AlertRuleParams) (internal.AlertRule, error) {
+	if err := validateAlertRule(&params); err != nil {
+		return internal.AlertRule{}, err
+	}
+
+	return s.store.UpdateAlertRule(ctx, id, params)
+}
+
+func (s *Alerts) DeleteAlertRule(ctx context.Context, id int64) error {
+	return s.store.DeleteAlertRule(ctx, id)
+}
+
+// ListAlerts returns the most recent alerts, optionally only those in status
+func (s *Alerts) ListAlerts(ctx context.Context, status string) ([]internal.Alert, error) {
+	switch status {
+	case "", internal.AlertOpen, internal.AlertAcknowledged, internal.AlertResolved:
+	default:
+		return nil, internal.NewInputError("unknown alert status %q", status)
+	}
+
+	return s.store.ListAlerts(ctx, status, defaultAlertsLimit)
+}
+
+// AcknowledgeAlert marks an open alert as seen. The alert stays unresolved
+// until its rule stops firing.
+func (s *Alerts) AcknowledgeAlert(ctx context.Context, id int64, by string) (internal.Alert, error) {
+	alert, err := s.store.GetAlert(ctx, id)
+	if err != nil {
+		return internal.Alert{}, err
+	}
+	if alert.Status != internal.AlertOpen {
+		return internal.Alert{}, internal.NewInputError("alert is already %s", alert.Status)
+	}
+
+	return s.store.AcknowledgeAlert(ctx, id, by)
+}
+
+// EvaluateAlertRules evaluates every enabled rule. It runs as a background
+// job rather than as readings arrive, so that evaluating rules never holds
+// up ingest.
+func (s *Alerts) EvaluateAlertRules(ctx context.Context) error {
+	rules, err := s.store.ListEnabledAlertRules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list alert rules because %w", err)
+	}
+
+	return s.evaluateRules(ctx, rules)
+}
+
+func (s *Alerts) evaluateRules(ctx context.Context, rules []internal.AlertRule) error {
+	now := time.Now()
+	for _, rule := range rules {
+		if err := s.evaluate(ctx, rule, now); err != nil {
+			return fmt.Errorf("failed to evaluate alert rule %d because %w", rule.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// verdict is the outcome of evaluating a rule. A rule that is neither firing
+// nor clear, for lack of data, leaves any alert as it is.
+type verdict struct {
+	firing  bool
+	clear   bool
+	value   *float64
+	message string
+}
+
+func (s *Alerts) evaluate(ctx context.Context, rule internal.AlertRule, now time.Time) error {
+	var v verdict
+	var err error
+	switch rule.Kind {
+	case internal.AlertThreshold:
+		v, err = s.evaluateThreshold(ctx, rule, now)
+	case internal.AlertRateOfChange:
+		v, err = s.evaluateRateOfChange(ctx, rule, now)
+	case internal.AlertMissingData:
+		v, err = s.evaluateMissingData(ctx, rule, now)
+	case internal.AlertCommandTimeout:
+		v, err = s.evaluateCommandTimeout(ctx, rule, now)
+	default:
+		return fmt.Errorf("unknown rule kind %q", rule.Kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case v.firing:
+		alert, created, err := s.store.CreateAlert(ctx, internal.CreateAlertParams{
+			RuleID:     rule.ID,
+			SensorType: rule.SensorType,
+			Severity:   rule.Severity,
+			Message:    v.message,
+			Value:      v.value,
+			Silenced:   s.silenced(ctx, rule, now),
+		})
+		if err != nil {
+			return err
+		}
+		if created {
+			slog.Warn("alert opened",
+				"alert_id", alert.ID,
+				"rule_id", rule.ID,
+				"severity", alert.Severity,
+				"message", alert.Message,
+				"silenced", alert.Silenced,
+			)
+			if !alert.Silenced {
+				s.notify(ctx, alert)
+			}
+		}
+	case v.clear:
+		open, err := s.store.GetUnresolvedAlertByRule(ctx, rule.ID)
+		if errors.Is(err, internal.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		resolved, err := s.store.ResolveAlert(ctx, open.ID)
+		if errors.Is(err, internal.ErrNotFound) {
+			// Resolved concurrently by another replica
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		slog.Info("alert resolved", "alert_id", resolved.ID, "rule_id", rule.ID)
+		// Nobody heard about a silenced alert opening, so nobody needs to
+		// hear about it resolving
+		if !resolved.Silenced && !s.silenced(ctx, rule, now) {
+			s.notify(ctx, resolved)
+		}
+	}
+
+	return nil
+}
+
+// readingsBetween returns the readings a rule watches in [from, to): those
+// of its sensor from devices in its zone, or from every device if it has none
+func (s *Alerts) readingsBetween(ctx context.Context, rule internal.AlertRule, from, to time.Time) ([]internal.SensorReading, error) {
+	if rule.Zone != nil {
+		return s.readings.GetZoneReadingsBetween(ctx, rule.SensorType, *rule.Zone, from, to)
+	}
+	return s.readings.GetSensorReadingsBetween(ctx, []string{rule.SensorType}, from, to)
+}
+
+// latestReading returns the most recent usable reading a rule watches
+func (s *Alerts) latestReading(ctx context.Context, rule internal.AlertRule) (internal.SensorReading, error) {
+	if rule.Zone != nil {
+		return s.readings.GetLatestUsableZoneReading(ctx, rule.SensorType, *rule.Zone)
+	}
+	return s.readings.GetLatestUsableSensorReading(ctx, rule.SensorType)
+}
+
+// windowReadings returns the usable readings the rule watches from the one
+// in effect at the start of the rule's window up to now, or nil if there is
+// no reading old enough to cover the whole window
+func (s *Alerts) windowReadings(ctx context.Context, rule internal.AlertRule, now time.Time) ([]internal.SensorReading, error) {
+	start := now.Add(-rule.Duration())
+	readings, err := s.readingsBetween(ctx, rule, start.Add(-alertLookback), now.Add(alertClockSkew))
+	if err != nil {
+		return nil, err
+	}
+
+	usable := make([]internal.SensorReading, 0, len(readings))
+	for _, r := range readings {
+		if r.Quality != internal.QualityBad {
+			usable = append(usable, r)
+		}
+	}
+
+	// Readings come back oldest first; the anchor is the last one taken at or
+	// before the window opened
+	anchor := -1
+	for i, r := range usable {
+		if r.Timestamp.After(start) {
+			break
+		}
+		anchor = i
+	}
+	if anchor < 0 {
+		if rule.Duration() == 0 && len(usable) > 0 {
+			return usable[len(usable)-1:], nil
+		}
+		return nil, nil
+	}
+
+	return usable[anchor:], nil
+}
+
+func (s *Alerts) evaluateThreshold(ctx context.Context, rule internal.AlertRule, now time.Time) (verdict, error) {
+	window, err := s.windowReadings(ctx, rule, now)
+	if err != nil {
+		return verdict{}, err
+	}
+
+	latest, err := s.latestReading(ctx, rule)
+	if errors.Is(err, internal.ErrNotFound) {
+		return verdict{}, nil
+	}
+	if err != nil {
+		return verdict{}, err
+	}
+
+	v := verdict{value: &latest.Value, clear: !rule.Breaches(latest.Value)}
+	if len(window) == 0 {
+		return v, nil
+	}
+
+	v.firing = true
+	for _, r := range window {
+		if !rule.Breaches(r.Value) {
+			v.firing = false
+			break
+		}
+	}
+	if v.firing {
+		v.message = fmt.Sprintf("%s: %s has been %s %g for %s (now %g)",
+			rule.Name, rule.SensorType, rule.Comparison, rule.Threshold, rule.Duration(), latest.Value)
+	}
+
+	return v, nil
+}
+
+func (s *Alerts) evaluateRateOfChange(ctx context.Context, rule internal.AlertRule, now time.Time) (verdict, error) {
+	window, err := s.windowReadings(ctx, rule, now)
+	if err != nil {
+		return verdict{}, err
+	}
+	if len(window) < 2 {
+		return verdict{}, nil
+	}
+
+	first, last := window[0], window[len(window)-1]
+	change := last.Value - first.Value
+	if change < 0 {
+		change = -change
+	}
+
+	v := verdict{value: &last.Value, firing: change > rule.Threshold}
+	v.clear = !v.firing
+	if v.firing {
+		v.message = fmt.Sprintf("%s: %s changed by %g within %s (from %g to %g)",
+			rule.Name, rule.SensorType, change, rule.Duration(), first.Value, last.Value)
+	}
+
+	return v, nil
+}
+
+func (s *Alerts) evaluateMissingData(ctx context.Context, rule internal.AlertRule, now time.Time) (verdict, error) {
+	latest, err := s.latestReading(ctx, rule)
+	if errors.Is(err, internal.ErrNotFound) {
+		return verdict{
+			firing:  true,
+			message: fmt.Sprintf("%s: %s has never reported", rule.Name, rule.SensorType),
+		}, nil
+	}
+	if err != nil {
+		return verdict{}, err
+	}
+
+	silence := now.Sub(latest.Timestamp)
+	if silence <= rule.Duration() {
+		return verdict{clear: true}, nil
+	}
+
+	return verdict{
+		firing:  true,
+		message: fmt.Sprintf("%s: no %s reading for %s", rule.Name, rule.SensorType, silence.Truncate(time.Minute)),
+	}, nil
+}
+
+func (s *Alerts) evaluateCommandTimeout(ctx context.Context, rule internal.AlertRule, now time.Time) (verdict, error) {
+	if s.commands == nil {
+		return verdict{}, nil
+	}
+
+	pending, err := s.commands.ListPendingCommands(ctx, rule.SensorType, now.Add(-rule.Duration()))
+	if err != nil {
+		return verdict{}, err
+	}
+	if len(pending) == 0 {
+		return verdict{clear: true}, nil
+	}
+
+	devices := make([]string, len(pending))
+	for i, p := range pending {
+		devices[i] = p.DeviceID
+	}
+	// Pending commands come back oldest first
+	var waiting time.Duration
+	if oldest := pending[0].DesiredAt; oldest != nil {
+		waiting = now.Sub(*oldest).Truncate(time.Second)
+	}
+
+	return verdict{
+		firing: true,
+		message: fmt.Sprintf("%s: %s command not acknowledged for %s by %s",
+			rule.Name, rule.SensorType, waiting, strings.Join(devices, ", ")),
+	}, nil
+}
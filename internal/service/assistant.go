@@ -0,0 +1,420 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/advisor"
+	"github.com/lulzshadowwalker/green-backend/internal/llm"
+)
+
+const (
+	// MaxAssistantMessageLength caps a single question, in characters
+	MaxAssistantMessageLength = 4000
+	// Token budget for the turns sent verbatim with each question
+	maxHistoryTokens = 1500
+	// Once this many turns have not been summarized, the older ones are
+	// folded into the conversation summary
+	maxUnsummarizedMessages = 12
+	// Turns kept verbatim after summarizing, so the latest exchange reads
+	// naturally
+	keepRecentMessages = 6
+	maxSummaryTokens   = 300
+	summaryTimeout     = time.Minute
+	maxTitleLength     = 60
+)
+
+// assistantSystemPrompts is the assistant's system prompt in each language
+var assistantSystemPrompts = map[string]string{
+	internal.LanguageEnglish: "You are a friendly greenhouse assistant chatting with a farmer about their plants. Answer their questions, including follow-ups, using the conversation so far and the current greenhouse state you are given with every question. Be concise and practical, and keep the language simple, as the farmer is likely not very technical. If the data does not answer a question, say so rather than guessing. You cannot use rich text formatting in your responses.",
+	internal.LanguageArabic:  "أنت مساعد ودود للبيوت المحمية تتحدث مع مزارع عن نباتاته. أجب عن أسئلته، بما فيها أسئلة المتابعة، مستعينًا بالمحادثة حتى الآن وبحالة البيت المحمي الحالية التي تُعطى لك مع كل سؤال. كن موجزًا وعمليًا، واستخدم لغة بسيطة لأن المزارع غالبًا ليست لديه خبرة تقنية. إذا لم تُجب البيانات عن سؤال ما فقل ذلك بدلًا من التخمين. لا يمكنك استخدام التنسيق الغني في ردودك. أجب باللغة العربية.",
+}
+
+// assistantToolsPrompts is added to the system prompt when the model may
+// call tools
+var assistantToolsPrompts = map[string]string{
+	internal.LanguageEnglish: " You are only given the latest reading of each sensor; use the tools to look at history, statistics, thresholds and controls before answering questions about them. If a control should change, propose it with propose_control_change and tell the farmer an operator has to approve it. Never claim a control was changed.",
+	internal.LanguageArabic:  " لا تُعطى إلا آخر قراءة لكل حساس؛ استخدم الأدوات للاطلاع على السجل والإحصاءات والحدود وأجهزة التحكم قبل الإجابة عن أسئلة تخصها. إذا كان ينبغي تغيير جهاز تحكم فاقترح ذلك باستخدام propose_control_change وأخبر المزارع أن على أحد المشغّلين الموافقة عليه. لا تدّعِ أبدًا أنه تم تغيير جهاز تحكم.",
+}
+
+const conversationSummaryPrompt = "You summarize conversations between a farmer and a greenhouse assistant. Write a short plain-text summary of what was asked, what was advised and anything the farmer told you about their greenhouse, so the conversation can continue without the full transcript. Keep it under 150 words."
+
+type AssistantStore interface {
+	ListConversations(ctx context.Context, userID int) ([]internal.Conversation, error)
+	GetConversation(ctx context.Context, userID int, id int64) (internal.Conversation, error)
+	CreateConversation(ctx context.Context, userID int, params internal.CreateConversationParams) (internal.Conversation, error)
+	DeleteConversation(ctx context.Context, userID int, id int64) error
+	ListConversationMessages(ctx context.Context, conversationID, afterID int64) ([]internal.ConversationMessage, error)
+	AddConversationMessage(ctx context.Context, params internal.CreateConversationMessageParams) (internal.ConversationMessage, error)
+	SummarizeConversation(ctx context.Context, id int64, summary string, through int64) error
+}
+
+// ControlLister lists how each actuator is being controlled
+type ControlLister interface {
+	GetAllSensorControls(ctx context.Context) ([]internal.SensorControl, error)
+}
+
+// Assistant holds conversations with the greenhouse assistant. Every
+// question is answered with fresh readings, controls and target ranges, on
+// top of a summary of older turns and the latest turns verbatim. With tool
+// calling on, the model looks up history and statistics itself and may
+// propose control changes, which wait for an operator's approval.
+type Assistant struct {
+	store     AssistantStore
+	advice    *llmService
+	controls  ControlLister
+	proposals ControlProposer
+	// summarizing holds the IDs of conversations being summarized, so that
+	// quick turns do not summarize the same messages twice
+	summarizing sync.Map
+}
+
+// NewAssistant builds the assistant. proposals may be nil, in which case
+// the model cannot propose control changes.
+func NewAssistant(store AssistantStore, readingsStore SensorReadingsStore, provider llm.Provider, controls ControlLister, proposals ControlProposer, opts ...LLMOption) *Assistant {
+	return &Assistant{
+		store:     store,
+		advice:    newLLMService(readingsStore, provider, opts...),
+		controls:  controls,
+		proposals: proposals,
+	}
+}
+
+func (s *Assistant) ListConversations(ctx context.Context, userID int) ([]internal.Conversation, error) {
+	return s.store.ListConversations(ctx, userID)
+}
+
+func (s *Assistant) GetConversation(ctx context.Context, userID int, id int64) (internal.Conversation, error) {
+	return s.store.GetConversation(ctx, userID, id)
+}
+
+// ListConversationMessages returns every message in the user's
+// conversation, oldest first
+func (s *Assistant) ListConversationMessages(ctx context.Context, userID int, id int64) ([]internal.ConversationMessage, error) {
+	conv, err := s.store.GetConversation(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.ListConversationMessages(ctx, conv.ID, 0)
+}
+
+func (s *Assistant) CreateConversation(ctx context.Context, userID int, params internal.CreateConversationParams) (internal.Conversation, error) {
+	params.Title = strings.TrimSpace(params.Title)
+	params.Plant = strings.TrimSpace(params.Plant)
+	if params.Plant == "" {
+		params.Plant = "strawberry"
+	}
+	if utf8.RuneCountInString(params.Title) > maxTitleLength {
+		return internal.Conversation{}, internal.NewInputError("title must be at most %d characters", maxTitleLength)
+	}
+	plant, err := knownPlant(params.Plant)
+	if err != nil {
+		return internal.Conversation{}, err
+	}
+	params.Plant = plant
+
+	return s.store.CreateConversation(ctx, userID, params)
+}
+
+func (s *Assistant) DeleteConversation(ctx context.Context, userID int, id int64) error {
+	return s.store.DeleteConversation(ctx, userID, id)
+}
+
+// SendMessage saves the user's question, streams the assistant's reply to w
+// and saves that too. The reply is in locale, or the user's preferred
+// language and units where locale leaves them empty, and falls back to
+// offline advice like plant advice does. Older turns are summarized in the
+// background once the conversation grows long.
+func (s *Assistant) SendMessage(ctx context.Context, userID int, id int64, content string, locale internal.Locale, w io.Writer) (internal.ConversationMessage, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return internal.ConversationMessage{}, internal.NewInputError("content is required")
+	}
+	if utf8.RuneCountInString(content) > MaxAssistantMessageLength {
+		return internal.ConversationMessage{}, internal.NewInputError("content must be at most %d characters", MaxAssistantMessageLength)
+	}
+
+	conv, err := s.store.GetConversation(ctx, userID, id)
+	if err != nil {
+		return internal.ConversationMessage{}, err
+	}
+
+	history, err := s.store.ListConversationMessages(ctx, conv.ID, conv.SummarizedThrough)
+	if err != nil {
+		return internal.ConversationMessage{}, fmt.Errorf("failed to load conversation: %w", err)
+	}
+
+	question, err := s.store.AddConversationMessage(ctx, internal.CreateConversationMessageParams{
+		ConversationID: conv.ID,
+		Role:           internal.AssistantRoleUser,
+		Content:        content,
+		Title:          conversationTitle(content),
+	})
+	if err != nil {
+		return internal.ConversationMessage{}, fmt.Errorf("failed to save message: %w", err)
+	}
+
+	readings, err := s.advice.readingsStore.GetSensorReadingsSince(ctx, time.Now().Add(-6*time.Hour))
+	if err != nil {
+		return internal.ConversationMessage{}, fmt.Errorf("failed to fetch sensor readings: %w", err)
+	}
+
+	locale = s.advice.resolveLocale(ctx, &userID, locale)
+	setAdviceLocale(w, locale)
+
+	prompt := systemPrompt(assistantSystemPrompts, locale)
+	var tools *toolbox
+	if s.advice.toolCalling {
+		prompt += assistantToolsPrompts[locale.Language]
+		tools = s.toolbox(userID, conv, locale)
+	}
+
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: prompt},
+		{Role: llm.RoleSystem, Content: s.greenhouseContext(ctx, conv, readings, locale)},
+	}
+	for _, m := range recentHistory(s.advice.provider.Model(), history) {
+		role := llm.RoleUser
+		if m.Role == internal.AssistantRoleAssistant {
+			role = llm.RoleAssistant
+		}
+		messages = append(messages, llm.Message{Role: role, Content: m.Content})
+	}
+	messages = append(messages, llm.Message{Role: llm.RoleUser, Content: content})
+
+	reply := &replyRecorder{w: w}
+	call := llmCall{feature: internal.LLMFeatureAssistant, userID: &userID, locale: locale}
+	if err := s.advice.streamReply(ctx, call, messages, tools, conv.Plant, readings, reply); err != nil {
+		return internal.ConversationMessage{}, err
+	}
+
+	answer, err := s.store.AddConversationMessage(ctx, internal.CreateConversationMessageParams{
+		ConversationID: conv.ID,
+		Role:           internal.AssistantRoleAssistant,
+		Content:        reply.String(),
+		Source:         &reply.source,
+	})
+	if err != nil {
+		return internal.ConversationMessage{}, fmt.Errorf("failed to save reply: %w", err)
+	}
+
+	// Summaries need the language model; there is no point trying while it
+	// is out of reach
+	unsummarized := append(history, question, answer)
+	if len(unsummarized) > maxUnsummarizedMessages && reply.source == AdviceSourceLLM {
+		go s.summarize(context.WithoutCancel(ctx), conv.UserID, conv.ID)
+	}
+
+	return answer, nil
+}
+
+// summarize folds all but the latest turns of the conversation into its
+// summary. Only one summary of a conversation is made at a time, and it
+// starts from the conversation as it is then, so turns already folded in by
+// an earlier one are not summarized again.
+func (s *Assistant) summarize(ctx context.Context, userID int, id int64) {
+	if _, busy := s.summarizing.LoadOrStore(id, struct{}{}); busy {
+		return
+	}
+	defer s.summarizing.Delete(id)
+
+	ctx, cancel := context.WithTimeout(ctx, summaryTimeout)
+	defer cancel()
+
+	conv, err := s.store.GetConversation(ctx, userID, id)
+	if err != nil {
+		slog.Warn("Failed to load conversation to summarize", "conversation_id", id, "error", err)
+		return
+	}
+	unsummarized, err := s.store.ListConversationMessages(ctx, conv.ID, conv.SummarizedThrough)
+	if err != nil {
+		slog.Warn("Failed to load conversation to summarize", "conversation_id", id, "error", err)
+		return
+	}
+	if len(unsummarized) <= maxUnsummarizedMessages {
+		return
+	}
+	msgs := unsummarized[:len(unsummarized)-keepRecentMessages]
+
+	var b strings.Builder
+	if conv.Summary != "" {
+		b.WriteString("Summary of the conversation so far:\n")
+		b.WriteString(conv.Summary)
+		b.WriteString("\n\nWhat was said since:\n")
+	}
+	for _, m := range msgs {
+		speaker := "Farmer"
+		if m.Role == internal.AssistantRoleAssistant {
+			speaker = "Assistant"
+		}
+		b.WriteString(fmt.Sprintf("%s: %s\n", speaker, m.Content))
+	}
+
+	res, err := s.advice.complete(ctx, llmCall{feature: internal.LLMFeatureSummary, userID: &conv.UserID}, llm.Request{
+		MaxTokens: maxSummaryTokens,
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: conversationSummaryPrompt},
+			{Role: llm.RoleUser, Content: b.String()},
+		},
+	})
+	if err != nil {
+		slog.Warn("Failed to summarize conversation", "conversation_id", conv.ID, "error", err)
+		return
+	}
+
+	through := msgs[len(msgs)-1].ID
+	if err := s.store.SummarizeConversation(ctx, conv.ID, strings.TrimSpace(res.Content), through); err != nil {
+		slog.Error("Failed to save conversation summary", "conversation_id", conv.ID, "error", err)
+		return
+	}
+
+	slog.Info("Summarized conversation", "conversation_id", conv.ID, "through", through)
+}
+
+// greenhouseContext describes the greenhouse as it is now, in locale's
+// units, and what was said before the turns sent verbatim
+func (s *Assistant) greenhouseContext(ctx context.Context, conv internal.Conversation, readings []internal.SensorReading, locale internal.Locale) string {
+	words := readingsWordings[locale.Language]
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(words.state, time.Now().Format("15:04")))
+	// Conversations from before plants were checked may name anything
+	profile, rules := s.advice.limits(ctx, conv.Plant)
+	b.WriteString(fmt.Sprintf(words.plant, advisor.PlantName(profile.Name, locale)))
+
+	if s.advice.toolCalling {
+		// The model can look up anything older itself
+		writeReadings(&b, latestReadings(readings), locale)
+	} else {
+		summaries := advisor.Summarize(advisor.Input{
+			Profile:  profile,
+			Rules:    rules,
+			Readings: readings,
+			Now:      time.Now(),
+		})
+		b.WriteString(fitSummaries(s.advice.provider.Model(), summaries, maxReadingsTokens, locale))
+	}
+
+	if s.controls != nil {
+		controls, err := s.controls.GetAllSensorControls(ctx)
+		if err != nil {
+			slog.Warn("Failed to load controls for the assistant", "error", err)
+		} else {
+			writeControls(&b, controls, locale)
+		}
+	}
+
+	writeLimits(&b, profile, rules, locale)
+
+	if conv.Summary != "" {
+		b.WriteString(words.earlier)
+		b.WriteString(conv.Summary)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// writeControls lists how each actuator is being controlled, in locale's
+// language
+func writeControls(b *strings.Builder, controls []internal.SensorControl, locale internal.Locale) {
+	if len(controls) == 0 {
+		return
+	}
+
+	words := readingsWordings[locale.Language]
+	b.WriteString(words.controls)
+	for _, c := range controls {
+		mode, ok := words.modes[c.Mode]
+		if !ok {
+			mode = c.Mode
+		}
+		b.WriteString(fmt.Sprintf(words.control, advisor.SensorName(c.SensorType, locale), mode))
+		switch {
+		case c.ManualBoolValue != nil && *c.ManualBoolValue:
+			b.WriteString(words.on)
+		case c.ManualBoolValue != nil:
+			b.WriteString(words.off)
+		case c.ManualIntValue != nil:
+			b.WriteString(fmt.Sprintf(words.setTo, *c.ManualIntValue))
+		}
+		if c.ManualUntil != nil {
+			b.WriteString(fmt.Sprintf(words.until, c.ManualUntil.Format("15:04")))
+		}
+		if c.Suppressed {
+			b.WriteString(words.paused)
+		}
+		b.WriteString("\n")
+	}
+}
+
+// writeLimits lists the ranges the plant should be kept in, as narrowed by
+// the farm's threshold alert rules, in locale's units
+func writeLimits(b *strings.Builder, profile internal.PlantProfile, rules []internal.AlertRule, locale internal.Locale) {
+	limits := advisor.Limits(profile, rules)
+	sensors := make([]string, 0, len(limits))
+	for sensor := range limits {
+		sensors = append(sensors, sensor)
+	}
+	sort.Strings(sensors)
+
+	words := readingsWordings[locale.Language]
+	b.WriteString(fmt.Sprintf(words.limits, advisor.PlantName(profile.Name, locale)))
+	for _, sensor := range sensors {
+		r := limits[sensor]
+		b.WriteString(fmt.Sprintf(words.limit,
+			advisor.SensorName(sensor, locale), formatSensorValue(locale, sensor, r.Min), formatSensorValue(locale, sensor, r.Max)))
+	}
+}
+
+// latestReadings is the latest usable reading of each sensor
+func latestReadings(readings []internal.SensorReading) []internal.SensorReading {
+	latest := map[string]internal.SensorReading{}
+	for _, r := range readings {
+		if r.Quality == internal.QualityBad {
+			continue
+		}
+		if l, ok := latest[r.SensorType]; !ok || r.Timestamp.After(l.Timestamp) {
+			latest[r.SensorType] = r
+		}
+	}
+
+	res := make([]internal.SensorReading, 0, len(latest))
+	for _, r := range latest {
+		res = append(res, r)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].SensorType < res[j].SensorType })
+	return res
+}
+
+// recentHistory is the latest turns that fit in the history token budget
+func recentHistory(model string, history []internal.ConversationMessage) []internal.ConversationMessage {
+	tokens := 0
+	start := len(history)
+	for start > 0 {
+		tokens += llm.CountTokens(model, history[start-1].Content)
+		if tokens > maxHistoryTokens {
+			break
+		}
+		start--
+	}
+	return history[start:]
+}
+
+// conversationTitle names a conversation after its first question
+func conversationTitle(content string) string {
+	title := strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(title) <= maxTitleLength {
+		return title
+	}
+	runes := []rune(title)
+	return strings.TrimSpace(string(runes[:maxTitleLength-1])) + "…"
+}
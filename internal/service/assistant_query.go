@@ -0,0 +1,349 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/advisor"
+	"github.com/lulzshadowwalker/green-backend/internal/llm"
+)
+
+const (
+	// MaxQueryLength caps a history question, in characters
+	MaxQueryLength = 500
+	// Longest period a history question may cover
+	maxQueryRange  = 366 * 24 * time.Hour
+	maxQueryTokens = 200
+	queryTimeout   = 30 * time.Second
+	// Buckets the period is split into to find when a minimum or maximum
+	// was read
+	queryBuckets = 48
+)
+
+const queryTimeLayout = "Mon 2 Jan 15:04"
+
+// Weekdays, from Sunday, and months in Arabic, for times laid out with
+// queryTimeLayout
+var (
+	arabicWeekdays = [...]string{"الأحد", "الاثنين", "الثلاثاء", "الأربعاء", "الخميس", "الجمعة", "السبت"}
+	arabicMonths   = [...]string{"يناير", "فبراير", "مارس", "أبريل", "مايو", "يونيو", "يوليو", "أغسطس", "سبتمبر", "أكتوبر", "نوفمبر", "ديسمبر"}
+)
+
+// formatQueryTime lays t out with layout, naming days and months in
+// locale's language
+func formatQueryTime(t time.Time, layout string, locale internal.Locale) string {
+	if locale.Language != internal.LanguageArabic || layout != queryTimeLayout {
+		return t.Format(layout)
+	}
+	return fmt.Sprintf("%s %d %s %s", arabicWeekdays[t.Weekday()], t.Day(), arabicMonths[t.Month()-1], t.Format("15:04"))
+}
+
+// ErrQueryUnavailable is returned when the language model needed to read a
+// history question is out of reach. There is no offline fallback.
+var ErrQueryUnavailable = errors.New("questions about sensor history cannot be answered right now")
+
+const queryPlanPrompt = `You turn a farmer's question about their greenhouse sensor history into a query plan. Reply with a single JSON object and nothing else, in this form:
+{"sensor_type": "...", "aggregate": "...", "from": "...", "to": "..."}
+sensor_type is one of: %s.
+aggregate is one of: min (lowest), max (highest), avg (average), count (number of readings).
+from and to bound the period asked about, as RFC 3339 times with the farmer's UTC offset; to is exclusive.
+Resolve words like today, yesterday or last week from the current time you are given. Morning is 06:00 to 12:00, afternoon 12:00 to 18:00, evening 18:00 to 22:00 and night 22:00 to 06:00.
+If the question is not about one of these sensors over a period of time, reply {"error": "..."} with a short reason instead.`
+
+// queryPlanReply is what the model is asked to reply with
+type queryPlanReply struct {
+	SensorType string `json:"sensor_type"`
+	Aggregate  string `json:"aggregate"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Error      string `json:"error"`
+}
+
+// Query answers a question about sensor history, such as the highest
+// temperature yesterday afternoon. The language model only reads the
+// question into a QueryPlan; the plan is checked and run against the
+// aggregate store, and the answer is written from the numbers, in locale or
+// the user's preferred language and units. timezone names the farmer's time
+// zone and defaults to UTC.
+func (s *Assistant) Query(ctx context.Context, userID int, question, timezone string, locale internal.Locale) (internal.QueryAnswer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return internal.QueryAnswer{}, internal.NewInputError("question is required")
+	}
+	if utf8.RuneCountInString(question) > MaxQueryLength {
+		return internal.QueryAnswer{}, internal.NewInputError("question must be at most %d characters", MaxQueryLength)
+	}
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return internal.QueryAnswer{}, internal.NewInputError("unknown time zone %q", timezone)
+	}
+
+	now := time.Now().In(loc)
+	plan, err := s.planQuery(ctx, userID, question, now)
+	if err != nil {
+		return internal.QueryAnswer{}, err
+	}
+	plan.Timezone = loc.String()
+
+	answer, err := s.runQuery(ctx, plan, s.advice.resolveLocale(ctx, &userID, locale))
+	if err != nil {
+		return internal.QueryAnswer{}, err
+	}
+	answer.Question = question
+
+	return answer, nil
+}
+
+// planQuery has the language model read the question into a plan, and
+// refuses any plan that is not one of the queries on offer
+func (s *Assistant) planQuery(ctx context.Context, userID int, question string, now time.Time) (internal.QueryPlan, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var sensors []string
+	for _, def := range internal.SensorCatalog {
+		if !def.Virtual {
+			sensors = append(sensors, def.Type)
+		}
+	}
+
+	res, err := s.advice.complete(ctx, llmCall{feature: internal.LLMFeatureQuery, userID: &userID}, llm.Request{
+		MaxTokens: maxQueryTokens,
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: fmt.Sprintf(queryPlanPrompt, strings.Join(sensors, ", "))},
+			{Role: llm.RoleSystem, Content: fmt.Sprintf("The current time is %s, %s.", now.Format(time.RFC3339), now.Weekday())},
+			{Role: llm.RoleUser, Content: question},
+		},
+	})
+	if errors.Is(err, ErrLLMBudgetExceeded) {
+		return internal.QueryPlan{}, err
+	}
+	if err != nil {
+		slog.Warn("LLM unavailable for a history question",
+			"provider", s.advice.provider.Name(),
+			"model", s.advice.provider.Model(),
+			"error", err,
+		)
+		return internal.QueryPlan{}, fmt.Errorf("%w: %v", ErrQueryUnavailable, err)
+	}
+
+	slog.Info("LLM query plan",
+		"provider", s.advice.provider.Name(),
+		"model", res.Model,
+		"prompt_tokens", res.Usage.PromptTokens,
+		"completion_tokens", res.Usage.CompletionTokens,
+		"plan", res.Content,
+	)
+
+	return parseQueryPlan(res.Content, now)
+}
+
+// parseQueryPlan reads the model's reply strictly: one JSON object with
+// only the expected fields, naming a known sensor, aggregate and period
+func parseQueryPlan(content string, now time.Time) (internal.QueryPlan, error) {
+	// Models like to wrap JSON in code fences or a sentence
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return internal.QueryPlan{}, internal.NewInputError("could not understand the question")
+	}
+
+	var reply queryPlanReply
+	dec := json.NewDecoder(bytes.NewReader([]byte(content[start : end+1])))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&reply); err != nil {
+		return internal.QueryPlan{}, internal.NewInputError("could not understand the question")
+	}
+	if reply.Error != "" {
+		return internal.QueryPlan{}, internal.NewInputError("cannot answer the question: %s", reply.Error)
+	}
+
+	def, ok := internal.LookupSensor(reply.SensorType)
+	if !ok || def.Virtual {
+		return internal.QueryPlan{}, internal.NewInputError("cannot answer questions about %q", reply.SensorType)
+	}
+	switch reply.Aggregate {
+	case internal.QueryMin, internal.QueryMax, internal.QueryAvg, internal.QueryCount:
+	default:
+		return internal.QueryPlan{}, internal.NewInputError("cannot work out %q", reply.Aggregate)
+	}
+
+	from, err := time.Parse(time.RFC3339, reply.From)
+	if err != nil {
+		return internal.QueryPlan{}, internal.NewInputError("could not work out the period asked about")
+	}
+	to, err := time.Parse(time.RFC3339, reply.To)
+	if err != nil {
+		return internal.QueryPlan{}, internal.NewInputError("could not work out the period asked about")
+	}
+	from, to = from.In(now.Location()), to.In(now.Location())
+	if to.After(now) {
+		to = now
+	}
+	if !from.Before(to) {
+		return internal.QueryPlan{}, internal.NewInputError("the period asked about has not happened yet")
+	}
+	if to.Sub(from) > maxQueryRange {
+		return internal.QueryPlan{}, internal.NewInputError("questions may cover at most %d days", int(maxQueryRange.Hours()/24))
+	}
+
+	return internal.QueryPlan{
+		SensorType: def.Type,
+		Aggregate:  reply.Aggregate,
+		From:       from,
+		To:         to,
+	}, nil
+}
+
+func (s *Assistant) runQuery(ctx context.Context, plan internal.QueryPlan, locale internal.Locale) (internal.QueryAnswer, error) {
+	answer := internal.QueryAnswer{Unit: locale.Unit(plan.SensorType), Plan: plan}
+
+	bucket := max(time.Minute, (plan.To.Sub(plan.From) / queryBuckets).Round(time.Minute))
+	aggregates, err := s.advice.readingsStore.AggregateSensorReadings(ctx, internal.AggregateSensorReadingsParams{
+		SensorTypes: []string{plan.SensorType},
+		From:        plan.From,
+		To:          plan.To,
+		Bucket:      bucket,
+	})
+	if err != nil {
+		return internal.QueryAnswer{}, fmt.Errorf("failed to aggregate sensor readings: %w", err)
+	}
+
+	var minAt, maxAt internal.SensorAggregate
+	sum := 0.0
+	for i, a := range aggregates {
+		if i == 0 || a.Min < minAt.Min {
+			minAt = a
+		}
+		if i == 0 || a.Max > maxAt.Max {
+			maxAt = a
+		}
+		sum += a.Avg * float64(a.Count)
+		answer.Count += a.Count
+	}
+
+	if answer.Count > 0 {
+		avg := sum / float64(answer.Count)
+		answer.Min, answer.Max, answer.Avg = &minAt.Min, &maxAt.Max, &avg
+
+		switch plan.Aggregate {
+		case internal.QueryMin:
+			answer.Value = answer.Min
+			answer.At = s.readAt(ctx, plan, minAt.Bucket, bucket, minAt.Min)
+		case internal.QueryMax:
+			answer.Value = answer.Max
+			answer.At = s.readAt(ctx, plan, maxAt.Bucket, bucket, maxAt.Max)
+		case internal.QueryAvg:
+			answer.Value = answer.Avg
+		case internal.QueryCount:
+			count := float64(answer.Count)
+			answer.Value = &count
+		}
+	} else if plan.Aggregate == internal.QueryCount {
+		count := 0.0
+		answer.Value = &count
+	}
+
+	// Converted last, as readAt looks up the stored values
+	for _, v := range []*float64{answer.Min, answer.Max, answer.Avg} {
+		if v != nil {
+			*v = locale.Value(plan.SensorType, *v)
+		}
+	}
+
+	answer.Answer = describeQueryAnswer(answer, locale)
+	return answer, nil
+}
+
+// readAt finds when value was read within the bucket starting at from. It
+// falls back to the start of the bucket.
+func (s *Assistant) readAt(ctx context.Context, plan internal.QueryPlan, from time.Time, bucket time.Duration, value float64) *time.Time {
+	to := from.Add(bucket)
+	if to.After(plan.To) {
+		to = plan.To
+	}
+	readings, err := s.advice.readingsStore.GetSensorReadingsBetween(ctx, []string{plan.SensorType}, from, to)
+	if err != nil {
+		slog.Warn("Failed to find when a reading was taken", "sensor_type", plan.SensorType, "error", err)
+	}
+
+	at := from
+	for _, r := range readings {
+		if r.Value == value {
+			at = r.Timestamp
+			break
+		}
+	}
+	at = at.In(plan.From.Location())
+	return &at
+}
+
+// describeQueryAnswer writes the answer as a sentence, such as "The highest
+// temperature between Sun 18 Oct 12:00 and 18:00 was 31.4°C, at 15:42."
+func describeQueryAnswer(a internal.QueryAnswer, locale internal.Locale) string {
+	plan := a.Plan
+	y, m, d := plan.From.Date()
+	y2, m2, d2 := plan.To.Date()
+	sameDay := y == y2 && m == m2 && d == d2
+
+	toLayout, atLayout := queryTimeLayout, queryTimeLayout
+	if sameDay {
+		toLayout, atLayout = "15:04", "15:04"
+	}
+	from, to := formatQueryTime(plan.From, queryTimeLayout, locale), formatQueryTime(plan.To, toLayout, locale)
+
+	if locale.Language == internal.LanguageArabic {
+		return describeQueryAnswerArabic(a, from, to, atLayout)
+	}
+
+	period := fmt.Sprintf("between %s and %s", from, to)
+	if a.Count == 0 {
+		return fmt.Sprintf("There are no %s readings %s.", plan.SensorType, period)
+	}
+
+	switch plan.Aggregate {
+	case internal.QueryMin, internal.QueryMax:
+		word := "lowest"
+		if plan.Aggregate == internal.QueryMax {
+			word = "highest"
+		}
+		return fmt.Sprintf("The %s %s %s was %s, at %s.", word, plan.SensorType, period, formatReading(*a.Value, a.Unit), a.At.Format(atLayout))
+	case internal.QueryAvg:
+		return fmt.Sprintf("The average %s %s was %s, over %d readings.", plan.SensorType, period, formatReading(*a.Value, a.Unit), a.Count)
+	default:
+		return fmt.Sprintf("There were %d %s readings %s.", a.Count, plan.SensorType, period)
+	}
+}
+
+func describeQueryAnswerArabic(a internal.QueryAnswer, from, to, atLayout string) string {
+	plan := a.Plan
+	arabic := internal.Locale{Language: internal.LanguageArabic}
+	sensor := advisor.SensorName(plan.SensorType, arabic)
+	period := fmt.Sprintf("بين %s و%s", from, to)
+	if a.Count == 0 {
+		return fmt.Sprintf("%s: لا توجد قراءات %s.", sensor, period)
+	}
+
+	switch plan.Aggregate {
+	case internal.QueryMin, internal.QueryMax:
+		word := "أدنى"
+		if plan.Aggregate == internal.QueryMax {
+			word = "أعلى"
+		}
+		return fmt.Sprintf("%s: %s قيمة %s كانت %s، عند %s.", sensor, word, period, formatReading(*a.Value, a.Unit), formatQueryTime(*a.At, atLayout, arabic))
+	case internal.QueryAvg:
+		return fmt.Sprintf("%s: المتوسط %s كان %s، من %d قراءة.", sensor, period, formatReading(*a.Value, a.Unit), a.Count)
+	default:
+		return fmt.Sprintf("%s: %d قراءة %s.", sensor, a.Count, period)
+	}
+}
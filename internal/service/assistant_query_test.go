@@ -0,0 +1,48 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+func TestParseQueryPlan(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	plan, err := parseQueryPlan("Here you go:\n```json\n"+
+		`{"sensor_type": "temp", "aggregate": "max", "from": "2026-10-18T00:00:00Z", "to": "2026-10-20T00:00:00Z"}`+
+		"\n```", now)
+	if err != nil {
+		t.Fatalf("parseQueryPlan() error = %v", err)
+	}
+	if plan.SensorType != "temperature" || plan.Aggregate != internal.QueryMax {
+		t.Errorf("plan = %s %s, want temperature max", plan.SensorType, plan.Aggregate)
+	}
+	if !plan.To.Equal(now) {
+		t.Errorf("plan.To = %s, want it capped at now, %s", plan.To, now)
+	}
+
+	rejected := map[string]string{
+		"unknown sensor":    `{"sensor_type": "radiation", "aggregate": "max", "from": "2026-10-18T00:00:00Z", "to": "2026-10-19T00:00:00Z"}`,
+		"virtual sensor":    `{"sensor_type": "vpd", "aggregate": "max", "from": "2026-10-18T00:00:00Z", "to": "2026-10-19T00:00:00Z"}`,
+		"unknown aggregate": `{"sensor_type": "temperature", "aggregate": "median", "from": "2026-10-18T00:00:00Z", "to": "2026-10-19T00:00:00Z"}`,
+		"unknown field":     `{"sensor_type": "temperature", "aggregate": "max", "from": "2026-10-18T00:00:00Z", "to": "2026-10-19T00:00:00Z", "sql": "DROP TABLE users"}`,
+		"unparsable time":   `{"sensor_type": "temperature", "aggregate": "max", "from": "yesterday", "to": "2026-10-19T00:00:00Z"}`,
+		"backwards range":   `{"sensor_type": "temperature", "aggregate": "max", "from": "2026-10-19T00:00:00Z", "to": "2026-10-18T00:00:00Z"}`,
+		"future range":      `{"sensor_type": "temperature", "aggregate": "max", "from": "2026-10-20T00:00:00Z", "to": "2026-10-21T00:00:00Z"}`,
+		"too long a range":  `{"sensor_type": "temperature", "aggregate": "max", "from": "2025-01-01T00:00:00Z", "to": "2026-10-19T00:00:00Z"}`,
+		"model refusal":     `{"error": "I can only answer questions about sensor history"}`,
+		"no json":           `The highest temperature was 31 °C.`,
+	}
+	for name, content := range rejected {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseQueryPlan(content, now)
+			var inputErr internal.InputError
+			if !errors.As(err, &inputErr) {
+				t.Fatalf("parseQueryPlan() error = %v, want an input error", err)
+			}
+		})
+	}
+}
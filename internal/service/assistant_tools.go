@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/advisor"
+	"github.com/lulzshadowwalker/green-backend/internal/llm"
+)
+
+const (
+	defaultToolHours = 6
+	maxToolHours     = 7 * 24
+	// Most readings get_readings returns; longer ranges are thinned out
+	maxToolReadings = 48
+	// Most buckets get_stats breaks a range into
+	maxToolBuckets = 24
+)
+
+// ControlProposer holds control changes for an operator to approve
+type ControlProposer interface {
+	ProposeControlChange(ctx context.Context, params internal.CreateControlProposalParams) (internal.ControlProposal, error)
+}
+
+var (
+	sensorRangeSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"sensor_type": {"type": "string", "description": "temperature, humidity, light, water or soil"},
+		"hours": {"type": "integer", "description": "How many hours back to look, 1 to 168. Defaults to 6."}
+	},
+	"required": ["sensor_type"]
+}`)
+	noArgumentsSchema = json.RawMessage(`{"type": "object", "properties": {}}`)
+	proposalSchema    = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"sensor_type": {"type": "string", "description": "The control to change, as listed by get_controls"},
+		"mode": {"type": "string", "enum": ["automatic", "manual"]},
+		"on": {"type": "boolean", "description": "For manual mode on switched controls: turn on or off"},
+		"value": {"type": "integer", "description": "For manual mode on numeric controls: the level to set"},
+		"duration_minutes": {"type": "integer", "description": "For manual mode: how long the override lasts. Leave out to keep it until changed."},
+		"reason": {"type": "string", "description": "Why, in a sentence the operator will read"}
+	},
+	"required": ["sensor_type", "mode", "reason"]
+}`)
+)
+
+var assistantTools = []llm.Tool{
+	{
+		Name:        "get_readings",
+		Description: "Readings of one sensor over the last few hours, oldest first. Long ranges are thinned out.",
+		Parameters:  sensorRangeSchema,
+	},
+	{
+		Name:        "get_stats",
+		Description: "Minimum, maximum and average of one sensor over the last few hours, overall and broken into time buckets.",
+		Parameters:  sensorRangeSchema,
+	},
+	{
+		Name:        "get_thresholds",
+		Description: "The ranges the plant should be kept in, and the farm's threshold alert rules.",
+		Parameters:  noArgumentsSchema,
+	},
+	{
+		Name:        "get_controls",
+		Description: "How each actuator is controlled right now: automatic or manual, and any manual value.",
+		Parameters:  noArgumentsSchema,
+	},
+	{
+		Name:        "propose_control_change",
+		Description: "Suggest changing a control. Nothing changes until an operator approves it; tell the farmer it is waiting for approval.",
+		Parameters:  proposalSchema,
+	},
+}
+
+type sensorRangeArgs struct {
+	SensorType string `json:"sensor_type"`
+	Hours      int    `json:"hours"`
+}
+
+// parse resolves the sensor and the time range asked for
+func (a sensorRangeArgs) parse(now time.Time) (internal.SensorDefinition, time.Time, error) {
+	def, ok := internal.LookupSensor(a.SensorType)
+	if !ok || def.Virtual {
+		return internal.SensorDefinition{}, time.Time{}, internal.NewInputError("unknown sensor type %q", a.SensorType)
+	}
+	hours := a.Hours
+	if hours == 0 {
+		hours = defaultToolHours
+	}
+	if hours < 1 || hours > maxToolHours {
+		return internal.SensorDefinition{}, time.Time{}, internal.NewInputError("hours must be between 1 and %d", maxToolHours)
+	}
+	return def, now.Add(-time.Duration(hours) * time.Hour), nil
+}
+
+type proposalArgs struct {
+	SensorType      string `json:"sensor_type"`
+	Mode            string `json:"mode"`
+	On              *bool  `json:"on"`
+	Value           *int   `json:"value"`
+	DurationMinutes *int   `json:"duration_minutes"`
+	Reason          string `json:"reason"`
+}
+
+// toolbox gives the model the data behind the user's conversation, in
+// locale's units
+func (s *Assistant) toolbox(userID int, conv internal.Conversation, locale internal.Locale) *toolbox {
+	tools := assistantTools
+	if s.proposals == nil {
+		tools = tools[:len(tools)-1]
+	}
+
+	return &toolbox{
+		tools: tools,
+		run: func(ctx context.Context, call llm.ToolCall) string {
+			result, err := s.runTool(ctx, userID, conv, locale, call)
+			if err != nil {
+				var ie internal.InputError
+				if !errors.As(err, &ie) {
+					slog.Error("Assistant tool failed", "tool", call.Name, "error", err, "conversation_id", conv.ID)
+					err = fmt.Errorf("%s failed, try again later", call.Name)
+				}
+				result = map[string]string{"error": err.Error()}
+			}
+
+			out, err := json.Marshal(result)
+			if err != nil {
+				return `{"error": "could not encode the result"}`
+			}
+			return string(out)
+		},
+	}
+}
+
+func (s *Assistant) runTool(ctx context.Context, userID int, conv internal.Conversation, locale internal.Locale, call llm.ToolCall) (any, error) {
+	switch call.Name {
+	case "get_readings":
+		var args sensorRangeArgs
+		if err := decodeToolArgs(call, &args); err != nil {
+			return nil, err
+		}
+		return s.toolReadings(ctx, args, locale)
+	case "get_stats":
+		var args sensorRangeArgs
+		if err := decodeToolArgs(call, &args); err != nil {
+			return nil, err
+		}
+		return s.toolStats(ctx, args, locale)
+	case "get_thresholds":
+		return s.toolThresholds(ctx, conv, locale)
+	case "get_controls":
+		if s.controls == nil {
+			return []internal.SensorControl{}, nil
+		}
+		return s.controls.GetAllSensorControls(ctx)
+	case "propose_control_change":
+		if s.proposals == nil {
+			break
+		}
+		var args proposalArgs
+		if err := decodeToolArgs(call, &args); err != nil {
+			return nil, err
+		}
+		return s.toolPropose(ctx, userID, conv, args)
+	}
+
+	return nil, internal.NewInputError("there is no tool called %q", call.Name)
+}
+
+func decodeToolArgs(call llm.ToolCall, v any) error {
+	args := strings.TrimSpace(call.Arguments)
+	if args == "" {
+		args = "{}"
+	}
+	if err := json.Unmarshal([]byte(args), v); err != nil {
+		return internal.NewInputError("invalid arguments for %s: %v", call.Name, err)
+	}
+	return nil
+}
+
+type toolReading struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+func (s *Assistant) toolReadings(ctx context.Context, args sensorRangeArgs, locale internal.Locale) (any, error) {
+	now := time.Now()
+	def, from, err := args.parse(now)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.advice.readingsStore.GetSensorReadingsBetween(ctx, []string{def.Type}, from, now)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Timestamp.Before(rows[j].Timestamp) })
+
+	readings := make([]toolReading, 0, len(rows))
+	for _, r := range rows {
+		if r.Quality != internal.QualityBad {
+			readings = append(readings, toolReading{Time: r.Timestamp, Value: locale.Value(def.Type, r.Value)})
+		}
+	}
+	total := len(readings)
+	if total > maxToolReadings {
+		// Evenly spaced, always keeping the latest
+		thinned := make([]toolReading, maxToolReadings)
+		for i := range thinned {
+			thinned[i] = readings[(total-1)-(maxToolReadings-1-i)*(total-1)/(maxToolReadings-1)]
+		}
+		readings = thinned
+	}
+
+	return map[string]any{
+		"sensor_type": def.Type,
+		"unit":        locale.Unit(def.Type),
+		"from":        from,
+		"to":          now,
+		"total":       total,
+		"readings":    readings,
+	}, nil
+}
+
+type toolStats struct {
+	From  time.Time `json:"from"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Avg   float64   `json:"avg"`
+	Count int64     `json:"count"`
+}
+
+func (s *Assistant) toolStats(ctx context.Context, args sensorRangeArgs, locale internal.Locale) (any, error) {
+	now := time.Now()
+	def, from, err := args.parse(now)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := now.Sub(from) / maxToolBuckets
+	bucket = max(time.Hour, bucket.Round(time.Hour))
+	aggregates, err := s.advice.readingsStore.AggregateSensorReadings(ctx, internal.AggregateSensorReadingsParams{
+		SensorTypes: []string{def.Type},
+		From:        from,
+		To:          now,
+		Bucket:      bucket,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{
+		"sensor_type": def.Type,
+		"unit":        locale.Unit(def.Type),
+		"from":        from,
+		"to":          now,
+	}
+	if len(aggregates) == 0 {
+		result["count"] = 0
+		return result, nil
+	}
+
+	value := func(v float64) float64 { return locale.Value(def.Type, v) }
+	overall := toolStats{From: from, Min: value(aggregates[0].Min), Max: value(aggregates[0].Max)}
+	buckets := make([]toolStats, len(aggregates))
+	sum := 0.0
+	for i, a := range aggregates {
+		a.Min, a.Max, a.Avg = value(a.Min), value(a.Max), value(a.Avg)
+		buckets[i] = toolStats{From: a.Bucket, Min: a.Min, Max: a.Max, Avg: a.Avg, Count: a.Count}
+		overall.Min = min(overall.Min, a.Min)
+		overall.Max = max(overall.Max, a.Max)
+		overall.Count += a.Count
+		sum += a.Avg * float64(a.Count)
+	}
+	if overall.Count > 0 {
+		overall.Avg = sum / float64(overall.Count)
+	}
+
+	result["min"] = overall.Min
+	result["max"] = overall.Max
+	result["avg"] = overall.Avg
+	result["count"] = overall.Count
+	result["bucket_minutes"] = int(bucket.Minutes())
+	result["buckets"] = buckets
+	return result, nil
+}
+
+type toolThresholdRule struct {
+	Name       string  `json:"name"`
+	SensorType string  `json:"sensor_type"`
+	Comparison string  `json:"comparison"`
+	Threshold  float64 `json:"threshold"`
+	Severity   string  `json:"severity"`
+	Zone       *string `json:"zone,omitempty"`
+}
+
+func (s *Assistant) toolThresholds(ctx context.Context, conv internal.Conversation, locale internal.Locale) (any, error) {
+	profile, ok := internal.LookupPlantProfile(conv.Plant)
+	if !ok {
+		profile = internal.GenericPlantProfile
+	}
+
+	var rules []internal.AlertRule
+	if s.advice.rules != nil {
+		var err error
+		if rules, err = s.advice.rules.ListAlertRules(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	thresholds := []toolThresholdRule{}
+	for _, r := range rules {
+		if r.Enabled && r.Kind == internal.AlertThreshold {
+			thresholds = append(thresholds, toolThresholdRule{
+				Name:       r.Name,
+				SensorType: r.SensorType,
+				Comparison: r.Comparison,
+				Threshold:  locale.Value(r.SensorType, r.Threshold),
+				Severity:   r.Severity,
+				Zone:       r.Zone,
+			})
+		}
+	}
+
+	ranges := advisor.Limits(profile, rules)
+	for sensor, r := range ranges {
+		ranges[sensor] = locale.Range(sensor, r)
+	}
+
+	return map[string]any{
+		"plant":       profile.Name,
+		"ranges":      ranges,
+		"alert_rules": thresholds,
+	}, nil
+}
+
+func (s *Assistant) toolPropose(ctx context.Context, userID int, conv internal.Conversation, args proposalArgs) (any, error) {
+	proposal, err := s.proposals.ProposeControlChange(ctx, internal.CreateControlProposalParams{
+		SensorType:      args.SensorType,
+		Mode:            args.Mode,
+		ManualIntValue:  args.Value,
+		ManualBoolValue: args.On,
+		DurationMinutes: args.DurationMinutes,
+		Reason:          args.Reason,
+		ProposedBy:      "assistant",
+		UserID:          &userID,
+		ConversationID:  &conv.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Assistant proposed a control change",
+		"proposal_id", proposal.ID,
+		"sensor_type", proposal.SensorType,
+		"mode", proposal.Mode,
+		"conversation_id", conv.ID,
+		"user_id", userID,
+	)
+
+	return map[string]any{
+		"proposal_id": proposal.ID,
+		"status":      proposal.Status,
+		"note":        "Nothing has changed yet. An operator has to approve this proposal first.",
+	}, nil
+}
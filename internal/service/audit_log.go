@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// defaultAuditLimit caps how many audit entries are listed
+const defaultAuditLimit = 100
+
+type AuditLogStore interface {
+	ListAuditEntries(ctx context.Context, filter internal.AuditFilter, limit int) ([]internal.AuditEntry, error)
+}
+
+type AuditLog struct {
+	store AuditLogStore
+}
+
+func NewAuditLog(store AuditLogStore) *AuditLog {
+	return &AuditLog{store: store}
+}
+
+// ListAuditEntries returns the most recent changes matching filter, newest
+// first
+func (s *AuditLog) ListAuditEntries(ctx context.Context, filter internal.AuditFilter) ([]internal.AuditEntry, error) {
+	return s.store.ListAuditEntries(ctx, filter, defaultAuditLimit)
+}
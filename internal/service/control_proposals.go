@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+const (
+	maxProposalReasonLength = 500
+	// maxProposalDuration caps how long a proposed manual override may last
+	maxProposalDuration = 7 * 24 * time.Hour
+)
+
+type ControlProposalsStore interface {
+	ListControlProposals(ctx context.Context, status string) ([]internal.ControlProposal, error)
+	GetControlProposal(ctx context.Context, id int64) (internal.ControlProposal, error)
+	CreateControlProposal(ctx context.Context, params internal.CreateControlProposalParams) (internal.ControlProposal, error)
+	ClaimControlProposal(ctx context.Context, id int64, by string) (internal.ControlProposal, error)
+	RejectControlProposal(ctx context.Context, id int64, by string) (internal.ControlProposal, error)
+	RecordControlProposalApplied(ctx context.Context, id int64, by string, control internal.SensorControl) error
+	FailControlProposal(ctx context.Context, id int64, by string, cause error) (internal.ControlProposal, error)
+}
+
+// ControlSetter applies approved control changes. It is the control service,
+// so that devices waiting on the controls hear of the change.
+type ControlSetter interface {
+	ControlLister
+	SetSensorControlModeWithValue(ctx context.Context, sensorType, mode string, manualUntil *time.Time, manualIntValue *int, manualBoolValue *bool) (internal.SensorControl, error)
+}
+
+// ControlProposals holds control changes suggested by the assistant until
+// an operator approves or rejects them. Nothing is applied without approval.
+type ControlProposals struct {
+	store    ControlProposalsStore
+	controls ControlSetter
+}
+
+func NewControlProposals(store ControlProposalsStore, controls ControlSetter) *ControlProposals {
+	return &ControlProposals{store: store, controls: controls}
+}
+
+// ProposeControlChange validates a suggested change against the controls
+// that exist and saves it for review
+func (s *ControlProposals) ProposeControlChange(ctx context.Context, params internal.CreateControlProposalParams) (internal.ControlProposal, error) {
+	params.SensorType = strings.TrimSpace(params.SensorType)
+	params.Reason = strings.TrimSpace(params.Reason)
+	params.ProposedBy = strings.TrimSpace(params.ProposedBy)
+
+	controls, err := s.controls.GetAllSensorControls(ctx)
+	if err != nil {
+		return internal.ControlProposal{}, err
+	}
+	found := false
+	for _, c := range controls {
+		if strings.EqualFold(c.SensorType, params.SensorType) {
+			params.SensorType = c.SensorType
+			found = true
+			break
+		}
+	}
+	if !found {
+		return internal.ControlProposal{}, internal.NewInputError("there is no control for %q", params.SensorType)
+	}
+
+	switch params.Mode {
+	case "automatic":
+		if params.ManualIntValue != nil || params.ManualBoolValue != nil || params.DurationMinutes != nil {
+			return internal.ControlProposal{}, internal.NewInputError("automatic mode takes no value or duration")
+		}
+	case "manual":
+		if params.ManualIntValue != nil && params.ManualBoolValue != nil {
+			return internal.ControlProposal{}, internal.NewInputError("give either an on/off or a numeric value, not both")
+		}
+		if params.DurationMinutes != nil {
+			d := time.Duration(*params.DurationMinutes) * time.Minute
+			if d <= 0 || d > maxProposalDuration {
+				return internal.ControlProposal{}, internal.NewInputError("duration must be between 1 minute and %s", maxProposalDuration)
+			}
+		}
+	default:
+		return internal.ControlProposal{}, internal.NewInputError("mode must be automatic or manual")
+	}
+
+	if params.Reason == "" {
+		return internal.ControlProposal{}, internal.NewInputError("reason is required")
+	}
+	if utf8.RuneCountInString(params.Reason) > maxProposalReasonLength {
+		return internal.ControlProposal{}, internal.NewInputError("reason must be at most %d characters", maxProposalReasonLength)
+	}
+	if params.ProposedBy == "" {
+		params.ProposedBy = "assistant"
+	}
+
+	return s.store.CreateControlProposal(ctx, params)
+}
+
+func (s *ControlProposals) ListControlProposals(ctx context.Context, status string) ([]internal.ControlProposal, error) {
+	switch status {
+	case "", internal.ProposalPending, internal.ProposalApproved, internal.ProposalRejected, internal.ProposalFailed:
+	default:
+		return nil, internal.NewInputError("invalid status %q", status)
+	}
+
+	return s.store.ListControlProposals(ctx, status)
+}
+
+func (s *ControlProposals) GetControlProposal(ctx context.Context, id int64) (internal.ControlProposal, error) {
+	return s.store.GetControlProposal(ctx, id)
+}
+
+// ApproveControlProposal claims the proposal, so that no concurrent review
+// can reject or approve it as well, then applies the change through the
+// control service. A change that cannot be applied leaves the proposal
+// failed. A manual override's duration counts from now.
+func (s *ControlProposals) ApproveControlProposal(ctx context.Context, id int64, by string) (internal.ControlProposal, error) {
+	by = strings.TrimSpace(by)
+	if _, err := s.pendingProposal(ctx, id, by); err != nil {
+		return internal.ControlProposal{}, err
+	}
+
+	proposal, err := s.store.ClaimControlProposal(ctx, id, by)
+	if err != nil {
+		return internal.ControlProposal{}, err
+	}
+
+	var until *time.Time
+	if proposal.DurationMinutes != nil {
+		t := time.Now().Add(time.Duration(*proposal.DurationMinutes) * time.Minute)
+		until = &t
+	}
+
+	control, err := s.controls.SetSensorControlModeWithValue(ctx, proposal.SensorType, proposal.Mode, until, proposal.ManualIntValue, proposal.ManualBoolValue)
+	if err != nil {
+		if _, ferr := s.store.FailControlProposal(ctx, id, by, err); ferr != nil {
+			slog.Error("Failed to mark control proposal failed", "error", ferr, "id", id)
+		}
+		return internal.ControlProposal{}, fmt.Errorf("failed to apply control proposal %d: %w", id, err)
+	}
+
+	// The change is made; a missing audit entry must not report it as failed
+	if err := s.store.RecordControlProposalApplied(ctx, id, by, control); err != nil {
+		slog.Error("Failed to record applied control proposal", "error", err, "id", id)
+	}
+
+	return proposal, nil
+}
+
+func (s *ControlProposals) RejectControlProposal(ctx context.Context, id int64, by string) (internal.ControlProposal, error) {
+	by = strings.TrimSpace(by)
+	if _, err := s.pendingProposal(ctx, id, by); err != nil {
+		return internal.ControlProposal{}, err
+	}
+
+	return s.store.RejectControlProposal(ctx, id, by)
+}
+
+func (s *ControlProposals) pendingProposal(ctx context.Context, id int64, by string) (internal.ControlProposal, error) {
+	if by == "" {
+		return internal.ControlProposal{}, internal.NewInputError("reviewer is required")
+	}
+
+	proposal, err := s.store.GetControlProposal(ctx, id)
+	if err != nil {
+		return internal.ControlProposal{}, err
+	}
+	if proposal.Status != internal.ProposalPending {
+		return internal.ControlProposal{}, internal.NewInputError("proposal %d is %s, not pending", id, proposal.Status)
+	}
+
+	return proposal, nil
+}
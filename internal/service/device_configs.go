@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+const (
+	maxSamplingIntervalSeconds = 24 * 60 * 60
+	maxUploadBatchSize         = 1000
+)
+
+type DeviceConfigsStore interface {
+	GetDeviceConfig(ctx context.Context, deviceID string) (internal.DeviceConfigDocument, error)
+	UpdateDeviceConfig(ctx context.Context, deviceID string, config internal.DeviceConfig, by string) (internal.DeviceConfigDocument, error)
+}
+
+// DeviceGetter looks up registered devices
+type DeviceGetter interface {
+	GetDevice(ctx context.Context, id string) (internal.Device, error)
+}
+
+type DeviceConfigs struct {
+	store   DeviceConfigsStore
+	devices DeviceGetter
+	changed *signal
+}
+
+func NewDeviceConfigs(store DeviceConfigsStore, devices DeviceGetter) *DeviceConfigs {
+	return &DeviceConfigs{
+		store:   store,
+		devices: devices,
+		changed: newSignal(),
+	}
+}
+
+// canonicalSensor resolves name to a stored sensor type
+func canonicalSensor(name string) (string, error) {
+	def, ok := internal.LookupSensor(name)
+	if !ok || def.Virtual {
+		return "", internal.NewInputError("unknown sensor type %q", name)
+	}
+	return def.Type, nil
+}
+
+func validateDeviceConfig(config *internal.DeviceConfig) error {
+	if config.SamplingIntervalSeconds < 1 || config.SamplingIntervalSeconds > maxSamplingIntervalSeconds {
+		return internal.NewInputError("sampling_interval_seconds must be between 1 and %d", maxSamplingIntervalSeconds)
+	}
+	if config.UploadBatchSize < 1 || config.UploadBatchSize > maxUploadBatchSize {
+		return internal.NewInputError("upload_batch_size must be between 1 and %d", maxUploadBatchSize)
+	}
+
+	sensors := make([]string, 0, len(config.EnabledSensors))
+	for _, name := range config.EnabledSensors {
+		sensorType, err := canonicalSensor(name)
+		if err != nil {
+			return err
+		}
+		sensors = append(sensors, sensorType)
+	}
+	slices.Sort(sensors)
+	config.EnabledSensors = slices.Compact(sensors)
+
+	calibration := make(map[string][]float64, len(config.Calibration))
+	for name, coefficients := range config.Calibration {
+		sensorType, err := canonicalSensor(name)
+		if err != nil {
+			return err
+		}
+		if err := validateCalibration(internal.CalibrationPolynomial, coefficients); err != nil {
+			return err
+		}
+		calibration[sensorType] = coefficients
+	}
+	config.Calibration = calibration
+
+	thresholds := make(map[string]internal.LocalThreshold, len(config.LocalThresholds))
+	for name, t := range config.LocalThresholds {
+		sensorType, err := canonicalSensor(name)
+		if err != nil {
+			return err
+		}
+		if t.Min == nil && t.Max == nil {
+			return internal.NewInputError("%s local threshold needs a min or a max", sensorType)
+		}
+		if t.Min != nil && t.Max != nil && *t.Min > *t.Max {
+			return internal.NewInputError("%s local threshold min is above its max", sensorType)
+		}
+		thresholds[sensorType] = t
+	}
+	config.LocalThresholds = thresholds
+
+	return nil
+}
+
+// GetDeviceConfig returns the configuration to serve a device, falling back
+// to the defaults for devices that have never been configured
+func (s *DeviceConfigs) GetDeviceConfig(ctx context.Context, deviceID string) (internal.DeviceConfigDocument, error) {
+	doc, err := s.store.GetDeviceConfig(ctx, deviceID)
+	if errors.Is(err, internal.ErrNotFound) {
+		if _, err := s.devices.GetDevice(ctx, deviceID); err != nil {
+			return internal.DeviceConfigDocument{}, err
+		}
+		doc = internal.DeviceConfigDocument{
+			DeviceID: deviceID,
+			Config:   internal.DefaultDeviceConfig(),
+		}
+	} else if err != nil {
+		return internal.DeviceConfigDocument{}, err
+	}
+
+	doc.ServerTime = time.Now().UTC()
+	return doc, nil
+}
+
+// DeviceConfigVersion returns the version of the device's configuration, 0
+// for the defaults
+func (s *DeviceConfigs) DeviceConfigVersion(ctx context.Context, deviceID string) (int64, error) {
+	doc, err := s.store.GetDeviceConfig(ctx, deviceID)
+	if errors.Is(err, internal.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return doc.Version, nil
+}
+
+// UpdateDeviceConfig replaces a device's configuration, bumping its version
+func (s *DeviceConfigs) UpdateDeviceConfig(ctx context.Context, deviceID string, config internal.DeviceConfig, by string) (internal.DeviceConfigDocument, error) {
+	if err := validateDeviceConfig(&config); err != nil {
+		return internal.DeviceConfigDocument{}, err
+	}
+
+	doc, err := s.store.UpdateDeviceConfig(ctx, deviceID, config, strings.TrimSpace(by))
+	if err != nil {
+		return internal.DeviceConfigDocument{}, err
+	}
+
+	s.changed.notify()
+	doc.ServerTime = time.Now().UTC()
+	return doc, nil
+}
+
+// ConfigsChanged returns a channel that is closed the next time a device's
+// configuration is updated through this process
+func (s *DeviceConfigs) ConfigsChanged() <-chan struct{} {
+	return s.changed.wait()
+}
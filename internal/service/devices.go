@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// DefaultDeviceOfflineAfter is how long a device may stay silent before it
+// is marked offline
+const DefaultDeviceOfflineAfter = 5 * time.Minute
+
+type DevicesStore interface {
+	ListDevices(ctx context.Context) ([]internal.Device, error)
+	GetDevice(ctx context.Context, id string) (internal.Device, error)
+	CreateDevice(ctx context.Context, id string, params internal.DeviceParams) (internal.Device, error)
+	UpdateDevice(ctx context.Context, id string, params internal.DeviceParams) (internal.Device, error)
+	DeleteDevice(ctx context.Context, id string) error
+	RecordDeviceHeartbeat(ctx context.Context, id string, hb internal.DeviceHeartbeat) (internal.Device, error)
+	TouchDevice(ctx context.Context, id, sensorType string) (internal.Device, error)
+	MarkStaleDevicesOffline(ctx context.Context, before time.Time) ([]internal.Device, error)
+	GetDeviceTwin(ctx context.Context, id string) (internal.DeviceTwin, error)
+	ReportActuatorStates(ctx context.Context, id string, reports []internal.ActuatorReport) (internal.DeviceTwin, error)
+	ListPendingActuatorCommands(ctx context.Context, actuator string, before time.Time) ([]internal.ActuatorState, error)
+}
+
+type Devices struct {
+	store        DevicesStore
+	offlineAfter time.Duration
+}
+
+func NewDevices(store DevicesStore, offlineAfter time.Duration) *Devices {
+	if offlineAfter <= 0 {
+		offlineAfter = DefaultDeviceOfflineAfter
+	}
+
+	return &Devices{
+		store:        store,
+		offlineAfter: offlineAfter,
+	}
+}
+
+func validateDevice(params *internal.DeviceParams) {
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Zone != nil {
+		zone := strings.TrimSpace(*params.Zone)
+		params.Zone = &zone
+		if zone == "" {
+			params.Zone = nil
+		}
+	}
+}
+
+func (s *Devices) ListDevices(ctx context.Context) ([]internal.Device, error) {
+	return s.store.ListDevices(ctx)
+}
+
+func (s *Devices) GetDevice(ctx context.Context, id string) (internal.Device, error) {
+	return s.store.GetDevice(ctx, id)
+}
+
+// CreateDevice registers a device before it first reports, so that it can
+// be named and placed in a zone up front
+func (s *Devices) CreateDevice(ctx context.Context, id string, params internal.DeviceParams) (internal.Device, error) {
+	if err := internal.ValidateDeviceID(id); err != nil {
+		return internal.Device{}, err
+	}
+	validateDevice(&params)
+
+	return s.store.CreateDevice(ctx, id, params)
+}
+
+func (s *Devices) UpdateDevice(ctx context.Context, id string, params internal.DeviceParams) (internal.Device, error) {
+	validateDevice(&params)
+
+	return s.store.UpdateDevice(ctx, id, params)
+}
+
+func (s *Devices) DeleteDevice(ctx context.Context, id string) error {
+	return s.store.DeleteDevice(ctx, id)
+}
+
+// RecordDeviceHeartbeat marks a device as online with what it reported about
+// itself, registering it if it is new
+func (s *Devices) RecordDeviceHeartbeat(ctx context.Context, id string, hb internal.DeviceHeartbeat) (internal.Device, error) {
+	if err := internal.ValidateDeviceID(id); err != nil {
+		return internal.Device{}, err
+	}
+
+	sensors := make([]string, 0, len(hb.Sensors))
+	for _, name := range hb.Sensors {
+		def, ok := internal.LookupSensor(name)
+		if !ok || def.Virtual {
+			return internal.Device{}, internal.NewInputError("unknown sensor type %q", name)
+		}
+		sensors = append(sensors, def.Type)
+	}
+	slices.Sort(sensors)
+	hb.Sensors = slices.Compact(sensors)
+
+	return s.store.RecordDeviceHeartbeat(ctx, id, hb)
+}
+
+// DeviceSeen marks a device as online because it just sent a reading
+func (s *Devices) DeviceSeen(ctx context.Context, deviceID, sensorType string) error {
+	_, err := s.store.TouchDevice(ctx, deviceID, sensorType)
+	return err
+}
+
+// DetectOfflineDevices marks devices that have been silent for longer than
+// the offline threshold as offline
+func (s *Devices) DetectOfflineDevices(ctx context.Context) error {
+	devices, err := s.store.MarkStaleDevicesOffline(ctx, time.Now().Add(-s.offlineAfter))
+	if err != nil {
+		return fmt.Errorf("failed to mark stale devices offline because %w", err)
+	}
+
+	for _, d := range devices {
+		slog.Warn("device went offline", "device_id", d.ID, "last_seen_at", d.LastSeenAt, "zone", d.Zone)
+	}
+
+	return nil
+}
+
+// GetDeviceTwin returns the desired and reported state of each actuator the
+// device has reported on
+func (s *Devices) GetDeviceTwin(ctx context.Context, id string) (internal.DeviceTwin, error) {
+	return s.store.GetDeviceTwin(ctx, id)
+}
+
+// ReportActuatorStates records the state a device says its actuators are in.
+// Each report acknowledges the desired version the device applied; actuators
+// left out of reports are untouched.
+func (s *Devices) ReportActuatorStates(ctx context.Context, id string, reports []internal.ActuatorReport) (internal.DeviceTwin, error) {
+	if len(reports) == 0 {
+		return internal.DeviceTwin{}, internal.NewInputError("at least one actuator must be reported")
+	}
+	for _, r := range reports {
+		if !internal.IsActuator(r.Actuator) {
+			return internal.DeviceTwin{}, internal.NewInputError("unknown actuator %q", r.Actuator)
+		}
+		if len(r.State) == 0 || !json.Valid(r.State) {
+			return internal.DeviceTwin{}, internal.NewInputError("%s state must be valid JSON", r.Actuator)
+		}
+		if r.Version < 0 {
+			return internal.DeviceTwin{}, internal.NewInputError("%s version cannot be negative", r.Actuator)
+		}
+	}
+	// Concurrent reports then lock rows in the same order
+	slices.SortFunc(reports, func(a, b internal.ActuatorReport) int {
+		return strings.Compare(a.Actuator, b.Actuator)
+	})
+
+	return s.store.ReportActuatorStates(ctx, id, reports)
+}
+
+// ListPendingCommands returns the desired states of actuator that devices
+// have not acknowledged since before
+func (s *Devices) ListPendingCommands(ctx context.Context, actuator string, before time.Time) ([]internal.ActuatorState, error) {
+	return s.store.ListPendingActuatorCommands(ctx, actuator, before)
+}
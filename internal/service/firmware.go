@@ -0,0 +1,384 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/blob"
+	"github.com/lulzshadowwalker/green-backend/internal/firmware"
+)
+
+// MaxFirmwareSize bounds uploaded firmware images
+const MaxFirmwareSize = 64 << 20
+
+const defaultFailureThreshold = 1
+
+var defaultRolloutStages = []int{10, 50, 100}
+
+type FirmwareStore interface {
+	ListFirmwareReleases(ctx context.Context) ([]internal.FirmwareRelease, error)
+	GetFirmwareRelease(ctx context.Context, id int64) (internal.FirmwareRelease, error)
+	CreateFirmwareRelease(ctx context.Context, params internal.FirmwareReleaseParams) (internal.FirmwareRelease, error)
+	DeleteFirmwareRelease(ctx context.Context, id int64) (internal.FirmwareRelease, error)
+	ListRolloutGroups(ctx context.Context) ([]internal.RolloutGroup, error)
+	GetRolloutGroup(ctx context.Context, id int64) (internal.RolloutGroup, error)
+	CreateRolloutGroup(ctx context.Context, params internal.RolloutGroupParams) (internal.RolloutGroup, error)
+	UpdateRolloutGroup(ctx context.Context, id int64, params internal.RolloutGroupParams) (internal.RolloutGroup, error)
+	DeleteRolloutGroup(ctx context.Context, id int64) error
+	ListFirmwareRollouts(ctx context.Context) ([]internal.FirmwareRollout, error)
+	GetFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error)
+	CreateFirmwareRollout(ctx context.Context, params internal.FirmwareRolloutParams) (internal.FirmwareRollout, error)
+	AdvanceFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error)
+	PauseFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error)
+	ResumeFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error)
+	ListFirmwareRolloutCandidates(ctx context.Context, deviceID string) ([]internal.FirmwareRolloutCandidate, error)
+	OfferFirmwareUpdate(ctx context.Context, deviceID string, rolloutID int64) (internal.DeviceFirmwareUpdate, error)
+	RecordFirmwareUpdateStatus(ctx context.Context, deviceID string, rolloutID int64, status, errMsg string) (internal.DeviceFirmwareUpdate, *internal.FirmwareRollout, error)
+	ListDeviceFirmwareUpdates(ctx context.Context, rolloutID int64) ([]internal.DeviceFirmwareUpdate, error)
+}
+
+type Firmware struct {
+	store   FirmwareStore
+	blobs   blob.Store
+	devices DeviceGetter
+}
+
+func NewFirmware(store FirmwareStore, blobs blob.Store, devices DeviceGetter) *Firmware {
+	return &Firmware{
+		store:   store,
+		blobs:   blobs,
+		devices: devices,
+	}
+}
+
+// FirmwareDownloadURL is where devices fetch a release's image
+func FirmwareDownloadURL(releaseID int64) string {
+	return fmt.Sprintf("/api/firmware/releases/%d/download", releaseID)
+}
+
+func (s *Firmware) ListFirmwareReleases(ctx context.Context) ([]internal.FirmwareRelease, error) {
+	return s.store.ListFirmwareReleases(ctx)
+}
+
+func (s *Firmware) GetFirmwareRelease(ctx context.Context, id int64) (internal.FirmwareRelease, error) {
+	return s.store.GetFirmwareRelease(ctx, id)
+}
+
+// UploadFirmwareRelease stores a firmware image of size bytes read from r.
+// The checksum is computed while storing; if the uploader sent one too, the
+// two must agree.
+func (s *Firmware) UploadFirmwareRelease(ctx context.Context, version, notes, checksum string, r io.Reader, size int64) (internal.FirmwareRelease, error) {
+	v, err := firmware.Parse(version)
+	if err != nil {
+		return internal.FirmwareRelease{}, internal.NewInputError("%s", err.Error())
+	}
+	if size <= 0 {
+		return internal.FirmwareRelease{}, internal.NewInputError("firmware image is empty")
+	}
+	if size > MaxFirmwareSize {
+		return internal.FirmwareRelease{}, internal.NewInputError("firmware image must be at most %d bytes", MaxFirmwareSize)
+	}
+	checksum = strings.ToLower(strings.TrimSpace(checksum))
+
+	key := "firmware/" + uuid.NewString() + ".bin"
+	h := sha256.New()
+	if err := s.blobs.Put(ctx, key, io.TeeReader(io.LimitReader(r, size), h), size); err != nil {
+		return internal.FirmwareRelease{}, fmt.Errorf("failed to store firmware image: %w", err)
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if checksum != "" && checksum != sum {
+		s.deleteImage(ctx, key)
+		return internal.FirmwareRelease{}, internal.NewInputError("sha256 mismatch: uploaded image hashes to %s", sum)
+	}
+
+	release, err := s.store.CreateFirmwareRelease(ctx, internal.FirmwareReleaseParams{
+		Version:    v.String(),
+		SHA256:     sum,
+		Size:       size,
+		StorageKey: key,
+		Notes:      strings.TrimSpace(notes),
+	})
+	if err != nil {
+		s.deleteImage(ctx, key)
+		return internal.FirmwareRelease{}, err
+	}
+
+	return release, nil
+}
+
+func (s *Firmware) deleteImage(ctx context.Context, key string) {
+	if err := s.blobs.Delete(ctx, key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+		slog.Error("Failed to delete firmware image", "error", err, "key", key)
+	}
+}
+
+// DeleteFirmwareRelease deletes a release and its image. Releases that a
+// rollout used are kept for its history.
+func (s *Firmware) DeleteFirmwareRelease(ctx context.Context, id int64) error {
+	release, err := s.store.DeleteFirmwareRelease(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.deleteImage(ctx, release.StorageKey)
+	return nil
+}
+
+// OpenFirmwareRelease returns a release with a reader for its image
+func (s *Firmware) OpenFirmwareRelease(ctx context.Context, id int64) (internal.FirmwareRelease, io.ReadCloser, error) {
+	release, err := s.store.GetFirmwareRelease(ctx, id)
+	if err != nil {
+		return internal.FirmwareRelease{}, nil, err
+	}
+
+	r, err := s.blobs.Open(ctx, release.StorageKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		return internal.FirmwareRelease{}, nil, fmt.Errorf("firmware image for release %d is missing: %w", id, err)
+	}
+	if err != nil {
+		return internal.FirmwareRelease{}, nil, err
+	}
+
+	return release, r, nil
+}
+
+func validateRolloutGroup(params *internal.RolloutGroupParams) error {
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		return internal.NewInputError("name is required")
+	}
+
+	ids := make([]string, 0, len(params.DeviceIDs))
+	for _, id := range params.DeviceIDs {
+		id = strings.TrimSpace(id)
+		if err := internal.ValidateDeviceID(id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	params.DeviceIDs = slices.Compact(ids)
+
+	return nil
+}
+
+func (s *Firmware) ListRolloutGroups(ctx context.Context) ([]internal.RolloutGroup, error) {
+	return s.store.ListRolloutGroups(ctx)
+}
+
+func (s *Firmware) GetRolloutGroup(ctx context.Context, id int64) (internal.RolloutGroup, error) {
+	return s.store.GetRolloutGroup(ctx, id)
+}
+
+func (s *Firmware) CreateRolloutGroup(ctx context.Context, params internal.RolloutGroupParams) (internal.RolloutGroup, error) {
+	if err := validateRolloutGroup(&params); err != nil {
+		return internal.RolloutGroup{}, err
+	}
+
+	return s.store.CreateRolloutGroup(ctx, params)
+}
+
+func (s *Firmware) UpdateRolloutGroup(ctx context.Context, id int64, params internal.RolloutGroupParams) (internal.RolloutGroup, error) {
+	if err := validateRolloutGroup(&params); err != nil {
+		return internal.RolloutGroup{}, err
+	}
+
+	return s.store.UpdateRolloutGroup(ctx, id, params)
+}
+
+func (s *Firmware) DeleteRolloutGroup(ctx context.Context, id int64) error {
+	return s.store.DeleteRolloutGroup(ctx, id)
+}
+
+func validateRollout(params *internal.FirmwareRolloutParams) error {
+	if len(params.Stages) == 0 {
+		params.Stages = slices.Clone(defaultRolloutStages)
+	}
+	if params.FailureThreshold == 0 {
+		params.FailureThreshold = defaultFailureThreshold
+	}
+
+	prev := 0
+	for _, pct := range params.Stages {
+		if pct <= prev || pct > 100 {
+			return internal.NewInputError("stages must be increasing percentages between 1 and 100")
+		}
+		prev = pct
+	}
+	if prev != 100 {
+		return internal.NewInputError("the last stage must reach 100 percent")
+	}
+	if params.FailureThreshold < 1 {
+		return internal.NewInputError("failure_threshold must be at least 1")
+	}
+
+	return nil
+}
+
+func (s *Firmware) ListFirmwareRollouts(ctx context.Context) ([]internal.FirmwareRollout, error) {
+	return s.store.ListFirmwareRollouts(ctx)
+}
+
+func (s *Firmware) GetFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error) {
+	return s.store.GetFirmwareRollout(ctx, id)
+}
+
+// CreateFirmwareRollout starts offering a release at the first stage. Without
+// stages the release goes to 10, 50 and then 100 percent of its targets.
+func (s *Firmware) CreateFirmwareRollout(ctx context.Context, params internal.FirmwareRolloutParams) (internal.FirmwareRollout, error) {
+	if err := validateRollout(&params); err != nil {
+		return internal.FirmwareRollout{}, err
+	}
+
+	return s.store.CreateFirmwareRollout(ctx, params)
+}
+
+// AdvanceFirmwareRollout moves an active rollout to its next stage
+func (s *Firmware) AdvanceFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error) {
+	rollout, err := s.store.GetFirmwareRollout(ctx, id)
+	if err != nil {
+		return internal.FirmwareRollout{}, err
+	}
+	if rollout.Status != internal.RolloutActive {
+		return internal.FirmwareRollout{}, internal.NewInputError("rollout %d is %s; resume it first", id, rollout.Status)
+	}
+	if rollout.Stage >= len(rollout.Stages)-1 {
+		return internal.FirmwareRollout{}, internal.NewInputError("rollout %d is already at its last stage", id)
+	}
+
+	return s.store.AdvanceFirmwareRollout(ctx, id)
+}
+
+func (s *Firmware) PauseFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error) {
+	rollout, err := s.store.GetFirmwareRollout(ctx, id)
+	if err != nil {
+		return internal.FirmwareRollout{}, err
+	}
+	if rollout.Status != internal.RolloutActive {
+		return internal.FirmwareRollout{}, internal.NewInputError("rollout %d is %s, not active", id, rollout.Status)
+	}
+
+	return s.store.PauseFirmwareRollout(ctx, id)
+}
+
+// ResumeFirmwareRollout reactivates a paused or halted rollout. Failures
+// reported before it resumed no longer count towards halting it.
+func (s *Firmware) ResumeFirmwareRollout(ctx context.Context, id int64) (internal.FirmwareRollout, error) {
+	rollout, err := s.store.GetFirmwareRollout(ctx, id)
+	if err != nil {
+		return internal.FirmwareRollout{}, err
+	}
+	if rollout.Status == internal.RolloutActive {
+		return internal.FirmwareRollout{}, internal.NewInputError("rollout %d is already active", id)
+	}
+
+	return s.store.ResumeFirmwareRollout(ctx, id)
+}
+
+func (s *Firmware) ListDeviceFirmwareUpdates(ctx context.Context, rolloutID int64) ([]internal.DeviceFirmwareUpdate, error) {
+	if _, err := s.store.GetFirmwareRollout(ctx, rolloutID); err != nil {
+		return nil, err
+	}
+
+	return s.store.ListDeviceFirmwareUpdates(ctx, rolloutID)
+}
+
+// GetFirmwareUpdate tells a device whether to update. Of the active rollouts
+// whose current stage reaches it, the one with the newest release above the
+// device's firmware wins; rollouts it already failed are not offered again.
+func (s *Firmware) GetFirmwareUpdate(ctx context.Context, deviceID string) (internal.FirmwareOffer, error) {
+	device, err := s.devices.GetDevice(ctx, deviceID)
+	if err != nil {
+		return internal.FirmwareOffer{}, err
+	}
+
+	// Devices that never reported a parsable version take any release
+	current, currentErr := firmware.Parse(device.FirmwareVersion)
+
+	candidates, err := s.store.ListFirmwareRolloutCandidates(ctx, deviceID)
+	if err != nil {
+		return internal.FirmwareOffer{}, err
+	}
+
+	var best *internal.FirmwareRolloutCandidate
+	var bestVersion firmware.Version
+	for i, c := range candidates {
+		if c.UpdateStatus != nil && *c.UpdateStatus == internal.UpdateFailed {
+			continue
+		}
+		if !firmware.InStage(deviceID, c.Rollout.ID, c.Rollout.Percent()) {
+			continue
+		}
+		v, err := firmware.Parse(c.Release.Version)
+		if err != nil {
+			continue
+		}
+		if currentErr == nil && v.Compare(current) <= 0 {
+			continue
+		}
+		if best == nil || v.Compare(bestVersion) > 0 {
+			best, bestVersion = &candidates[i], v
+		}
+	}
+
+	offer := internal.FirmwareOffer{CurrentVersion: device.FirmwareVersion}
+	if best == nil {
+		return offer, nil
+	}
+
+	update, err := s.store.OfferFirmwareUpdate(ctx, deviceID, best.Rollout.ID)
+	if err != nil {
+		return internal.FirmwareOffer{}, err
+	}
+
+	offer.UpdateAvailable = true
+	offer.Version = best.Release.Version
+	offer.SHA256 = best.Release.SHA256
+	offer.Size = best.Release.Size
+	offer.URL = FirmwareDownloadURL(best.Release.ID)
+	offer.RolloutID = best.Rollout.ID
+	offer.Status = update.Status
+	return offer, nil
+}
+
+// ReportFirmwareUpdateStatus records how a device is getting on with an
+// offered update. Enough failures halt the rollout.
+func (s *Firmware) ReportFirmwareUpdateStatus(ctx context.Context, deviceID string, rolloutID int64, status, errMsg string) (internal.DeviceFirmwareUpdate, error) {
+	switch status {
+	case internal.UpdateDownloading, internal.UpdateInstalling, internal.UpdateSucceeded:
+		errMsg = ""
+	case internal.UpdateFailed:
+		errMsg = strings.TrimSpace(errMsg)
+	default:
+		return internal.DeviceFirmwareUpdate{}, internal.NewInputError(
+			"status must be one of %s, %s, %s or %s",
+			internal.UpdateDownloading, internal.UpdateInstalling, internal.UpdateSucceeded, internal.UpdateFailed,
+		)
+	}
+
+	update, halted, err := s.store.RecordFirmwareUpdateStatus(ctx, deviceID, rolloutID, status, errMsg)
+	if err != nil {
+		return internal.DeviceFirmwareUpdate{}, err
+	}
+
+	if halted != nil {
+		slog.Warn("Halted firmware rollout after failed updates",
+			"rollout_id", halted.ID,
+			"version", halted.Version,
+			"failures", halted.Failures,
+			"device_id", deviceID,
+		)
+	}
+
+	return update, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/advisor"
+	"github.com/lulzshadowwalker/green-backend/internal/llm"
+)
+
+// fakeReadings serves a fixed set of readings to the language model service
+type fakeReadings struct {
+	SensorReadingsStore
+	readings []internal.SensorReading
+}
+
+func (f fakeReadings) GetSensorReadingsSince(ctx context.Context, since time.Time) ([]internal.SensorReading, error) {
+	return f.readings, nil
+}
+
+// adviceRecorder records advice along with where it came from
+type adviceRecorder struct {
+	strings.Builder
+	sources []string
+}
+
+func (w *adviceRecorder) SetAdviceSource(source string) {
+	w.sources = append(w.sources, source)
+}
+
+func TestStreamPlantAdviceFallsBackWhenTheModelIsSlowToStart(t *testing.T) {
+	provider := &llm.Fake{Reply: "Water the plants in the morning.", Delay: time.Second}
+	s := newLLMService(fakeReadings{}, provider, WithFirstTokenTimeout(20*time.Millisecond))
+
+	var w adviceRecorder
+	start := time.Now()
+	if err := s.StreamPlantAdvice(context.Background(), nil, "tomato", internal.Locale{}, &w); err != nil {
+		t.Fatalf("StreamPlantAdvice() error = %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed >= provider.Delay {
+		t.Errorf("advice took %s, want the fallback well before the model's first word at %s", elapsed, provider.Delay)
+	}
+	if len(w.sources) != 1 || w.sources[0] != AdviceSourceRules {
+		t.Errorf("advice sources = %v, want only %q", w.sources, AdviceSourceRules)
+	}
+	if strings.Contains(w.String(), provider.Reply) {
+		t.Errorf("advice %q contains the late model reply", w.String())
+	}
+	if w.Len() == 0 {
+		t.Error("no offline advice was written")
+	}
+}
+
+func TestFitSummariesStaysWithinBudget(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	var summaries []advisor.Summary
+	for _, sensor := range []string{"temperature", "humidity", "light", "soil"} {
+		s := advisor.Summary{
+			SensorType: sensor,
+			Count:      720,
+			From:       now.Add(-6 * time.Hour),
+			To:         now,
+			Min:        10,
+			Max:        40,
+			Mean:       25,
+			Latest:     30,
+			Limit:      &internal.Range{Min: 15, Max: 30},
+		}
+		for i := 0; i < 30; i++ {
+			at := s.From.Add(time.Duration(i) * 10 * time.Minute)
+			s.Excursions = append(s.Excursions, advisor.Excursion{Problem: "high", Bound: 30, From: at, To: at.Add(5 * time.Minute), Peak: 35})
+		}
+		summaries = append(summaries, s)
+	}
+
+	model := llm.KindFake
+	for _, locale := range []internal.Locale{
+		{Language: internal.LanguageEnglish, TemperatureUnit: internal.TemperatureCelsius},
+		{Language: internal.LanguageArabic, TemperatureUnit: internal.TemperatureCelsius},
+	} {
+		var full strings.Builder
+		writeSummaries(&full, summaries, len(summaries[0].Excursions), false, locale)
+		var latest strings.Builder
+		writeSummaries(&latest, summaries, 0, true, locale)
+
+		for _, budget := range []int{llm.CountTokens(model, latest.String()), 200, 400, maxReadingsTokens} {
+			t.Run(fmt.Sprintf("%s/%d", locale.Language, budget), func(t *testing.T) {
+				got := fitSummaries(model, summaries, budget, locale)
+				if n := llm.CountTokens(model, got); n > budget {
+					t.Errorf("summary takes %d tokens, over the budget of %d", n, budget)
+				}
+			})
+		}
+
+		if got := fitSummaries(model, summaries, llm.CountTokens(model, full.String()), locale); got != full.String() {
+			t.Errorf("%s summary with room for everything left details out", locale.Language)
+		}
+	}
+}
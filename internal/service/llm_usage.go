@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// ErrLLMBudgetExceeded is returned when the day's language model budget has
+// been spent. Advice falls back to the offline advisor instead.
+var ErrLLMBudgetExceeded = errors.New("the daily language model budget has been used up")
+
+type LLMUsageStore interface {
+	CreateLLMUsage(ctx context.Context, params internal.CreateLLMUsageParams) (internal.LLMUsage, error)
+	SumLLMTokens(ctx context.Context, since time.Time, userID *int) (int64, error)
+	SumAnonymousLLMTokens(ctx context.Context, since time.Time) (int64, error)
+	SummarizeLLMUsage(ctx context.Context, params internal.SummarizeLLMUsageParams) ([]internal.LLMUsageTotal, error)
+}
+
+// LLMPricing is what the provider charges, in US dollars per million tokens
+type LLMPricing struct {
+	PromptPerMillion     float64
+	CompletionPerMillion float64
+}
+
+// LLMUsage records what each language model request cost and keeps spending
+// within the daily budgets. Budgets are checked before a request is made, so
+// requests running at the same time may overshoot them slightly.
+type LLMUsage struct {
+	store   LLMUsageStore
+	budget  internal.LLMBudget
+	pricing LLMPricing
+}
+
+func NewLLMUsage(store LLMUsageStore, budget internal.LLMBudget, pricing LLMPricing) *LLMUsage {
+	return &LLMUsage{store: store, budget: budget, pricing: pricing}
+}
+
+// startOfDay is when the current budget day began; budgets reset at
+// midnight UTC
+func startOfDay(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour)
+}
+
+// CheckLLMBudget returns ErrLLMBudgetExceeded if today's tokens, overall or
+// for userID, are spent. Requests made without signing in share the
+// anonymous budget, so leaving out the token does not lift the per-user cap.
+func (s *LLMUsage) CheckLLMBudget(ctx context.Context, userID *int) error {
+	day := startOfDay(time.Now())
+
+	if s.budget.DailyTokens > 0 {
+		used, err := s.store.SumLLMTokens(ctx, day, nil)
+		if err != nil {
+			return fmt.Errorf("failed to check language model budget: %w", err)
+		}
+		if used >= s.budget.DailyTokens {
+			return fmt.Errorf("%w: %d of %d tokens spent today", ErrLLMBudgetExceeded, used, s.budget.DailyTokens)
+		}
+	}
+
+	if s.budget.UserDailyTokens > 0 && userID != nil {
+		used, err := s.store.SumLLMTokens(ctx, day, userID)
+		if err != nil {
+			return fmt.Errorf("failed to check language model budget: %w", err)
+		}
+		if used >= s.budget.UserDailyTokens {
+			return fmt.Errorf("%w: %d of your %d tokens spent today", ErrLLMBudgetExceeded, used, s.budget.UserDailyTokens)
+		}
+	}
+
+	if s.budget.AnonymousDailyTokens > 0 && userID == nil {
+		used, err := s.store.SumAnonymousLLMTokens(ctx, day)
+		if err != nil {
+			return fmt.Errorf("failed to check language model budget: %w", err)
+		}
+		if used >= s.budget.AnonymousDailyTokens {
+			return fmt.Errorf("%w: %d of %d tokens for guests spent today", ErrLLMBudgetExceeded, used, s.budget.AnonymousDailyTokens)
+		}
+	}
+
+	return nil
+}
+
+// RecordLLMUsage saves a request's usage, pricing it unless it was answered
+// from the cache
+func (s *LLMUsage) RecordLLMUsage(ctx context.Context, params internal.CreateLLMUsageParams) (internal.LLMUsage, error) {
+	if !params.Cached {
+		params.CostUSD = (float64(params.PromptTokens)*s.pricing.PromptPerMillion +
+			float64(params.CompletionTokens)*s.pricing.CompletionPerMillion) / 1_000_000
+	}
+
+	return s.store.CreateLLMUsage(ctx, params)
+}
+
+// LLMUsageReport totals usage per day, feature and user between
+// params.From and params.To, along with what has been spent of today's
+// budget
+func (s *LLMUsage) LLMUsageReport(ctx context.Context, params internal.SummarizeLLMUsageParams) (internal.LLMUsageReport, error) {
+	totals, err := s.store.SummarizeLLMUsage(ctx, params)
+	if err != nil {
+		return internal.LLMUsageReport{}, err
+	}
+	if totals == nil {
+		totals = []internal.LLMUsageTotal{}
+	}
+
+	day := startOfDay(time.Now())
+	today, err := s.store.SumLLMTokens(ctx, day, nil)
+	if err != nil {
+		return internal.LLMUsageReport{}, err
+	}
+
+	report := internal.LLMUsageReport{
+		From:        params.From,
+		To:          params.To,
+		Totals:      totals,
+		Budget:      s.budget,
+		TokensToday: today,
+	}
+	if params.UserID != nil {
+		userToday, err := s.store.SumLLMTokens(ctx, day, params.UserID)
+		if err != nil {
+			return internal.LLMUsageReport{}, err
+		}
+		report.UserTokensToday = &userToday
+	}
+
+	return report, nil
+}
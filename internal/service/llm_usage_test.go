@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// fakeLLMUsage holds today's token totals: overall, per user and for
+// requests made without signing in
+type fakeLLMUsage struct {
+	LLMUsageStore
+	total     int64
+	users     map[int]int64
+	anonymous int64
+}
+
+func (f fakeLLMUsage) SumLLMTokens(ctx context.Context, since time.Time, userID *int) (int64, error) {
+	if userID == nil {
+		return f.total, nil
+	}
+	return f.users[*userID], nil
+}
+
+func (f fakeLLMUsage) SumAnonymousLLMTokens(ctx context.Context, since time.Time) (int64, error) {
+	return f.anonymous, nil
+}
+
+func TestCheckLLMBudget(t *testing.T) {
+	budget := internal.LLMBudget{DailyTokens: 10_000, UserDailyTokens: 1_000, AnonymousDailyTokens: 500}
+	spender, saver := 1, 2
+	store := fakeLLMUsage{total: 2_000, users: map[int]int64{spender: 1_000, saver: 999}, anonymous: 500}
+
+	tests := []struct {
+		name     string
+		store    fakeLLMUsage
+		userID   *int
+		exceeded bool
+	}{
+		{"user within budget", store, &saver, false},
+		{"user over budget", store, &spender, true},
+		{"anonymous over budget", store, nil, true},
+		{"anonymous within budget", fakeLLMUsage{total: 2_000, anonymous: 499}, nil, false},
+		{"global budget spent for users", fakeLLMUsage{total: 10_000, users: map[int]int64{saver: 0}}, &saver, true},
+		{"global budget spent for guests", fakeLLMUsage{total: 10_000}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewLLMUsage(tt.store, budget, LLMPricing{}).CheckLLMBudget(context.Background(), tt.userID)
+			if got := errors.Is(err, ErrLLMBudgetExceeded); got != tt.exceeded {
+				t.Errorf("CheckLLMBudget() error = %v, want exceeded %t", err, tt.exceeded)
+			}
+			if err != nil && !errors.Is(err, ErrLLMBudgetExceeded) {
+				t.Errorf("CheckLLMBudget() error = %v", err)
+			}
+		})
+	}
+
+	t.Run("no budgets", func(t *testing.T) {
+		spent := fakeLLMUsage{total: 1 << 40, users: map[int]int64{spender: 1 << 40}, anonymous: 1 << 40}
+		for _, userID := range []*int{nil, &spender} {
+			if err := NewLLMUsage(spent, internal.LLMBudget{}, LLMPricing{}).CheckLLMBudget(context.Background(), userID); err != nil {
+				t.Errorf("CheckLLMBudget() without budgets error = %v", err)
+			}
+		}
+	})
+}
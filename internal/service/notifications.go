@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/notify"
+)
+
+// deliveryTimeout bounds how long a notification is retried in the background
+const deliveryTimeout = 15 * time.Minute
+
+type NotificationChannelsStore interface {
+	ListNotificationChannels(ctx context.Context, userID int) ([]internal.NotificationChannel, error)
+	ListEnabledNotificationChannels(ctx context.Context) ([]internal.NotificationChannel, error)
+	GetNotificationChannel(ctx context.Context, userID int, id int64) (internal.NotificationChannel, error)
+	CreateNotificationChannel(ctx context.Context, userID int, params internal.NotificationChannelParams) (internal.NotificationChannel, error)
+	UpdateNotificationChannel(ctx context.Context, userID int, id int64, params internal.NotificationChannelParams) (internal.NotificationChannel, error)
+	DeleteNotificationChannel(ctx context.Context, userID int, id int64) error
+}
+
+type Notifications struct {
+	store    NotificationChannelsStore
+	settings notify.Settings
+	backoff  notify.Backoff
+}
+
+func NewNotifications(store NotificationChannelsStore, settings notify.Settings) *Notifications {
+	return &Notifications{
+		store:    store,
+		settings: settings,
+		backoff:  notify.DefaultBackoff(),
+	}
+}
+
+func (s *Notifications) validate(params *internal.NotificationChannelParams) error {
+	if params.Config == nil {
+		params.Config = map[string]string{}
+	}
+	for _, k := range internal.RedactedConfigKeys {
+		if params.Config[k] == internal.RedactedConfigValue {
+			return internal.NewInputError("%s must be the value itself, not the redacted placeholder", k)
+		}
+	}
+	if _, err := notify.New(params.Kind, params.Config, s.settings); err != nil {
+		return internal.NewInputError("%s", err.Error())
+	}
+
+	if params.Name == "" {
+		params.Name = params.Kind
+	}
+
+	if params.MinSeverity == "" {
+		params.MinSeverity = internal.SeverityWarning
+	}
+	switch params.MinSeverity {
+	case internal.SeverityInfo, internal.SeverityWarning, internal.SeverityCritical:
+	default:
+		return internal.NewInputError("unknown severity %q", params.MinSeverity)
+	}
+
+	if params.Timezone == "" {
+		params.Timezone = "UTC"
+	}
+	if (params.QuietHoursStart == nil) != (params.QuietHoursEnd == nil) {
+		return internal.NewInputError("quiet hours need both a start and an end")
+	}
+	if params.QuietHoursStart != nil {
+		if _, err := notify.ParseQuietHours(*params.QuietHoursStart, *params.QuietHoursEnd, params.Timezone); err != nil {
+			return internal.NewInputError("%s", err.Error())
+		}
+	} else if _, err := time.LoadLocation(params.Timezone); err != nil {
+		return internal.NewInputError("unknown time zone %q", params.Timezone)
+	}
+
+	return nil
+}
+
+func (s *Notifications) ListNotificationChannels(ctx context.Context, userID int) ([]internal.NotificationChannel, error) {
+	return s.store.ListNotificationChannels(ctx, userID)
+}
+
+func (s *Notifications) CreateNotificationChannel(ctx context.Context, userID int, params internal.NotificationChannelParams) (internal.NotificationChannel, error) {
+	if err := s.validate(&params); err != nil {
+		return internal.NotificationChannel{}, err
+	}
+
+	return s.store.CreateNotificationChannel(ctx, userID, params)
+}
+
+// UpdateNotificationChannel replaces a channel's settings. Redacted settings
+// sent back as they were listed keep their stored values.
+func (s *Notifications) UpdateNotificationChannel(ctx context.Context, userID int, id int64, params internal.NotificationChannelParams) (internal.NotificationChannel, error) {
+	var current *internal.NotificationChannel
+	for _, k := range internal.RedactedConfigKeys {
+		if params.Config[k] != internal.RedactedConfigValue {
+			continue
+		}
+		if current == nil {
+			channel, err := s.store.GetNotificationChannel(ctx, userID, id)
+			if err != nil {
+				return internal.NotificationChannel{}, err
+			}
+			current = &channel
+		}
+		if v, ok := current.Config[k]; ok && current.Kind == params.Kind {
+			params.Config[k] = v
+		}
+	}
+
+	if err := s.validate(&params); err != nil {
+		return internal.NotificationChannel{}, err
+	}
+
+	return s.store.UpdateNotificationChannel(ctx, userID, id, params)
+}
+
+func (s *Notifications) DeleteNotificationChannel(ctx context.Context, userID int, id int64) error {
+	return s.store.DeleteNotificationChannel(ctx, userID, id)
+}
+
+// TestNotificationChannel sends a test message once, ignoring quiet hours, so
+// that a misconfigured channel fails in front of the user
+func (s *Notifications) TestNotificationChannel(ctx context.Context, userID int, id int64) error {
+	channel, err := s.store.GetNotificationChannel(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	notifier, err := notify.New(channel.Kind, channel.Config, s.settings)
+	if err != nil {
+		return internal.NewInputError("%s", err.Error())
+	}
+
+	return notifier.Notify(ctx, notify.Notification{
+		Title:    "Green: test notification",
+		Body:     fmt.Sprintf("Notifications for %q are working.", channel.Name),
+		Severity: internal.SeverityInfo,
+		At:       time.Now(),
+	})
+}
+
+func alertNotification(alert internal.Alert) notify.Notification {
+	n := notify.Notification{
+		Severity:   alert.Severity,
+		AlertID:    alert.ID,
+		SensorType: alert.SensorType,
+		Status:     alert.Status,
+		At:         alert.UpdatedAt,
+	}
+
+	if alert.Status == internal.AlertResolved {
+		n.Title = fmt.Sprintf("Resolved: %s alert", alert.SensorType)
+		n.Body = fmt.Sprintf("%s\n\nOpened %s, resolved %s.", alert.Message,
+			alert.OpenedAt.Format(time.RFC1123), alert.UpdatedAt.Format(time.RFC1123))
+		return n
+	}
+
+	n.Title = fmt.Sprintf("[%s] %s alert", strings.ToUpper(alert.Severity), alert.SensorType)
+	n.Body = fmt.Sprintf("%s\n\nOpened %s.", alert.Message, alert.OpenedAt.Format(time.RFC1123))
+	return n
+}
+
+// NotifyAlert delivers an opened or resolved alert to every enabled channel
+// that wants it. Delivery, including retries, happens in the background so
+// that ingest is never held up by a slow channel.
+func (s *Notifications) NotifyAlert(ctx context.Context, alert internal.Alert) error {
+	channels, err := s.store.ListEnabledNotificationChannels(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list notification channels because %w", err)
+	}
+
+	n := alertNotification(alert)
+	now := time.Now()
+	for _, channel := range channels {
+		if !internal.SeverityAtLeast(alert.Severity, channel.MinSeverity) {
+			continue
+		}
+		if channel.QuietHoursStart != nil && alert.Severity != internal.SeverityCritical {
+			quiet, err := notify.ParseQuietHours(*channel.QuietHoursStart, *channel.QuietHoursEnd, channel.Timezone)
+			if err == nil && quiet.Contains(now) {
+				slog.Info("notification suppressed by quiet hours", "channel_id", channel.ID, "alert_id", alert.ID)
+				continue
+			}
+		}
+
+		notifier, err := notify.New(channel.Kind, channel.Config, s.settings)
+		if err != nil {
+			slog.Error("notification channel is misconfigured", "channel_id", channel.ID, "error", err)
+			continue
+		}
+
+		go s.deliver(context.WithoutCancel(ctx), channel, notifier, n)
+	}
+
+	return nil
+}
+
+func (s *Notifications) deliver(ctx context.Context, channel internal.NotificationChannel, notifier notify.Notifier, n notify.Notification) {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	if err := notify.Send(ctx, notifier, n, s.backoff); err != nil {
+		slog.Error("failed to deliver notification",
+			"channel_id", channel.ID,
+			"kind", channel.Kind,
+			"alert_id", n.AlertID,
+			"error", err,
+		)
+		return
+	}
+
+	slog.Info("delivered notification", "channel_id", channel.ID, "kind", channel.Kind, "alert_id", n.AlertID)
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// DefaultClaimTTL is how long a claim may wait for an admin, and then for
+// its device to collect the key, before it expires
+const DefaultClaimTTL = 24 * time.Hour
+
+const (
+	minClaimCodeLength  = 8
+	maxClaimCodeLength  = 128
+	claimCodeHintLength = 4
+)
+
+type ProvisioningStore interface {
+	ListDeviceClaims(ctx context.Context, status string) ([]internal.DeviceClaim, error)
+	GetDeviceClaim(ctx context.Context, id int64) (internal.DeviceClaim, error)
+	GetDeviceClaimByCode(ctx context.Context, codeHash string) (internal.DeviceClaim, error)
+	CreateDeviceClaim(ctx context.Context, codeHash, codeHint string, req internal.ProvisionRequest, expiresAt time.Time) (internal.DeviceClaim, error)
+	ReopenDeviceClaim(ctx context.Context, id int64, req internal.ProvisionRequest, expiresAt time.Time) (internal.DeviceClaim, error)
+	ApproveDeviceClaim(ctx context.Context, id int64, approval internal.ClaimApproval, expiresAt time.Time) (internal.DeviceClaim, error)
+	RejectDeviceClaim(ctx context.Context, id int64, by string) (internal.DeviceClaim, error)
+	CompleteDeviceClaim(ctx context.Context, id int64, keyHash string) (internal.DeviceClaim, error)
+	ExpireDeviceClaims(ctx context.Context) ([]internal.DeviceClaim, error)
+	AuthenticateDeviceAPIKey(ctx context.Context, keyHash string) (string, error)
+	DeviceHasAPIKey(ctx context.Context, deviceID string) (bool, error)
+	RevokeDeviceAPIKey(ctx context.Context, deviceID string) error
+}
+
+// DeviceConfigGetter looks up the configuration a device should run with
+type DeviceConfigGetter interface {
+	GetDeviceConfig(ctx context.Context, deviceID string) (internal.DeviceConfigDocument, error)
+}
+
+type Provisioning struct {
+	store   ProvisioningStore
+	configs DeviceConfigGetter
+	ttl     time.Duration
+}
+
+func NewProvisioning(store ProvisioningStore, configs DeviceConfigGetter, ttl time.Duration) *Provisioning {
+	if ttl <= 0 {
+		ttl = DefaultClaimTTL
+	}
+
+	return &Provisioning{
+		store:   store,
+		configs: configs,
+		ttl:     ttl,
+	}
+}
+
+// hashSecret is how claim codes and API keys are stored. Both are long and
+// random, so a fast unsalted hash is enough and lets them be looked up.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func newDeviceAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "gdk_" + hex.EncodeToString(b), nil
+}
+
+func claimExpired(claim internal.DeviceClaim, now time.Time) bool {
+	switch claim.Status {
+	case internal.ClaimExpired:
+		return true
+	case internal.ClaimPending, internal.ClaimApproved:
+		return !claim.ExpiresAt.After(now)
+	default:
+		return false
+	}
+}
+
+// Provision handles a board booting with its factory claim code. The first
+// request opens a claim for an admin to review; once it is approved, the
+// next request collects the device's API key and config. Devices should
+// retry while the claim is pending.
+func (s *Provisioning) Provision(ctx context.Context, req internal.ProvisionRequest) (internal.ProvisionResult, error) {
+	req.ClaimCode = strings.TrimSpace(req.ClaimCode)
+	req.DeviceID = strings.TrimSpace(req.DeviceID)
+	req.FirmwareVersion = strings.TrimSpace(req.FirmwareVersion)
+	if n := len(req.ClaimCode); n < minClaimCodeLength || n > maxClaimCodeLength {
+		return internal.ProvisionResult{}, internal.NewInputError("claim_code must be %d to %d characters", minClaimCodeLength, maxClaimCodeLength)
+	}
+	if err := internal.ValidateDeviceID(req.DeviceID); err != nil {
+		return internal.ProvisionResult{}, err
+	}
+
+	now := time.Now()
+	codeHash := hashSecret(req.ClaimCode)
+	claim, err := s.store.GetDeviceClaimByCode(ctx, codeHash)
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		hint := req.ClaimCode[len(req.ClaimCode)-claimCodeHintLength:]
+		if claim, err = s.store.CreateDeviceClaim(ctx, codeHash, hint, req, now.Add(s.ttl)); err != nil {
+			return internal.ProvisionResult{}, err
+		}
+		slog.Info("Device requested provisioning", "claim_id", claim.ID, "device_id", claim.DeviceID)
+		return pendingResult(claim), nil
+	case err != nil:
+		return internal.ProvisionResult{}, err
+	case claimExpired(claim, now):
+		if claim, err = s.store.ReopenDeviceClaim(ctx, claim.ID, req, now.Add(s.ttl)); err != nil {
+			return internal.ProvisionResult{}, err
+		}
+		slog.Info("Device requested provisioning again after its claim expired", "claim_id", claim.ID, "device_id", claim.DeviceID)
+		return pendingResult(claim), nil
+	}
+
+	if claim.DeviceID != req.DeviceID {
+		return internal.ProvisionResult{}, internal.NewInputError("claim code is in use by another device")
+	}
+
+	switch claim.Status {
+	case internal.ClaimPending:
+		return pendingResult(claim), nil
+	case internal.ClaimRejected:
+		return internal.ProvisionResult{}, internal.NewInputError("claim was rejected")
+	case internal.ClaimClaimed:
+		return internal.ProvisionResult{}, internal.ErrConflict
+	}
+
+	key, err := newDeviceAPIKey()
+	if err != nil {
+		return internal.ProvisionResult{}, fmt.Errorf("failed to generate device api key because %w", err)
+	}
+	if claim, err = s.store.CompleteDeviceClaim(ctx, claim.ID, hashSecret(key)); err != nil {
+		return internal.ProvisionResult{}, err
+	}
+
+	config, err := s.configs.GetDeviceConfig(ctx, claim.DeviceID)
+	if err != nil {
+		return internal.ProvisionResult{}, err
+	}
+
+	slog.Info("Device provisioned", "claim_id", claim.ID, "device_id", claim.DeviceID)
+
+	return internal.ProvisionResult{
+		Status:   claim.Status,
+		DeviceID: claim.DeviceID,
+		APIKey:   key,
+		Config:   &config,
+	}, nil
+}
+
+func pendingResult(claim internal.DeviceClaim) internal.ProvisionResult {
+	expiresAt := claim.ExpiresAt
+	return internal.ProvisionResult{
+		Status:    claim.Status,
+		DeviceID:  claim.DeviceID,
+		ExpiresAt: &expiresAt,
+	}
+}
+
+func (s *Provisioning) ListDeviceClaims(ctx context.Context, status string) ([]internal.DeviceClaim, error) {
+	switch status {
+	case "", internal.ClaimPending, internal.ClaimApproved, internal.ClaimRejected, internal.ClaimClaimed, internal.ClaimExpired:
+	default:
+		return nil, internal.NewInputError("unknown claim status %q", status)
+	}
+
+	return s.store.ListDeviceClaims(ctx, status)
+}
+
+func (s *Provisioning) GetDeviceClaim(ctx context.Context, id int64) (internal.DeviceClaim, error) {
+	return s.store.GetDeviceClaim(ctx, id)
+}
+
+// ApproveDeviceClaim admits a pending claim, registering the device under
+// the given name and zone. The device then has a full TTL to collect its key.
+func (s *Provisioning) ApproveDeviceClaim(ctx context.Context, id int64, approval internal.ClaimApproval) (internal.DeviceClaim, error) {
+	claim, err := s.store.GetDeviceClaim(ctx, id)
+	if err != nil {
+		return internal.DeviceClaim{}, err
+	}
+	now := time.Now()
+	if claimExpired(claim, now) {
+		return internal.DeviceClaim{}, internal.NewInputError("claim %d has expired; the device must ask again", id)
+	}
+	if claim.Status != internal.ClaimPending {
+		return internal.DeviceClaim{}, internal.NewInputError("claim %d is %s, not pending", id, claim.Status)
+	}
+
+	approval.Name = strings.TrimSpace(approval.Name)
+	approval.ReviewedBy = strings.TrimSpace(approval.ReviewedBy)
+	if approval.Zone != nil {
+		zone := strings.TrimSpace(*approval.Zone)
+		approval.Zone = &zone
+		if zone == "" {
+			approval.Zone = nil
+		}
+	}
+
+	return s.store.ApproveDeviceClaim(ctx, id, approval, now.Add(s.ttl))
+}
+
+// RejectDeviceClaim turns a claim down. A rejected claim code cannot be used
+// again.
+func (s *Provisioning) RejectDeviceClaim(ctx context.Context, id int64, by string) (internal.DeviceClaim, error) {
+	claim, err := s.store.GetDeviceClaim(ctx, id)
+	if err != nil {
+		return internal.DeviceClaim{}, err
+	}
+	if claim.Status != internal.ClaimPending && claim.Status != internal.ClaimApproved {
+		return internal.DeviceClaim{}, internal.NewInputError("claim %d is %s and can no longer be rejected", id, claim.Status)
+	}
+
+	return s.store.RejectDeviceClaim(ctx, id, strings.TrimSpace(by))
+}
+
+// ExpireDeviceClaims expires claims that were not approved, or not collected
+// after approval, in time
+func (s *Provisioning) ExpireDeviceClaims(ctx context.Context) error {
+	claims, err := s.store.ExpireDeviceClaims(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to expire device claims because %w", err)
+	}
+
+	for _, c := range claims {
+		slog.Info("device claim expired", "claim_id", c.ID, "device_id", c.DeviceID, "code_hint", c.CodeHint)
+	}
+
+	return nil
+}
+
+// AuthenticateDevice returns the device an API key was issued to
+func (s *Provisioning) AuthenticateDevice(ctx context.Context, key string) (string, error) {
+	return s.store.AuthenticateDeviceAPIKey(ctx, hashSecret(key))
+}
+
+// DeviceHasAPIKey reports whether a device must authenticate with an API key
+func (s *Provisioning) DeviceHasAPIKey(ctx context.Context, deviceID string) (bool, error) {
+	return s.store.DeviceHasAPIKey(ctx, deviceID)
+}
+
+// RevokeDeviceAPIKey withdraws a device's API key, such as when a board is
+// lost or retired
+func (s *Provisioning) RevokeDeviceAPIKey(ctx context.Context, deviceID string) error {
+	return s.store.RevokeDeviceAPIKey(ctx, deviceID)
+}
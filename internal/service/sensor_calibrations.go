@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// recalibrationBatchSize is the number of readings rewritten per statement
+// when a calibration is recomputed
+const recalibrationBatchSize = 1000
+
+type SensorCalibrationsStore interface {
+	ListSensorCalibrations(ctx context.Context) ([]internal.SensorCalibration, error)
+	ListSensorCalibrationsBySensorType(ctx context.Context, sensorType string) ([]internal.SensorCalibration, error)
+	GetSensorCalibration(ctx context.Context, id int64) (internal.SensorCalibration, error)
+	CreateSensorCalibration(ctx context.Context, params internal.CreateSensorCalibrationParams) (internal.SensorCalibration, error)
+	UpdateSensorCalibration(ctx context.Context, id int64, params internal.UpdateSensorCalibrationParams) (internal.SensorCalibration, error)
+	ClearSensorCalibrationRecomputeFrom(ctx context.Context, id int64, updatedAt time.Time) error
+}
+
+// RecalibrationStore gives calibrations access to the readings they correct
+type RecalibrationStore interface {
+	GetSensorReadingsForRecalibration(ctx context.Context, sensorType string, after time.Time, afterID int64, limit int) ([]internal.SensorReading, error)
+	UpdateCalibratedValues(ctx context.Context, readings []internal.SensorReading) error
+}
+
+// QualityReplayer re-grades readings whose values have been rewritten
+type QualityReplayer interface {
+	NewQualityReplay(ctx context.Context) (*QualityReplay, error)
+}
+
+type SensorCalibrations struct {
+	store    SensorCalibrationsStore
+	readings RecalibrationStore
+	quality  QualityReplayer
+}
+
+func NewSensorCalibrations(store SensorCalibrationsStore, readings RecalibrationStore, quality QualityReplayer) *SensorCalibrations {
+	return &SensorCalibrations{
+		store:    store,
+		readings: readings,
+		quality:  quality,
+	}
+}
+
+func validateCalibration(method string, coefficients []float64) error {
+	switch method {
+	case internal.CalibrationLinear, internal.CalibrationTwoPoint:
+		if len(coefficients) != 2 {
+			return internal.NewInputError("%s calibration needs exactly two coefficients", method)
+		}
+	case internal.CalibrationPolynomial:
+		if len(coefficients) == 0 || len(coefficients) > 6 {
+			return internal.NewInputError("polynomial calibration needs between one and six coefficients")
+		}
+	default:
+		return internal.NewInputError("unknown calibration method %q", method)
+	}
+
+	return nil
+}
+
+func (s *SensorCalibrations) ListSensorCalibrations(ctx context.Context) ([]internal.SensorCalibration, error) {
+	return s.store.ListSensorCalibrations(ctx)
+}
+
+func (s *SensorCalibrations) CreateSensorCalibration(ctx context.Context, params internal.CreateSensorCalibrationParams) (internal.SensorCalibration, error) {
+	def, ok := internal.LookupSensor(params.SensorType)
+	if !ok || def.Virtual {
+		return internal.SensorCalibration{}, internal.NewInputError("unknown sensor type %q", params.SensorType)
+	}
+	params.SensorType = def.Type
+
+	if err := validateCalibration(params.Method, params.Coefficients); err != nil {
+		return internal.SensorCalibration{}, err
+	}
+
+	return s.store.CreateSensorCalibration(ctx, params)
+}
+
+// UpdateSensorCalibration corrects a calibration. Stored readings keep their
+// old values until RecomputeSensorCalibration is called, which starts from
+// the earlier of the old and new effective_from.
+func (s *SensorCalibrations) UpdateSensorCalibration(ctx context.Context, id int64, params internal.UpdateSensorCalibrationParams) (internal.SensorCalibration, error) {
+	if err := validateCalibration(params.Method, params.Coefficients); err != nil {
+		return internal.SensorCalibration{}, err
+	}
+
+	return s.store.UpdateSensorCalibration(ctx, id, params)
+}
+
+// Calibrate fills in the calibrated value of a reading about to be stored,
+// keeping what the sensor reported as its raw value
+func (s *SensorCalibrations) Calibrate(ctx context.Context, params internal.CreateSensorReadingParams) (internal.CreateSensorReadingParams, error) {
+	params.RawValue = params.Value
+
+	calibrations, err := s.store.ListSensorCalibrationsBySensorType(ctx, params.SensorType)
+	if err != nil {
+		return params, fmt.Errorf("failed to load calibrations for %s: %w", params.SensorType, err)
+	}
+
+	deviceID := ""
+	if params.DeviceID != nil {
+		deviceID = *params.DeviceID
+	}
+	if c := internal.EffectiveCalibration(calibrations, deviceID, time.Now()); c != nil {
+		params.Value = c.Apply(params.RawValue)
+		params.CalibrationID = &c.ID
+	}
+
+	return params, nil
+}
+
+// CalibrationReplay calibrates readings in bulk, such as an import, against
+// the calibrations in effect when each was taken
+type CalibrationReplay struct {
+	calibrations map[string][]internal.SensorCalibration
+}
+
+// NewCalibrationReplay loads every calibration for a replay
+func (s *SensorCalibrations) NewCalibrationReplay(ctx context.Context) (*CalibrationReplay, error) {
+	calibrations, err := s.store.ListSensorCalibrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calibrations: %w", err)
+	}
+
+	replay := &CalibrationReplay{calibrations: map[string][]internal.SensorCalibration{}}
+	for _, c := range calibrations {
+		replay.calibrations[c.SensorType] = append(replay.calibrations[c.SensorType], c)
+	}
+
+	return replay, nil
+}
+
+// Calibrate treats r.Value as what the sensor reported and fills in the
+// calibrated value, keeping the reported one as the raw value
+func (c *CalibrationReplay) Calibrate(r internal.SensorReading) internal.SensorReading {
+	r.RawValue, r.CalibrationID = r.Value, nil
+
+	deviceID := ""
+	if r.DeviceID != nil {
+		deviceID = *r.DeviceID
+	}
+	if cal := internal.EffectiveCalibration(c.calibrations[r.SensorType], deviceID, r.Timestamp); cal != nil {
+		r.Value = cal.Apply(r.RawValue)
+		r.CalibrationID = &cal.ID
+	}
+
+	return r
+}
+
+// RecomputeSensorCalibration re-derives the stored value of every reading the
+// calibration may affect from its raw value, returning how many were
+// rewritten. If effective_from was moved later, the readings it no longer
+// covers are recomputed too. Each rewritten reading is graded again, against
+// the readings rewritten before it.
+func (s *SensorCalibrations) RecomputeSensorCalibration(ctx context.Context, id int64) (int, error) {
+	calibration, err := s.store.GetSensorCalibration(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	calibrations, err := s.store.ListSensorCalibrationsBySensorType(ctx, calibration.SensorType)
+	if err != nil {
+		return 0, err
+	}
+
+	since := calibration.EffectiveFrom
+	if calibration.RecomputeFrom != nil && calibration.RecomputeFrom.Before(since) {
+		since = *calibration.RecomputeFrom
+	}
+
+	replay, err := s.quality.NewQualityReplay(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	after, afterID := since, int64(0)
+	for {
+		batch, err := s.readings.GetSensorReadingsForRecalibration(ctx, calibration.SensorType, after, afterID, recalibrationBatchSize)
+		if err != nil {
+			return updated, err
+		}
+		if len(batch) == 0 {
+			if calibration.RecomputeFrom == nil {
+				return updated, nil
+			}
+			// An update made meanwhile leaves its readings to be recomputed
+			return updated, s.store.ClearSensorCalibrationRecomputeFrom(ctx, id, calibration.UpdatedAt)
+		}
+
+		for i := range batch {
+			r := &batch[i]
+			r.Value, r.CalibrationID = r.RawValue, nil
+			deviceID := ""
+			if r.DeviceID != nil {
+				deviceID = *r.DeviceID
+			}
+			if c := internal.EffectiveCalibration(calibrations, deviceID, r.Timestamp); c != nil {
+				r.Value = c.Apply(r.RawValue)
+				r.CalibrationID = &c.ID
+			}
+			r.Quality, r.QualityReason = replay.Assess(*r)
+		}
+
+		if err := s.readings.UpdateCalibratedValues(ctx, batch); err != nil {
+			return updated, err
+		}
+		updated += len(batch)
+
+		last := batch[len(batch)-1]
+		after = last.Timestamp
+		afterID, err = strconv.ParseInt(last.ID, 10, 64)
+		if err != nil {
+			return updated, err
+		}
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"log/slog"
 	"time"
 
 	"github.com/lulzshadowwalker/green-backend/internal"
@@ -13,6 +14,12 @@ type SensorControlsService interface {
 	GetSensorControlByType(ctx context.Context, sensorType string) (internal.SensorControl, error)
 	SetSensorControlMode(ctx context.Context, sensorType, mode string, manualUntil *time.Time) (internal.SensorControl, error)
 	SetSensorControlModeWithValue(ctx context.Context, sensorType, mode string, manualUntil *time.Time, manualIntValue *int, manualBoolValue *bool) (internal.SensorControl, error)
+	// ControlsChanged returns a channel that is closed the next time a
+	// control is set through this process
+	ControlsChanged() <-chan struct{}
+	// AuditSuppressions records when silences and maintenance windows
+	// start and stop pausing automation
+	AuditSuppressions(ctx context.Context) error
 }
 
 // SensorControlsStore defines the data access interface for sensor controls.
@@ -23,18 +30,141 @@ type SensorControlsStore interface {
 	InsertOrUpdateSensorControl(ctx context.Context, sensorType, mode string, manualUntil *time.Time, manualIntValue *int, manualBoolValue *bool) (internal.SensorControl, error)
 }
 
+// ControlSuppressor reports the silences and maintenance windows in effect,
+// during which automation is suspended.
+type ControlSuppressor interface {
+	ActiveSuppressions(ctx context.Context, at time.Time) ([]internal.Suppression, error)
+}
+
+// ControlAuditor keeps the audit log of when automation is paused and
+// resumed
+type ControlAuditor interface {
+	ListAuditEntries(ctx context.Context, filter internal.AuditFilter, limit int) ([]internal.AuditEntry, error)
+	RecordAuditEntry(ctx context.Context, entityType, entityID, action, actor string, data any) error
+}
+
+// suppressionActor is who the audit log says paused or resumed automation
+const suppressionActor = "system"
+
 // sensorControlsService is the concrete implementation of SensorControlsService.
 type sensorControlsService struct {
-	store SensorControlsStore
+	store      SensorControlsStore
+	suppressor ControlSuppressor
+	auditor    ControlAuditor
+	changed    *signal
+}
+
+type SensorControlsOption func(*sensorControlsService)
+
+// WithControlSuppressor flags automatic controls covered by a silence or
+// maintenance window as suppressed.
+func WithControlSuppressor(suppressor ControlSuppressor) SensorControlsOption {
+	return func(s *sensorControlsService) {
+		s.suppressor = suppressor
+	}
+}
+
+// WithControlAuditor records in the audit log when suppressions pause and
+// resume automation.
+func WithControlAuditor(auditor ControlAuditor) SensorControlsOption {
+	return func(s *sensorControlsService) {
+		s.auditor = auditor
+	}
 }
 
 // NewSensorControlsService creates a new SensorControlsService.
-func NewSensorControlsService(store SensorControlsStore) SensorControlsService {
-	return &sensorControlsService{store: store}
+func NewSensorControlsService(store SensorControlsStore, opts ...SensorControlsOption) SensorControlsService {
+	s := &sensorControlsService{
+		store:   store,
+		changed: newSignal(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *sensorControlsService) GetAllSensorControls(ctx context.Context) ([]internal.SensorControl, error) {
-	return s.store.GetAllSensorControls(ctx)
+	controls, err := s.store.GetAllSensorControls(ctx)
+	if err != nil || s.suppressor == nil {
+		return controls, err
+	}
+
+	active, err := s.suppressor.ActiveSuppressions(ctx, time.Now())
+	if err != nil {
+		// Devices keep running their automation rather than losing control
+		// status altogether
+		slog.Error("failed to check control suppressions", "error", err)
+		return controls, nil
+	}
+
+	for i, c := range controls {
+		controls[i].Suppressed = len(suppressionsOf(c, active)) > 0
+	}
+
+	return controls, nil
+}
+
+// suppressionsOf returns the suppressions in active that pause c's
+// automation. Controls in manual mode are never paused.
+func suppressionsOf(c internal.SensorControl, active []internal.Suppression) []internal.Suppression {
+	matching := []internal.Suppression{}
+	if c.Mode != "automatic" {
+		return matching
+	}
+	for _, sup := range active {
+		if sup.MatchesControl(c.SensorType) {
+			matching = append(matching, sup)
+		}
+	}
+	return matching
+}
+
+// AuditSuppressions records a suppress entry in the audit log when a control
+// starts being paused and a resume entry when it stops. Each control's last
+// entry says whether it was paused, so nothing is recorded twice.
+func (s *sensorControlsService) AuditSuppressions(ctx context.Context) error {
+	if s.suppressor == nil || s.auditor == nil {
+		return nil
+	}
+
+	controls, err := s.store.GetAllSensorControls(ctx)
+	if err != nil {
+		return err
+	}
+	active, err := s.suppressor.ActiveSuppressions(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, c := range controls {
+		matching := suppressionsOf(c, active)
+		c.Suppressed = len(matching) > 0
+
+		last, err := s.auditor.ListAuditEntries(ctx, internal.AuditFilter{EntityType: internal.AuditSensorControl, EntityID: c.SensorType}, 1)
+		if err != nil {
+			return err
+		}
+		wasSuppressed := len(last) > 0 && last[0].Action == internal.AuditSuppress
+		if c.Suppressed == wasSuppressed {
+			continue
+		}
+
+		action, msg := internal.AuditResume, "control automation resumed"
+		if c.Suppressed {
+			action, msg = internal.AuditSuppress, "control automation paused"
+		}
+		err = s.auditor.RecordAuditEntry(ctx, internal.AuditSensorControl, c.SensorType, action, suppressionActor, map[string]any{
+			"control":      c,
+			"suppressions": matching,
+		})
+		if err != nil {
+			return err
+		}
+		slog.Info(msg, "sensor_type", c.SensorType, "suppressions", len(matching))
+	}
+
+	return nil
 }
 
 func (s *sensorControlsService) GetSensorControlByType(ctx context.Context, sensorType string) (internal.SensorControl, error) {
@@ -42,10 +172,20 @@ func (s *sensorControlsService) GetSensorControlByType(ctx context.Context, sens
 }
 
 func (s *sensorControlsService) SetSensorControlMode(ctx context.Context, sensorType, mode string, manualUntil *time.Time) (internal.SensorControl, error) {
-	// Use InsertOrUpdate to ensure the row exists for the sensor type.
-	return s.store.InsertOrUpdateSensorControl(ctx, sensorType, mode, manualUntil, nil, nil)
+	return s.SetSensorControlModeWithValue(ctx, sensorType, mode, manualUntil, nil, nil)
 }
 
 func (s *sensorControlsService) SetSensorControlModeWithValue(ctx context.Context, sensorType, mode string, manualUntil *time.Time, manualIntValue *int, manualBoolValue *bool) (internal.SensorControl, error) {
-	return s.store.InsertOrUpdateSensorControl(ctx, sensorType, mode, manualUntil, manualIntValue, manualBoolValue)
+	// Use InsertOrUpdate to ensure the row exists for the sensor type.
+	control, err := s.store.InsertOrUpdateSensorControl(ctx, sensorType, mode, manualUntil, manualIntValue, manualBoolValue)
+	if err != nil {
+		return control, err
+	}
+
+	s.changed.notify()
+	return control, nil
+}
+
+func (s *sensorControlsService) ControlsChanged() <-chan struct{} {
+	return s.changed.wait()
 }
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+// defaultHealthEventsLimit caps how many device-health events are listed
+const defaultHealthEventsLimit = 100
+
+type SensorQualityStore interface {
+	ListSensorQualityRules(ctx context.Context) ([]internal.SensorQualityRule, error)
+	GetSensorQualityRule(ctx context.Context, sensorType string) (internal.SensorQualityRule, error)
+	UpsertSensorQualityRule(ctx context.Context, rule internal.SensorQualityRule) (internal.SensorQualityRule, error)
+	ListDeviceHealthEvents(ctx context.Context, limit int) ([]internal.DeviceHealthEvent, error)
+	ListOpenDeviceHealthEvents(ctx context.Context, sensorType string) ([]internal.DeviceHealthEvent, error)
+	GetOpenDeviceHealthEvent(ctx context.Context, sensorType string, deviceID *string, kind string) (internal.DeviceHealthEvent, error)
+	CreateDeviceHealthEvent(ctx context.Context, sensorType string, deviceID *string, kind, message string) (internal.DeviceHealthEvent, error)
+	ResolveDeviceHealthEvent(ctx context.Context, id int64) error
+}
+
+// QualityReadingsStore gives quality checks access to recent readings, kept
+// apart by device so that boards sharing a sensor type are judged alone
+type QualityReadingsStore interface {
+	GetLatestUsableDeviceReading(ctx context.Context, sensorType string, deviceID *string) (internal.SensorReading, error)
+	GetSensorReadingStatsSince(ctx context.Context, sensorType string, since time.Time) ([]internal.SensorReadingStats, error)
+}
+
+type SensorQuality struct {
+	store    SensorQualityStore
+	readings QualityReadingsStore
+}
+
+func NewSensorQuality(store SensorQualityStore, readings QualityReadingsStore) *SensorQuality {
+	return &SensorQuality{
+		store:    store,
+		readings: readings,
+	}
+}
+
+func (s *SensorQuality) ListSensorQualityRules(ctx context.Context) ([]internal.SensorQualityRule, error) {
+	return s.store.ListSensorQualityRules(ctx)
+}
+
+func (s *SensorQuality) UpsertSensorQualityRule(ctx context.Context, rule internal.SensorQualityRule) (internal.SensorQualityRule, error) {
+	def, ok := internal.LookupSensor(rule.SensorType)
+	if !ok || def.Virtual {
+		return internal.SensorQualityRule{}, internal.NewInputError("unknown sensor type %q", rule.SensorType)
+	}
+	rule.SensorType = def.Type
+
+	if rule.Min >= rule.Max {
+		return internal.SensorQualityRule{}, internal.NewInputError("min_value must be less than max_value")
+	}
+	if rule.MaxChangePerMinute != nil && *rule.MaxChangePerMinute <= 0 {
+		return internal.SensorQualityRule{}, internal.NewInputError("max_change_per_minute must be positive")
+	}
+	if rule.FlatlineMinutes < 5 {
+		return internal.SensorQualityRule{}, internal.NewInputError("flatline_minutes must be at least 5")
+	}
+	if rule.FlatlineTolerance < 0 {
+		return internal.SensorQualityRule{}, internal.NewInputError("flatline_tolerance cannot be negative")
+	}
+
+	return s.store.UpsertSensorQualityRule(ctx, rule)
+}
+
+func (s *SensorQuality) ListDeviceHealthEvents(ctx context.Context) ([]internal.DeviceHealthEvent, error) {
+	return s.store.ListDeviceHealthEvents(ctx, defaultHealthEventsLimit)
+}
+
+// Assess flags readings that fall outside the plausible range of their sensor
+// or change faster than it physically can since the device's previous
+// reading. Sensors without a rule are accepted as good.
+func (s *SensorQuality) Assess(ctx context.Context, params internal.CreateSensorReadingParams) (internal.CreateSensorReadingParams, error) {
+	params.Quality = internal.QualityGood
+
+	rule, err := s.store.GetSensorQualityRule(ctx, params.SensorType)
+	if errors.Is(err, internal.ErrNotFound) {
+		return params, nil
+	}
+	if err != nil {
+		return params, fmt.Errorf("failed to get quality rule because %w", err)
+	}
+
+	var prev *internal.SensorReading
+	last, err := s.readings.GetLatestUsableDeviceReading(ctx, params.SensorType, params.DeviceID)
+	switch {
+	case err == nil:
+		prev = &last
+	case !errors.Is(err, internal.ErrNotFound):
+		return params, fmt.Errorf("failed to get previous reading because %w", err)
+	}
+
+	params.Quality, params.QualityReason = rule.Assess(params.Value, time.Now(), prev)
+
+	return params, nil
+}
+
+// QualityReplay grades readings that are already stored, or about to be
+// stored in bulk, the way Assess grades them as they arrive. Readings should
+// be fed in the order they were taken; each is judged against the latest
+// usable reading of the same sensor on the same device fed before it.
+type QualityReplay struct {
+	rules map[string]internal.SensorQualityRule
+	prev  map[string]internal.SensorReading
+}
+
+// NewQualityReplay loads the quality rules for a replay
+func (s *SensorQuality) NewQualityReplay(ctx context.Context) (*QualityReplay, error) {
+	rules, err := s.store.ListSensorQualityRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quality rules because %w", err)
+	}
+
+	replay := &QualityReplay{
+		rules: make(map[string]internal.SensorQualityRule, len(rules)),
+		prev:  map[string]internal.SensorReading{},
+	}
+	for _, rule := range rules {
+		replay.rules[rule.SensorType] = rule
+	}
+
+	return replay, nil
+}
+
+// Assess grades r against the latest usable reading assessed before it
+func (q *QualityReplay) Assess(r internal.SensorReading) (quality, reason string) {
+	rule, ok := q.rules[r.SensorType]
+	if !ok {
+		return internal.QualityGood, ""
+	}
+
+	key := r.SensorType + "|"
+	if r.DeviceID != nil {
+		key += *r.DeviceID
+	}
+
+	var prev *internal.SensorReading
+	p, ok := q.prev[key]
+	if ok {
+		prev = &p
+	}
+	quality, reason = rule.Assess(r.Value, r.Timestamp, prev)
+	if quality != internal.QualityBad && (!ok || r.Timestamp.After(p.Timestamp)) {
+		q.prev[key] = r
+	}
+
+	return quality, reason
+}
+
+// DetectFlatlines raises a device-health event for every sensor on every
+// device whose usable readings have not moved beyond its flatline tolerance
+// for its whole flatline window, and resolves events for sensors that have
+// recovered, gone silent or no longer report enough to tell.
+func (s *SensorQuality) DetectFlatlines(ctx context.Context) error {
+	rules, err := s.store.ListSensorQualityRules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list quality rules because %w", err)
+	}
+
+	for _, rule := range rules {
+		if err := s.detectFlatline(ctx, rule); err != nil {
+			return fmt.Errorf("failed to check %s for flatlines because %w", rule.SensorType, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *SensorQuality) detectFlatline(ctx context.Context, rule internal.SensorQualityRule) error {
+	window := time.Duration(rule.FlatlineMinutes) * time.Minute
+	since := time.Now().Add(-window)
+
+	devices, err := s.readings.GetSensorReadingStatsSince(ctx, rule.SensorType, since)
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, stats := range devices {
+		seen[deviceKey(stats.DeviceID)] = true
+		if err := s.checkFlatline(ctx, rule, window, since, stats); err != nil {
+			return err
+		}
+	}
+
+	// A device with no usable readings in the window is not in the stats,
+	// but neither is it stuck any more
+	open, err := s.store.ListOpenDeviceHealthEvents(ctx, rule.SensorType)
+	if err != nil {
+		return err
+	}
+	for _, event := range open {
+		if seen[deviceKey(event.DeviceID)] || (event.Kind != internal.HealthStuck && event.Kind != internal.HealthFlatline) {
+			continue
+		}
+		if err := s.store.ResolveDeviceHealthEvent(ctx, event.ID); err != nil {
+			return err
+		}
+		slog.Info("sensor health event closed, no recent readings", "sensor_type", rule.SensorType, "device_id", deviceKey(event.DeviceID), "kind", event.Kind, "event_id", event.ID)
+	}
+
+	return nil
+}
+
+// deviceKey is deviceID, or empty for readings from no device
+func deviceKey(deviceID *string) string {
+	if deviceID == nil {
+		return ""
+	}
+	return *deviceID
+}
+
+// checkFlatline raises or resolves the health events of one device's sensor
+func (s *SensorQuality) checkFlatline(ctx context.Context, rule internal.SensorQualityRule, window time.Duration, since time.Time, stats internal.SensorReadingStats) error {
+	sensor, device := rule.SensorType, ""
+	if stats.DeviceID != nil {
+		device = *stats.DeviceID
+		sensor = fmt.Sprintf("%s on %s", rule.SensorType, device)
+	}
+
+	// Too few readings, or readings covering only part of the window, say
+	// nothing about whether the sensor is stuck, so no event stays open on
+	// them; missing data is a separate problem
+	spread := stats.Max - stats.Min
+	var kind, message string
+	switch {
+	case stats.Count < 3 || stats.First.After(since.Add(window/2)):
+	case spread == 0:
+		kind = internal.HealthStuck
+		message = fmt.Sprintf("%s has reported exactly %g for %d readings over %s", sensor, stats.Min, stats.Count, window)
+	case spread <= rule.FlatlineTolerance:
+		kind = internal.HealthFlatline
+		message = fmt.Sprintf("%s has stayed within %g of %g for %s", sensor, spread, stats.Min, window)
+	}
+
+	for _, k := range []string{internal.HealthStuck, internal.HealthFlatline} {
+		open, err := s.store.GetOpenDeviceHealthEvent(ctx, rule.SensorType, stats.DeviceID, k)
+		if errors.Is(err, internal.ErrNotFound) {
+			if k == kind {
+				event, err := s.store.CreateDeviceHealthEvent(ctx, rule.SensorType, stats.DeviceID, kind, message)
+				if err != nil {
+					return err
+				}
+				slog.Warn("sensor health degraded", "sensor_type", rule.SensorType, "device_id", device, "kind", kind, "event_id", event.ID)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if k != kind {
+			if err := s.store.ResolveDeviceHealthEvent(ctx, open.ID); err != nil {
+				return err
+			}
+			slog.Info("sensor health recovered", "sensor_type", rule.SensorType, "device_id", device, "kind", k, "event_id", open.ID)
+		}
+	}
+
+	return nil
+}
@@ -2,36 +2,364 @@ package service
 
 import (
 	"context"
+	"io"
+	"log/slog"
+	"sort"
 	"time"
 
 	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/derived"
+	"github.com/lulzshadowwalker/green-backend/internal/importer"
 )
 
 type SensorReadings struct {
-	r SensorReadingsStore
+	r          SensorReadingsStore
+	derived    derived.Config
+	calibrator Calibrator
+	quality    QualityChecker
+	devices    DeviceTracker
+}
+
+// Calibrator corrects raw sensor values before they are stored
+type Calibrator interface {
+	Calibrate(ctx context.Context, params internal.CreateSensorReadingParams) (internal.CreateSensorReadingParams, error)
+	NewCalibrationReplay(ctx context.Context) (*CalibrationReplay, error)
+}
+
+// QualityChecker grades readings before they are stored
+type QualityChecker interface {
+	Assess(ctx context.Context, params internal.CreateSensorReadingParams) (internal.CreateSensorReadingParams, error)
+	NewQualityReplay(ctx context.Context) (*QualityReplay, error)
+}
+
+// DeviceTracker records that a device has just reported a reading
+type DeviceTracker interface {
+	DeviceSeen(ctx context.Context, deviceID, sensorType string) error
 }
 
 type SensorReadingsStore interface {
 	GetSensorReadings(ctx context.Context) ([]internal.SensorReading, error)
 	CreateSensorReading(ctx context.Context, params internal.CreateSensorReadingParams) (internal.SensorReading, error)
 	GetSensorReadingsSince(ctx context.Context, since time.Time) ([]internal.SensorReading, error)
+	GetLatestSensorReadings(ctx context.Context) ([]internal.SensorReading, error)
+	GetSensorReadingsBetween(ctx context.Context, sensorTypes []string, from, to time.Time) ([]internal.SensorReading, error)
+	AggregateSensorReadings(ctx context.Context, params internal.AggregateSensorReadingsParams) ([]internal.SensorAggregate, error)
+	StreamSensorReadings(ctx context.Context, params internal.ExportSensorReadingsParams, fn func(internal.SensorReading) error) error
+	ImportSensorReadings(ctx context.Context, next func() (internal.ImportSensorReadingParams, error), dryRun bool) (copied int, inserted int, err error)
+}
+
+type SensorReadingsOption func(*SensorReadings)
+
+// WithDerivedConfig sets how virtual sensors such as VPD and DLI are computed
+func WithDerivedConfig(cfg derived.Config) SensorReadingsOption {
+	return func(s *SensorReadings) {
+		s.derived = cfg
+	}
+}
+
+// WithCalibrator applies per-sensor calibration to readings on ingest
+func WithCalibrator(c Calibrator) SensorReadingsOption {
+	return func(s *SensorReadings) {
+		s.calibrator = c
+	}
+}
+
+// WithQualityChecker flags implausible readings on ingest
+func WithQualityChecker(q QualityChecker) SensorReadingsOption {
+	return func(s *SensorReadings) {
+		s.quality = q
+	}
 }
 
-func NewSensorReadings(r SensorReadingsStore) *SensorReadings {
-	return &SensorReadings{
-		r: r,
+// WithDeviceTracker updates the device registry as readings arrive. Tracker
+// failures are logged and never reject a reading.
+func WithDeviceTracker(t DeviceTracker) SensorReadingsOption {
+	return func(s *SensorReadings) {
+		s.devices = t
 	}
 }
 
-func (s SensorReadings) GetSensorReadings(ctx context.Context) ([]internal.SensorReading, error) {
-	return s.r.GetSensorReadings(ctx)
+func NewSensorReadings(r SensorReadingsStore, opts ...SensorReadingsOption) *SensorReadings {
+	s := &SensorReadings{
+		r:       r,
+		derived: derived.DefaultConfig(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 func (s SensorReadings) CreateSensorReading(ctx context.Context, params internal.CreateSensorReadingParams) (internal.SensorReading, error) {
+	params.RawValue = params.Value
+	if s.calibrator != nil {
+		calibrated, err := s.calibrator.Calibrate(ctx, params)
+		if err != nil {
+			return internal.SensorReading{}, err
+		}
+		params = calibrated
+	}
+
+	// Plausibility is judged on the calibrated value
+	if s.quality != nil {
+		assessed, err := s.quality.Assess(ctx, params)
+		if err != nil {
+			return internal.SensorReading{}, err
+		}
+		params = assessed
+	}
+
 	m, err := s.r.CreateSensorReading(ctx, params)
 	if err != nil {
 		return internal.SensorReading{}, err
 	}
 
+	if s.devices != nil && m.DeviceID != nil {
+		if err := s.devices.DeviceSeen(ctx, *m.DeviceID, m.SensorType); err != nil {
+			slog.Error("failed to update device last seen", "error", err, "device_id", *m.DeviceID)
+		}
+	}
+
 	return m, nil
 }
+
+// splitSensorTypes separates stored sensor types from virtual ones and returns
+// the stored types that must be fetched to answer for both
+func splitSensorTypes(sensorTypes []string) (raw, virtual, fetch []string) {
+	seen := map[string]bool{}
+	add := func(t string) {
+		if !seen[t] {
+			seen[t] = true
+			fetch = append(fetch, t)
+		}
+	}
+
+	for _, t := range sensorTypes {
+		if def, ok := internal.LookupSensor(t); ok && def.Virtual {
+			virtual = append(virtual, def.Type)
+			for _, src := range derived.Sources(def.Type) {
+				add(src)
+			}
+			continue
+		}
+		raw = append(raw, t)
+		add(t)
+	}
+
+	return raw, virtual, fetch
+}
+
+// computeVirtual derives a virtual sensor over [from, to) from readings
+// fetched for that window. DLI is a daily total, so its light is fetched
+// again over the whole days the window touches; otherwise the first and last
+// day would only count the light inside the window.
+func (s SensorReadings) computeVirtual(ctx context.Context, sensorType string, readings []internal.SensorReading, from, to time.Time) ([]internal.SensorReading, error) {
+	if sensorType != derived.TypeDLI {
+		return derived.Compute(s.derived, sensorType, readings)
+	}
+
+	loc := s.derived.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	start := from.In(loc)
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	end := to.In(loc)
+	if midnight := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc); !midnight.Equal(end) {
+		end = midnight.AddDate(0, 0, 1)
+	}
+	if now := time.Now(); end.After(now) {
+		end = now
+	}
+
+	light, err := s.r.GetSensorReadingsBetween(ctx, derived.Sources(derived.TypeDLI), start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return derived.Compute(s.derived, derived.TypeDLI, light)
+}
+
+// GetSensorReadingsBetween returns readings of the given stored or virtual
+// sensor types in [from, to), newest first. No sensor types means every
+// stored one.
+func (s SensorReadings) GetSensorReadingsBetween(ctx context.Context, sensorTypes []string, from, to time.Time) ([]internal.SensorReading, error) {
+	if len(sensorTypes) == 0 {
+		for _, def := range internal.SensorCatalog {
+			if !def.Virtual {
+				sensorTypes = append(sensorTypes, def.Type)
+			}
+		}
+	}
+	raw, virtual, fetch := splitSensorTypes(sensorTypes)
+
+	readings, err := s.r.GetSensorReadingsBetween(ctx, fetch, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := map[string]bool{}
+	for _, t := range raw {
+		wanted[t] = true
+	}
+
+	res := make([]internal.SensorReading, 0, len(readings))
+	for _, r := range readings {
+		if wanted[r.SensorType] {
+			res = append(res, r)
+		}
+	}
+
+	for _, t := range virtual {
+		series, err := s.computeVirtual(ctx, t, readings, from, to)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, series...)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Timestamp.After(res[j].Timestamp)
+	})
+
+	return res, nil
+}
+
+// GetLatestSensorReadings returns the most recent reading of every stored
+// sensor, along with the current value of every virtual sensor
+func (s SensorReadings) GetLatestSensorReadings(ctx context.Context) ([]internal.SensorReading, error) {
+	latest, err := s.r.GetLatestSensorReadings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := append([]internal.SensorReading{}, latest...)
+	for _, t := range []string{derived.TypeVPD, derived.TypeDewPoint, derived.TypeHeatIndex} {
+		series, err := derived.Compute(s.derived, t, latest)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, series...)
+	}
+
+	// DLI accumulates over the day, so today's value needs all of today's light
+	now := time.Now()
+	series, err := s.computeVirtual(ctx, derived.TypeDLI, nil, now, now)
+	if err != nil {
+		return nil, err
+	}
+	res = append(res, series...)
+
+	return res, nil
+}
+
+// AggregateSensorReadings buckets stored and virtual sensor readings between
+// params.From and params.To
+func (s SensorReadings) AggregateSensorReadings(ctx context.Context, params internal.AggregateSensorReadingsParams) ([]internal.SensorAggregate, error) {
+	raw, virtual, fetch := splitSensorTypes(params.SensorTypes)
+
+	var res []internal.SensorAggregate
+	if len(raw) > 0 {
+		rawParams := params
+		rawParams.SensorTypes = raw
+		aggregates, err := s.r.AggregateSensorReadings(ctx, rawParams)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, aggregates...)
+	}
+
+	if len(virtual) > 0 {
+		readings, err := s.r.GetSensorReadingsBetween(ctx, fetch, params.From, params.To)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range virtual {
+			series, err := s.computeVirtual(ctx, t, readings, params.From, params.To)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, derived.Aggregate(series, params.From, params.Bucket)...)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Bucket.Before(res[j].Bucket)
+	})
+
+	return res, nil
+}
+
+// ExportSensorReadings streams every reading matching params to fn without
+// loading the whole range into memory.
+func (s SensorReadings) ExportSensorReadings(ctx context.Context, params internal.ExportSensorReadingsParams, fn func(internal.SensorReading) error) error {
+	return s.r.StreamSensorReadings(ctx, params, fn)
+}
+
+// ImportSensorReadings bulk loads historical readings from a CSV or NDJSON
+// source. Rows that fail validation are listed in the report instead of
+// aborting the import; readings already stored are skipped. Imported readings
+// are calibrated and graded as they would have been on ingest, in the order
+// the source lists them.
+func (s SensorReadings) ImportSensorReadings(ctx context.Context, src io.Reader, opts importer.Options, dryRun bool) (internal.ImportReport, error) {
+	rd, err := importer.NewReader(src, opts)
+	if err != nil {
+		return internal.ImportReport{}, internal.NewInputError("%s", err.Error())
+	}
+
+	var calibrations *CalibrationReplay
+	if s.calibrator != nil {
+		calibrations, err = s.calibrator.NewCalibrationReplay(ctx)
+		if err != nil {
+			return internal.ImportReport{}, err
+		}
+	}
+	var qualities *QualityReplay
+	if s.quality != nil {
+		qualities, err = s.quality.NewQualityReplay(ctx)
+		if err != nil {
+			return internal.ImportReport{}, err
+		}
+	}
+
+	next := func() (internal.ImportSensorReadingParams, error) {
+		p, err := rd.Next()
+		if err != nil {
+			return p, err
+		}
+
+		r := internal.SensorReading{
+			SensorType: p.SensorType,
+			Value:      p.Value,
+			RawValue:   p.Value,
+			Timestamp:  p.Timestamp,
+			Quality:    internal.QualityGood,
+		}
+		if calibrations != nil {
+			r = calibrations.Calibrate(r)
+		}
+		// Plausibility is judged on the calibrated value
+		if qualities != nil {
+			r.Quality, r.QualityReason = qualities.Assess(r)
+		}
+
+		p.Value, p.RawValue, p.CalibrationID = r.Value, r.RawValue, r.CalibrationID
+		p.Quality, p.QualityReason = r.Quality, r.QualityReason
+		return p, nil
+	}
+
+	copied, inserted, err := s.r.ImportSensorReadings(ctx, next, dryRun)
+	if err != nil {
+		return rd.Report(), err
+	}
+
+	report := rd.Report()
+	report.DryRun = dryRun
+	report.Inserted = inserted
+	report.Duplicates = copied - inserted
+
+	return report, nil
+}
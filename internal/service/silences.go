@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/notify"
+)
+
+// defaultSilencesLimit caps how many silences are listed
+const defaultSilencesLimit = 100
+
+type SilencesStore interface {
+	ListAlertSilences(ctx context.Context, activeOnly bool, limit int) ([]internal.AlertSilence, error)
+	ListActiveAlertSilences(ctx context.Context, at time.Time) ([]internal.AlertSilence, error)
+	CreateAlertSilence(ctx context.Context, params internal.AlertSilenceParams) (internal.AlertSilence, error)
+	ExpireAlertSilence(ctx context.Context, id int64) (internal.AlertSilence, error)
+	ListMaintenanceWindows(ctx context.Context) ([]internal.MaintenanceWindow, error)
+	ListEnabledMaintenanceWindows(ctx context.Context) ([]internal.MaintenanceWindow, error)
+	CreateMaintenanceWindow(ctx context.Context, params internal.MaintenanceWindowParams) (internal.MaintenanceWindow, error)
+	UpdateMaintenanceWindow(ctx context.Context, id int64, params internal.MaintenanceWindowParams) (internal.MaintenanceWindow, error)
+	DeleteMaintenanceWindow(ctx context.Context, id int64) error
+}
+
+type Silences struct {
+	store SilencesStore
+}
+
+func NewSilences(store SilencesStore) *Silences {
+	return &Silences{store: store}
+}
+
+func validateSilenceMatcher(m *internal.SilenceMatcher) error {
+	if m.SensorType != nil {
+		sensorType := strings.TrimSpace(*m.SensorType)
+		if sensorType == "" {
+			return internal.NewInputError("sensor_type cannot be empty")
+		}
+		// Controls such as the pump are not in the sensor catalog, so
+		// unknown types are kept as given
+		if def, ok := internal.LookupSensor(sensorType); ok {
+			sensorType = def.Type
+		}
+		m.SensorType = &sensorType
+	}
+	if m.Zone != nil && strings.TrimSpace(*m.Zone) == "" {
+		return internal.NewInputError("zone cannot be empty")
+	}
+
+	return nil
+}
+
+func (s *Silences) ListAlertSilences(ctx context.Context, activeOnly bool) ([]internal.AlertSilence, error) {
+	return s.store.ListAlertSilences(ctx, activeOnly, defaultSilencesLimit)
+}
+
+// CreateAlertSilence silences matching alerts and automation from StartsAt,
+// or now if it is unset, until EndsAt
+func (s *Silences) CreateAlertSilence(ctx context.Context, params internal.AlertSilenceParams) (internal.AlertSilence, error) {
+	if err := validateSilenceMatcher(&params.SilenceMatcher); err != nil {
+		return internal.AlertSilence{}, err
+	}
+	if strings.TrimSpace(params.Reason) == "" {
+		return internal.AlertSilence{}, internal.NewInputError("reason is required")
+	}
+	if params.StartsAt.IsZero() {
+		params.StartsAt = time.Now()
+	}
+	if !params.EndsAt.After(params.StartsAt) {
+		return internal.AlertSilence{}, internal.NewInputError("ends_at must be after starts_at")
+	}
+	if !params.EndsAt.After(time.Now()) {
+		return internal.AlertSilence{}, internal.NewInputError("ends_at must be in the future")
+	}
+
+	return s.store.CreateAlertSilence(ctx, params)
+}
+
+func (s *Silences) ExpireAlertSilence(ctx context.Context, id int64) (internal.AlertSilence, error) {
+	return s.store.ExpireAlertSilence(ctx, id)
+}
+
+func validateMaintenanceWindow(params *internal.MaintenanceWindowParams) error {
+	if strings.TrimSpace(params.Name) == "" {
+		return internal.NewInputError("name is required")
+	}
+	if err := validateSilenceMatcher(&params.SilenceMatcher); err != nil {
+		return err
+	}
+
+	for _, d := range params.Weekdays {
+		if d < 0 || d > 6 {
+			return internal.NewInputError("weekdays must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	}
+	slices.Sort(params.Weekdays)
+	params.Weekdays = slices.Compact(params.Weekdays)
+	if params.Weekdays == nil {
+		params.Weekdays = []int{}
+	}
+
+	if params.Timezone == "" {
+		params.Timezone = "UTC"
+	}
+	hours, err := notify.ParseQuietHours(params.StartTime, params.EndTime, params.Timezone)
+	if err != nil {
+		return internal.NewInputError("%s", err.Error())
+	}
+	if hours.Start == hours.End {
+		return internal.NewInputError("start_time and end_time cannot be the same")
+	}
+
+	return nil
+}
+
+func (s *Silences) ListMaintenanceWindows(ctx context.Context) ([]internal.MaintenanceWindow, error) {
+	return s.store.ListMaintenanceWindows(ctx)
+}
+
+func (s *Silences) CreateMaintenanceWindow(ctx context.Context, params internal.MaintenanceWindowParams) (internal.MaintenanceWindow, error) {
+	if err := validateMaintenanceWindow(&params); err != nil {
+		return internal.MaintenanceWindow{}, err
+	}
+
+	return s.store.CreateMaintenanceWindow(ctx, params)
+}
+
+func (s *Silences) UpdateMaintenanceWindow(ctx context.Context, id int64, params internal.MaintenanceWindowParams) (internal.MaintenanceWindow, error) {
+	if err := validateMaintenanceWindow(&params); err != nil {
+		return internal.MaintenanceWindow{}, err
+	}
+
+	return s.store.UpdateMaintenanceWindow(ctx, id, params)
+}
+
+func (s *Silences) DeleteMaintenanceWindow(ctx context.Context, id int64) error {
+	return s.store.DeleteMaintenanceWindow(ctx, id)
+}
+
+// windowOpen reports whether a maintenance window is open at t. A window that
+// wraps past midnight belongs to the day it opened on.
+func windowOpen(w internal.MaintenanceWindow, t time.Time) (bool, error) {
+	hours, err := notify.ParseQuietHours(w.StartTime, w.EndTime, w.Timezone)
+	if err != nil {
+		return false, err
+	}
+	if !hours.Contains(t) {
+		return false, nil
+	}
+	if len(w.Weekdays) == 0 {
+		return true, nil
+	}
+
+	local := t.In(hours.Location)
+	day := local.Weekday()
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	if hours.Start > hours.End && clock < hours.End {
+		day = (day + 6) % 7
+	}
+
+	return slices.Contains(w.Weekdays, int(day)), nil
+}
+
+// ActiveSuppressions returns the silences and maintenance windows in effect
+// at t
+func (s *Silences) ActiveSuppressions(ctx context.Context, at time.Time) ([]internal.Suppression, error) {
+	silences, err := s.store.ListActiveAlertSilences(ctx, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active silences because %w", err)
+	}
+	windows, err := s.store.ListEnabledMaintenanceWindows(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list maintenance windows because %w", err)
+	}
+
+	res := make([]internal.Suppression, 0, len(silences))
+	for _, silence := range silences {
+		res = append(res, internal.Suppression{
+			SilenceMatcher: silence.SilenceMatcher,
+			Source:         "silence",
+			SourceID:       silence.ID,
+			Reason:         silence.Reason,
+		})
+	}
+	for _, w := range windows {
+		open, err := windowOpen(w, at)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance window %d is invalid because %w", w.ID, err)
+		}
+		if !open {
+			continue
+		}
+		reason := w.Reason
+		if reason == "" {
+			reason = w.Name
+		}
+		res = append(res, internal.Suppression{
+			SilenceMatcher: w.SilenceMatcher,
+			Source:         "maintenance_window",
+			SourceID:       w.ID,
+			Reason:         reason,
+		})
+	}
+
+	return res, nil
+}
+
+// RuleSilenced reports whether notifications for rule are suppressed at t
+func (s *Silences) RuleSilenced(ctx context.Context, rule internal.AlertRule, at time.Time) (bool, error) {
+	active, err := s.ActiveSuppressions(ctx, at)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.ContainsFunc(active, func(sup internal.Suppression) bool {
+		return sup.MatchesRule(rule)
+	}), nil
+}
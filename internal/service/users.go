@@ -11,6 +11,9 @@ import (
 
 type UserStore interface {
 	GetUserByUsername(ctx context.Context, username string) (internal.User, error)
+	GetUserRole(ctx context.Context, id int) (string, error)
+	GetUserLocale(ctx context.Context, id int) (internal.Locale, error)
+	UpdateUserLocale(ctx context.Context, id int, locale internal.Locale) (internal.Locale, error)
 }
 
 type UserService struct {
@@ -37,3 +40,36 @@ func (s *UserService) Authenticate(ctx context.Context, username, password strin
 	log.Printf("[AUTH] Login successful for user: '%s'", username)
 	return user, nil
 }
+
+// GetUserRole returns the role of the user, which decides whether they may
+// use admin routes
+func (s *UserService) GetUserRole(ctx context.Context, userID int) (string, error) {
+	return s.store.GetUserRole(ctx, userID)
+}
+
+// GetUserLocale returns the language and units the user wants advice in
+func (s *UserService) GetUserLocale(ctx context.Context, userID int) (internal.Locale, error) {
+	return s.store.GetUserLocale(ctx, userID)
+}
+
+// UpdateUserLocale changes the user's language and units. Fields left empty
+// keep their current value.
+func (s *UserService) UpdateUserLocale(ctx context.Context, userID int, locale internal.Locale) (internal.Locale, error) {
+	current, err := s.store.GetUserLocale(ctx, userID)
+	if err != nil {
+		return internal.Locale{}, err
+	}
+
+	if locale.Language != "" {
+		if locale.Language, err = internal.ParseLanguage(locale.Language); err != nil {
+			return internal.Locale{}, err
+		}
+	}
+	if locale.TemperatureUnit != "" {
+		if locale.TemperatureUnit, err = internal.ParseTemperatureUnit(locale.TemperatureUnit); err != nil {
+			return internal.Locale{}, err
+		}
+	}
+
+	return s.store.UpdateUserLocale(ctx, userID, locale.Or(current))
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+	"github.com/lulzshadowwalker/green-backend/internal/notify"
+)
+
+const (
+	// webhookBatchSize caps how many due deliveries one dispatch picks up
+	webhookBatchSize = 100
+	// webhookConcurrency caps how many deliveries are in flight at once
+	webhookConcurrency = 8
+	// webhookMaxAttempts is how many times a delivery is tried before it is
+	// moved to the dead-letter queue
+	webhookMaxAttempts = 10
+	// webhookRetryBase and webhookRetryMax bound the exponential backoff
+	// between attempts; ten attempts span roughly a day
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = 6 * time.Hour
+	// webhookRetention is how long delivered and dead events are kept
+	webhookRetention = 30 * 24 * time.Hour
+	// defaultWebhookDeliveriesLimit caps how many deliveries are listed
+	defaultWebhookDeliveriesLimit = 100
+
+	// WebhookEventHeader names the event type of a delivery
+	WebhookEventHeader = "X-Green-Event"
+	// WebhookDeliveryHeader carries the delivery ID, which stays the same
+	// across retries so receivers can drop duplicates
+	WebhookDeliveryHeader = "X-Green-Delivery"
+)
+
+type WebhooksStore interface {
+	ListWebhookSubscriptions(ctx context.Context) ([]internal.WebhookSubscription, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (internal.WebhookSubscription, error)
+	CreateWebhookSubscription(ctx context.Context, params internal.WebhookSubscriptionParams, secret string) (internal.WebhookSubscription, error)
+	UpdateWebhookSubscription(ctx context.Context, id int64, params internal.WebhookSubscriptionParams) (internal.WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, id int64) error
+	ListDueWebhookDeliveries(ctx context.Context, limit int) ([]internal.DueWebhookDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, deliveryID int64, result internal.WebhookAttemptResult) error
+	ListWebhookDeliveries(ctx context.Context, subscriptionID int64, status string, limit int) ([]internal.WebhookDelivery, error)
+	ListWebhookDeliveryAttempts(ctx context.Context, subscriptionID, deliveryID int64) ([]internal.WebhookDeliveryAttempt, error)
+	RetryWebhookDelivery(ctx context.Context, subscriptionID, deliveryID int64) error
+	PruneWebhookOutbox(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Webhooks struct {
+	store  WebhooksStore
+	client *http.Client
+}
+
+// metadataAddrs are cloud instance metadata endpoints that fall outside the
+// link-local ranges
+var metadataAddrs = []netip.Addr{
+	netip.MustParseAddr("fd00:ec2::254"),
+	netip.MustParseAddr("100.100.100.200"),
+}
+
+// errBlockedWebhookAddr is returned when a webhook would reach this host or
+// its cloud metadata service
+var errBlockedWebhookAddr = errors.New("webhook address is not publicly routable")
+
+// blockedWebhookAddr reports whether deliveries must not be sent to addr
+func blockedWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		slices.Contains(metadataAddrs, addr)
+}
+
+// webhookDialControl refuses connections to blocked addresses. It runs after
+// name resolution, so a host that resolved to a public address when the
+// subscription was saved cannot be repointed at this host later.
+func webhookDialControl(network, address string, c syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse webhook address %q because %w", address, err)
+	}
+	if blockedWebhookAddr(addrPort.Addr()) {
+		return fmt.Errorf("refusing to dial %s: %w", address, errBlockedWebhookAddr)
+	}
+	return nil
+}
+
+func NewWebhooks(store WebhooksStore, client *http.Client) *Webhooks {
+	if client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		// A proxy would be dialled instead of the receiver and slip past the check
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   webhookDialControl,
+		}).DialContext
+		client = &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	}
+
+	return &Webhooks{
+		store:  store,
+		client: client,
+	}
+}
+
+// webhookEnvelope is the body of every delivery
+type webhookEnvelope struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func validateWebhookSubscription(ctx context.Context, params *internal.WebhookSubscriptionParams) error {
+	u, err := url.Parse(params.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return internal.NewInputError("url must be an absolute http or https URL")
+	}
+	if err := validateWebhookHost(ctx, u.Hostname()); err != nil {
+		return err
+	}
+
+	if len(params.EventTypes) == 0 {
+		return internal.NewInputError("at least one event type is required")
+	}
+	for _, t := range params.EventTypes {
+		if !slices.Contains(internal.WebhookEventTypes, t) {
+			return internal.NewInputError("unknown event type %q", t)
+		}
+	}
+	slices.Sort(params.EventTypes)
+	params.EventTypes = slices.Compact(params.EventTypes)
+
+	return nil
+}
+
+// validateWebhookHost rejects hosts that are, or resolve to, an address
+// deliveries must not reach
+func validateWebhookHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if blockedWebhookAddr(addr) {
+			return internal.NewInputError("url must not point at a loopback, link-local or metadata address")
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return internal.NewInputError("url host %q could not be resolved", host)
+	}
+	for _, addr := range addrs {
+		if blockedWebhookAddr(addr) {
+			return internal.NewInputError("url must not point at a loopback, link-local or metadata address")
+		}
+	}
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func (s *Webhooks) ListWebhookSubscriptions(ctx context.Context) ([]internal.WebhookSubscription, error) {
+	return s.store.ListWebhookSubscriptions(ctx)
+}
+
+func (s *Webhooks) GetWebhookSubscription(ctx context.Context, id int64) (internal.WebhookSubscription, error) {
+	return s.store.GetWebhookSubscription(ctx, id)
+}
+
+// CreateWebhookSubscription registers a subscription with a fresh signing
+// secret, which is only ever returned here
+func (s *Webhooks) CreateWebhookSubscription(ctx context.Context, params internal.WebhookSubscriptionParams) (internal.WebhookSubscription, error) {
+	if err := validateWebhookSubscription(ctx, &params); err != nil {
+		return internal.WebhookSubscription{}, err
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return internal.WebhookSubscription{}, fmt.Errorf("failed to generate webhook secret because %w", err)
+	}
+
+	return s.store.CreateWebhookSubscription(ctx, params, secret)
+}
+
+func (s *Webhooks) UpdateWebhookSubscription(ctx context.Context, id int64, params internal.WebhookSubscriptionParams) (internal.WebhookSubscription, error) {
+	if err := validateWebhookSubscription(ctx, &params); err != nil {
+		return internal.WebhookSubscription{}, err
+	}
+
+	return s.store.UpdateWebhookSubscription(ctx, id, params)
+}
+
+func (s *Webhooks) DeleteWebhookSubscription(ctx context.Context, id int64) error {
+	return s.store.DeleteWebhookSubscription(ctx, id)
+}
+
+// ListWebhookDeliveries returns a subscription's recent deliveries. Passing
+// status "dead" lists its dead-letter queue.
+func (s *Webhooks) ListWebhookDeliveries(ctx context.Context, subscriptionID int64, status string) ([]internal.WebhookDelivery, error) {
+	switch status {
+	case "", internal.DeliveryPending, internal.DeliveryDelivered, internal.DeliveryDead:
+	default:
+		return nil, internal.NewInputError("unknown delivery status %q", status)
+	}
+
+	if _, err := s.store.GetWebhookSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	return s.store.ListWebhookDeliveries(ctx, subscriptionID, status, defaultWebhookDeliveriesLimit)
+}
+
+func (s *Webhooks) ListWebhookDeliveryAttempts(ctx context.Context, subscriptionID, deliveryID int64) ([]internal.WebhookDeliveryAttempt, error) {
+	return s.store.ListWebhookDeliveryAttempts(ctx, subscriptionID, deliveryID)
+}
+
+// RetryWebhookDelivery takes a delivery off the dead-letter queue
+func (s *Webhooks) RetryWebhookDelivery(ctx context.Context, subscriptionID, deliveryID int64) error {
+	return s.store.RetryWebhookDelivery(ctx, subscriptionID, deliveryID)
+}
+
+// DispatchWebhooks sends every delivery that is due, recording each attempt
+// and scheduling a retry or dead-lettering the ones that fail
+func (s *Webhooks) DispatchWebhooks(ctx context.Context) error {
+	due, err := s.store.ListDueWebhookDeliveries(ctx, webhookBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to list due webhook deliveries because %w", err)
+	}
+
+	sem := make(chan struct{}, webhookConcurrency)
+	var wg sync.WaitGroup
+	for _, d := range due {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			result := s.deliver(ctx, d)
+			if err := s.store.RecordWebhookAttempt(ctx, d.ID, result); err != nil {
+				slog.Error("failed to record webhook attempt", "error", err, "delivery_id", d.ID)
+				return
+			}
+
+			if result.Status == internal.DeliveryDead {
+				slog.Warn("webhook delivery moved to dead-letter queue",
+					"delivery_id", d.ID,
+					"subscription_id", d.SubscriptionID,
+					"event_type", d.EventType,
+					"error", result.Error,
+				)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (s *Webhooks) deliver(ctx context.Context, d internal.DueWebhookDelivery) internal.WebhookAttemptResult {
+	body, err := json.Marshal(webhookEnvelope{
+		ID:        d.EventID,
+		Type:      d.EventType,
+		CreatedAt: d.EventCreatedAt,
+		Data:      d.Payload,
+	})
+	if err != nil {
+		return internal.WebhookAttemptResult{Status: internal.DeliveryDead, Error: err.Error()}
+	}
+
+	header := http.Header{}
+	header.Set(WebhookEventHeader, d.EventType)
+	header.Set(WebhookDeliveryHeader, strconv.FormatInt(d.ID, 10))
+
+	start := time.Now()
+	code, err := notify.PostSigned(ctx, s.client, d.URL, d.Secret, body, header)
+	result := internal.WebhookAttemptResult{Duration: time.Since(start)}
+	if code != 0 {
+		result.StatusCode = &code
+	}
+
+	if err == nil {
+		result.Status = internal.DeliveryDelivered
+		return result
+	}
+
+	result.Error = err.Error()
+	attempts := d.Attempts + 1
+	if attempts >= webhookMaxAttempts {
+		result.Status = internal.DeliveryDead
+		return result
+	}
+
+	result.Status = internal.DeliveryPending
+	result.NextAttemptAt = time.Now().Add(webhookRetryDelay(attempts))
+	return result
+}
+
+// webhookRetryDelay doubles the wait after every failed attempt
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookRetryMax)
+}
+
+// PruneWebhookOutbox drops events whose deliveries have all finished and that
+// are older than the retention period
+func (s *Webhooks) PruneWebhookOutbox(ctx context.Context) error {
+	n, err := s.store.PruneWebhookOutbox(ctx, time.Now().Add(-webhookRetention))
+	if err != nil {
+		return fmt.Errorf("failed to prune webhook outbox because %w", err)
+	}
+
+	if n > 0 {
+		slog.Info("pruned webhook outbox", "events", n)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lulzshadowwalker/green-backend/internal"
+)
+
+func TestValidateWebhookSubscriptionRejectsInternalAddresses(t *testing.T) {
+	for _, u := range []string{
+		"http://127.0.0.1/hook",
+		"http://localhost:8080/hook",
+		"http://[::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://0.0.0.0/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[fe80::1]/hook",
+		"http://[fd00:ec2::254]/latest/meta-data/",
+	} {
+		params := internal.WebhookSubscriptionParams{URL: u, EventTypes: internal.WebhookEventTypes[:1]}
+		err := validateWebhookSubscription(context.Background(), &params)
+		var inputErr internal.InputError
+		if !errors.As(err, &inputErr) {
+			t.Errorf("validateWebhookSubscription(%q) error = %v, want an input error", u, err)
+		}
+	}
+
+	params := internal.WebhookSubscriptionParams{URL: "https://203.0.113.7/hook", EventTypes: internal.WebhookEventTypes[:1]}
+	if err := validateWebhookSubscription(context.Background(), &params); err != nil {
+		t.Errorf("validateWebhookSubscription(%q) error = %v", params.URL, err)
+	}
+}
+
+func TestWebhookDialControlChecksResolvedAddress(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:80", "[::1]:443", "169.254.169.254:80"} {
+		if err := webhookDialControl("tcp", address, nil); !errors.Is(err, errBlockedWebhookAddr) {
+			t.Errorf("webhookDialControl(%q) error = %v, want it refused", address, err)
+		}
+	}
+	if err := webhookDialControl("tcp", "203.0.113.7:443", nil); err != nil {
+		t.Errorf("webhookDialControl(203.0.113.7:443) error = %v", err)
+	}
+}
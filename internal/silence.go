@@ -0,0 +1,93 @@
+package internal
+
+import "time"
+
+// SilenceMatcher picks the alerts and controls a silence or maintenance
+// window applies to. Unset fields match anything, so an empty matcher
+// silences everything.
+type SilenceMatcher struct {
+	SensorType *string `json:"sensor_type,omitempty"`
+	Zone       *string `json:"zone,omitempty"`
+	RuleID     *int64  `json:"rule_id,omitempty"`
+}
+
+// MatchesRule reports whether alerts raised by rule are silenced
+func (m SilenceMatcher) MatchesRule(rule AlertRule) bool {
+	if m.SensorType != nil && *m.SensorType != rule.SensorType {
+		return false
+	}
+	if m.Zone != nil && (rule.Zone == nil || *m.Zone != *rule.Zone) {
+		return false
+	}
+	if m.RuleID != nil && *m.RuleID != rule.ID {
+		return false
+	}
+	return true
+}
+
+// MatchesControl reports whether automation of the control for sensorType is
+// suspended. Silences scoped to a rule or a zone never match controls, which
+// belong to neither.
+func (m SilenceMatcher) MatchesControl(sensorType string) bool {
+	if m.RuleID != nil || m.Zone != nil {
+		return false
+	}
+	return m.SensorType == nil || *m.SensorType == sensorType
+}
+
+// AlertSilence is a one-off silence, such as while a tank is cleaned
+type AlertSilence struct {
+	ID int64 `json:"id"`
+	SilenceMatcher
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AlertSilenceParams struct {
+	SilenceMatcher
+	StartsAt  time.Time
+	EndsAt    time.Time
+	Reason    string
+	CreatedBy string
+}
+
+// MaintenanceWindow silences matching alerts and controls at the same time
+// every week, such as every Sunday from 06:00 to 07:00
+type MaintenanceWindow struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	SilenceMatcher
+	// Weekdays lists the days the window opens on, 0 being Sunday. An empty
+	// list means every day.
+	Weekdays  []int     `json:"weekdays"`
+	StartTime string    `json:"start_time"`
+	EndTime   string    `json:"end_time"`
+	Timezone  string    `json:"timezone"`
+	Reason    string    `json:"reason"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type MaintenanceWindowParams struct {
+	Name string
+	SilenceMatcher
+	Weekdays  []int
+	StartTime string
+	EndTime   string
+	Timezone  string
+	Reason    string
+	Enabled   bool
+}
+
+// Suppression is a silence or maintenance window in effect right now
+type Suppression struct {
+	SilenceMatcher
+	// Source is "silence" or "maintenance_window"
+	Source   string `json:"source"`
+	SourceID int64  `json:"source_id"`
+	Reason   string `json:"reason"`
+}
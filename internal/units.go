@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unitConversion converts a value in some unit to the canonical unit it shares
+// a dimension with, and back again
+type unitConversion struct {
+	canonical string
+	toCanon   func(float64) float64
+	fromCanon func(float64) float64
+}
+
+func scale(factor float64) (func(float64) float64, func(float64) float64) {
+	return func(v float64) float64 { return v * factor }, func(v float64) float64 { return v / factor }
+}
+
+var units = func() map[string]unitConversion {
+	identity := func(v float64) float64 { return v }
+	klux, kluxInv := scale(1000)
+	fc, fcInv := scale(10.7639)
+	frac, fracInv := scale(100)
+
+	m := map[string]unitConversion{}
+	add := func(c unitConversion, names ...string) {
+		for _, n := range names {
+			m[n] = c
+		}
+	}
+
+	add(unitConversion{"°C", identity, identity}, "°c", "c", "celsius", "degc")
+	add(unitConversion{"°C", func(v float64) float64 { return (v - 32) * 5 / 9 }, func(v float64) float64 { return v*9/5 + 32 }}, "°f", "f", "fahrenheit", "degf")
+	add(unitConversion{"°C", func(v float64) float64 { return v - 273.15 }, func(v float64) float64 { return v + 273.15 }}, "k", "kelvin")
+	add(unitConversion{"lux", identity, identity}, "lux", "lx")
+	add(unitConversion{"lux", klux, kluxInv}, "klux", "klx")
+	add(unitConversion{"lux", fc, fcInv}, "fc", "footcandle", "footcandles")
+	add(unitConversion{"%", identity, identity}, "%", "percent", "pct")
+	add(unitConversion{"%", frac, fracInv}, "fraction", "ratio")
+
+	return m
+}()
+
+func lookupUnit(sensorType, unit string) (SensorDefinition, unitConversion, error) {
+	def, ok := LookupSensor(sensorType)
+	if !ok {
+		return SensorDefinition{}, unitConversion{}, fmt.Errorf("unknown sensor type %q", sensorType)
+	}
+
+	conv, ok := units[strings.ToLower(strings.TrimSpace(unit))]
+	if !ok {
+		return def, unitConversion{}, fmt.Errorf("unknown unit %q", unit)
+	}
+	if conv.canonical != def.Unit {
+		return def, unitConversion{}, fmt.Errorf("unit %q cannot be used for %s readings measured in %s", unit, def.Type, def.Unit)
+	}
+
+	return def, conv, nil
+}
+
+// ConvertToSensorUnit converts v, measured in unit, to the unit readings of
+// sensorType are stored in. An empty unit means v is already canonical.
+func ConvertToSensorUnit(sensorType, unit string, v float64) (float64, error) {
+	if unit == "" {
+		return v, nil
+	}
+
+	_, conv, err := lookupUnit(sensorType, unit)
+	if err != nil {
+		return 0, err
+	}
+
+	return conv.toCanon(v), nil
+}
+
+// ConvertFromSensorUnit converts a stored value of sensorType into unit
+func ConvertFromSensorUnit(sensorType, unit string, v float64) (float64, error) {
+	if unit == "" {
+		return v, nil
+	}
+
+	_, conv, err := lookupUnit(sensorType, unit)
+	if err != nil {
+		return 0, err
+	}
+
+	return conv.fromCanon(v), nil
+}
@@ -1,5 +1,11 @@
 package internal
 
+// User roles. Admins may use the /api/admin routes.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID           int
 	Username     string
@@ -0,0 +1,101 @@
+package internal
+
+import "time"
+
+// Webhook event types
+const (
+	EventReadingCreated        = "reading.created"
+	EventControlChanged        = "control.changed"
+	EventAlertOpened           = "alert.opened"
+	EventAlertResolved         = "alert.resolved"
+	EventDeviceOnline          = "device.online"
+	EventDeviceOffline         = "device.offline"
+	EventFirmwareRolloutHalted = "firmware.rollout_halted"
+)
+
+// WebhookEventTypes lists every event a subscription may ask for
+var WebhookEventTypes = []string{
+	EventReadingCreated,
+	EventControlChanged,
+	EventAlertOpened,
+	EventAlertResolved,
+	EventDeviceOnline,
+	EventDeviceOffline,
+	EventFirmwareRolloutHalted,
+}
+
+// Webhook delivery states. Dead deliveries have exhausted their retries and
+// stay in the dead-letter queue until retried by hand or pruned.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+type WebhookSubscription struct {
+	ID         int64    `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret is only returned when the subscription is created
+	Secret      string    `json:"secret,omitempty"`
+	Description string    `json:"description"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type WebhookSubscriptionParams struct {
+	URL         string
+	EventTypes  []string
+	Description string
+	Enabled     bool
+}
+
+type WebhookDelivery struct {
+	ID             int64                    `json:"id"`
+	SubscriptionID int64                    `json:"subscription_id"`
+	EventID        int64                    `json:"event_id"`
+	EventType      string                   `json:"event_type"`
+	Status         string                   `json:"status"`
+	Attempts       int                      `json:"attempts"`
+	NextAttemptAt  time.Time                `json:"next_attempt_at"`
+	LastError      string                   `json:"last_error,omitempty"`
+	LastStatusCode *int                     `json:"last_status_code,omitempty"`
+	CreatedAt      time.Time                `json:"created_at"`
+	DeliveredAt    *time.Time               `json:"delivered_at,omitempty"`
+	AttemptLog     []WebhookDeliveryAttempt `json:"attempt_log,omitempty"`
+}
+
+// WebhookDeliveryAttempt logs one try at delivering an event
+type WebhookDeliveryAttempt struct {
+	ID          int64     `json:"id"`
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  *int      `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMs  int       `json:"duration_ms"`
+}
+
+// DueWebhookDelivery is a pending delivery together with what the dispatcher
+// needs to send it
+type DueWebhookDelivery struct {
+	ID             int64
+	SubscriptionID int64
+	Attempts       int
+	EventID        int64
+	EventType      string
+	Payload        []byte
+	EventCreatedAt time.Time
+	URL            string
+	Secret         string
+}
+
+// WebhookAttemptResult is the outcome of one delivery attempt
+type WebhookAttemptResult struct {
+	StatusCode *int
+	Error      string
+	Duration   time.Duration
+	// Status is the delivery's state after the attempt, and NextAttemptAt
+	// when it is retried if still pending
+	Status        string
+	NextAttemptAt time.Time
+}